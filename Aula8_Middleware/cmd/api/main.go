@@ -1,39 +1,279 @@
 package main
 
 import (
+	"aula4/internal/cache"
+	"aula4/internal/grpcapi"
 	"aula4/internal/handler"
 	"aula4/internal/middleware"
-	"aula4/internal/repository"
-	"aula4/internal/repository/storage"
+	"aula4/internal/openapi"
 	"aula4/internal/service"
+	"aula4/internal/tenant"
+	"aula4/internal/utils"
+	"log"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi"
 )
 
+type routerConfig struct {
+	Products   *handler.ProductController
+	Categories *handler.CategoryController
+	Movements  *handler.MovementController
+	Suppliers  *handler.SupplierController
+	Orders     *handler.PurchaseOrderController
+	Webhooks   *handler.WebhookController
+	Stream     *handler.StreamController
+	GraphQL    *handler.GraphQLController
+	Doc        *openapi.Document
+	// Cache serves GET /products and GET /products/{id}, not cached when nil
+	Cache *cache.Cache
+	// Idempotency replays the POST and PATCH retries, a new one keeping the keys for
+	// middleware.DefaultIdempotencyTTL when nil
+	Idempotency *middleware.Idempotency
+	// MaxBodyBytes limits request bodies, openapi.DefaultMaxBodyBytes when zero
+	MaxBodyBytes int64
+	// Public is the catalogue router mounted on /catalogue, nil when it has its own port
+	Public *chi.Mux
+}
+
+type publicRouterConfig struct {
+	Products *handler.ProductController
+	// Cache serves the whole catalogue, not cached when nil
+	Cache *cache.Cache
+}
+
 func main() {
-	st := storage.NewStorageProducts()
-	rp := repository.NewRepositoryProducts(&st)
-	sv := service.NewServiceProducts(&rp)
-	hd := handler.NewHandlerProducts(&sv)
+	// every tenant of TENANTS_FILE gets its own files, services and caches, the requests
+	// without a tenant token or X-Tenant-ID go to the default tenant
+	registry, err := tenant.LoadRegistry(os.Getenv("TENANTS_FILE"))
+	if err != nil {
+		panic(err)
+	}
+
+	maxBodyBytes, _ := strconv.ParseInt(os.Getenv("MAX_BODY_BYTES"), 10, 64)
+	idempotencyTTL, _ := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL"))
+	schedulerInterval, _ := time.ParseDuration(os.Getenv("SCHEDULER_INTERVAL"))
+
+	// the public catalogue gets its own port with PUBLIC_ADDR, it shares the admin
+	// port otherwise
+	publicAddr := os.Getenv("PUBLIC_ADDR")
 
+	cfg := tenantConfig{
+		ImagesDir:          os.Getenv("IMAGES_DIR"),
+		OutboxFile:         os.Getenv("OUTBOX_FILE"),
+		CacheControl:       os.Getenv("CACHE_CONTROL"),
+		PublicCacheControl: os.Getenv("PUBLIC_CACHE_CONTROL"),
+		SchedulerInterval:  schedulerInterval,
+		IdempotencyTTL:     idempotencyTTL,
+		MaxBodyBytes:       maxBodyBytes,
+		SharedPort:         publicAddr == "",
+	}
+
+	services := make(map[string]service.Service)
+	admins := make(map[string]http.Handler)
+	publics := make(map[string]http.Handler)
+	for _, t := range registry.Tenants() {
+		ts, err := newTenantStack(t, cfg)
+		if err != nil {
+			panic(err)
+		}
+		defer ts.Stop()
+
+		services[t.Id] = ts.Products
+		admins[t.Id] = ts.Admin
+		publics[t.Id] = ts.Public
+	}
+
+	// the gRPC API shares the tenants and services of the REST API
+	grpcAddr := os.Getenv("GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = ":9090"
+	}
+	lis, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		panic(err)
+	}
+	grpcServer := grpcapi.NewGRPCServer(grpcapi.NewTenantServer(registry, services))
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			log.Printf("grpc: %v", err)
+		}
+	}()
+	defer grpcServer.GracefulStop()
+
+	if publicAddr != "" {
+		go func() {
+			if err := http.ListenAndServe(publicAddr, newPublicServer(tenant.NewRouter(registry, publics))); err != nil {
+				log.Printf("public: %v", err)
+			}
+		}()
+	}
+
+	if err := http.ListenAndServe(":8080", tenant.NewRouter(registry, admins)); err != nil {
+		panic(err)
+	}
+}
+
+// newAdminRouter serves the full API behind the token, and the public catalogue when
+// cfg.Public is set
+func newAdminRouter(cfg routerConfig) *chi.Mux {
 	rt := chi.NewRouter()
 
 	rt.Use(middleware.LoggingMiddleware)
-	rt.Use(middleware.ValidateToken)
+
+	rt.Get(handler.OpenAPIPath, cfg.Doc.ServeHTTP)
+	rt.Get(handler.DocsPath, openapi.UI(handler.OpenAPIPath))
+
+	validateRequests := cfg.Doc.ValidateRequests(openapi.ValidatorConfig{
+		MaxBodyBytes: cfg.MaxBodyBytes,
+		OnError:      utils.ResponseWithError,
+	})
+
+	idempotency := cfg.Idempotency
+	if idempotency == nil {
+		idempotency = middleware.NewIdempotency(0)
+	}
+
+	cacheResponses := func(next http.Handler) http.Handler { return next }
+	if cfg.Cache != nil {
+		cacheResponses = cfg.Cache.Middleware
+	}
 
 	rt.Route("/products", func(r chi.Router) {
-		r.Get("/", hd.GetAll)
-		r.Get("/{id}", hd.GetById)
-		r.Get("/search", hd.Search)
-		r.Get("/consumer_price", hd.ConsumerPrice)
+		r.Use(middleware.ValidateToken)
+		r.Use(validateRequests)
+		r.Use(idempotency.Middleware)
+
+		hd := cfg.Products
+		r.With(middleware.Gzip, cacheResponses).Get("/", hd.GetAll)
+		r.With(cacheResponses).Get("/{id}", hd.GetById)
+		r.With(middleware.Gzip).Get("/search", hd.Search)
+		r.With(middleware.Gzip).Get("/consumer_price", hd.ConsumerPrice)
+		r.Get("/tags", hd.GetTags)
+		r.Get("/low-stock", hd.GetLowStock)
+		r.Get("/schedule", hd.GetSchedule)
+		r.Get("/stream", cfg.Stream.Stream)
+		r.With(cacheResponses).Get("/by-code/{code}", hd.GetByCode)
+		r.Get("/{id}/variants", hd.GetVariants)
+		r.Get("/{id}/barcode", hd.GetBarcode)
+		r.Get("/{id}/prices", hd.GetPrices)
+		r.Post("/{id}/prices", hd.SchedulePrice)
+		r.Delete("/{id}/prices/{priceId}", hd.CancelPrice)
+		r.Get("/{id}/images/{imageId}", hd.GetImage)
+		r.Get("/{id}/images/{imageId}/thumbnail", hd.GetThumbnail)
 		r.Post("/", hd.Create)
+		r.Post("/{id}/variants", hd.CreateVariant)
+		r.Post("/{id}/images", hd.UploadImage)
+		r.Put("/{id}/images/order", hd.ReorderImages)
+		r.Put("/{id}/images/{imageId}/primary", hd.SetPrimaryImage)
 		r.Put("/{id}", hd.UpdateOrCreate)
 		r.Patch("/{id}", hd.Update)
 		r.Delete("/{id}", hd.Delete)
+		r.Delete("/{id}/images/{imageId}", hd.DeleteImage)
+
+		hm := cfg.Movements
+		r.Get("/{id}/movements", hm.GetAll)
+		r.Post("/{id}/movements", hm.Create)
 	})
 
-	if err := http.ListenAndServe(":8080", rt); err != nil {
-		panic(err)
+	if cfg.Public != nil {
+		rt.Mount("/catalogue", cfg.Public)
+	}
+
+	rt.Route("/categories", func(r chi.Router) {
+		r.Use(middleware.ValidateToken)
+		r.Use(validateRequests)
+		r.Use(idempotency.Middleware)
+
+		hc := cfg.Categories
+		r.Get("/", hc.GetAll)
+		r.Get("/tree", hc.GetTree)
+		r.Get("/{id}", hc.GetById)
+		r.Get("/{id}/summary", hc.GetSummary)
+		r.Post("/", hc.Create)
+		r.Put("/{id}", hc.Update)
+		r.Delete("/{id}", hc.Delete)
+	})
+
+	rt.Route("/suppliers", func(r chi.Router) {
+		r.Use(middleware.ValidateToken)
+		r.Use(validateRequests)
+		r.Use(idempotency.Middleware)
+
+		hs := cfg.Suppliers
+		r.Get("/", hs.GetAll)
+		r.Get("/{id}", hs.GetById)
+		r.Post("/", hs.Create)
+		r.Put("/{id}", hs.Update)
+		r.Delete("/{id}", hs.Delete)
+	})
+
+	rt.Route("/purchase-orders", func(r chi.Router) {
+		r.Use(middleware.ValidateToken)
+		r.Use(validateRequests)
+		r.Use(idempotency.Middleware)
+
+		ho := cfg.Orders
+		r.Get("/", ho.GetAll)
+		r.Get("/inbound", ho.GetInbound)
+		r.Get("/{id}", ho.GetById)
+		r.Post("/", ho.Create)
+		r.Post("/{id}/receive", ho.Receive)
+		r.Post("/{id}/cancel", ho.Cancel)
+	})
+
+	rt.Route("/graphql", func(r chi.Router) {
+		r.Use(middleware.ValidateToken)
+		r.Use(validateRequests)
+
+		r.Post("/", cfg.GraphQL.Serve)
+	})
+
+	rt.Route("/webhooks", func(r chi.Router) {
+		r.Use(middleware.ValidateToken)
+		r.Use(validateRequests)
+		r.Use(idempotency.Middleware)
+
+		hw := cfg.Webhooks
+		r.Get("/", hw.GetAll)
+		r.Get("/deliveries", hw.GetDeliveries)
+		r.Get("/dead-letters", hw.GetDeadLetters)
+		r.Post("/", hw.Create)
+		r.Delete("/{id}", hw.Delete)
+	})
+
+	return rt
+}
+
+// newPublicRouter serves the read-only catalogue without a token: GET only, the products
+// published now and not expired, in the reduced PublicData representation
+func newPublicRouter(cfg publicRouterConfig) *chi.Mux {
+	rt := chi.NewRouter()
+
+	cacheResponses := func(next http.Handler) http.Handler { return next }
+	if cfg.Cache != nil {
+		cacheResponses = cfg.Cache.Middleware
 	}
+	// the images are cached by the clients, the response cache keeps the listings
+	hd := cfg.Products
+	rt.With(middleware.Gzip, cacheResponses).Get("/", hd.GetPublished)
+	rt.With(middleware.Gzip, cacheResponses).Get("/{id}", hd.GetPublishedById)
+	rt.Get("/{id}/images/{imageId}", hd.GetPublishedImage)
+	rt.Get("/{id}/images/{imageId}/thumbnail", hd.GetPublishedThumbnail)
+
+	return rt
+}
+
+// newPublicServer mounts the public router on /catalogue of its own port
+func newPublicServer(public http.Handler) *chi.Mux {
+	rt := chi.NewRouter()
+
+	rt.Use(middleware.LoggingMiddleware)
+	rt.Mount("/catalogue", public)
+
+	return rt
 }
@@ -0,0 +1,582 @@
+package main
+
+import (
+	"aula4/internal/cache"
+	"aula4/internal/gql"
+	"aula4/internal/handler"
+	"aula4/internal/middleware"
+	"aula4/internal/openapi"
+	"aula4/internal/repository"
+	"aula4/internal/service"
+	"aula4/internal/stream"
+	"aula4/internal/tenant"
+	"aula4/internal/utils"
+	"aula4/internal/webhook"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRouter(maxBodyBytes int64) *chi.Mux {
+	mockRepo := repository.NewRepositoryProductsMock()
+	mockCategoryRepo := repository.NewRepositoryCategoriesMock()
+	mockMovementRepo := repository.NewRepositoryMovementsMock()
+	productService := service.NewServiceProducts(&mockRepo)
+	productService.Categories = &mockCategoryRepo
+	productService.Movements = &mockMovementRepo
+	categoryService := service.NewServiceCategories(&mockCategoryRepo, &mockRepo)
+	movementService := service.NewServiceMovements(&mockMovementRepo, &mockRepo)
+	mockSupplierRepo := repository.NewRepositorySuppliersMock()
+	mockOrderRepo := repository.NewRepositoryPurchaseOrdersMock()
+	supplierService := service.NewServiceSuppliers(&mockSupplierRepo)
+	supplierService.Orders = &mockOrderRepo
+	orderService := service.NewServicePurchaseOrders(&mockOrderRepo, &mockSupplierRepo, &mockRepo, &movementService)
+	mockSubscriberRepo := repository.NewRepositorySubscribersMock()
+	webhookService := service.NewServiceWebhooks(&mockSubscriberRepo)
+	dispatcher := webhook.NewDispatcher(&mockSubscriberRepo, webhook.Config{})
+	schema, err := gql.NewSchema(&productService)
+	if err != nil {
+		panic(err)
+	}
+
+	return newAdminRouter(routerConfig{
+		Products:     handler.NewHandlerProducts(&productService),
+		Categories:   handler.NewHandlerCategories(&categoryService),
+		Movements:    handler.NewHandlerMovements(&movementService),
+		Suppliers:    handler.NewHandlerSuppliers(&supplierService),
+		Orders:       handler.NewHandlerPurchaseOrders(&orderService),
+		Webhooks:     handler.NewHandlerWebhooks(&webhookService, dispatcher),
+		Stream:       handler.NewHandlerStream(stream.NewBroker(0), 0),
+		GraphQL:      handler.NewHandlerGraphQL(schema),
+		Doc:          handler.NewOpenAPI(),
+		MaxBodyBytes: maxBodyBytes,
+		Public:       newPublicRouter(publicRouterConfig{Products: handler.NewHandlerProducts(&productService)}),
+	})
+}
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	doc := handler.NewOpenAPI()
+	rt := newTestRouter(0)
+
+	registered := make(map[string]bool)
+	err := chi.Walk(rt, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		key := method + " " + openapi.NormalizePath(route)
+		registered[key] = true
+
+		_, ok := doc.Operation(method, route)
+		require.True(t, ok, "route %s is not documented in the OpenAPI spec", key)
+		return nil
+	})
+	require.NoError(t, err)
+
+	for _, route := range doc.Routes() {
+		require.True(t, registered[route], "documented route %s is not registered", route)
+	}
+
+	public := newPublicServer(newPublicRouter(publicRouterConfig{}))
+	err = chi.Walk(public, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		require.Equal(t, http.MethodGet, method, "the public router is read-only")
+		_, ok := doc.Operation(method, route)
+		require.True(t, ok, "public route %s %s is not documented in the OpenAPI spec", method, route)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	rt := newTestRouter(0)
+
+	req, _ := http.NewRequest("GET", handler.OpenAPIPath, nil)
+	rr := httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, "spec must be served without a token")
+
+	var spec openapi.Document
+	err := json.NewDecoder(rr.Body).Decode(&spec)
+	require.NoError(t, err, "could not decode spec")
+	require.Equal(t, openapi.Version, spec.OpenAPI)
+
+	createBody := spec.Paths["/products"]["post"].RequestBody.Content[openapi.ContentTypeJSON].Schema
+	require.Contains(t, createBody.Properties, "code_value")
+	require.NotContains(t, createBody.Required, "is_published")
+
+	req, _ = http.NewRequest("GET", handler.DocsPath, nil)
+	rr = httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), handler.OpenAPIPath)
+}
+
+func TestValidateRequests(t *testing.T) {
+	validBody := `{"name":"Product A","quantity":5,"code_value":"123yy","is_published":true,"expiration":"01/01/2025","price":10}`
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		contentType  string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "Valid body reaches the handler",
+			method:       "POST",
+			path:         "/products",
+			contentType:  "application/json; charset=utf-8",
+			body:         validBody,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "Unknown field",
+			method:       "POST",
+			path:         "/products",
+			contentType:  "application/json",
+			body:         `{"name":"Product A","quantity":5,"code_valeu":"123yy","code_value":"123yy","expiration":"01/01/2025","price":10}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Missing required field",
+			method:       "POST",
+			path:         "/products",
+			contentType:  "application/json",
+			body:         `{"name":"Product A","quantity":5,"expiration":"01/01/2025","price":10}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Wrong type",
+			method:       "POST",
+			path:         "/products",
+			contentType:  "application/json",
+			body:         `{"name":"Product A","quantity":"5","code_value":"123yy","expiration":"01/01/2025","price":10}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Too long",
+			method:       "POST",
+			path:         "/products",
+			contentType:  "application/json",
+			body:         `{"name":"Product A","quantity":5,"code_value":"` + strings.Repeat("x", 65) + `","expiration":"01/01/2025","price":10}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Not JSON",
+			method:       "POST",
+			path:         "/products",
+			contentType:  "text/plain",
+			body:         validBody,
+			expectedCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:         "Body too large",
+			method:       "POST",
+			path:         "/products",
+			contentType:  "application/json",
+			body:         `{"name":"` + strings.Repeat("x", 2048) + `"}`,
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "Patch clearing a schedule reaches the handler",
+			method:       "PATCH",
+			path:         "/products/684963bb-7172-48ad-aecd-cdca3f0df012",
+			contentType:  "application/json",
+			body:         `{"unpublish_at":null}`,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Image upload that is not multipart",
+			method:       "POST",
+			path:         "/products/684963bb-7172-48ad-aecd-cdca3f0df012/images",
+			contentType:  "application/json",
+			body:         `{}`,
+			expectedCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:         "Cancel without a body reaches the handler",
+			method:       "POST",
+			path:         "/purchase-orders/684963bb-7172-48ad-aecd-cdca3f0df012/cancel",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Receipt without an actor",
+			method:       "POST",
+			path:         "/purchase-orders/684963bb-7172-48ad-aecd-cdca3f0df012/receive",
+			contentType:  "application/json",
+			body:         `{"lines":[]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Data after the body",
+			method:       "POST",
+			path:         "/products",
+			contentType:  "application/json",
+			body:         `{"name":"Product A","quantity":5,"code_value":"TRAIL","is_published":true,"expiration":"01/01/2030","price":10} {}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Scheduled price without a date",
+			method:       "POST",
+			path:         "/products/684963bb-7172-48ad-aecd-cdca3f0df012/prices",
+			contentType:  "application/json",
+			body:         `{"price":12}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Patch with unknown field",
+			method:       "PATCH",
+			path:         "/products/684963bb-7172-48ad-aecd-cdca3f0df012",
+			contentType:  "application/json",
+			body:         `{"nmae":"Product AA"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("TOKEN", "1234")
+			rt := newTestRouter(1024)
+
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Token", "1234")
+			req.Header.Set("Content-Type", tt.contentType)
+
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code, "handler returned wrong status code: %s", rr.Body.String())
+		})
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	os.Setenv("TOKEN", "1234")
+	rt := newTestRouter(0)
+
+	send := func(key string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/products", strings.NewReader(body))
+		req.Header.Set("Token", "1234")
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.HeaderIdempotencyKey, key)
+
+		rr := httptest.NewRecorder()
+		rt.ServeHTTP(rr, req)
+		return rr
+	}
+
+	body := `{"name":"Product A","quantity":5,"code_value":"IDEM","is_published":true,"expiration":"01/01/2030","price":10}`
+
+	first := send("retry-1", body)
+	require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+	require.Empty(t, first.Header().Get(middleware.HeaderIdempotentReplayed))
+
+	retry := send("retry-1", body)
+	require.Equal(t, http.StatusCreated, retry.Code, "a retry replays the first response instead of failing on code_value")
+	require.Equal(t, "true", retry.Header().Get(middleware.HeaderIdempotentReplayed))
+	require.JSONEq(t, first.Body.String(), retry.Body.String())
+
+	reused := send("retry-1", strings.Replace(body, "IDEM", "OTHER", 1))
+	require.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+
+	other := send("retry-2", body)
+	require.Equal(t, http.StatusBadRequest, other.Code, "another key is another request")
+}
+
+func TestIdempotencyKeyExpires(t *testing.T) {
+	now := time.Now()
+	idempotency := middleware.NewIdempotency(time.Minute)
+	idempotency.Now = func() time.Time { return now }
+
+	calls := 0
+	hd := idempotency.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	send := func() {
+		req, _ := http.NewRequest("POST", "/products", strings.NewReader(`{}`))
+		req.Header.Set(middleware.HeaderIdempotencyKey, "key")
+		hd.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	send()
+	send()
+	require.Equal(t, 1, calls)
+
+	now = now.Add(time.Minute)
+	send()
+	require.Equal(t, 2, calls, "the key can be used again once it expired")
+}
+
+func TestIdempotencyKeyReleasedOnPanic(t *testing.T) {
+	idempotency := middleware.NewIdempotency(time.Minute)
+
+	calls := 0
+	hd := idempotency.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			panic("handler failed")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	send := func() *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/products", strings.NewReader(`{}`))
+		req.Header.Set(middleware.HeaderIdempotencyKey, "key")
+		rr := httptest.NewRecorder()
+		hd.ServeHTTP(rr, req)
+		return rr
+	}
+
+	require.Panics(t, func() { send() })
+	rr := send()
+	require.Equal(t, http.StatusCreated, rr.Code, "the retry is not refused as in progress")
+	require.Equal(t, 2, calls)
+}
+
+func TestIdempotencyForgetsTheOldestKey(t *testing.T) {
+	idempotency := middleware.NewIdempotency(time.Minute)
+	idempotency.MaxEntries = 2
+
+	calls := 0
+	hd := idempotency.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	send := func(key string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/products", strings.NewReader(`{}`))
+		req.Header.Set(middleware.HeaderIdempotencyKey, key)
+		rr := httptest.NewRecorder()
+		hd.ServeHTTP(rr, req)
+		return rr
+	}
+
+	send("first")
+	send("second")
+	send("third")
+	require.Equal(t, 3, calls)
+
+	rr := send("third")
+	require.Equal(t, "true", rr.Header().Get(middleware.HeaderIdempotentReplayed))
+	rr = send("second")
+	require.Equal(t, "true", rr.Header().Get(middleware.HeaderIdempotentReplayed))
+	require.Equal(t, 3, calls)
+
+	rr = send("first")
+	require.Empty(t, rr.Header().Get(middleware.HeaderIdempotentReplayed), "the oldest key made room for the third one")
+	require.Equal(t, 4, calls)
+}
+
+func TestIdempotencyBodyTooLarge(t *testing.T) {
+	idempotency := middleware.NewIdempotency(time.Minute)
+	hd := idempotency.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/products", strings.NewReader(strings.Repeat("a", 100)))
+	req.Header.Set(middleware.HeaderIdempotencyKey, "key")
+	req.Body = http.MaxBytesReader(rr, req.Body, 10)
+	hd.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code, rr.Body.String())
+}
+
+func TestGzipAndFormats(t *testing.T) {
+	os.Setenv("TOKEN", "1234")
+	rt := newTestRouter(0)
+
+	get := func(accept string, acceptEncoding string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/products/search?price=0", nil)
+		req.Header.Set("Token", "1234")
+		req.Header.Set("Accept", accept)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+
+		rr := httptest.NewRecorder()
+		rt.ServeHTTP(rr, req)
+		return rr
+	}
+
+	req, _ := http.NewRequest("POST", "/products", strings.NewReader(`{"name":"Product A","quantity":5,"code_value":"GZ","expiration":"01/01/2030","price":10}`))
+	req.Header.Set("Token", "1234")
+	req.Header.Set("Content-Type", "application/json")
+	rt.ServeHTTP(httptest.NewRecorder(), req)
+
+	rr := get("text/csv", "gzip, deflate")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+
+	reader, err := gzip.NewReader(rr.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(body), "id,name,quantity"), string(body))
+
+	rr = get("application/json", "gzip;q=0")
+	require.Empty(t, rr.Header().Get("Content-Encoding"))
+	require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	rr = get("image/png", "gzip")
+	require.Equal(t, http.StatusNotAcceptable, rr.Code)
+}
+
+func TestPublicCatalogue(t *testing.T) {
+	os.Setenv("TOKEN", "1234")
+
+	mockRepo := repository.NewRepositoryProductsMock()
+	productService := service.NewServiceProducts(&mockRepo)
+	publicCache := cache.NewCache(0, cache.PublicCacheControl)
+	productService.Events = publicCache
+
+	public := newPublicRouter(publicRouterConfig{Products: handler.NewHandlerProducts(&productService), Cache: publicCache})
+	admin := newAdminRouter(routerConfig{
+		Products:   handler.NewHandlerProducts(&productService),
+		Categories: handler.NewHandlerCategories(nil),
+		Movements:  handler.NewHandlerMovements(nil),
+		Webhooks:   handler.NewHandlerWebhooks(nil, nil),
+		Stream:     handler.NewHandlerStream(stream.NewBroker(0), 0),
+		GraphQL:    handler.NewHandlerGraphQL(nil),
+		Doc:        handler.NewOpenAPI(),
+	})
+
+	create := func(body string) {
+		req, _ := http.NewRequest("POST", "/products", strings.NewReader(body))
+		req.Header.Set("Token", "1234")
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		admin.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	list := func() *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/catalogue", nil)
+		rr := httptest.NewRecorder()
+		newPublicServer(public).ServeHTTP(rr, req)
+		return rr
+	}
+
+	create(`{"name":"Live","quantity":2,"code_value":"LIVE","is_published":true,"expiration":"01/01/2030","price":10}`)
+	create(`{"name":"Expired","quantity":5,"code_value":"EXPIRED","is_published":true,"expiration":"01/01/2020","price":10}`)
+	create(`{"name":"Draft","quantity":5,"code_value":"DRAFT","is_published":false,"expiration":"01/01/2030","price":10}`)
+
+	rr := list()
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, "MISS", rr.Header().Get(cache.HeaderCache))
+	require.Equal(t, cache.PublicCacheControl, rr.Header().Get("Cache-Control"))
+
+	var products []map[string]any
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&products))
+	require.Len(t, products, 1, "neither the expired nor the unpublished products are public")
+	require.Equal(t, "LIVE", products[0]["code_value"])
+	require.Equal(t, true, products[0]["in_stock"])
+	for _, field := range []string{"quantity", "is_published", "low_stock_threshold", "publish_at"} {
+		require.NotContains(t, products[0], field)
+	}
+
+	require.Equal(t, "HIT", list().Header().Get(cache.HeaderCache))
+
+	create(`{"name":"New","quantity":1,"code_value":"NEW","is_published":true,"expiration":"01/01/2030","price":10}`)
+	rr = list()
+	require.Equal(t, "MISS", rr.Header().Get(cache.HeaderCache), "a write empties the public cache")
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&products))
+	require.Len(t, products, 2)
+
+	req, _ := http.NewRequest("POST", "/catalogue", strings.NewReader(`{}`))
+	rr = httptest.NewRecorder()
+	newPublicServer(public).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code, "the public router is read-only")
+
+	req, _ = http.NewRequest("GET", "/products", nil)
+	rr = httptest.NewRecorder()
+	newPublicServer(public).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code, "the public port has no admin routes")
+
+	req, _ = http.NewRequest("GET", "/catalogue", nil)
+	rr = httptest.NewRecorder()
+	admin.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code, "the admin router has no catalogue when it has its own port")
+}
+
+func TestTenants(t *testing.T) {
+	os.Setenv("TOKEN", "1234")
+
+	registry, err := tenant.NewRegistry(
+		tenant.Tenant{Id: "shop-a", Token: "token-a", Pricing: []service.PricingTier{{MinQuantity: 0, Tax: 1}}},
+		tenant.Tenant{Id: "shop-b"},
+	)
+	require.NoError(t, err)
+
+	admins := make(map[string]http.Handler)
+	for _, tn := range registry.Tenants() {
+		ts, err := newTenantStack(tn, tenantConfig{Dir: t.TempDir(), SchedulerInterval: time.Hour, SharedPort: true})
+		require.NoError(t, err)
+		t.Cleanup(ts.Stop)
+		admins[tn.Id] = ts.Admin
+	}
+	rt := tenant.NewRouter(registry, admins)
+
+	do := func(method, path, token, tenantId, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Token", token)
+		req.Header.Set("Content-Type", "application/json")
+		if tenantId != "" {
+			req.Header.Set(tenant.HeaderTenantID, tenantId)
+		}
+		rr := httptest.NewRecorder()
+		rt.ServeHTTP(rr, req)
+		return rr
+	}
+
+	product := `{"name":"Shared code","quantity":2,"code_value":"SAME","is_published":true,"expiration":"01/01/2030","price":10}`
+
+	tests := []struct {
+		name         string
+		token        string
+		tenantId     string
+		expectedCode int
+	}{
+		{name: "Tenant token", token: "token-a", expectedCode: http.StatusCreated},
+		{name: "Shared token and header", token: "1234", tenantId: "shop-b", expectedCode: http.StatusCreated},
+		{name: "Default tenant", token: "1234", expectedCode: http.StatusCreated},
+		{name: "Code taken in the tenant", token: "token-a", tenantId: "shop-a", expectedCode: http.StatusBadRequest},
+		{name: "Token of another tenant", token: "token-a", tenantId: "shop-b", expectedCode: http.StatusForbidden},
+		{name: "Shared token on a tenant with its own", token: "1234", tenantId: "shop-a", expectedCode: http.StatusUnauthorized},
+		{name: "Unknown tenant", token: "1234", tenantId: "shop-z", expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := do("POST", "/products", tt.token, tt.tenantId, product)
+			require.Equal(t, tt.expectedCode, rr.Code, rr.Body.String())
+			require.Contains(t, rr.Header().Values("Vary"), tenant.HeaderTenantID)
+		})
+	}
+
+	quote := func(token, tenantId string) float64 {
+		rr := do("GET", "/products/consumer_price", token, tenantId, "")
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		var body utils.ResponseBodyTotalPrice
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+		require.Len(t, body.Products, 1, "a tenant only sees its own products")
+		return body.TotalPrice
+	}
+
+	require.InDelta(t, 10, quote("token-a", ""), 1e-9, "shop-a has its own pricing tiers")
+	require.InDelta(t, 10*service.TaxLessThanTen, quote("1234", "shop-b"), 1e-9)
+
+	rr := do("GET", "/catalogue", "", "shop-b", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var public []utils.PublicData
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&public))
+	require.Len(t, public, 1, "the public catalogue of a tenant needs no token")
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"aula4/internal/barcode"
+	"aula4/internal/repository/storage"
+	"aula4/internal/tenant"
+	"aula4/internal/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The stress test fires concurrent mixed requests at an in-process server over the JSON
+// storages, run it with -race. The requests are random but seeded by worker, the final state
+// must hold the invariants whatever order the requests ran in.
+
+const (
+	stressWorkers  = 6
+	stressRequests = 25
+	// stressBurst are the requests every worker sends at once to each stock product after the mixed traffic
+	stressBurst = 6
+	// stressStock are the products that get the movements, the stock patches and the scheduled prices
+	stressStock         = 3
+	stressStockQuantity = 50
+)
+
+// stressCodes collide on purpose, the GTINs are the same product spelled two ways
+var stressCodes = []struct {
+	Format string
+	Value  string
+}{
+	{"", "STRESS-1"},
+	{"", "STRESS-2"},
+	{"", "STRESS-3"},
+	{barcode.FormatEAN13, "4006381333931"},
+	{barcode.FormatGTIN14, "04006381333931"},
+}
+
+// stressState is what the workers know of the products and what they expect of the final state
+type stressState struct {
+	mu sync.Mutex
+	// ids of the products the workers create, replace, patch and delete
+	ids []string
+
+	stock []string
+	// scheduled are the ids of the accepted scheduled prices of each stock product
+	scheduled map[string][]string
+
+	creates atomic.Int64
+	deleted atomic.Int64
+}
+
+// created counts the product of a 201 answer and lets the workers pick it
+func (s *stressState) created(t *testing.T, content []byte) bool {
+	id, ok := productId(t, content)
+	if !ok {
+		return false
+	}
+
+	s.creates.Add(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids = append(s.ids, id)
+	return true
+}
+
+func (s *stressState) schedule(t *testing.T, stockId string, content []byte) bool {
+	var change utils.PriceChangeData
+	if !assert.NoError(t, json.Unmarshal(content, &change)) {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scheduled[stockId] = append(s.scheduled[stockId], change.Id)
+	return true
+}
+
+func (s *stressState) pick(r *rand.Rand) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.ids) == 0 {
+		return "", false
+	}
+	return s.ids[r.Intn(len(s.ids))], true
+}
+
+type stressClient struct {
+	t      *testing.T
+	server *httptest.Server
+}
+
+// expect reports a status the request may not answer, the body tells which request it was.
+// It runs in the workers, so it fails the test without stopping it and returns false.
+func (c stressClient) expect(method, path, body string, codes ...int) (int, []byte, bool) {
+	req, err := http.NewRequest(method, c.server.URL+path, strings.NewReader(body))
+	if !assert.NoError(c.t, err) {
+		return 0, nil, false
+	}
+	req.Header.Set("Token", "1234")
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.server.Client().Do(req)
+	if !assert.NoError(c.t, err) {
+		return 0, nil, false
+	}
+	defer res.Body.Close()
+
+	content, err := io.ReadAll(res.Body)
+	if !assert.NoError(c.t, err) {
+		return 0, nil, false
+	}
+	ok := assert.Contains(c.t, codes, res.StatusCode, "%s %s %s: %s", method, path, body, content)
+	return res.StatusCode, content, ok
+}
+
+// stressCode is the code_value and code_format fields of a random code
+func stressCode(r *rand.Rand) string {
+	code := stressCodes[r.Intn(len(stressCodes))]
+	if code.Format == "" {
+		return fmt.Sprintf(`"code_value":%q`, code.Value)
+	}
+	return fmt.Sprintf(`"code_value":%q,"code_format":%q`, code.Value, code.Format)
+}
+
+func stressProduct(r *rand.Rand, name string) string {
+	return fmt.Sprintf(`{"name":%q,"quantity":%d,%s,"is_published":true,"expiration":"01/01/2030","price":%d}`,
+		name, r.Intn(10), stressCode(r), 1+r.Intn(20))
+}
+
+func productId(t *testing.T, content []byte) (string, bool) {
+	var response utils.ResponseBodyProduct
+	if !assert.NoError(t, json.Unmarshal(content, &response)) || !assert.NotNil(t, response.Data, string(content)) {
+		return "", false
+	}
+	return response.Data.Id, true
+}
+
+// stressWorker sends stressRequests random requests, the outcomes it counts are compared to
+// the final state. It stops at the first unexpected answer.
+func stressWorker(c stressClient, state *stressState, worker int) {
+	r := rand.New(rand.NewSource(int64(worker)))
+
+	for i := 0; i < stressRequests; i++ {
+		stockId := state.stock[r.Intn(stressStock)]
+		id, known := state.pick(r)
+
+		var ok bool
+		switch op := r.Intn(11); {
+		case op == 0 || !known:
+			var code int
+			var content []byte
+			code, content, ok = c.expect("POST", "/products", stressProduct(r, fmt.Sprintf("Worker %d %d", worker, i)),
+				http.StatusCreated, http.StatusBadRequest)
+			if ok && code == http.StatusCreated {
+				ok = state.created(c.t, content)
+			}
+		case op == 1:
+			// a product deleted meanwhile is created again, under a new id
+			var code int
+			var content []byte
+			code, content, ok = c.expect("PUT", "/products/"+id, stressProduct(r, "Replaced"),
+				http.StatusOK, http.StatusCreated, http.StatusBadRequest)
+			if ok && code == http.StatusCreated {
+				ok = state.created(c.t, content)
+			}
+		case op == 2:
+			patch := fmt.Sprintf(`{"name":"Patched","price":%d,%s}`, 1+r.Intn(20), stressCode(r))
+			_, _, ok = c.expect("PATCH", "/products/"+id, patch, http.StatusOK, http.StatusNotFound, http.StatusBadRequest)
+		case op == 3:
+			var code int
+			code, _, ok = c.expect("DELETE", "/products/"+id, "", http.StatusNoContent, http.StatusNotFound)
+			if code == http.StatusNoContent {
+				state.deleted.Add(1)
+			}
+		case op <= 6:
+			movementType := storage.MovementSale
+			if op == 6 {
+				movementType = storage.MovementReceipt
+			}
+			quantity := 1 + r.Intn(8)
+			body := fmt.Sprintf(`{"type":%q,"quantity":%d,"actor":"worker %d"}`, movementType, quantity, worker)
+			_, _, ok = c.expect("POST", "/products/"+stockId+"/movements", body, http.StatusCreated, http.StatusConflict)
+		case op == 7:
+			// every worker and request schedules at its own time
+			at := time.Now().Add(time.Duration(24*(1+worker*stressRequests+i)) * time.Hour).UTC().Format(time.RFC3339)
+			body := fmt.Sprintf(`{"price":%d,"effective_at":%q}`, 1+r.Intn(20), at)
+			var content []byte
+			_, content, ok = c.expect("POST", "/products/"+stockId+"/prices", body, http.StatusCreated)
+			if ok {
+				ok = state.schedule(c.t, stockId, content)
+			}
+		case op == 8:
+			// a quote publishes the quoted products, it must not put back an older quantity
+			_, _, ok = c.expect("GET", "/products/consumer_price?ids="+stockId, "", http.StatusOK, http.StatusBadRequest)
+		case op == 9:
+			// the patches race the movements and the scheduled prices of the same product
+			patch := fmt.Sprintf(`{"quantity":%d,"price":%d}`, r.Intn(60), 1+r.Intn(20))
+			_, _, ok = c.expect("PATCH", "/products/"+stockId, patch, http.StatusOK)
+		default:
+			_, _, ok = c.expect("GET", "/products/"+id, "", http.StatusOK, http.StatusNotFound)
+			if ok {
+				_, _, ok = c.expect("GET", "/products", "", http.StatusOK)
+			}
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+// stressStockWorker sends patches, scheduled prices and movements to a single stock product,
+// each one a read-modify-write of the same product
+func stressStockWorker(c stressClient, state *stressState, stockId string, worker int) {
+	r := rand.New(rand.NewSource(int64(stressWorkers + worker)))
+
+	for i := 0; i < stressBurst; i++ {
+		var ok bool
+		switch i % 3 {
+		case 0:
+			patch := fmt.Sprintf(`{"quantity":%d,"price":%d}`, r.Intn(60), 1+r.Intn(20))
+			_, _, ok = c.expect("PATCH", "/products/"+stockId, patch, http.StatusOK)
+		case 1:
+			// after the days the mixed traffic scheduled at, every worker and request at its own day
+			day := 1 + stressWorkers*stressRequests + worker*stressBurst + i
+			at := time.Now().Add(time.Duration(24*day) * time.Hour).UTC().Format(time.RFC3339)
+			var content []byte
+			_, content, ok = c.expect("POST", "/products/"+stockId+"/prices", fmt.Sprintf(`{"price":%d,"effective_at":%q}`, 1+r.Intn(20), at), http.StatusCreated)
+			if ok {
+				ok = state.schedule(c.t, stockId, content)
+			}
+		default:
+			body := fmt.Sprintf(`{"type":%q,"quantity":%d,"actor":"worker %d"}`, storage.MovementReceipt, 1+r.Intn(8), worker)
+			_, _, ok = c.expect("POST", "/products/"+stockId+"/movements", body, http.StatusCreated)
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+func TestConcurrentRequests(t *testing.T) {
+	os.Setenv("TOKEN", "1234")
+
+	ts, err := newTenantStack(tenant.Tenant{Id: storage.DefaultTenant}, tenantConfig{Dir: t.TempDir(), SchedulerInterval: time.Hour})
+	require.NoError(t, err)
+	t.Cleanup(ts.Stop)
+	server := httptest.NewServer(ts.Admin)
+	t.Cleanup(server.Close)
+	c := stressClient{t: t, server: server}
+
+	state := &stressState{scheduled: make(map[string][]string)}
+	for i := 0; i < stressStock; i++ {
+		body := fmt.Sprintf(`{"name":"Stock %d","quantity":%d,"code_value":"STOCK-%d","is_published":true,"expiration":"01/01/2030","price":5}`,
+			i, stressStockQuantity, i)
+		_, content, ok := c.expect("POST", "/products", body, http.StatusCreated)
+		require.True(t, ok)
+		id, ok := productId(t, content)
+		require.True(t, ok)
+		state.stock = append(state.stock, id)
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < stressWorkers; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			stressWorker(c, state, worker)
+		}(worker)
+	}
+	wg.Wait()
+	require.False(t, t.Failed(), "the workers had unexpected answers")
+
+	for _, stockId := range state.stock {
+		for worker := 0; worker < stressWorkers; worker++ {
+			wg.Add(1)
+			go func(stockId string, worker int) {
+				defer wg.Done()
+				stressStockWorker(c, state, stockId, worker)
+			}(stockId, worker)
+		}
+	}
+	wg.Wait()
+	require.False(t, t.Failed(), "the stock workers had unexpected answers")
+
+	products, err := ts.Products.GetAll()
+	require.NoError(t, err)
+	require.Len(t, products, stressStock+int(state.creates.Load()-state.deleted.Load()), "no create or delete is lost")
+
+	keys := make(map[string]string)
+	for _, product := range products {
+		require.GreaterOrEqual(t, product.Quantity, 0, "quantity of %s", product.Id)
+
+		key := barcode.Key(product.Code_format, product.Code_value)
+		require.NotContains(t, keys, key, "%s and %s share the code %q", product.Id, keys[key], product.Code_value)
+		keys[key] = product.Id
+
+		_, content, ok := c.expect("GET", "/products/by-code/"+product.Code_value, "", http.StatusOK)
+		require.True(t, ok)
+		var found storage.Product
+		require.NoError(t, json.Unmarshal(content, &found))
+		require.Equal(t, product.Id, found.Id, "the index finds %q", product.Code_value)
+	}
+
+	for _, id := range state.stock {
+		product, err := ts.Products.GetById(id)
+		require.NoError(t, err)
+
+		prices := make(map[string]bool)
+		for _, change := range product.Prices {
+			prices[change.Id] = true
+		}
+		for _, priceId := range state.scheduled[id] {
+			require.True(t, prices[priceId], "the scheduled price %s of %s is lost", priceId, id)
+		}
+
+		_, content, ok := c.expect("GET", "/products/"+id+"/movements", "", http.StatusOK)
+		require.True(t, ok)
+		var ledger utils.LedgerData
+		require.NoError(t, json.Unmarshal(content, &ledger))
+		require.Equal(t, product.Quantity, ledger.Balance, "the movements of %s add up to its quantity", id)
+		require.True(t, ledger.Reconciled)
+	}
+}
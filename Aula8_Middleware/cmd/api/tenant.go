@@ -0,0 +1,204 @@
+package main
+
+import (
+	"aula4/internal/blob"
+	"aula4/internal/cache"
+	"aula4/internal/gql"
+	"aula4/internal/handler"
+	"aula4/internal/middleware"
+	"aula4/internal/outbox"
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"aula4/internal/search"
+	"aula4/internal/service"
+	"aula4/internal/stream"
+	"aula4/internal/tenant"
+	"aula4/internal/webhook"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+const defaultImagesDir = "../../docs/db/images"
+
+type tenantConfig struct {
+	// Dir holds the json files of the tenants, the default paths of the storages when empty
+	Dir string
+	// ImagesDir holds the images of the tenants, each in its own directory, defaultImagesDir when empty
+	ImagesDir string
+	// OutboxFile also writes the outbox entries of every tenant to a file, its name carrying the tenant
+	OutboxFile         string
+	CacheControl       string
+	PublicCacheControl string
+	SchedulerInterval  time.Duration
+	IdempotencyTTL     time.Duration
+	MaxBodyBytes       int64
+	// SharedPort mounts the public router on /catalogue of the admin router
+	SharedPort bool
+}
+
+func (cfg tenantConfig) imagesDir() string {
+	if cfg.ImagesDir != "" {
+		return cfg.ImagesDir
+	}
+	if cfg.Dir != "" {
+		return filepath.Join(cfg.Dir, "images")
+	}
+	return defaultImagesDir
+}
+
+// tenantStack is everything a tenant owns: its storage files, services, caches, background
+// workers and routers. Nothing is shared between tenants, so code_value is only unique
+// within a tenant.
+type tenantStack struct {
+	Tenant   tenant.Tenant
+	Products *service.ServiceProducts
+	Admin    *chi.Mux
+	Public   *chi.Mux
+
+	stop []func()
+}
+
+func newTenantStack(t tenant.Tenant, cfg tenantConfig) (*tenantStack, error) {
+	st := storage.NewStorageProducts()
+	st.Outbox = true
+	st.Tenant = t.Id
+	stc := storage.NewStorageCategories()
+	stc.Tenant = t.Id
+	stm := storage.NewStorageMovements()
+	stm.Tenant = t.Id
+	sts := storage.NewStorageSubscribers()
+	sts.Tenant = t.Id
+	stsp := storage.NewStorageSuppliers()
+	stsp.Tenant = t.Id
+	stpo := storage.NewStoragePurchaseOrders()
+	stpo.Tenant = t.Id
+	if cfg.Dir != "" {
+		st.Path = filepath.Join(cfg.Dir, "products.json")
+		stc.Path = filepath.Join(cfg.Dir, "categories.json")
+		stm.Path = filepath.Join(cfg.Dir, "movements.json")
+		sts.Path = filepath.Join(cfg.Dir, "subscribers.json")
+		stsp.Path = filepath.Join(cfg.Dir, "suppliers.json")
+		stpo.Path = filepath.Join(cfg.Dir, "purchase_orders.json")
+	}
+
+	rp := repository.NewRepositoryProducts(&st)
+	index := search.NewIndex()
+	irp, err := search.NewIndexedRepository(&rp, index)
+	if err != nil {
+		return nil, err
+	}
+	rpc := repository.NewRepositoryCategories(&stc)
+	rpm := repository.NewRepositoryMovements(&stm)
+	rps := repository.NewRepositorySubscribers(&sts)
+	rpsp := repository.NewRepositorySuppliers(&stsp)
+	rppo := repository.NewRepositoryPurchaseOrders(&stpo)
+
+	ts := &tenantStack{Tenant: t}
+
+	dispatcher := webhook.NewDispatcher(&rps, webhook.Config{})
+	dispatcher.Start()
+	ts.stop = append(ts.stop, dispatcher.Stop)
+
+	sv := service.NewServiceProducts(&irp)
+	sv.Index = index
+	sv.Categories = &rpc
+	sv.Movements = &rpm
+	sv.Pricing = t.Pricing
+	sv.Blobs = blob.NewFileStore(storage.TenantPath(cfg.imagesDir(), t.Id))
+	if t.CodePattern != "" {
+		pattern, err := regexp.Compile(t.CodePattern)
+		if err != nil {
+			ts.Stop()
+			return nil, err
+		}
+		sv.CodePattern = pattern
+	}
+	broker := stream.NewBroker(0)
+
+	// the events come from the outbox of the products storage instead of the services,
+	// so a change is never lost between the write and the delivery
+	sinks := []outbox.Sink{
+		outbox.LogSink{},
+		outbox.NewPublisherSink("webhooks", dispatcher),
+		outbox.NewPublisherSink("stream", broker),
+	}
+	if cfg.OutboxFile != "" {
+		sinks = append(sinks, outbox.NewFileSink(storage.TenantPath(cfg.OutboxFile, t.Id)))
+	}
+	outboxDispatcher := outbox.NewDispatcher(&st, 0, sinks...)
+	outboxDispatcher.Start()
+	ts.stop = append(ts.stop, outboxDispatcher.Stop)
+
+	// the services empty the response caches right after their writes
+	responseCache := cache.NewCache(0, cfg.CacheControl)
+	publicCacheControl := cfg.PublicCacheControl
+	if publicCacheControl == "" {
+		publicCacheControl = cache.PublicCacheControl
+	}
+	publicCache := cache.NewCache(0, publicCacheControl)
+	// the expirations and the scheduled changes end the stored responses, no write announces them
+	responseCache.NextChange = sv.NextChange
+	publicCache.NextChange = sv.NextChange
+	caches := service.Publishers{responseCache, publicCache}
+	sv.Events = caches
+
+	svc := service.NewServiceCategories(&rpc, &irp)
+	svc.Events = caches
+	svm := service.NewServiceMovements(&rpm, &irp)
+	svm.Events = caches
+	svw := service.NewServiceWebhooks(&rps)
+	svsp := service.NewServiceSuppliers(&rpsp)
+	svsp.Orders = &rppo
+	// the receipts of the orders go through the movements, like any other stock change
+	svpo := service.NewServicePurchaseOrders(&rppo, &rpsp, &irp, &svm)
+
+	scheduler := service.NewScheduler(&sv, cfg.SchedulerInterval)
+	scheduler.Start()
+	ts.stop = append(ts.stop, scheduler.Stop)
+
+	schema, err := gql.NewSchema(&sv)
+	if err != nil {
+		ts.Stop()
+		return nil, err
+	}
+
+	ts.Products = &sv
+	ts.Public = newPublicRouter(publicRouterConfig{
+		Products: handler.NewHandlerProducts(&sv),
+		Cache:    publicCache,
+	})
+
+	adminPublic := ts.Public
+	if !cfg.SharedPort {
+		adminPublic = nil
+	}
+
+	ts.Admin = newAdminRouter(routerConfig{
+		Products:     handler.NewHandlerProducts(&sv),
+		Categories:   handler.NewHandlerCategories(&svc),
+		Movements:    handler.NewHandlerMovements(&svm),
+		Suppliers:    handler.NewHandlerSuppliers(&svsp),
+		Orders:       handler.NewHandlerPurchaseOrders(&svpo),
+		Webhooks:     handler.NewHandlerWebhooks(&svw, dispatcher),
+		Stream:       handler.NewHandlerStream(broker, 0),
+		GraphQL:      handler.NewHandlerGraphQL(schema),
+		Doc:          handler.NewOpenAPI(),
+		Cache:        responseCache,
+		Idempotency:  middleware.NewIdempotency(cfg.IdempotencyTTL),
+		MaxBodyBytes: cfg.MaxBodyBytes,
+		Public:       adminPublic,
+	})
+
+	return ts, nil
+}
+
+// Stop stops the background workers, the last started first
+func (ts *tenantStack) Stop() {
+	for i := len(ts.stop) - 1; i >= 0; i-- {
+		ts.stop[i]()
+	}
+	ts.stop = nil
+}
@@ -0,0 +1,240 @@
+// Command migrate moves the products between storages and migrates the SQLite schema.
+//
+//	migrate copy -from json:products.json -to sqlite:products.db [-replace]
+//	migrate verify -from json:products.json -to sqlite:products.db
+//	migrate status sqlite:products.db
+//	migrate schema -db products.db [-to VERSION]
+//
+// It runs offline, the API must be stopped while it writes.
+package main
+
+import (
+	"aula4/internal/migrate"
+	"aula4/internal/repository/storage"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const usage = `Usage:
+  migrate copy -from LOCATION -to LOCATION [-replace]   copy the products and verify the count and the checksum
+  migrate verify -from LOCATION -to LOCATION            compare the count and the checksum of two storages
+  migrate status LOCATION                               count, checksum and schema version of a storage
+  migrate schema -db FILE [-to VERSION]                 run the SQLite schema migrations up or down, to the latest by default
+
+A LOCATION is json:FILE or sqlite:FILE.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	commands := map[string]func([]string, io.Writer) error{
+		"copy":   copyProducts,
+		"verify": verify,
+		"status": status,
+		"schema": schema,
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "migrate: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	if err := command(args[1:], stdout); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fmt.Fprint(stderr, usage)
+			return 2
+		}
+		fmt.Fprintf(stderr, "migrate %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func copyProducts(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("copy", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	from := flags.String("from", "", "source location")
+	to := flags.String("to", "", "destination location")
+	replace := flags.Bool("replace", false, "replace the products of the destination")
+	if err := flags.Parse(args); err != nil || *from == "" || *to == "" || flags.NArg() > 0 {
+		return flag.ErrHelp
+	}
+
+	source, closeSource, err := open(*from, false)
+	if err != nil {
+		return err
+	}
+	defer closeSource()
+
+	destination, closeDestination, err := open(*to, true)
+	if err != nil {
+		return err
+	}
+	defer closeDestination()
+
+	report, err := migrate.Copy(source, destination, *replace)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "copied %d products, checksum %s\n", report.Destination.Count, report.Destination.Checksum)
+	return nil
+}
+
+func verify(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	from := flags.String("from", "", "source location")
+	to := flags.String("to", "", "destination location")
+	if err := flags.Parse(args); err != nil || *from == "" || *to == "" || flags.NArg() > 0 {
+		return flag.ErrHelp
+	}
+
+	var summaries []migrate.Summary
+	for _, location := range []string{*from, *to} {
+		st, closeStorage, err := open(location, false)
+		if err != nil {
+			return err
+		}
+		summary, err := migrate.Summarize(st)
+		closeStorage()
+		if err != nil {
+			return err
+		}
+		summaries = append(summaries, summary)
+	}
+
+	if summaries[0] != summaries[1] {
+		return fmt.Errorf("%w: %d products with checksum %s, %d products with checksum %s", migrate.ErrVerificationFailed,
+			summaries[0].Count, summaries[0].Checksum, summaries[1].Count, summaries[1].Checksum)
+	}
+
+	fmt.Fprintf(stdout, "%d products, checksum %s\n", summaries[0].Count, summaries[0].Checksum)
+	return nil
+}
+
+func status(args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return flag.ErrHelp
+	}
+
+	if path, ok := strings.CutPrefix(args[0], "sqlite:"); ok {
+		db, err := sql.Open(storage.SQLiteDriver, path)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		version, err := storage.SQLiteSchemaVersion(db)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "schema version %d, latest known %d\n", version, storage.LatestSQLiteSchemaVersion())
+	}
+
+	st, closeStorage, err := open(args[0], false)
+	if err != nil {
+		return err
+	}
+	defer closeStorage()
+
+	summary, err := migrate.Summarize(st)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "%d products, checksum %s\n", summary.Count, summary.Checksum)
+	return nil
+}
+
+func schema(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("schema", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	path := flags.String("db", "", "SQLite database file")
+	to := flags.Int("to", storage.LatestSQLiteSchemaVersion(), "schema version")
+	if err := flags.Parse(args); err != nil || *path == "" || flags.NArg() > 0 {
+		return flag.ErrHelp
+	}
+
+	db, err := sql.Open(storage.SQLiteDriver, *path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	applied, err := storage.MigrateSQLite(db, *to)
+	for _, migration := range applied {
+		fmt.Fprintf(stdout, "applied %d %s\n", migration.Version, migration.Description)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "schema version %d\n", *to)
+	return nil
+}
+
+// open returns the storage of a location. A SQLite destination is migrated to the latest
+// schema, a SQLite source must already have it.
+func open(location string, destination bool) (storage.Storage, func() error, error) {
+	kind, path, ok := strings.Cut(location, ":")
+	if !ok || path == "" {
+		return nil, nil, fmt.Errorf("invalid location %q, expected json:FILE or sqlite:FILE", location)
+	}
+
+	switch kind {
+	case "json":
+		if !destination {
+			if _, err := os.Stat(path); err != nil {
+				return nil, nil, err
+			}
+		}
+		st := storage.NewStorageProducts()
+		st.Path = path
+		// a file with an outbox keeps it, so the pending events are still dispatched
+		if content, err := os.ReadFile(path); err == nil {
+			st.Outbox = strings.HasPrefix(strings.TrimSpace(string(content)), "{")
+		}
+		return &st, func() error { return nil }, nil
+	case "sqlite":
+		if !destination {
+			if _, err := os.Stat(path); err != nil {
+				return nil, nil, err
+			}
+		}
+		db, err := sql.Open(storage.SQLiteDriver, path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if destination {
+			_, err = storage.MigrateSQLite(db, storage.LatestSQLiteSchemaVersion())
+		} else {
+			err = storage.CheckSQLiteSchema(db)
+		}
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+
+		st := storage.NewStorageProductsSQLite(db)
+		return &st, db.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage %q, expected json or sqlite", kind)
+	}
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"aula4/internal/utils"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (c *cli) list(args []string) error {
+	flags := newFlagSet("list")
+	category := flags.String("category", "", "category id")
+	tag := flags.String("tag", "", "tag")
+	if err := parseFlags(flags, args, 0); err != nil {
+		return err
+	}
+
+	products, err := c.Client.List(*category, *tag)
+	if err != nil {
+		return err
+	}
+
+	sortProducts(products)
+	return c.print(products, func(w io.Writer) { writeProductTable(w, products...) })
+}
+
+func (c *cli) show(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	product, err := c.Client.Get(args[0])
+	if err != nil {
+		return err
+	}
+
+	return c.print(product, func(w io.Writer) {
+		writeProductTable(w, product.Product)
+		if len(product.Variants) > 0 {
+			fmt.Fprintln(w, "\nVariants:")
+			sortProducts(product.Variants)
+			writeProductTable(w, product.Variants...)
+		}
+	})
+}
+
+func (c *cli) create(args []string) error {
+	flags := newFlagSet("create")
+	file := flags.String("f", "", "JSON file of the product, - for stdin")
+	if err := parseFlags(flags, args, 0); err != nil || *file == "" {
+		return errUsage
+	}
+
+	var body utils.RequestBodyProduct
+	if err := c.readJSON(*file, &body); err != nil {
+		return err
+	}
+
+	product, err := c.Client.Create(body)
+	if err != nil {
+		return err
+	}
+	return c.print(product, func(w io.Writer) { writeDataTable(w, product) })
+}
+
+func (c *cli) update(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	flags := newFlagSet("update")
+	file := flags.String("f", "", "JSON file of the product, - for stdin")
+	if err := parseFlags(flags, args[1:], 0); err != nil || *file == "" {
+		return errUsage
+	}
+
+	var body utils.RequestBodyProduct
+	if err := c.readJSON(*file, &body); err != nil {
+		return err
+	}
+
+	product, err := c.Client.Update(args[0], body)
+	if err != nil {
+		return err
+	}
+	return c.print(product, func(w io.Writer) { writeDataTable(w, product) })
+}
+
+func (c *cli) patch(args []string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	id := args[0]
+
+	updates := make(map[string]any)
+	if args[1] == "-f" {
+		if len(args) != 3 {
+			return errUsage
+		}
+		if err := c.readJSON(args[2], &updates); err != nil {
+			return err
+		}
+	} else {
+		for _, arg := range args[1:] {
+			field, value, ok := strings.Cut(arg, "=")
+			if !ok || field == "" {
+				return fmt.Errorf("%q is not a field=value pair", arg)
+			}
+			updates[field] = parseValue(value)
+		}
+	}
+
+	product, err := c.Client.Patch(id, updates)
+	if err != nil {
+		return err
+	}
+	return c.print(product, func(w io.Writer) { writeDataTable(w, product) })
+}
+
+func (c *cli) delete(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	if err := c.Client.Delete(args[0]); err != nil {
+		return err
+	}
+	if c.Output == OutputTable {
+		fmt.Fprintln(c.Stdout, utils.MessageProductDeleted)
+	}
+	return nil
+}
+
+// importedProduct is a product of an export, the products with an id are replaced with
+// a PUT so an export can be imported again
+type importedProduct struct {
+	Id string `json:"id"`
+	utils.RequestBodyProduct
+}
+
+// ImportResult reports an import, Failed maps the line or index of the product to the error
+type ImportResult struct {
+	Imported int               `json:"imported"`
+	Failed   map[string]string `json:"failed,omitempty"`
+}
+
+func (c *cli) importProducts(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	var products []importedProduct
+	var err error
+	if strings.EqualFold(filepath.Ext(args[0]), ".csv") {
+		products, err = c.readCSV(args[0])
+	} else {
+		err = c.readJSON(args[0], &products)
+	}
+	if err != nil {
+		return err
+	}
+
+	result := ImportResult{Failed: make(map[string]string)}
+	var lastErr error
+	for i, product := range products {
+		if product.Id != "" {
+			_, err = c.Client.Update(product.Id, product.RequestBodyProduct)
+		} else {
+			_, err = c.Client.Create(product.RequestBodyProduct)
+		}
+		if err != nil {
+			result.Failed[strconv.Itoa(i+1)] = err.Error()
+			lastErr = err
+			continue
+		}
+		result.Imported++
+	}
+
+	if printErr := c.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "%d imported, %d failed\n", result.Imported, len(result.Failed))
+		for i := range products {
+			if message, ok := result.Failed[strconv.Itoa(i+1)]; ok {
+				fmt.Fprintf(w, "  %d %s: %s\n", i+1, products[i].Code_value, message)
+			}
+		}
+	}); printErr != nil {
+		return printErr
+	}
+
+	if lastErr != nil {
+		return fmt.Errorf("%d of %d products were not imported: %w", len(result.Failed), len(products), lastErr)
+	}
+	return nil
+}
+
+func (c *cli) export(args []string) error {
+	flags := newFlagSet("export")
+	format := flags.String("format", "json", "json, csv or xml")
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+
+	contentTypes := map[string]string{"json": utils.ContentTypeJSON, "csv": utils.ContentTypeCSV, "xml": utils.ContentTypeXML}
+	contentType, ok := contentTypes[*format]
+	if !ok {
+		return errUsage
+	}
+
+	content, err := c.Client.Export(contentType)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		_, err = c.Stdout.Write(content)
+		return err
+	}
+	return os.WriteFile(flags.Arg(0), content, 0644)
+}
+
+func (c *cli) quote(args []string) error {
+	flags := newFlagSet("quote")
+	atFlag := flags.String("at", "", "date of the prices, RFC 3339")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	var at time.Time
+	if *atFlag != "" {
+		parsed, err := time.Parse(time.RFC3339, *atFlag)
+		if err != nil {
+			return errUsage
+		}
+		at = parsed
+	}
+
+	quote, err := c.Client.Quote(flags.Args(), at)
+	if err != nil {
+		return err
+	}
+
+	return c.print(quote, func(w io.Writer) {
+		writeDataTable(w, quote.Products...)
+		fmt.Fprintf(w, "TOTAL PRICE %.2f\n", quote.TotalPrice)
+	})
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return flags
+}
+
+// parseFlags parses the flags of a command accepting up to maxArgs positional arguments
+func parseFlags(flags *flag.FlagSet, args []string, maxArgs int) error {
+	if err := flags.Parse(args); err != nil || flags.NArg() > maxArgs {
+		return errUsage
+	}
+	return nil
+}
+
+// parseValue reads a patch value as JSON, e.g. 5, true or ["a","b"], and as a string otherwise
+func parseValue(value string) any {
+	var parsed any
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return value
+	}
+	return parsed
+}
+
+func (c *cli) open(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(c.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+func (c *cli) readJSON(path string, v any) error {
+	file, err := c.open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := json.NewDecoder(file).Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON in %s: %w", path, err)
+	}
+	return nil
+}
+
+// readCSV reads a CSV export, the columns are found by the names of utils.DataCSVHeader
+func (c *cli) readCSV(path string) ([]importedProduct, error) {
+	file, err := c.open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("the CSV file has no header")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[name] = i
+	}
+	for _, name := range []string{"name", "quantity", "code_value", "expiration", "price"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("the CSV file has no %s column", name)
+		}
+	}
+
+	var products []importedProduct
+	for line, record := range records[1:] {
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+
+		product, err := productFromCSV(get)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line+2, err)
+		}
+		products = append(products, product)
+	}
+	return products, nil
+}
+
+func productFromCSV(get func(string) string) (importedProduct, error) {
+	quantity, err := strconv.Atoi(get("quantity"))
+	if err != nil {
+		return importedProduct{}, errors.New("invalid quantity")
+	}
+	price, err := strconv.ParseFloat(get("price"), 64)
+	if err != nil {
+		return importedProduct{}, errors.New("invalid price")
+	}
+
+	product := importedProduct{
+		Id: get("id"),
+		RequestBodyProduct: utils.RequestBodyProduct{
+			Name:        get("name"),
+			Quantity:    quantity,
+			Code_value:  get("code_value"),
+			Expiration:  get("expiration"),
+			Price:       price,
+			Category_id: get("category_id"),
+		},
+	}
+
+	if value := get("is_published"); value != "" {
+		isPublished, err := strconv.ParseBool(value)
+		if err != nil {
+			return importedProduct{}, errors.New("invalid is_published")
+		}
+		product.Is_published = &isPublished
+	}
+	if value := get("low_stock_threshold"); value != "" {
+		threshold, err := strconv.Atoi(value)
+		if err != nil {
+			return importedProduct{}, errors.New("invalid low_stock_threshold")
+		}
+		product.Low_stock_threshold = threshold
+	}
+	for name, target := range map[string]**time.Time{"publish_at": &product.Publish_at, "unpublish_at": &product.Unpublish_at} {
+		if value := get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return importedProduct{}, errors.New("invalid " + name)
+			}
+			*target = &t
+		}
+	}
+	if value := get("tags"); value != "" {
+		product.Tags = strings.Split(value, "|")
+	}
+	if value := get("attributes"); value != "" {
+		product.Attributes = make(map[string]string)
+		for _, pair := range strings.Split(value, ";") {
+			key, value, _ := strings.Cut(pair, "=")
+			product.Attributes[key] = value
+		}
+	}
+	return product, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const DefaultEndpoint = "http://localhost:8080"
+
+// Config is the JSON config file, e.g. {"endpoint": "http://localhost:8080", "token": "1234", "tenant": "shop-a"}
+type Config struct {
+	Endpoint string `json:"endpoint"`
+	Token    string `json:"token"`
+	Tenant   string `json:"tenant,omitempty"`
+}
+
+// DefaultConfigPath is PRODUCTCTL_CONFIG, or productctl/config.json in the user config directory
+func DefaultConfigPath() string {
+	if path := os.Getenv("PRODUCTCTL_CONFIG"); path != "" {
+		return path
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "productctl", "config.json")
+}
+
+// LoadConfig reads the config file and overrides it with PRODUCTCTL_ENDPOINT, PRODUCTCTL_TOKEN and PRODUCTCTL_TENANT.
+// The default file may be missing, a path that was asked for may not.
+func LoadConfig(path string) (Config, error) {
+	config := Config{Endpoint: DefaultEndpoint}
+
+	optional := path == ""
+	if optional {
+		path = DefaultConfigPath()
+	}
+
+	if path != "" {
+		content, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(content, &config); err != nil {
+				return Config{}, errors.New("invalid config file " + path + ": " + err.Error())
+			}
+		case optional && errors.Is(err, fs.ErrNotExist):
+		default:
+			return Config{}, err
+		}
+	}
+
+	if endpoint := os.Getenv("PRODUCTCTL_ENDPOINT"); endpoint != "" {
+		config.Endpoint = endpoint
+	}
+	if token := os.Getenv("PRODUCTCTL_TOKEN"); token != "" {
+		config.Token = token
+	}
+	if tenant := os.Getenv("PRODUCTCTL_TENANT"); tenant != "" {
+		config.Tenant = tenant
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = DefaultEndpoint
+	}
+	return config, nil
+}
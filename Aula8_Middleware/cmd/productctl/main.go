@@ -0,0 +1,156 @@
+// Command productctl administers the product catalogue through the product API.
+//
+//	productctl [-endpoint URL] [-token TOKEN] [-config FILE] [-o table|json] <command> [arguments]
+//
+// The endpoint and the token come from the flags, then from PRODUCTCTL_ENDPOINT and
+// PRODUCTCTL_TOKEN, then from the config file.
+package main
+
+import (
+	"aula4/internal/client"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// Exit codes, so scripts can tell the failures apart
+const (
+	ExitOK = 0
+	// ExitError is a network failure or an error of the API
+	ExitError        = 1
+	ExitUsage        = 2
+	ExitNotFound     = 3
+	ExitUnauthorized = 4
+	// ExitInvalid is a request rejected by the API, e.g. a repeated code_value
+	ExitInvalid = 5
+)
+
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+)
+
+var errUsage = errors.New("usage")
+
+const usage = `Usage: productctl [-endpoint URL] [-token TOKEN] [-tenant ID] [-config FILE] [-o table|json] <command> [arguments]
+
+Commands:
+  list [-category ID] [-tag TAG]       list the products
+  show ID                              show a product and its variants
+  create -f FILE                       create the product of a JSON file, - reads stdin
+  update ID -f FILE                    replace the product, or create it with that id
+  patch ID field=value... | -f FILE    change some fields, values are read as JSON when they parse
+  delete ID                            delete the product and its variants
+  import FILE                          create or replace the products of a JSON or CSV export
+  export [-format json|csv|xml] [FILE] write every product to FILE or stdout
+  quote [-at TIME] [ID...]             consumer price of the products, of every stock unit without ids
+`
+
+// cli is a run of the command with its client and output streams
+type cli struct {
+	Client *client.Client
+	Output string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("productctl", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	endpoint := flags.String("endpoint", "", "URL of the product API")
+	token := flags.String("token", "", "value of the Token header")
+	tenant := flags.String("tenant", "", "value of the X-Tenant-ID header")
+	configPath := flags.String("config", "", "config file, "+DefaultConfigPath()+" by default")
+	output := flags.String("o", OutputTable, "output format, table or json")
+
+	if err := flags.Parse(args); err != nil || flags.NArg() == 0 {
+		fmt.Fprint(stderr, usage)
+		return ExitUsage
+	}
+	if *output != OutputTable && *output != OutputJSON {
+		fmt.Fprintf(stderr, "productctl: unknown output %q\n", *output)
+		return ExitUsage
+	}
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "productctl: %v\n", err)
+		return ExitUsage
+	}
+	if *endpoint != "" {
+		config.Endpoint = *endpoint
+	}
+	if *token != "" {
+		config.Token = *token
+	}
+	if *tenant != "" {
+		config.Tenant = *tenant
+	}
+
+	apiClient := client.NewClient(config.Endpoint, config.Token)
+	apiClient.Tenant = config.Tenant
+
+	c := &cli{
+		Client: apiClient,
+		Output: *output,
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	}
+
+	commands := map[string]func([]string) error{
+		"list":   c.list,
+		"show":   c.show,
+		"create": c.create,
+		"update": c.update,
+		"patch":  c.patch,
+		"delete": c.delete,
+		"import": c.importProducts,
+		"export": c.export,
+		"quote":  c.quote,
+	}
+
+	name := flags.Arg(0)
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "productctl: unknown command %q\n\n%s", name, usage)
+		return ExitUsage
+	}
+
+	if err := command(flags.Args()[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprint(stderr, usage)
+			return ExitUsage
+		}
+
+		fmt.Fprintf(stderr, "productctl %s: %v\n", name, err)
+		return exitCode(err)
+	}
+	return ExitOK
+}
+
+func exitCode(err error) int {
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) {
+		return ExitError
+	}
+
+	switch {
+	case apiErr.StatusCode == http.StatusNotFound:
+		return ExitNotFound
+	case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+		return ExitUnauthorized
+	case apiErr.StatusCode < http.StatusInternalServerError:
+		return ExitInvalid
+	default:
+		return ExitError
+	}
+}
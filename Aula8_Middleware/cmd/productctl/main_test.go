@@ -0,0 +1,190 @@
+package main
+
+import (
+	"aula4/internal/handler"
+	"aula4/internal/middleware"
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+const productCafe = "684963bb-7172-48ad-aecd-cdca3f0df030"
+
+func newTestServer(t *testing.T) (*httptest.Server, repository.MockRepository) {
+	os.Setenv("TOKEN", "1234")
+	t.Setenv("PRODUCTCTL_CONFIG", filepath.Join(t.TempDir(), "missing.json"))
+	t.Setenv("PRODUCTCTL_ENDPOINT", "")
+	t.Setenv("PRODUCTCTL_TOKEN", "")
+	t.Setenv("PRODUCTCTL_TENANT", "")
+
+	isPublished := true
+	mockRepo := repository.NewRepositoryProductsMock()
+	mockRepo.Products[productCafe] = &storage.Product{
+		Id:           productCafe,
+		Name:         "Cafe",
+		Quantity:     2,
+		Code_value:   "CAFE",
+		Is_published: &isPublished,
+		Expiration:   "01/01/2030",
+		Price:        10,
+	}
+
+	productService := service.NewServiceProducts(&mockRepo)
+	hd := handler.NewHandlerProducts(&productService)
+
+	rt := chi.NewRouter()
+	rt.Route("/products", func(r chi.Router) {
+		r.Use(middleware.ValidateToken)
+		r.Get("/", hd.GetAll)
+		r.Get("/consumer_price", hd.ConsumerPrice)
+		r.Get("/{id}", hd.GetById)
+		r.Post("/", hd.Create)
+		r.Put("/{id}", hd.UpdateOrCreate)
+		r.Patch("/{id}", hd.Update)
+		r.Delete("/{id}", hd.Delete)
+	})
+
+	server := httptest.NewServer(rt)
+	t.Cleanup(server.Close)
+	return server, mockRepo
+}
+
+// runCLI runs productctl against the server and returns the exit code and the outputs
+func runCLI(server *httptest.Server, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"-endpoint", server.URL, "-token", "1234"}, args...)
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCommands(t *testing.T) {
+	server, mockRepo := newTestServer(t)
+
+	code, stdout, stderr := runCLI(server, `{"name":"Pao","quantity":5,"code_value":"PAO","expiration":"01/01/2030","price":2}`, "-o", "json", "create", "-f", "-")
+	require.Equal(t, ExitOK, code, stderr)
+	var created utils.Data
+	require.NoError(t, json.Unmarshal([]byte(stdout), &created))
+	require.Equal(t, "PAO", created.Code_value)
+
+	code, stdout, stderr = runCLI(server, "", "list")
+	require.Equal(t, ExitOK, code, stderr)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, lines, 3)
+	require.True(t, strings.HasPrefix(lines[0], "ID"))
+	require.Contains(t, lines[1], "CAFE", "the products are sorted by name")
+	require.Contains(t, lines[2], "PAO")
+
+	code, stdout, stderr = runCLI(server, "", "patch", created.Id, "price=2.5", "name=Pao de queijo")
+	require.Equal(t, ExitOK, code, stderr)
+	require.Contains(t, stdout, "Pao de queijo")
+	require.Equal(t, 2.5, mockRepo.Products[created.Id].Price)
+
+	code, stdout, stderr = runCLI(server, "", "-o", "json", "quote", productCafe)
+	require.Equal(t, ExitOK, code, stderr)
+	var quote utils.ResponseBodyTotalPrice
+	require.NoError(t, json.Unmarshal([]byte(stdout), &quote))
+	require.InDelta(t, 10*service.TaxLessThanTen, quote.TotalPrice, 0.0001)
+
+	code, stdout, stderr = runCLI(server, "", "-o", "json", "quote", "-at", "2030-01-01T00:00:00Z", productCafe)
+	require.Equal(t, ExitOK, code, stderr)
+	require.NoError(t, json.Unmarshal([]byte(stdout), &quote))
+	require.InDelta(t, 10*service.TaxLessThanTen, quote.TotalPrice, 0.0001)
+
+	code, stdout, stderr = runCLI(server, "", "show", productCafe)
+	require.Equal(t, ExitOK, code, stderr)
+	require.Contains(t, stdout, "Cafe")
+
+	code, _, stderr = runCLI(server, "", "delete", created.Id)
+	require.Equal(t, ExitOK, code, stderr)
+	require.NotContains(t, mockRepo.Products, created.Id)
+}
+
+func TestExportImport(t *testing.T) {
+	server, mockRepo := newTestServer(t)
+	dir := t.TempDir()
+
+	for _, format := range []string{"csv", "json"} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(dir, "products."+format)
+			code, _, stderr := runCLI(server, "", "export", "-format", format, path)
+			require.Equal(t, ExitOK, code, stderr)
+
+			mockRepo.Products[productCafe].Price = 99
+
+			code, stdout, stderr := runCLI(server, "", "import", path)
+			require.Equal(t, ExitOK, code, stderr)
+			require.Contains(t, stdout, "1 imported, 0 failed")
+			require.Len(t, mockRepo.Products, 1, "the products of an export are replaced")
+			require.Equal(t, 10.0, mockRepo.Products[productCafe].Price)
+		})
+	}
+
+	path := filepath.Join(dir, "new.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[
+		{"name":"Pao","quantity":5,"code_value":"PAO","expiration":"01/01/2030","price":2},
+		{"name":"Cafe","quantity":5,"code_value":"CAFE","expiration":"01/01/2030","price":2}
+	]`), 0644))
+
+	code, stdout, stderr := runCLI(server, "", "import", path)
+	require.Equal(t, ExitInvalid, code, "the repeated code_value is rejected")
+	require.Contains(t, stdout, "1 imported, 1 failed")
+	require.Contains(t, stderr, "code_value")
+}
+
+func TestExitCodes(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+	}{
+		{name: "Not found", args: []string{"show", "684963bb-7172-48ad-aecd-cdca3f0df099"}, expectedCode: ExitNotFound},
+		{name: "Unauthorized", args: []string{"-token", "wrong", "list"}, expectedCode: ExitUnauthorized},
+		{name: "Invalid", args: []string{"show", "not-an-id"}, expectedCode: ExitInvalid},
+		{name: "Unknown command", args: []string{"explode"}, expectedCode: ExitUsage},
+		{name: "Missing argument", args: []string{"delete"}, expectedCode: ExitUsage},
+		{name: "Unknown output", args: []string{"-o", "yaml", "list"}, expectedCode: ExitUsage},
+		{name: "Invalid quote date", args: []string{"quote", "-at", "tomorrow"}, expectedCode: ExitUsage},
+		{name: "Server down", args: []string{"-endpoint", "http://127.0.0.1:1", "list"}, expectedCode: ExitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, stderr := runCLI(server, "", tt.args...)
+			require.Equal(t, tt.expectedCode, code, stderr)
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"endpoint":"http://catalogue:8080","token":"file","tenant":"shop-a"}`), 0600))
+
+	t.Setenv("PRODUCTCTL_ENDPOINT", "")
+	t.Setenv("PRODUCTCTL_TOKEN", "env")
+	t.Setenv("PRODUCTCTL_TENANT", "")
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, Config{Endpoint: "http://catalogue:8080", Token: "env", Tenant: "shop-a"}, config, "the env overrides the file")
+
+	_, err = LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err, "a config file that was asked for must exist")
+
+	t.Setenv("PRODUCTCTL_CONFIG", filepath.Join(t.TempDir(), "missing.json"))
+	config, err = LoadConfig("")
+	require.NoError(t, err)
+	require.Equal(t, DefaultEndpoint, config.Endpoint)
+}
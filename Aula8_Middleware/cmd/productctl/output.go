@@ -0,0 +1,58 @@
+package main
+
+import (
+	"aula4/internal/repository/storage"
+	"aula4/internal/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+var tableHeader = []string{"ID", "NAME", "CODE", "QUANTITY", "PRICE", "PUBLISHED", "TAGS"}
+
+// print writes v as indented JSON, or as the table written by table
+func (c *cli) print(v any, table func(w io.Writer)) error {
+	if c.Output == OutputJSON {
+		encoder := json.NewEncoder(c.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+
+	table(c.Stdout)
+	return nil
+}
+
+func writeProductTable(w io.Writer, products ...*storage.Product) {
+	var data []*utils.Data
+	for _, product := range products {
+		dt := utils.ToData(product)
+		data = append(data, &dt)
+	}
+	writeDataTable(w, data...)
+}
+
+func writeDataTable(w io.Writer, data ...*utils.Data) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(tableHeader, "\t"))
+	for _, dt := range data {
+		if dt == nil {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%.2f\t%t\t%s\n",
+			dt.Id, dt.Name, dt.Code_value, dt.Quantity, dt.Price, dt.Is_published, strings.Join(dt.Tags, ","))
+	}
+	tw.Flush()
+}
+
+// sortProducts sorts by name then code, the API lists the products in no particular order
+func sortProducts(products []*storage.Product) {
+	sort.Slice(products, func(i, j int) bool {
+		if products[i].Name != products[j].Name {
+			return products[i].Name < products[j].Name
+		}
+		return products[i].Code_value < products[j].Code_value
+	})
+}
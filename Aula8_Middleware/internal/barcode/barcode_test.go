@@ -0,0 +1,173 @@
+package barcode
+
+import (
+	"bytes"
+	"image/png"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	pattern := regexp.MustCompile(`^SKU-[0-9]{4}$`)
+
+	tests := []struct {
+		name     string
+		format   string
+		code     string
+		expected string
+		err      string
+	}{
+		{name: "EAN-13", format: FormatEAN13, code: "4006381333931", expected: "4006381333931"},
+		{name: "EAN-13 with separators", format: FormatEAN13, code: " 400-6381 333931 ", expected: "4006381333931"},
+		{name: "EAN-13 wrong check digit", format: FormatEAN13, code: "4006381333932", err: "invalid EAN-13: wrong check digit, expected 1"},
+		{name: "EAN-13 too short", format: FormatEAN13, code: "400638133393", err: "invalid EAN-13: must have 13 digits"},
+		{name: "EAN-13 with letters", format: FormatEAN13, code: "40063813339A1", err: "invalid EAN-13: only digits, spaces and hyphens are allowed"},
+		{name: "UPC-A", format: FormatUPCA, code: "036000291452", expected: "036000291452"},
+		{name: "UPC-A wrong check digit", format: FormatUPCA, code: "036000291453", err: "invalid UPC-A: wrong check digit, expected 2"},
+		{name: "GTIN-14", format: FormatGTIN14, code: "00012345600012", expected: "00012345600012"},
+		{name: "GTIN-14 wrong length", format: FormatGTIN14, code: "4006381333931", err: "invalid GTIN-14: must have 14 digits"},
+		{name: "Internal", format: FormatInternal, code: " SKU-0042 ", expected: "SKU-0042"},
+		{name: "Internal not matching", format: FormatInternal, code: "SKU-42", err: "code_value does not match the internal pattern ^SKU-[0-9]{4}$"},
+		{name: "No format", format: "", code: " anything ", expected: " anything "},
+		{name: "Unknown format", format: "isbn", code: "1", err: "invalid code_format, use one of ean13, upca, gtin14, internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Normalize(tt.format, tt.code, pattern)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, code)
+		})
+	}
+
+	_, err := Normalize(FormatInternal, "SKU-0042", nil)
+	require.EqualError(t, err, "no internal code pattern is configured")
+}
+
+func TestKeys(t *testing.T) {
+	// a UPC-A, its EAN-13 and its GTIN-14 are the same item
+	require.Equal(t, "00036000291452", Key(FormatUPCA, "036000291452"))
+	require.Equal(t, "00036000291452", Key(FormatEAN13, "0036000291452"))
+	require.Equal(t, "00036000291452", Key(FormatGTIN14, "00036000291452"))
+	require.Equal(t, "036000291452", Key("", "036000291452"))
+
+	require.Equal(t, []string{"00036000291452", "036000291452"}, LookupKeys(" 036000291452"))
+	require.Equal(t, []string{"00012345600012"}, LookupKeys("00012345600012"))
+	require.Equal(t, []string{"036000291453"}, LookupKeys("036000291453"), "a wrong check digit is not a GTIN")
+	require.Equal(t, []string{"SKU-0042"}, LookupKeys("SKU-0042"))
+	require.Empty(t, LookupKeys(" "))
+}
+
+func bits(modules []bool) string {
+	var b strings.Builder
+	for _, bar := range modules {
+		if bar {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+	return b.String()
+}
+
+func TestEncodeEAN13(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		// G is the mirror of the complement of L, every pattern has two bars and two spaces
+		var complement strings.Builder
+		for j := len(eanL[i]) - 1; j >= 0; j-- {
+			complement.WriteByte('0' + '1' - eanL[i][j])
+		}
+		require.Equal(t, complement.String(), eanG[i])
+	}
+
+	symbol, err := Encode(FormatEAN13, "4006381333931")
+	require.NoError(t, err)
+	modules := bits(symbol.Modules)
+	require.Len(t, modules, 95)
+	require.Equal(t, "101", modules[:3])
+	require.Equal(t, "01010", modules[45:50])
+	require.Equal(t, "101", modules[92:])
+	// the first digit, 4, sets the parity of the left half to LGLLGG
+	require.Equal(t, eanL[0], modules[3:10])
+	require.Equal(t, eanG[0], modules[10:17])
+	require.Equal(t, eanL[6], modules[17:24])
+	require.Equal(t, "1100110", modules[85:92], "the right half is the complement of L")
+
+	upca, err := Encode(FormatUPCA, "036000291452")
+	require.NoError(t, err)
+	ean, _ := Encode(FormatEAN13, "0036000291452")
+	require.Equal(t, ean.Modules, upca.Modules)
+	require.Equal(t, "036000291452", upca.Text)
+}
+
+func TestEncodeITF14(t *testing.T) {
+	symbol, err := Encode(FormatGTIN14, "00012345600012")
+	require.NoError(t, err)
+
+	modules := bits(symbol.Modules)
+	// start, 7 pairs of 2 wide and 3 narrow bars and spaces each, stop
+	require.Len(t, modules, 4+7*2*(2*3+3)+5)
+	require.Equal(t, "1010", modules[:4])
+	require.Equal(t, "11101", modules[len(modules)-5:])
+	// the pair 00: the bars of 0 with the spaces of 0
+	require.Equal(t, "101011100011100010", modules[4:22])
+}
+
+func TestEncodeCode128(t *testing.T) {
+	seen := make(map[string]bool)
+	for value, pattern := range code128Patterns {
+		sum, bars := 0, 0
+		for i, width := range pattern {
+			sum += int(width - '0')
+			if i%2 == 0 {
+				bars += int(width - '0')
+			}
+		}
+		require.Equal(t, 11, sum, "value %d", value)
+		require.Zero(t, bars%2, "the bars of value %d add up to an even width", value)
+		require.False(t, seen[pattern], "value %d", value)
+		seen[pattern] = true
+	}
+
+	symbol, err := Encode(FormatInternal, "SKU-0042")
+	require.NoError(t, err)
+	modules := bits(symbol.Modules)
+	// start, 8 characters, checksum and stop
+	require.Len(t, modules, 11*10+13)
+	require.Equal(t, "11010010000", modules[:11], "start B")
+	require.Equal(t, "1100011101011", modules[len(modules)-13:], "stop")
+
+	_, err = Encode("", "café")
+	require.Error(t, err)
+}
+
+func TestRender(t *testing.T) {
+	symbol, err := Encode(FormatEAN13, "4006381333931")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, PNG(&buf, symbol, 2))
+	img, err := png.Decode(&buf)
+	require.NoError(t, err)
+	require.Equal(t, (95+2*QuietZone)*2, img.Bounds().Dx())
+
+	// the first module is a bar, the one before is the quiet zone
+	r, _, _, _ := img.At(QuietZone*2, QuietZone*2).RGBA()
+	require.Zero(t, r)
+	r, _, _, _ = img.At(QuietZone*2-1, QuietZone*2).RGBA()
+	require.NotZero(t, r)
+
+	buf.Reset()
+	require.NoError(t, SVG(&buf, Symbol{Modules: []bool{true, true, false, true}, Text: "<1>"}, 1))
+	svg := buf.String()
+	require.Contains(t, svg, `<rect x="10" y="10" width="2" height="50" fill="#000"/>`)
+	require.Contains(t, svg, `<rect x="13" y="10" width="1" height="50" fill="#000"/>`)
+	require.Contains(t, svg, `&lt;1&gt;</text>`)
+}
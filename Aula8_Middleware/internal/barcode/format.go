@@ -0,0 +1,137 @@
+package barcode
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The formats of code_value. A product without a format keeps its code as it was given.
+const (
+	FormatEAN13  = "ean13"
+	FormatUPCA   = "upca"
+	FormatGTIN14 = "gtin14"
+	// FormatInternal codes match the pattern of the catalogue, e.g. ^SKU-[0-9]{6}$
+	FormatInternal = "internal"
+)
+
+var Formats = []string{FormatEAN13, FormatUPCA, FormatGTIN14, FormatInternal}
+
+// gtinLengths are the digits of the GS1 formats, the last one being the check digit
+var gtinLengths = map[string]int{
+	FormatEAN13:  13,
+	FormatUPCA:   12,
+	FormatGTIN14: 14,
+}
+
+var formatNames = map[string]string{
+	FormatEAN13:  "EAN-13",
+	FormatUPCA:   "UPC-A",
+	FormatGTIN14: "GTIN-14",
+}
+
+// IsGTIN tells whether the codes of the format are GS1 trade item numbers
+func IsGTIN(format string) bool {
+	_, ok := gtinLengths[format]
+	return ok
+}
+
+// Normalize validates the code of a product in its format and returns the code to store:
+// the digits alone for the GS1 formats, so "400-6381 333931" becomes "4006381333931",
+// and the trimmed code for the internal format. The internal format needs the pattern
+// of the catalogue, a code without a format is returned unchanged.
+func Normalize(format string, code string, pattern *regexp.Regexp) (string, error) {
+	switch {
+	case format == "":
+		return code, nil
+	case IsGTIN(format):
+		return normalizeGTIN(format, code)
+	case format == FormatInternal:
+		code = strings.TrimSpace(code)
+		if pattern == nil {
+			return "", errors.New("no internal code pattern is configured")
+		}
+		if !pattern.MatchString(code) {
+			return "", errors.New("code_value does not match the internal pattern " + pattern.String())
+		}
+		return code, nil
+	default:
+		return "", errors.New("invalid code_format, use one of " + strings.Join(Formats, ", "))
+	}
+}
+
+func normalizeGTIN(format string, code string) (string, error) {
+	name := formatNames[format]
+	digits := stripSeparators(code)
+
+	if !isDigits(digits) {
+		return "", errors.New("invalid " + name + ": only digits, spaces and hyphens are allowed")
+	}
+	if len(digits) != gtinLengths[format] {
+		return "", errors.New("invalid " + name + ": must have " + strconv.Itoa(gtinLengths[format]) + " digits")
+	}
+	if expected := CheckDigit(digits[:len(digits)-1]); digits[len(digits)-1] != expected {
+		return "", errors.New("invalid " + name + ": wrong check digit, expected " + string(expected))
+	}
+
+	return digits, nil
+}
+
+// CheckDigit computes the GS1 check digit of the digits that precede it: from the right,
+// the digits are weighted 3, 1, 3, 1... and the check digit rounds their sum up to a
+// multiple of 10
+func CheckDigit(digits string) byte {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		digit := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+
+	return byte('0' + (10-sum%10)%10)
+}
+
+// Key is the value the codes are compared and looked up by. The GS1 codes are padded to
+// 14 digits, so a UPC-A and the EAN-13 of the same item have the same key.
+func Key(format string, code string) string {
+	if IsGTIN(format) && len(code) < 14 {
+		return strings.Repeat("0", 14-len(code)) + code
+	}
+	return code
+}
+
+// LookupKeys are the keys a searched code may have: its GTIN-14 when it reads as a valid
+// UPC-A, EAN-13 or GTIN-14, then the code as it was given
+func LookupKeys(code string) []string {
+	var keys []string
+
+	digits := stripSeparators(code)
+	if isDigits(digits) && len(digits) >= 12 && len(digits) <= 14 && CheckDigit(digits[:len(digits)-1]) == digits[len(digits)-1] {
+		keys = append(keys, Key(FormatGTIN14, digits))
+	}
+
+	if code = strings.TrimSpace(code); code != "" && (len(keys) == 0 || keys[0] != code) {
+		keys = append(keys, code)
+	}
+
+	return keys
+}
+
+func stripSeparators(code string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code))
+}
+
+func isDigits(code string) bool {
+	if code == "" {
+		return false
+	}
+	for i := 0; i < len(code); i++ {
+		if code[i] < '0' || code[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package barcode
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+const (
+	// QuietZone is the blank margin on each side of the bars, in modules
+	QuietZone = 10
+	// BarHeight is the height of the bars, in modules
+	BarHeight = 50
+
+	// textHeight is the room left under the bars of an SVG for the text, in modules
+	textHeight = 12
+)
+
+// PNG draws the symbol with scale pixels per module, without the text
+func PNG(w io.Writer, symbol Symbol, scale int) error {
+	scale = max(scale, 1)
+	width := (len(symbol.Modules) + 2*QuietZone) * scale
+	height := (BarHeight + 2*QuietZone) * scale
+
+	img := image.NewPaletted(image.Rect(0, 0, width, height), color.Palette{color.White, color.Black})
+	for i, bar := range symbol.Modules {
+		if !bar {
+			continue
+		}
+		for x := (QuietZone + i) * scale; x < (QuietZone+i+1)*scale; x++ {
+			for y := QuietZone * scale; y < (QuietZone+BarHeight)*scale; y++ {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+
+	return png.Encode(w, img)
+}
+
+// SVG draws the symbol with scale user units per module, with the text under the bars.
+// The consecutive bar modules are drawn as one rectangle.
+func SVG(w io.Writer, symbol Symbol, scale int) error {
+	scale = max(scale, 1)
+	width := (len(symbol.Modules) + 2*QuietZone) * scale
+	height := (BarHeight + textHeight + 2*QuietZone) * scale
+
+	if _, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, `<rect width="%d" height="%d" fill="#fff"/>`+"\n", width, height)
+
+	for i := 0; i < len(symbol.Modules); {
+		if !symbol.Modules[i] {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(symbol.Modules) && symbol.Modules[i] {
+			i++
+		}
+		fmt.Fprintf(w, `<rect x="%d" y="%d" width="%d" height="%d" fill="#000"/>`+"\n",
+			(QuietZone+start)*scale, QuietZone*scale, (i-start)*scale, BarHeight*scale)
+	}
+
+	fmt.Fprintf(w, `<text x="%d" y="%d" font-family="monospace" font-size="%d" text-anchor="middle">%s</text>`+"\n",
+		width/2, (QuietZone+BarHeight+textHeight-2)*scale, 10*scale, html.EscapeString(symbol.Text))
+	_, err := fmt.Fprintln(w, `</svg>`)
+	return err
+}
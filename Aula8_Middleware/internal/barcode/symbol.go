@@ -0,0 +1,161 @@
+package barcode
+
+import (
+	"errors"
+	"strings"
+)
+
+// Symbol is a linear barcode, one entry per module from left to right, true for a bar
+type Symbol struct {
+	Modules []bool
+	// Text is printed under the bars
+	Text string
+}
+
+// Encode draws the code in the symbology of its format: EAN-13 for the EAN-13 and UPC-A
+// codes, ITF-14 for the GTIN-14 codes and Code 128 for the others. The code must be
+// normalized, see Normalize.
+func Encode(format string, code string) (Symbol, error) {
+	switch format {
+	case FormatEAN13:
+		return encodeEAN13(code)
+	case FormatUPCA:
+		// a UPC-A symbol is the EAN-13 symbol of the code with a leading zero
+		symbol, err := encodeEAN13("0" + code)
+		symbol.Text = code
+		return symbol, err
+	case FormatGTIN14:
+		return encodeITF(code)
+	default:
+		return encodeCode128(code)
+	}
+}
+
+// appendWidths adds the modules of alternating bars and spaces of the given widths,
+// starting with a bar when bar is true
+func appendWidths(modules []bool, widths string, bar bool) []bool {
+	for _, width := range widths {
+		for i := 0; i < int(width-'0'); i++ {
+			modules = append(modules, bar)
+		}
+		bar = !bar
+	}
+	return modules
+}
+
+// appendBits adds a module per "1" or "0"
+func appendBits(modules []bool, bits string) []bool {
+	for _, bit := range bits {
+		modules = append(modules, bit == '1')
+	}
+	return modules
+}
+
+// EAN-13 digit patterns of the left half, with odd (L) and even (G) parity, the right
+// half (R) is the complement of L
+var (
+	eanL = [10]string{"0001101", "0011001", "0010011", "0111101", "0100011", "0110001", "0101111", "0111011", "0110111", "0001011"}
+	eanG = [10]string{"0100111", "0110011", "0011011", "0100001", "0011101", "0111001", "0000101", "0010001", "0001001", "0010111"}
+	// eanParity is the parity of the six left digits, set by the first digit
+	eanParity = [10]string{"LLLLLL", "LLGLGG", "LLGGLG", "LLGGGL", "LGLLGG", "LGGLLG", "LGGGLL", "LGLGLG", "LGLGGL", "LGGLGL"}
+)
+
+func encodeEAN13(code string) (Symbol, error) {
+	if len(code) != 13 || !isDigits(code) {
+		return Symbol{}, errors.New("an EAN-13 symbol needs 13 digits")
+	}
+
+	modules := appendBits(nil, "101")
+	parity := eanParity[code[0]-'0']
+	for i := 1; i <= 6; i++ {
+		digit := code[i] - '0'
+		if parity[i-1] == 'L' {
+			modules = appendBits(modules, eanL[digit])
+		} else {
+			modules = appendBits(modules, eanG[digit])
+		}
+	}
+	modules = appendBits(modules, "01010")
+	for i := 7; i <= 12; i++ {
+		for _, bit := range eanL[code[i]-'0'] {
+			modules = append(modules, bit == '0')
+		}
+	}
+	modules = appendBits(modules, "101")
+
+	return Symbol{Modules: modules, Text: code}, nil
+}
+
+// itfDigits are the narrow (1) and wide (3) elements of the Interleaved 2 of 5 digits
+var itfDigits = [10]string{"11331", "31113", "13113", "33111", "11313", "31311", "13311", "11133", "31131", "13131"}
+
+func encodeITF(code string) (Symbol, error) {
+	if len(code)%2 != 0 || !isDigits(code) {
+		return Symbol{}, errors.New("an ITF symbol needs an even number of digits")
+	}
+
+	modules := appendWidths(nil, "1111", true)
+	for i := 0; i < len(code); i += 2 {
+		// the first digit of a pair is drawn in the bars, the second in the spaces
+		bars, spaces := itfDigits[code[i]-'0'], itfDigits[code[i+1]-'0']
+		var widths strings.Builder
+		for j := 0; j < 5; j++ {
+			widths.WriteByte(bars[j])
+			widths.WriteByte(spaces[j])
+		}
+		modules = appendWidths(modules, widths.String(), true)
+	}
+	modules = appendWidths(modules, "311", true)
+
+	return Symbol{Modules: modules, Text: code}, nil
+}
+
+// code128Patterns are the bar and space widths of the Code 128 values, 103 to 105 being
+// the start codes A, B and C
+var code128Patterns = [106]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232",
+}
+
+const (
+	code128StartB = 104
+	code128Stop   = "2331112"
+)
+
+// encodeCode128 uses the code set B, which holds the printable ASCII characters
+func encodeCode128(code string) (Symbol, error) {
+	if code == "" {
+		return Symbol{}, errors.New("a Code 128 symbol needs at least one character")
+	}
+
+	values := []int{code128StartB}
+	for i := 0; i < len(code); i++ {
+		if code[i] < ' ' || code[i] > '~' {
+			return Symbol{}, errors.New("only printable ASCII characters can be drawn in Code 128")
+		}
+		values = append(values, int(code[i]-' '))
+	}
+
+	checksum := values[0]
+	for i := 1; i < len(values); i++ {
+		checksum += i * values[i]
+	}
+	values = append(values, checksum%103)
+
+	var modules []bool
+	for _, value := range values {
+		modules = appendWidths(modules, code128Patterns[value], true)
+	}
+	modules = appendWidths(modules, code128Stop, true)
+
+	return Symbol{Modules: modules, Text: code}, nil
+}
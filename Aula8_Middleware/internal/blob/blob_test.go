@@ -0,0 +1,38 @@
+package blob
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+
+	require.NoError(t, store.Put("products/a/b", strings.NewReader("first")))
+	require.NoError(t, store.Put("products/a/b", strings.NewReader("second")))
+
+	content, err := store.Get("products/a/b")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(content)
+	content.Close()
+	require.Equal(t, "second", string(body))
+
+	require.NoError(t, store.Delete("products/a/b"))
+	require.NoError(t, store.Delete("products/a/b"), "deleting a missing key is not an error")
+	_, err = store.Get("products/a/b")
+	require.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestFileStoreRefusesKeysOutsideDir(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+
+	for _, key := range []string{"", "/etc/passwd", "../products", "products/../../a", "products/.hidden", "products//a", `products\a`} {
+		require.ErrorIs(t, store.Put(key, strings.NewReader("x")), ErrInvalidKey, key)
+		_, err := store.Get(key)
+		require.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
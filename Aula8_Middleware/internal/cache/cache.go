@@ -0,0 +1,242 @@
+package cache
+
+import (
+	"aula4/internal/service"
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCacheControl lets clients and shared caches store the responses but
+	// revalidate them on every use, which is cheap thanks to the 304 responses
+	DefaultCacheControl = "no-cache"
+	DefaultMaxEntries   = 1000
+	// DefaultTTL bounds the life of a stored response when nothing tells when it changes
+	DefaultTTL = 5 * time.Minute
+	// PublicCacheControl lets browsers and CDNs serve the public catalogue for a minute
+	// without asking, and a stale copy for five more while they revalidate it
+	PublicCacheControl = "public, max-age=60, stale-while-revalidate=300"
+
+	HeaderCache = "X-Cache"
+)
+
+// entry is a response stored by the cache
+type entry struct {
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// Cache stores the GET responses of the catalogue and answers conditional requests
+// with 304 Not Modified. It implements service.Publisher: the services empty it after
+// every write, so a stored response never outlives the products it lists. The changes
+// no write announces, an expiration or a scheduled publication, end the stored responses
+// through NextChange.
+type Cache struct {
+	CacheControl string
+	MaxEntries   int
+	// TTL is the longest a response is stored, DefaultTTL when zero
+	TTL time.Duration
+	// NextChange returns the first time after now a response may change without a write,
+	// the responses stored before it expire then. ok is false when no change is due.
+	NextChange func(now time.Time) (next time.Time, ok bool)
+	// Now dates the responses, time.Now when nil
+	Now func() time.Time
+
+	mu         sync.Mutex
+	entries    map[string]entry
+	generation uint64
+}
+
+// NewCache stores at most maxEntries responses, DefaultMaxEntries when zero, and sends
+// cacheControl as Cache-Control header, DefaultCacheControl when empty
+func NewCache(maxEntries int, cacheControl string) *Cache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	if cacheControl == "" {
+		cacheControl = DefaultCacheControl
+	}
+
+	return &Cache{
+		CacheControl: cacheControl,
+		MaxEntries:   maxEntries,
+		entries:      make(map[string]entry),
+	}
+}
+
+// Publish invalidates every stored response, lists included, on any product or category event
+func (c *Cache) Publish(event service.Event) {
+	c.Invalidate()
+}
+
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]entry)
+	c.generation++
+}
+
+// Len returns the number of stored responses
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// Middleware serves GET requests from the cache. A miss runs the handler and stores its
+// 200 response, with the ETag and Last-Modified set by the handler.
+func (c *Cache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// the handlers negotiate the media type with the Accept header
+		key := r.URL.RequestURI() + "\n" + r.Header.Get("Accept")
+
+		now := c.now()
+		c.mu.Lock()
+		stored, hit := c.entries[key]
+		if hit && !now.Before(stored.expires) {
+			delete(c.entries, key)
+			hit = false
+		}
+		generation := c.generation
+		c.mu.Unlock()
+
+		if hit {
+			w.Header().Set(HeaderCache, "HIT")
+		} else {
+			// the expiry is taken before the handler reads the products it lists
+			expires := c.expires(now)
+			recorder := &bufferedWriter{header: http.Header{}, status: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			stored = entry{status: recorder.status, header: recorder.header, body: recorder.body.Bytes(), expires: expires}
+			if stored.status == http.StatusOK {
+				c.store(key, stored, generation)
+			}
+			w.Header().Set(HeaderCache, "MISS")
+		}
+
+		for name, values := range stored.header {
+			// the middlewares in front of the cache may already vary the response
+			if name == "Vary" {
+				w.Header()[name] = append(w.Header()[name], values...)
+				continue
+			}
+			w.Header()[name] = values
+		}
+		if stored.status == http.StatusOK {
+			w.Header().Set("Cache-Control", c.CacheControl)
+			if notModified(r, stored.header) {
+				w.Header().Del("Content-Type")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+
+		w.WriteHeader(stored.status)
+		w.Write(stored.body)
+	})
+}
+
+// store keeps the response unless a write invalidated the cache while it was built
+func (c *Cache) store(key string, stored entry, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if len(c.entries) >= c.MaxEntries {
+		c.entries = make(map[string]entry)
+	}
+	c.entries[key] = stored
+}
+
+// expires is the end of a response stored at now: after TTL, or at the next change of the
+// products when it comes first
+func (c *Cache) expires(now time.Time) time.Time {
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	expires := now.Add(ttl)
+	if c.NextChange != nil {
+		if next, ok := c.NextChange(now); ok && next.Before(expires) {
+			expires = next
+		}
+	}
+	return expires
+}
+
+func (c *Cache) now() time.Time {
+	if c.Now == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when the request has no
+// If-None-Match, against the validators of the response
+func notModified(r *http.Request, header http.Header) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		etag := header.Get("ETag")
+		if etag == "" {
+			return false
+		}
+
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// bufferedWriter keeps the response of a handler so the cache can store it before sending it
+type bufferedWriter struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (bw *bufferedWriter) Header() http.Header {
+	return bw.header
+}
+
+func (bw *bufferedWriter) WriteHeader(status int) {
+	if !bw.wroteHeader {
+		bw.status = status
+		bw.wroteHeader = true
+	}
+}
+
+func (bw *bufferedWriter) Write(b []byte) (int, error) {
+	bw.wroteHeader = true
+	return bw.body.Write(b)
+}
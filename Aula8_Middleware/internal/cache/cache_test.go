@@ -0,0 +1,310 @@
+package cache
+
+import (
+	"aula4/internal/blob"
+	"aula4/internal/handler"
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"bytes"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRouter(t *testing.T) (*chi.Mux, *service.ServiceProducts, *Cache, storage.Product) {
+	mockRepo := repository.NewRepositoryProductsMock()
+	productService := service.NewServiceProducts(&mockRepo)
+	productService.Blobs = blob.NewFileStore(t.TempDir())
+	responseCache := NewCache(0, "")
+	productService.Events = responseCache
+
+	isPublished := true
+	product, err := productService.Create(storage.Product{
+		Name:         "Coffee",
+		Quantity:     10,
+		Code_value:   "COF",
+		Is_published: &isPublished,
+		Expiration:   "01/01/2030",
+		Price:        5.0,
+	})
+	require.NoError(t, err)
+
+	hd := handler.NewHandlerProducts(&productService)
+	rt := chi.NewRouter()
+	rt.With(responseCache.Middleware).Get("/products", hd.GetAll)
+	rt.With(responseCache.Middleware).Get("/products/{id}", hd.GetById)
+	rt.Patch("/products/{id}", hd.Update)
+	rt.Post("/products/{id}/images", hd.UploadImage)
+	rt.Post("/products/{id}/prices", hd.SchedulePrice)
+
+	return rt, &productService, responseCache, product
+}
+
+func get(rt http.Handler, path string, header map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+
+	rr := httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestCacheHitAndInvalidation(t *testing.T) {
+	rt, productService, responseCache, product := newTestRouter(t)
+
+	first := get(rt, "/products/"+product.Id, nil)
+	require.Equal(t, http.StatusOK, first.Code)
+	require.Equal(t, "MISS", first.Header().Get(HeaderCache))
+	require.Equal(t, DefaultCacheControl, first.Header().Get("Cache-Control"))
+	require.NotEmpty(t, first.Header().Get("ETag"))
+	require.NotEmpty(t, first.Header().Get("Last-Modified"))
+
+	second := get(rt, "/products/"+product.Id, nil)
+	require.Equal(t, "HIT", second.Header().Get(HeaderCache))
+	require.Equal(t, first.Body.String(), second.Body.String())
+	require.Equal(t, first.Header().Get("ETag"), second.Header().Get("ETag"))
+
+	product.Price = 6
+	_, err := productService.Update(product)
+	require.NoError(t, err)
+	require.Zero(t, responseCache.Len(), "a write through the service empties the cache")
+
+	third := get(rt, "/products/"+product.Id, nil)
+	require.Equal(t, "MISS", third.Header().Get(HeaderCache))
+	require.NotEqual(t, first.Header().Get("ETag"), third.Header().Get("ETag"), "the version of the product changed")
+	require.Contains(t, third.Body.String(), `"Price":6`)
+}
+
+func TestEveryChangeInvalidates(t *testing.T) {
+	imageForm, imageType := pngForm(t)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		contentType string
+		// setup patches the product before it is cached
+		setup string
+		// changed is a part of the product once the write is done
+		changed string
+	}{
+		{
+			name:    "Publication scheduled",
+			method:  "PATCH",
+			path:    "/products/{id}",
+			body:    `{"publish_at":"2031-01-01T09:00:00Z"}`,
+			changed: `"Publish_at":"2031-01-01T09:00:00Z"`,
+		},
+		{
+			name:    "Unpublication scheduled",
+			method:  "PATCH",
+			path:    "/products/{id}",
+			body:    `{"unpublish_at":"2031-02-01T09:00:00Z"}`,
+			changed: `"Unpublish_at":"2031-02-01T09:00:00Z"`,
+		},
+		{
+			name:    "Code format",
+			method:  "PATCH",
+			path:    "/products/{id}",
+			setup:   `{"code_value":"4006381333931"}`,
+			body:    `{"code_format":"ean13"}`,
+			changed: `"Code_format":"ean13"`,
+		},
+		{
+			name:    "Price scheduled",
+			method:  "POST",
+			path:    "/products/{id}/prices",
+			body:    `{"price":7,"effective_at":"2031-03-01T00:00:00Z"}`,
+			changed: `"Price":7,"Effective_at":"2031-03-01T00:00:00Z"`,
+		},
+		{
+			name:        "Image uploaded",
+			method:      "POST",
+			path:        "/products/{id}/images",
+			body:        imageForm,
+			contentType: imageType,
+			changed:     `"content_type":"image/png"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt, _, _, product := newTestRouter(t)
+			path := "/products/" + product.Id
+			if tt.setup != "" {
+				req, _ := http.NewRequest("PATCH", path, strings.NewReader(tt.setup))
+				rr := httptest.NewRecorder()
+				rt.ServeHTTP(rr, req)
+				require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+			}
+
+			get(rt, path, nil)
+			require.Equal(t, "HIT", get(rt, path, nil).Header().Get(HeaderCache))
+
+			req, _ := http.NewRequest(tt.method, strings.ReplaceAll(tt.path, "{id}", product.Id), strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, req)
+			require.Less(t, rr.Code, 300, rr.Body.String())
+
+			rr = get(rt, path, nil)
+			require.Equal(t, "MISS", rr.Header().Get(HeaderCache), "the write emptied the cache")
+			require.Contains(t, rr.Body.String(), tt.changed)
+		})
+	}
+}
+
+// pngForm is the multipart body of an image upload
+func pngForm(t *testing.T) (string, string) {
+	var content bytes.Buffer
+	require.NoError(t, png.Encode(&content, image.NewRGBA(image.Rect(0, 0, 4, 4))))
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image", "image.png")
+	require.NoError(t, err)
+	part.Write(content.Bytes())
+	require.NoError(t, form.Close())
+	return body.String(), form.FormDataContentType()
+}
+
+func TestConditionalGet(t *testing.T) {
+	rt, productService, _, product := newTestRouter(t)
+
+	first := get(rt, "/products", nil)
+	require.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	lastModified := first.Header().Get("Last-Modified")
+
+	tests := []struct {
+		name         string
+		header       map[string]string
+		expectedCode int
+	}{
+		{
+			name:         "Matching ETag",
+			header:       map[string]string{"If-None-Match": `"other", ` + etag},
+			expectedCode: http.StatusNotModified,
+		},
+		{
+			name:         "Weak comparison",
+			header:       map[string]string{"If-None-Match": "W/" + etag},
+			expectedCode: http.StatusNotModified,
+		},
+		{
+			name:         "Other ETag",
+			header:       map[string]string{"If-None-Match": `"other"`},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Not modified since",
+			header:       map[string]string{"If-Modified-Since": lastModified},
+			expectedCode: http.StatusNotModified,
+		},
+		{
+			name:         "Modified since",
+			header:       map[string]string{"If-Modified-Since": time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "If-None-Match wins over If-Modified-Since",
+			header:       map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": lastModified},
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := get(rt, "/products", tt.header)
+			require.Equal(t, tt.expectedCode, rr.Code)
+			require.Equal(t, etag, rr.Header().Get("ETag"))
+			if tt.expectedCode == http.StatusNotModified {
+				require.Empty(t, rr.Body.String())
+			}
+		})
+	}
+
+	err := productService.Delete(product.Id)
+	require.NoError(t, err)
+
+	rr := get(rt, "/products", map[string]string{"If-None-Match": etag})
+	require.NotEqual(t, http.StatusNotModified, rr.Code, "the list changed")
+}
+
+func TestErrorsAreNotCached(t *testing.T) {
+	rt, _, responseCache, _ := newTestRouter(t)
+
+	rr := get(rt, "/products/684963bb-7172-48ad-aecd-cdca3f0df099", nil)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Empty(t, rr.Header().Get("Cache-Control"))
+	require.Zero(t, responseCache.Len())
+}
+
+func TestCacheExpiresAtTheNextChange(t *testing.T) {
+	rt, productService, responseCache, _ := newTestRouter(t)
+	now := time.Date(2029, 12, 31, 12, 0, 0, 0, time.Local)
+	clock := func() time.Time { return now }
+	productService.Now = clock
+	responseCache.Now = clock
+	responseCache.TTL = 48 * time.Hour
+	responseCache.NextChange = productService.NextChange
+	rt.With(responseCache.Middleware).Get("/catalogue", handler.NewHandlerProducts(productService).GetPublished)
+
+	rr := get(rt, "/catalogue", nil)
+	require.Equal(t, "MISS", rr.Header().Get(HeaderCache))
+	require.Contains(t, rr.Body.String(), "Coffee")
+
+	now = time.Date(2030, 1, 1, 23, 0, 0, 0, time.Local)
+	rr = get(rt, "/catalogue", nil)
+	require.Equal(t, "HIT", rr.Header().Get(HeaderCache), "the coffee is good until the end of its expiration day")
+
+	// no write announces the expiration, the stored catalogue ends with the day
+	now = time.Date(2030, 1, 2, 0, 0, 0, 0, time.Local)
+	rr = get(rt, "/catalogue", nil)
+	require.Equal(t, "MISS", rr.Header().Get(HeaderCache))
+	require.NotContains(t, rr.Body.String(), "Coffee")
+
+	// without a change to come the TTL ends the stored responses
+	now = now.Add(responseCache.TTL - time.Second)
+	require.Equal(t, "HIT", get(rt, "/catalogue", nil).Header().Get(HeaderCache))
+	now = now.Add(time.Second)
+	require.Equal(t, "MISS", get(rt, "/catalogue", nil).Header().Get(HeaderCache))
+}
+
+func TestCategoryWritesInvalidate(t *testing.T) {
+	rt, productService, responseCache, _ := newTestRouter(t)
+	categoryRepo := repository.NewRepositoryCategoriesMock()
+	categoryService := service.NewServiceCategories(&categoryRepo, productService.Repository)
+	categoryService.Events = responseCache
+
+	require.Equal(t, http.StatusOK, get(rt, "/products", nil).Code)
+	require.NotZero(t, responseCache.Len())
+
+	category, err := categoryService.Create(storage.Category{Name: "Drinks"})
+	require.NoError(t, err)
+	require.Zero(t, responseCache.Len(), "a new category changes the tree of the listings by category")
+
+	get(rt, "/products", nil)
+	category.Name = "Beverages"
+	_, err = categoryService.Update(category)
+	require.NoError(t, err)
+	require.Zero(t, responseCache.Len())
+
+	get(rt, "/products", nil)
+	require.NoError(t, categoryService.Delete(category.Id))
+	require.Zero(t, responseCache.Len())
+}
@@ -0,0 +1,196 @@
+package client
+
+import (
+	"aula4/internal/repository/storage"
+	"aula4/internal/tenant"
+	"aula4/internal/utils"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const DefaultTimeout = 30 * time.Second
+
+// APIError is a response of the API with a status code of 400 or more
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return http.StatusText(e.StatusCode)
+	}
+	return e.Message
+}
+
+// Client calls the product API with the Token header
+type Client struct {
+	Endpoint string
+	Token    string
+	// Tenant is sent as X-Tenant-ID, the tenant of the token or the default one when empty
+	Tenant     string
+	HTTPClient *http.Client
+}
+
+func NewClient(endpoint string, token string) *Client {
+	return &Client{
+		Endpoint:   strings.TrimSuffix(endpoint, "/"),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: DefaultTimeout},
+	}
+}
+
+// List returns the products, filtered by category and tag when they are set
+func (c *Client) List(category string, tag string) ([]*storage.Product, error) {
+	query := url.Values{}
+	if category != "" {
+		query.Set("category", category)
+	}
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+
+	var products []*storage.Product
+	err := c.do(http.MethodGet, "/products?"+query.Encode(), nil, &products)
+	return products, err
+}
+
+func (c *Client) Get(id string) (*utils.ProductWithVariants, error) {
+	var product utils.ProductWithVariants
+	if err := c.do(http.MethodGet, "/products/"+url.PathEscape(id), nil, &product); err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (c *Client) Create(body utils.RequestBodyProduct) (*utils.Data, error) {
+	return c.writeProduct(http.MethodPost, "/products", body)
+}
+
+// Update replaces the product, or creates it with that id
+func (c *Client) Update(id string, body utils.RequestBodyProduct) (*utils.Data, error) {
+	return c.writeProduct(http.MethodPut, "/products/"+url.PathEscape(id), body)
+}
+
+func (c *Client) Patch(id string, updates map[string]any) (*utils.Data, error) {
+	return c.writeProduct(http.MethodPatch, "/products/"+url.PathEscape(id), updates)
+}
+
+func (c *Client) Delete(id string) error {
+	return c.do(http.MethodDelete, "/products/"+url.PathEscape(id), nil, nil)
+}
+
+// Quote returns the consumer price of the products, of every stock unit when ids is empty,
+// at the prices of at, now when at is zero
+func (c *Client) Quote(ids []string, at time.Time) (*utils.ResponseBodyTotalPrice, error) {
+	path := "/products/consumer_price?list=" + url.QueryEscape(strings.Join(ids, ","))
+	if !at.IsZero() {
+		path += "&at=" + url.QueryEscape(at.Format(time.RFC3339))
+	}
+
+	var quote utils.ResponseBodyTotalPrice
+	if err := c.do(http.MethodGet, path, nil, &quote); err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// Export returns the listing of the products in one of utils.ContentTypes
+func (c *Client) Export(contentType string) ([]byte, error) {
+	req, err := c.newRequest(http.MethodGet, "/products", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", contentType)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (c *Client) writeProduct(method string, path string, body any) (*utils.Data, error) {
+	var response utils.ResponseBodyProduct
+	if err := c.do(method, path, body, &response); err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+func (c *Client) newRequest(method string, path string, body any) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, c.Endpoint+path, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Token", c.Token)
+	if c.Tenant != "" {
+		req.Header.Set(tenant.HeaderTenantID, c.Tenant)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", utils.ContentTypeJSON)
+	}
+	return req, nil
+}
+
+// do sends the request and decodes the JSON response into out, unless out is nil
+func (c *Client) do(method string, path string, body any, out any) error {
+	req, err := c.newRequest(method, path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", utils.ContentTypeJSON)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("could not decode the response: %w", err)
+	}
+	return nil
+}
+
+// checkResponse turns the error responses into an APIError with the message of the API
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	var body utils.ResponseBodyProduct
+	if err := json.NewDecoder(resp.Body).Decode(&body); err == nil {
+		apiErr.Message = body.Message
+	}
+	return apiErr
+}
@@ -0,0 +1,406 @@
+package gql
+
+import (
+	"aula4/internal/repository/storage"
+	"aula4/internal/search"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	_ "embed"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var Schema string
+
+const maxPageSize = 100
+
+const (
+	// maxDepth lets a query reach the attributes of the variants of the listed products,
+	// each level of variants reads every product
+	maxDepth = 5
+	// maxParallelism bounds the resolvers of a query reading the storage at once
+	maxParallelism = 4
+)
+
+// NewSchema parses the schema with the resolvers of the product service
+func NewSchema(service service.Service) (*graphql.Schema, error) {
+	return graphql.ParseSchema(Schema, &Resolver{Service: service}, graphql.UseFieldResolvers(),
+		graphql.MaxDepth(maxDepth), graphql.MaxParallelism(maxParallelism))
+}
+
+// Resolver answers the queries and mutations with service.Service, so the business rules
+// are the ones of the REST API
+type Resolver struct {
+	Service service.Service
+}
+
+type productFilter struct {
+	Category  *graphql.ID
+	Tag       *string
+	Text      *string
+	MinPrice  *float64
+	MaxPrice  *float64
+	Published *bool
+}
+
+type productsArgs struct {
+	Filter   *productFilter
+	Page     int32
+	PageSize int32
+}
+
+type ProductPage struct {
+	Total    int32
+	Page     int32
+	PageSize int32
+	Items    []*ProductResolver
+}
+
+func (r *Resolver) Products(args productsArgs) (*ProductPage, error) {
+	if args.Page < 1 || args.PageSize < 1 || args.PageSize > maxPageSize {
+		return nil, errors.New("page must be at least 1 and pageSize between 1 and 100")
+	}
+
+	products, err := r.filter(args.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ProductPage{Total: int32(len(products)), Page: args.Page, PageSize: args.PageSize, Items: []*ProductResolver{}}
+	start := int(args.Page-1) * int(args.PageSize)
+	for i := start; i < len(products) && i < start+int(args.PageSize); i++ {
+		page.Items = append(page.Items, r.product(products[i]))
+	}
+	return page, nil
+}
+
+func (r *Resolver) Product(args struct{ Id graphql.ID }) (*ProductResolver, error) {
+	product, err := r.Service.GetById(string(args.Id))
+	if err != nil {
+		if err.Error() == "product not found" {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return r.product(product), nil
+}
+
+type Quote struct {
+	Products   []*ProductResolver
+	TotalPrice float64
+}
+
+func (r *Resolver) Quote(args struct {
+	Ids *[]graphql.ID
+	At  *string
+}) (*Quote, error) {
+	var ids []string
+	if args.Ids != nil {
+		for _, id := range *args.Ids {
+			ids = append(ids, string(id))
+		}
+	}
+
+	var at time.Time
+	if args.At != nil {
+		parsed, err := time.Parse(time.RFC3339, *args.At)
+		if err != nil {
+			return nil, errors.New("invalid at, use RFC 3339, e.g. 2030-01-02T15:04:05Z")
+		}
+		at = parsed
+	}
+
+	totalPrice, products, err := r.Service.GetTotalPrice(ids, at)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &Quote{TotalPrice: totalPrice, Products: []*ProductResolver{}}
+	for _, product := range products {
+		quote.Products = append(quote.Products, r.product(product))
+	}
+	return quote, nil
+}
+
+type attributeInput struct {
+	Name  string
+	Value string
+}
+
+type productInput struct {
+	Name              string
+	Quantity          int32
+	CodeValue         string
+	IsPublished       *bool
+	Expiration        string
+	Price             float64
+	CategoryId        *graphql.ID
+	Tags              *[]string
+	Attributes        *[]attributeInput
+	LowStockThreshold *int32
+}
+
+func (r *Resolver) CreateProduct(args struct{ Input productInput }) (*ProductResolver, error) {
+	product, err := r.Service.Create(fromInput(args.Input))
+	if err != nil {
+		return nil, err
+	}
+
+	return r.product(&product), nil
+}
+
+func (r *Resolver) UpdateProduct(args struct {
+	Id    graphql.ID
+	Input productInput
+}) (*ProductResolver, error) {
+	product := fromInput(args.Input)
+	product.Id = string(args.Id)
+
+	updated, err := r.Service.Update(product)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.product(&updated), nil
+}
+
+func (r *Resolver) DeleteProduct(args struct{ Id graphql.ID }) (graphql.ID, error) {
+	if _, err := r.Service.GetById(string(args.Id)); err != nil {
+		return "", err
+	}
+
+	if err := r.Service.Delete(string(args.Id)); err != nil {
+		return "", err
+	}
+
+	return args.Id, nil
+}
+
+// search reads every page of the hits of text, the filters and the paging of the query come after
+func (r *Resolver) search(text string) ([]*storage.Product, error) {
+	var products []*storage.Product
+	for page := 1; ; page++ {
+		result, err := r.Service.FullTextSearch(search.Query{Text: text, Page: page, PageSize: search.MaxPageSize})
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range result.Hits {
+			products = append(products, hit.Product)
+		}
+		if len(result.Hits) == 0 || len(products) >= result.Total {
+			return products, nil
+		}
+	}
+}
+
+// filter returns the products matching every filter, by relevance with a text and by name otherwise
+func (r *Resolver) filter(filter *productFilter) ([]*storage.Product, error) {
+	if filter == nil {
+		filter = &productFilter{}
+	}
+
+	var (
+		products []*storage.Product
+		err      error
+	)
+	if filter.Text != nil {
+		products, err = r.search(*filter.Text)
+	} else {
+		products, err = r.Service.GetAll()
+		if err != nil && err.Error() == "no Products" {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the category and tag filters of the service resolve the subcategories
+	var inCategory map[string]bool
+	if filter.Category != nil || filter.Tag != nil {
+		serviceFilter := service.ProductFilter{}
+		if filter.Category != nil {
+			serviceFilter.CategoryId = string(*filter.Category)
+		}
+		if filter.Tag != nil {
+			serviceFilter.Tag = *filter.Tag
+		}
+
+		filtered, err := r.Service.GetByFilter(serviceFilter)
+		if err != nil {
+			return nil, err
+		}
+		inCategory = make(map[string]bool)
+		for _, product := range filtered {
+			inCategory[product.Id] = true
+		}
+	}
+
+	var matching []*storage.Product
+	for _, product := range products {
+		if inCategory != nil && !inCategory[product.Id] {
+			continue
+		}
+		if filter.MinPrice != nil && product.Price < *filter.MinPrice ||
+			filter.MaxPrice != nil && product.Price > *filter.MaxPrice {
+			continue
+		}
+		if filter.Published != nil && (product.Is_published != nil && *product.Is_published) != *filter.Published {
+			continue
+		}
+		matching = append(matching, product)
+	}
+
+	if filter.Text == nil {
+		sort.SliceStable(matching, func(i, j int) bool {
+			if matching[i].Name != matching[j].Name {
+				return matching[i].Name < matching[j].Name
+			}
+			return matching[i].Code_value < matching[j].Code_value
+		})
+	}
+	return matching, nil
+}
+
+func (r *Resolver) product(product *storage.Product) *ProductResolver {
+	return &ProductResolver{product: product, service: r.Service}
+}
+
+// ProductResolver resolves the fields of a product, the variants are read only when asked for
+type ProductResolver struct {
+	product *storage.Product
+	service service.Service
+}
+
+func (p *ProductResolver) Id() graphql.ID {
+	return graphql.ID(p.product.Id)
+}
+
+func (p *ProductResolver) Name() string {
+	return p.product.Name
+}
+
+func (p *ProductResolver) Quantity() int32 {
+	return int32(p.product.Quantity)
+}
+
+func (p *ProductResolver) CodeValue() string {
+	return p.product.Code_value
+}
+
+func (p *ProductResolver) Expiration() string {
+	return p.product.Expiration
+}
+
+func (p *ProductResolver) Price() float64 {
+	return p.product.Price
+}
+
+func (p *ProductResolver) LowStockThreshold() int32 {
+	return int32(p.product.Low_stock_threshold)
+}
+
+func (p *ProductResolver) Version() int32 {
+	return int32(p.product.Version)
+}
+
+func (p *ProductResolver) IsPublished() bool {
+	return p.product.Is_published != nil && *p.product.Is_published
+}
+
+func (p *ProductResolver) CategoryId() *graphql.ID {
+	return optionalId(p.product.Category_id)
+}
+
+func (p *ProductResolver) ParentId() *graphql.ID {
+	return optionalId(p.product.Parent_id)
+}
+
+func (p *ProductResolver) Tags() []string {
+	if p.product.Tags == nil {
+		return []string{}
+	}
+	return p.product.Tags
+}
+
+type Attribute struct {
+	Name  string
+	Value string
+}
+
+// Attributes are sorted by name
+func (p *ProductResolver) Attributes() []Attribute {
+	attributes := []Attribute{}
+	for name, value := range p.product.Attributes {
+		attributes = append(attributes, Attribute{Name: name, Value: value})
+	}
+	sort.Slice(attributes, func(i, j int) bool { return attributes[i].Name < attributes[j].Name })
+	return attributes
+}
+
+func (p *ProductResolver) UpdatedAt() *string {
+	if p.product.Updated_at.IsZero() {
+		return nil
+	}
+	updatedAt := p.product.Updated_at.Format(time.RFC3339)
+	return &updatedAt
+}
+
+func (p *ProductResolver) Variants() ([]*ProductResolver, error) {
+	variants, err := p.service.GetVariants(p.product.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(variants, func(i, j int) bool { return variants[i].Code_value < variants[j].Code_value })
+
+	resolvers := []*ProductResolver{}
+	for _, variant := range variants {
+		resolvers = append(resolvers, &ProductResolver{product: variant, service: p.service})
+	}
+	return resolvers, nil
+}
+
+// fromInput builds the product of a write like the REST handlers do
+func fromInput(input productInput) storage.Product {
+	isPublished := input.IsPublished != nil && *input.IsPublished
+
+	product := storage.Product{
+		Name:         input.Name,
+		Quantity:     int(input.Quantity),
+		Code_value:   input.CodeValue,
+		Is_published: &isPublished,
+		Expiration:   input.Expiration,
+		Price:        input.Price,
+	}
+	if input.CategoryId != nil {
+		product.Category_id = string(*input.CategoryId)
+	}
+	if input.Tags != nil {
+		product.Tags = utils.NormalizeTags(*input.Tags)
+	}
+	if input.Attributes != nil {
+		product.Attributes = make(map[string]string)
+		for _, attribute := range *input.Attributes {
+			product.Attributes[attribute.Name] = attribute.Value
+		}
+	}
+	if input.LowStockThreshold != nil {
+		product.Low_stock_threshold = int(*input.LowStockThreshold)
+	}
+
+	return product
+}
+
+func optionalId(id string) *graphql.ID {
+	if id == "" {
+		return nil
+	}
+	gid := graphql.ID(id)
+	return &gid
+}
@@ -0,0 +1,80 @@
+package grpcapi
+
+import (
+	"aula4/internal/tenant"
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// MetadataToken is the metadata key of the token, the Token header of the REST API
+	MetadataToken = "token"
+	// MetadataTenantID is the metadata key of the tenant, the X-Tenant-ID header of the REST API
+	MetadataTenantID = "x-tenant-id"
+)
+
+// ResolveTenant is the gRPC counterpart of tenant.Router, it puts the tenant of the call in its context
+func ResolveTenant(registry *tenant.Registry) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		t, err := registry.Resolve(firstValue(md, MetadataToken), firstValue(md, MetadataTenantID))
+		if err == tenant.ErrTenantMismatch {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		return handler(tenant.NewContext(ctx, t), req)
+	}
+}
+
+// ValidateToken is the gRPC counterpart of middleware.ValidateToken
+func ValidateToken(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	token := firstValue(md, MetadataToken)
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "authorization header is missing")
+	}
+
+	expected := os.Getenv("TOKEN")
+	if t, ok := tenant.FromContext(ctx); ok && t.Token != "" {
+		expected = t.Token
+	}
+
+	if token != expected {
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+
+	return handler(ctx, req)
+}
+
+func firstValue(md metadata.MD, key string) string {
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// LoggingInterceptor is the gRPC counterpart of middleware.LoggingMiddleware
+func LoggingInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	startTime := time.Now()
+	resp, err := handler(ctx, req)
+	duration := time.Since(startTime)
+
+	log.Printf("Method: %s, Duration: %v, Code: %s",
+		info.FullMethod,
+		duration,
+		status.Code(err),
+	)
+
+	return resp, err
+}
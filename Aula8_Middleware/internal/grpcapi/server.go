@@ -0,0 +1,268 @@
+package grpcapi
+
+//go:generate protoc -I ../../proto --go_out=productpb --go_opt=paths=source_relative --go-grpc_out=productpb --go-grpc_opt=paths=source_relative product.proto
+
+import (
+	"aula4/internal/grpcapi/productpb"
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/tenant"
+	"aula4/internal/utils"
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server exposes service.Service as the ProductService of proto/product.proto
+type Server struct {
+	productpb.UnimplementedProductServiceServer
+	// Services are the services of every tenant, Registry resolves the tenant of a call
+	Services map[string]service.Service
+	Registry *tenant.Registry
+}
+
+// NewServer serves the default tenant alone
+func NewServer(sv service.Service) *Server {
+	return &Server{
+		Services: map[string]service.Service{storage.DefaultTenant: sv},
+	}
+}
+
+// NewTenantServer serves every tenant of the registry with its own service, like tenant.Router
+func NewTenantServer(registry *tenant.Registry, services map[string]service.Service) *Server {
+	return &Server{
+		Services: services,
+		Registry: registry,
+	}
+}
+
+// NewGRPCServer registers the server behind the logging, tenant and token interceptors
+func NewGRPCServer(server *Server, opts ...grpc.ServerOption) *grpc.Server {
+	interceptors := []grpc.UnaryServerInterceptor{LoggingInterceptor}
+	if server.Registry != nil {
+		interceptors = append(interceptors, ResolveTenant(server.Registry))
+	}
+	interceptors = append(interceptors, ValidateToken)
+	opts = append(opts, grpc.ChainUnaryInterceptor(interceptors...))
+
+	gs := grpc.NewServer(opts...)
+	productpb.RegisterProductServiceServer(gs, server)
+	return gs
+}
+
+func (s *Server) GetAll(ctx context.Context, req *productpb.GetAllRequest) (*productpb.ProductList, error) {
+	sv, err := s.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := sv.GetAll()
+	if err != nil && err.Error() != "no Products" {
+		return nil, toStatus(err, codes.Internal)
+	}
+
+	return toProductList(products), nil
+}
+
+func (s *Server) GetById(ctx context.Context, req *productpb.GetByIdRequest) (*productpb.Product, error) {
+	sv, err := s.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := utils.ValidateUUID(req.GetId()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	product, err := sv.GetById(req.GetId())
+	if err != nil {
+		return nil, toStatus(err, codes.Internal)
+	}
+
+	return toProduct(product), nil
+}
+
+func (s *Server) Create(ctx context.Context, req *productpb.CreateRequest) (*productpb.Product, error) {
+	sv, err := s.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := sv.Create(fromInput(req.GetProduct()))
+	if err != nil {
+		return nil, toStatus(err, codes.InvalidArgument)
+	}
+
+	return toProduct(&product), nil
+}
+
+func (s *Server) Update(ctx context.Context, req *productpb.UpdateRequest) (*productpb.Product, error) {
+	sv, err := s.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := utils.ValidateUUID(req.GetId()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	product := fromInput(req.GetProduct())
+	product.Id = req.GetId()
+
+	updated, err := sv.Update(product)
+	if err != nil {
+		return nil, toStatus(err, codes.InvalidArgument)
+	}
+
+	return toProduct(&updated), nil
+}
+
+func (s *Server) Patch(ctx context.Context, req *productpb.PatchRequest) (*productpb.Product, error) {
+	sv, err := s.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := utils.ValidateUUID(req.GetId()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if _, err := sv.GetById(req.GetId()); err != nil {
+		return nil, toStatus(err, codes.Internal)
+	}
+
+	product, err := sv.Patch(req.GetId(), req.GetUpdates().AsMap())
+	if err != nil {
+		return nil, toStatus(err, codes.InvalidArgument)
+	}
+
+	return toProduct(product), nil
+}
+
+func (s *Server) Delete(ctx context.Context, req *productpb.DeleteRequest) (*productpb.DeleteResponse, error) {
+	sv, err := s.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := utils.ValidateUUID(req.GetId()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if _, err := sv.GetById(req.GetId()); err != nil {
+		return nil, toStatus(err, codes.Internal)
+	}
+
+	if err := sv.Delete(req.GetId()); err != nil {
+		return nil, toStatus(err, codes.Internal)
+	}
+
+	return &productpb.DeleteResponse{}, nil
+}
+
+func (s *Server) SearchByPrice(ctx context.Context, req *productpb.SearchByPriceRequest) (*productpb.ProductList, error) {
+	sv, err := s.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := sv.SearchByPrice(req.GetPrice())
+	if err != nil && err.Error() != "no Products" {
+		return nil, toStatus(err, codes.Internal)
+	}
+
+	return toProductList(products), nil
+}
+
+func (s *Server) GetTotalPrice(ctx context.Context, req *productpb.GetTotalPriceRequest) (*productpb.TotalPrice, error) {
+	sv, err := s.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the request has no date yet, the quote is at the prices of now
+	totalPrice, products, err := sv.GetTotalPrice(req.GetIds(), time.Time{})
+	if err != nil {
+		return nil, toStatus(err, codes.InvalidArgument)
+	}
+
+	return &productpb.TotalPrice{
+		Products:   toProductList(products).Products,
+		TotalPrice: totalPrice,
+	}, nil
+}
+
+// service is the service of the tenant of the call, the default tenant when the server has no registry
+func (s *Server) service(ctx context.Context) (service.Service, error) {
+	id := storage.DefaultTenant
+	if t, ok := tenant.FromContext(ctx); ok {
+		id = t.Id
+	}
+
+	sv, ok := s.Services[id]
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, tenant.ErrUnknownTenant.Error())
+	}
+	return sv, nil
+}
+
+// toStatus maps the errors of the service to gRPC codes, code for the errors that are not "product not found"
+func toStatus(err error, code codes.Code) error {
+	if err.Error() == "product not found" {
+		code = codes.NotFound
+	}
+	return status.Error(code, err.Error())
+}
+
+func toProductList(products []*storage.Product) *productpb.ProductList {
+	list := &productpb.ProductList{Products: []*productpb.Product{}}
+	for _, product := range products {
+		list.Products = append(list.Products, toProduct(product))
+	}
+	return list
+}
+
+func toProduct(product *storage.Product) *productpb.Product {
+	pb := &productpb.Product{
+		Id:                product.Id,
+		Name:              product.Name,
+		Quantity:          int32(product.Quantity),
+		CodeValue:         product.Code_value,
+		IsPublished:       product.Is_published != nil && *product.Is_published,
+		Expiration:        product.Expiration,
+		Price:             product.Price,
+		CategoryId:        product.Category_id,
+		Tags:              product.Tags,
+		ParentId:          product.Parent_id,
+		Attributes:        product.Attributes,
+		LowStockThreshold: int32(product.Low_stock_threshold),
+		Version:           int32(product.Version),
+	}
+	if !product.Updated_at.IsZero() {
+		pb.UpdatedAt = timestamppb.New(product.Updated_at)
+	}
+	return pb
+}
+
+// fromInput builds the product of a write, is_published is false when missing like in the REST API
+func fromInput(input *productpb.ProductInput) storage.Product {
+	isPublished := input.GetIsPublished()
+
+	return storage.Product{
+		Name:         input.GetName(),
+		Quantity:     int(input.GetQuantity()),
+		Code_value:   input.GetCodeValue(),
+		Is_published: &isPublished,
+		Expiration:   input.GetExpiration(),
+		Price:        input.GetPrice(),
+		Category_id:  input.GetCategoryId(),
+		Tags:         utils.NormalizeTags(input.GetTags()),
+		Attributes:   input.GetAttributes(),
+
+		Low_stock_threshold: int(input.GetLowStockThreshold()),
+	}
+}
@@ -0,0 +1,183 @@
+package grpcapi
+
+import (
+	"aula4/internal/grpcapi/productpb"
+	"aula4/internal/repository"
+	"aula4/internal/service"
+	"aula4/internal/tenant"
+	"context"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const missingId = "684963bb-7172-48ad-aecd-cdca3f0df099"
+
+func newTestClient(t *testing.T) productpb.ProductServiceClient {
+	os.Setenv("TOKEN", "1234")
+
+	mockRepo := repository.NewRepositoryProductsMock()
+	productService := service.NewServiceProducts(&mockRepo)
+
+	return dial(t, NewServer(&productService))
+}
+
+func dial(t *testing.T, server *Server) productpb.ProductServiceClient {
+	lis := bufconn.Listen(1 << 20)
+	gs := NewGRPCServer(server)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return productpb.NewProductServiceClient(conn)
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), MetadataToken, token)
+}
+
+func newInput(code string) *productpb.ProductInput {
+	return &productpb.ProductInput{
+		Name:        "Coffee",
+		Quantity:    10,
+		CodeValue:   code,
+		IsPublished: proto.Bool(true),
+		Expiration:  "01/01/2030",
+		Price:       5,
+	}
+}
+
+func TestValidateToken(t *testing.T) {
+	client := newTestClient(t)
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		expectedCode codes.Code
+	}{
+		{name: "Missing token", ctx: context.Background(), expectedCode: codes.Unauthenticated},
+		{name: "Wrong token", ctx: withToken("4321"), expectedCode: codes.Unauthenticated},
+		{name: "Valid token", ctx: withToken("1234"), expectedCode: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.GetAll(tt.ctx, &productpb.GetAllRequest{})
+			require.Equal(t, tt.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestProductLifecycle(t *testing.T) {
+	client := newTestClient(t)
+	ctx := withToken("1234")
+
+	created, err := client.Create(ctx, &productpb.CreateRequest{Product: newInput("COF")})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.Id)
+	require.True(t, created.IsPublished)
+	require.EqualValues(t, 1, created.Version)
+
+	_, err = client.Create(ctx, &productpb.CreateRequest{Product: newInput("COF")})
+	require.Equal(t, codes.InvalidArgument, status.Code(err), "code_value must be unique")
+
+	updates, err := structpb.NewStruct(map[string]any{"price": 7.5, "quantity": 3})
+	require.NoError(t, err)
+	patched, err := client.Patch(ctx, &productpb.PatchRequest{Id: created.Id, Updates: updates})
+	require.NoError(t, err)
+	require.Equal(t, 7.5, patched.Price)
+
+	found, err := client.GetById(ctx, &productpb.GetByIdRequest{Id: created.Id})
+	require.NoError(t, err)
+	require.Equal(t, 7.5, found.Price)
+
+	expensive, err := client.SearchByPrice(ctx, &productpb.SearchByPriceRequest{Price: 7})
+	require.NoError(t, err)
+	require.Len(t, expensive.Products, 1)
+
+	quote, err := client.GetTotalPrice(ctx, &productpb.GetTotalPriceRequest{Ids: []string{created.Id}})
+	require.NoError(t, err)
+	require.Len(t, quote.Products, 1)
+	require.Greater(t, quote.TotalPrice, 0.0)
+
+	_, err = client.Delete(ctx, &productpb.DeleteRequest{Id: created.Id})
+	require.NoError(t, err)
+
+	all, err := client.GetAll(ctx, &productpb.GetAllRequest{})
+	require.NoError(t, err)
+	require.Empty(t, all.Products)
+}
+
+func TestErrorCodes(t *testing.T) {
+	client := newTestClient(t)
+	ctx := withToken("1234")
+
+	_, err := client.GetById(ctx, &productpb.GetByIdRequest{Id: missingId})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.GetById(ctx, &productpb.GetByIdRequest{Id: "not-a-uuid"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.Update(ctx, &productpb.UpdateRequest{Id: missingId, Product: newInput("COF")})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.Delete(ctx, &productpb.DeleteRequest{Id: missingId})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestTenants(t *testing.T) {
+	os.Setenv("TOKEN", "1234")
+
+	registry, err := tenant.NewRegistry(tenant.Tenant{Id: "acme", Token: "acme-token"}, tenant.Tenant{Id: "shared"})
+	require.NoError(t, err)
+	services := make(map[string]service.Service)
+	for _, tn := range registry.Tenants() {
+		mockRepo := repository.NewRepositoryProductsMock()
+		productService := service.NewServiceProducts(&mockRepo)
+		services[tn.Id] = &productService
+	}
+	client := dial(t, NewTenantServer(registry, services))
+
+	withTenant := func(token string, tenantId string) context.Context {
+		return metadata.AppendToOutgoingContext(withToken(token), MetadataTenantID, tenantId)
+	}
+
+	_, err = client.Create(withToken("acme-token"), &productpb.CreateRequest{Product: newInput("ACME")})
+	require.NoError(t, err)
+	_, err = client.Create(withTenant("1234", "shared"), &productpb.CreateRequest{Product: newInput("SHARED")})
+	require.NoError(t, err)
+
+	list, err := client.GetAll(withToken("acme-token"), &productpb.GetAllRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.Products, 1)
+	require.Equal(t, "ACME", list.Products[0].CodeValue, "the token selects its tenant")
+
+	list, err = client.GetAll(withToken("1234"), &productpb.GetAllRequest{})
+	require.NoError(t, err)
+	require.Empty(t, list.Products, "the shared token alone serves the default tenant")
+
+	_, err = client.GetAll(withTenant("1234", "acme"), &productpb.GetAllRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "a tenant with a token refuses the shared one")
+
+	_, err = client.GetAll(withTenant("acme-token", "shared"), &productpb.GetAllRequest{})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.GetAll(withTenant("1234", "unknown"), &productpb.GetAllRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
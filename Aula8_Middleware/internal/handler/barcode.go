@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"aula4/internal/barcode"
+	"aula4/internal/repository/storage"
+	"aula4/internal/utils"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+)
+
+const (
+	DefaultBarcodeScale = 2
+	MaxBarcodeScale     = 10
+)
+
+// GetByCode returns the product of a scanned code, a UPC-A finds the product of its EAN-13
+func (c *ProductController) GetByCode(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	if strings.TrimSpace(code) == "" {
+		utils.ResponseWithError(w, errors.New("code is required"), http.StatusBadRequest)
+		return
+	}
+
+	product, err := c.Service.GetByCode(code)
+	if err != nil {
+		if err.Error() == "product not found" {
+			utils.ResponseWithError(w, err, http.StatusNotFound)
+		} else {
+			utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	variants, err := c.Service.GetVariants(product.Id)
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.SetValidators(w, append([]*storage.Product{product}, variants...)...)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(utils.ProductWithVariants{Product: product, Variants: variants})
+}
+
+// GetBarcode draws the code_value of the product, ?format=png (the default) or svg and ?scale=
+// the width in pixels of the narrowest bar
+func (c *ProductController) GetBarcode(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "png"
+	}
+	if format != "png" && format != "svg" {
+		utils.ResponseWithError(w, errors.New("invalid format, use png or svg"), http.StatusBadRequest)
+		return
+	}
+
+	scale := DefaultBarcodeScale
+	if value := r.URL.Query().Get("scale"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > MaxBarcodeScale {
+			utils.ResponseWithError(w, errors.New("scale must be a number from 1 to "+strconv.Itoa(MaxBarcodeScale)), http.StatusBadRequest)
+			return
+		}
+		scale = parsed
+	}
+
+	symbol, err := c.Service.GetBarcode(idStr)
+	if err != nil {
+		switch {
+		case err.Error() == "product not found":
+			utils.ResponseWithError(w, err, http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "the code_value cannot be drawn"):
+			utils.ResponseWithError(w, err, http.StatusBadRequest)
+		default:
+			utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	var (
+		buf         bytes.Buffer
+		contentType string
+	)
+	if format == "svg" {
+		contentType = "image/svg+xml"
+		err = barcode.SVG(&buf, symbol, scale)
+	} else {
+		contentType = "image/png"
+		err = barcode.PNG(&buf, symbol, scale)
+	}
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
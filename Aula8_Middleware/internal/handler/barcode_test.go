@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"aula4/internal/barcode"
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"aula4/internal/search"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+// newBarcodeFixture serves the products of a JSON storage through the search index, as the API does
+func newBarcodeFixture(t *testing.T) *chi.Mux {
+	st := storage.NewStorageProducts()
+	st.Path = filepath.Join(t.TempDir(), "products.json")
+	rp := repository.NewRepositoryProducts(&st)
+	index := search.NewIndex()
+	irp, err := search.NewIndexedRepository(&rp, index)
+	require.NoError(t, err)
+
+	productService := service.NewServiceProducts(&irp)
+	productService.Index = index
+	productService.CodePattern = regexp.MustCompile(`^SKU-[0-9]{6}$`)
+	hd := NewHandlerProducts(&productService)
+
+	rt := chi.NewRouter()
+	rt.Post("/products", hd.Create)
+	rt.Patch("/products/{id}", hd.Update)
+	rt.Get("/products/by-code/{code}", hd.GetByCode)
+	rt.Get("/products/{id}/barcode", hd.GetBarcode)
+	return rt
+}
+
+func createCoded(t *testing.T, rt http.Handler, format string, code string) *utils.Data {
+	rr := serve(rt, "POST", "/products", codedBody(format, code))
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	var response utils.ResponseBodyProduct
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	return response.Data
+}
+
+func codedBody(format string, code string) string {
+	return `{"name":"Coffee","quantity":1,"code_value":"` + code + `","code_format":"` + format + `","is_published":true,"expiration":"01/01/2030","price":5}`
+}
+
+func TestCreateTypedCodes(t *testing.T) {
+	tests := []struct {
+		name         string
+		format       string
+		code         string
+		expectedCode int
+		expectedData string
+	}{
+		{name: "EAN-13 with separators", format: barcode.FormatEAN13, code: "400-6381 333931", expectedCode: http.StatusCreated, expectedData: "4006381333931"},
+		{name: "EAN-13 with a wrong check digit", format: barcode.FormatEAN13, code: "4006381333932", expectedCode: http.StatusBadRequest},
+		{name: "EAN-13 too short", format: barcode.FormatEAN13, code: "400638133393", expectedCode: http.StatusBadRequest},
+		{name: "UPC-A", format: barcode.FormatUPCA, code: "036000291452", expectedCode: http.StatusCreated, expectedData: "036000291452"},
+		{name: "GTIN-14", format: barcode.FormatGTIN14, code: "10036000291459", expectedCode: http.StatusCreated, expectedData: "10036000291459"},
+		{name: "Internal", format: barcode.FormatInternal, code: " SKU-000123 ", expectedCode: http.StatusCreated, expectedData: "SKU-000123"},
+		{name: "Internal outside the pattern", format: barcode.FormatInternal, code: "ABC", expectedCode: http.StatusBadRequest},
+		{name: "Unknown format", format: "isbn", code: "9780306406157", expectedCode: http.StatusBadRequest},
+		{name: "No format", code: "anything goes", expectedCode: http.StatusCreated, expectedData: "anything goes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := newBarcodeFixture(t)
+
+			rr := serve(rt, "POST", "/products", codedBody(tt.format, tt.code))
+			require.Equal(t, tt.expectedCode, rr.Code, rr.Body.String())
+			if tt.expectedCode != http.StatusCreated {
+				return
+			}
+
+			var response utils.ResponseBodyProduct
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+			require.Equal(t, tt.expectedData, response.Data.Code_value)
+			require.Equal(t, tt.format, response.Data.Code_format)
+		})
+	}
+}
+
+func TestUPCAndEANOfTheSameItemAreOneCode(t *testing.T) {
+	rt := newBarcodeFixture(t)
+	createCoded(t, rt, barcode.FormatUPCA, "036000291452")
+
+	rr := serve(rt, "POST", "/products", codedBody(barcode.FormatEAN13, "0036000291452"))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Contains(t, rr.Body.String(), "the code_value must be unique")
+}
+
+func TestGetByCode(t *testing.T) {
+	rt := newBarcodeFixture(t)
+	ean := createCoded(t, rt, barcode.FormatEAN13, "4006381333931")
+	upc := createCoded(t, rt, barcode.FormatUPCA, "036000291452")
+	sku := createCoded(t, rt, barcode.FormatInternal, "SKU-000123")
+
+	tests := []struct {
+		code         string
+		expectedCode int
+		expectedId   string
+	}{
+		{code: "4006381333931", expectedCode: http.StatusOK, expectedId: ean.Id},
+		{code: "04006381333931", expectedCode: http.StatusOK, expectedId: ean.Id},
+		{code: "036000291452", expectedCode: http.StatusOK, expectedId: upc.Id},
+		{code: "0036000291452", expectedCode: http.StatusOK, expectedId: upc.Id},
+		{code: "SKU-000123", expectedCode: http.StatusOK, expectedId: sku.Id},
+		{code: "4006381333932", expectedCode: http.StatusNotFound},
+		{code: "SKU-999999", expectedCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			rr := serve(rt, "GET", "/products/by-code/"+tt.code, "")
+			require.Equal(t, tt.expectedCode, rr.Code, rr.Body.String())
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var product storage.Product
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &product))
+			require.Equal(t, tt.expectedId, product.Id)
+		})
+	}
+}
+
+func TestPatchCode(t *testing.T) {
+	rt := newBarcodeFixture(t)
+	createCoded(t, rt, barcode.FormatEAN13, "4006381333931")
+	sku := createCoded(t, rt, barcode.FormatInternal, "SKU-000123")
+
+	// the code of another product, once normalized
+	rr := serve(rt, "PATCH", "/products/"+sku.Id, `{"code_format":"ean13","code_value":"400 6381 333931"}`)
+	require.NotEqual(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), "the code_value must be unique")
+
+	// the format alone is checked against the current code
+	rr = serve(rt, "PATCH", "/products/"+sku.Id, `{"code_format":"upca"}`)
+	require.NotEqual(t, http.StatusOK, rr.Code)
+
+	rr = serve(rt, "PATCH", "/products/"+sku.Id, `{"code_format":"upca","code_value":"0360-0029-1452"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	rr = serve(rt, "GET", "/products/by-code/0036000291452", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var product storage.Product
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &product))
+	require.Equal(t, sku.Id, product.Id)
+	require.Equal(t, "036000291452", product.Code_value)
+
+	rr = serve(rt, "GET", "/products/by-code/SKU-000123", "")
+	require.Equal(t, http.StatusNotFound, rr.Code, "the index forgets the old code")
+}
+
+func TestGetBarcode(t *testing.T) {
+	rt := newBarcodeFixture(t)
+	ean := createCoded(t, rt, barcode.FormatEAN13, "4006381333931")
+	free := createCoded(t, rt, "", "Café")
+
+	rr := serve(rt, "GET", "/products/"+ean.Id+"/barcode?scale=3", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+	config, err := png.DecodeConfig(rr.Body)
+	require.NoError(t, err)
+	require.Equal(t, (95+2*barcode.QuietZone)*3, config.Width, "an EAN-13 has 95 modules")
+
+	rr = serve(rt, "GET", "/products/"+ean.Id+"/barcode?format=svg", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, "image/svg+xml", rr.Header().Get("Content-Type"))
+	require.True(t, strings.HasPrefix(rr.Body.String(), "<svg"))
+	require.Contains(t, rr.Body.String(), "4006381333931")
+
+	for _, query := range []string{"?format=gif", "?scale=0", "?scale=11", "?scale=big"} {
+		rr = serve(rt, "GET", "/products/"+ean.Id+"/barcode"+query, "")
+		require.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+
+	rr = serve(rt, "GET", "/products/"+free.Id+"/barcode", "")
+	require.Equal(t, http.StatusBadRequest, rr.Code, "Code 128 draws ASCII only")
+
+	rr = serve(rt, "GET", "/products/684963bb-7172-48ad-aecd-cdca3f0df099/barcode", "")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
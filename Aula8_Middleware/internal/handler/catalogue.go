@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"aula4/internal/utils"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+// GetPublished lists the public catalogue, the products published now and not expired,
+// in the reduced PublicData representation. It needs no token.
+func (c *ProductController) GetPublished(w http.ResponseWriter, r *http.Request) {
+	contentType, err := utils.Negotiate(r.Header.Get("Accept"))
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusNotAcceptable)
+		return
+	}
+
+	products, err := c.Service.GetPublished()
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	data := []utils.PublicData{}
+	xmlBody := utils.PublicProductsXML{Products: []utils.PublicData{}}
+	records := [][]string{utils.PublicDataCSVHeader}
+	for _, product := range products {
+		public := utils.ToPublicData(product)
+		data = append(data, public)
+		xmlBody.Products = append(xmlBody.Products, public)
+		records = append(records, utils.PublicDataCSVRecord(public))
+	}
+
+	utils.SetValidators(w, products...)
+	utils.RespondFormatted(w, contentType, http.StatusOK, utils.Formatted{JSON: data, XML: xmlBody, CSV: records})
+}
+
+// GetPublishedById answers 404 for the products outside the public catalogue
+func (c *ProductController) GetPublishedById(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	product, err := c.Service.GetPublishedById(idStr)
+	if err != nil {
+		if err.Error() == "product not found" {
+			utils.ResponseWithError(w, err, http.StatusNotFound)
+		} else {
+			utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.SetValidators(w, product)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(utils.ToPublicData(product))
+}
+
+// GetSchedule lists the pending publish and unpublish changes, the earliest first
+func (c *ProductController) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	changes, err := c.Service.GetSchedule()
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	data := []utils.ScheduledChangeData{}
+	for _, change := range changes {
+		data = append(data, utils.ToScheduledChangeData(change.Product, change.Action, change.At, change.Due))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
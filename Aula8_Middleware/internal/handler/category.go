@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+type CategoryController struct {
+	Service service.CategoryService
+}
+
+func NewHandlerCategories(service service.CategoryService) *CategoryController {
+	return &CategoryController{
+		Service: service,
+	}
+}
+
+func (c *CategoryController) Create(w http.ResponseWriter, r *http.Request) {
+	var reqBody utils.RequestBodyCategory
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	category, err := c.Service.Create(storage.Category{
+		Name:      reqBody.Name,
+		Parent_id: reqBody.Parent_id,
+	})
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondWithCategory(w, &category, http.StatusCreated, utils.MessageCategoryCreated)
+}
+
+func (c *CategoryController) Update(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var reqBody utils.RequestBodyCategory
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	category, err := c.Service.Update(storage.Category{
+		Id:        idStr,
+		Name:      reqBody.Name,
+		Parent_id: reqBody.Parent_id,
+	})
+	if err != nil {
+		if err.Error() == "category not found" {
+			utils.ResponseWithError(w, err, http.StatusNotFound)
+			return
+		}
+
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondWithCategory(w, &category, http.StatusOK, utils.MessageCategoryUpdated)
+}
+
+func (c *CategoryController) Delete(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if _, err := c.Service.GetById(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusNotFound)
+		return
+	}
+
+	if err := c.Service.Delete(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusConflict)
+		return
+	}
+
+	utils.RespondWithCategory(w, nil, http.StatusNoContent, utils.MessageCategoryDeleted)
+}
+
+func (c *CategoryController) GetAll(w http.ResponseWriter, r *http.Request) {
+	categories, err := c.Service.GetAll()
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	data := []utils.CategoryData{}
+	for _, category := range categories {
+		data = append(data, utils.ToCategoryData(category))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (c *CategoryController) GetById(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	category, err := c.Service.GetById(idStr)
+	if err != nil {
+		if err.Error() == "category not found" {
+			utils.ResponseWithError(w, err, http.StatusNotFound)
+		} else {
+			utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(utils.ToCategoryData(category))
+}
+
+func (c *CategoryController) GetTree(w http.ResponseWriter, r *http.Request) {
+	roots, err := c.Service.GetTree()
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	data := []*utils.CategoryTreeData{}
+	for _, root := range roots {
+		data = append(data, toCategoryTreeData(root))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (c *CategoryController) GetSummary(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	summary, err := c.Service.GetSummary(idStr)
+	if err != nil {
+		if err.Error() == "category not found" {
+			utils.ResponseWithError(w, err, http.StatusNotFound)
+		} else {
+			utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(toCategorySummaryData(summary))
+}
+
+func toCategoryTreeData(node *service.CategoryNode) *utils.CategoryTreeData {
+	data := &utils.CategoryTreeData{
+		CategoryData: utils.ToCategoryData(node.Category),
+		Summary:      toCategorySummaryData(node.Summary),
+		Children:     []*utils.CategoryTreeData{},
+	}
+
+	for _, child := range node.Children {
+		data.Children = append(data.Children, toCategoryTreeData(child))
+	}
+
+	return data
+}
+
+func toCategorySummaryData(summary service.CategorySummary) utils.CategorySummaryData {
+	return utils.CategorySummaryData{
+		Count: summary.Count,
+		Stock: summary.Stock,
+		Value: summary.Value,
+	}
+}
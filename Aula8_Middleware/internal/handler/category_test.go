@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	categoryClothes = "11111111-1111-4111-8111-111111111111"
+	categoryShirts  = "22222222-2222-4222-8222-222222222222"
+	categoryShoes   = "33333333-3333-4333-8333-333333333333"
+)
+
+func newCategoryFixture() (repository.MockRepository, repository.MockCategoryRepository) {
+	mockRepo := repository.NewRepositoryProductsMock()
+	mockCategoryRepo := repository.NewRepositoryCategoriesMock()
+
+	mockCategoryRepo.Categories[categoryClothes] = &storage.Category{Id: categoryClothes, Name: "Clothes"}
+	mockCategoryRepo.Categories[categoryShirts] = &storage.Category{Id: categoryShirts, Name: "Shirts", Parent_id: categoryClothes}
+	mockCategoryRepo.Categories[categoryShoes] = &storage.Category{Id: categoryShoes, Name: "Shoes"}
+
+	mockRepo.Products["684963bb-7172-48ad-aecd-cdca3f0df012"] = &storage.Product{
+		Id:           "684963bb-7172-48ad-aecd-cdca3f0df012",
+		Name:         "Jacket",
+		Quantity:     2,
+		Code_value:   "J1",
+		Is_published: boolPtr(true),
+		Expiration:   "01/01/2030",
+		Price:        100.0,
+		Category_id:  categoryClothes,
+		Tags:         []string{"winter"},
+	}
+	mockRepo.Products["684963bb-7172-48ad-aecd-cdca3f0df013"] = &storage.Product{
+		Id:           "684963bb-7172-48ad-aecd-cdca3f0df013",
+		Name:         "Shirt",
+		Quantity:     3,
+		Code_value:   "S1",
+		Is_published: boolPtr(true),
+		Expiration:   "01/01/2030",
+		Price:        20.0,
+		Category_id:  categoryShirts,
+		Tags:         []string{"summer"},
+	}
+	mockRepo.Products["684963bb-7172-48ad-aecd-cdca3f0df014"] = &storage.Product{
+		Id:           "684963bb-7172-48ad-aecd-cdca3f0df014",
+		Name:         "Boot",
+		Quantity:     1,
+		Code_value:   "B1",
+		Is_published: boolPtr(true),
+		Expiration:   "01/01/2030",
+		Price:        80.0,
+		Category_id:  categoryShoes,
+		Tags:         []string{"winter"},
+	}
+
+	return mockRepo, mockCategoryRepo
+}
+
+func TestCreateCategory(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "Successful creation",
+			body:         `{"name":"Sandals","parent_id":"` + categoryShoes + `"}`,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "Missing name",
+			body:         `{"parent_id":"` + categoryShoes + `"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Parent not found",
+			body:         `{"name":"Sandals","parent_id":"44444444-4444-4444-8444-444444444444"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo, mockCategoryRepo := newCategoryFixture()
+			categoryService := service.NewServiceCategories(&mockCategoryRepo, &mockRepo)
+			categoryHandler := NewHandlerCategories(&categoryService)
+
+			req, _ := http.NewRequest("POST", "/categories", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(categoryHandler.Create).ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code, "handler returned wrong status code")
+		})
+	}
+}
+
+func TestUpdateCategoryCycle(t *testing.T) {
+	mockRepo, mockCategoryRepo := newCategoryFixture()
+	categoryService := service.NewServiceCategories(&mockCategoryRepo, &mockRepo)
+	categoryHandler := NewHandlerCategories(&categoryService)
+
+	rt := chi.NewRouter()
+	rt.Put("/categories/{id}", categoryHandler.Update)
+
+	body := `{"name":"Clothes","parent_id":"` + categoryShirts + `"}`
+	req, _ := http.NewRequest("PUT", "/categories/"+categoryClothes, strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code, "a category must not move under its own child")
+}
+
+func TestDeleteCategory(t *testing.T) {
+	tests := []struct {
+		name         string
+		categoryID   string
+		expectedCode int
+	}{
+		{
+			name:         "Has subcategories",
+			categoryID:   categoryClothes,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "Has products",
+			categoryID:   categoryShoes,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "Category not found",
+			categoryID:   "44444444-4444-4444-8444-444444444444",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo, mockCategoryRepo := newCategoryFixture()
+			categoryService := service.NewServiceCategories(&mockCategoryRepo, &mockRepo)
+			categoryHandler := NewHandlerCategories(&categoryService)
+
+			rt := chi.NewRouter()
+			rt.Delete("/categories/{id}", categoryHandler.Delete)
+
+			req, _ := http.NewRequest("DELETE", "/categories/"+tt.categoryID, nil)
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code, "handler returned wrong status code")
+		})
+	}
+}
+
+func TestGetCategoryTree(t *testing.T) {
+	mockRepo, mockCategoryRepo := newCategoryFixture()
+	categoryService := service.NewServiceCategories(&mockCategoryRepo, &mockRepo)
+	categoryHandler := NewHandlerCategories(&categoryService)
+
+	req, _ := http.NewRequest("GET", "/categories/tree", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(categoryHandler.GetTree).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var tree []utils.CategoryTreeData
+	err := json.NewDecoder(rr.Body).Decode(&tree)
+	require.NoError(t, err, "could not decode response body")
+
+	require.Len(t, tree, 2)
+	require.Equal(t, "Clothes", tree[0].Name)
+	require.Equal(t, utils.CategorySummaryData{Count: 2, Stock: 5, Value: 260}, tree[0].Summary)
+	require.Len(t, tree[0].Children, 1)
+	require.Equal(t, utils.CategorySummaryData{Count: 1, Stock: 3, Value: 60}, tree[0].Children[0].Summary)
+	require.Equal(t, "Shoes", tree[1].Name)
+}
+
+func TestGetAllByCategoryAndTag(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		expectedCount int
+	}{
+		{
+			name:          "Category includes subcategories",
+			query:         "?category=" + categoryClothes,
+			expectedCount: 2,
+		},
+		{
+			name:          "Leaf category",
+			query:         "?category=" + categoryShirts,
+			expectedCount: 1,
+		},
+		{
+			name:          "Tag",
+			query:         "?tag=Winter",
+			expectedCount: 2,
+		},
+		{
+			name:          "Category and tag",
+			query:         "?category=" + categoryClothes + "&tag=winter",
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo, mockCategoryRepo := newCategoryFixture()
+			productService := service.NewServiceProducts(&mockRepo)
+			productService.Categories = &mockCategoryRepo
+			productHandler := NewHandlerProducts(&productService)
+
+			req, _ := http.NewRequest("GET", "/products"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(productHandler.GetAll).ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var response []storage.Product
+			err := json.NewDecoder(rr.Body).Decode(&response)
+			require.NoError(t, err, "error decoding response")
+			require.Len(t, response, tt.expectedCount)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"aula4/internal/utils"
+	"encoding/csv"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept      string
+		expected    string
+		expectedErr error
+	}{
+		{accept: "", expected: utils.ContentTypeJSON},
+		{accept: "*/*", expected: utils.ContentTypeJSON},
+		{accept: "text/csv", expected: utils.ContentTypeCSV},
+		{accept: "text/*", expected: utils.ContentTypeCSV},
+		{accept: "application/xml;q=0.9, text/csv;q=0.5", expected: utils.ContentTypeXML},
+		{accept: "text/xml", expected: utils.ContentTypeTextXML},
+		{accept: "*/*, application/json;q=0", expected: utils.ContentTypeCSV},
+		{accept: "text/html", expectedErr: utils.ErrNotAcceptable},
+		{accept: "application/json;q=0", expectedErr: utils.ErrNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			contentType, err := utils.Negotiate(tt.accept)
+			require.Equal(t, tt.expectedErr, err)
+			require.Equal(t, tt.expected, contentType)
+		})
+	}
+}
+
+func TestListingFormats(t *testing.T) {
+	controller, _ := newSearchFixture(t)
+
+	tests := []struct {
+		name                string
+		handler             http.HandlerFunc
+		query               string
+		accept              string
+		expectedCode        int
+		expectedContentType string
+	}{
+		{
+			name:                "Products as JSON",
+			handler:             controller.GetAll,
+			accept:              "application/json",
+			expectedCode:        http.StatusOK,
+			expectedContentType: utils.ContentTypeJSON,
+		},
+		{
+			name:                "Products as CSV",
+			handler:             controller.GetAll,
+			accept:              "text/csv",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+		},
+		{
+			name:                "Search as XML",
+			handler:             controller.Search,
+			query:               "?q=pao",
+			accept:              "application/xml",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/xml; charset=utf-8",
+		},
+		{
+			name:                "Price search as CSV",
+			handler:             controller.Search,
+			query:               "?price=1",
+			accept:              "text/csv",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+		},
+		{
+			name:                "Consumer price as XML",
+			handler:             controller.ConsumerPrice,
+			query:               "?list=" + productCafe,
+			accept:              "text/xml",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "text/xml; charset=utf-8",
+		},
+		{
+			name:                "Unsupported type",
+			handler:             controller.GetAll,
+			accept:              "text/html",
+			expectedCode:        http.StatusNotAcceptable,
+			expectedContentType: utils.ContentTypeJSON,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/products"+tt.query, nil)
+			req.Header.Set("Accept", tt.accept)
+			rr := httptest.NewRecorder()
+			tt.handler(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code, rr.Body.String())
+			require.Equal(t, tt.expectedContentType, rr.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestProductsAsCSVAndXML(t *testing.T) {
+	controller, productService := newSearchFixture(t)
+
+	product, err := productService.GetById(productPao)
+	require.NoError(t, err)
+	tagged := *product
+	tagged.Tags = []string{"bakery", "sweet"}
+	tagged.Attributes = map[string]string{"weight": "500g"}
+	_, err = productService.Update(tagged)
+	require.NoError(t, err)
+
+	get := func(accept string, query string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/products"+query, nil)
+		req.Header.Set("Accept", accept)
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	rr := get("text/csv", "", controller.GetAll)
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	require.Equal(t, utils.DataCSVHeader, records[0])
+	for _, record := range records[1:] {
+		if record[0] == productPao {
+			require.Equal(t, "Pão de Açúcar", record[1])
+			require.Equal(t, "bakery|sweet", record[8])
+			require.Equal(t, "weight=500g", record[10])
+		}
+	}
+
+	rr = get("application/xml", "", controller.GetAll)
+	var products utils.ProductsXML
+	err = xml.Unmarshal(rr.Body.Bytes(), &products)
+	require.NoError(t, err)
+	require.Len(t, products.Products, 3)
+	require.Contains(t, rr.Body.String(), `<entry key="weight">500g</entry>`)
+	require.Contains(t, rr.Body.String(), `<tags><tag>bakery</tag><tag>sweet</tag></tags>`)
+
+	rr = get("text/csv", "?list="+productCafe+","+productPao, controller.ConsumerPrice)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NotEmpty(t, rr.Header().Get(HeaderTotalPrice))
+	require.Equal(t, 3, strings.Count(rr.Body.String(), "\n"), "a header line and a line per product")
+}
+
+func TestCSVEscapesFormulas(t *testing.T) {
+	controller, productService := newSearchFixture(t)
+
+	product, err := productService.GetById(productPao)
+	require.NoError(t, err)
+	formulas := *product
+	formulas.Name = `=HYPERLINK("http://example.com","Pão")`
+	formulas.Tags = []string{"@SUM(A1)", "bakery"}
+	formulas.Attributes = map[string]string{"+weight": "500g"}
+	_, err = productService.Update(formulas)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("GET", "/products", nil)
+	req.Header.Set("Accept", "text/csv")
+	rr := httptest.NewRecorder()
+	controller.GetAll(rr, req)
+
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	require.NoError(t, err)
+	found := false
+	for _, record := range records[1:] {
+		if record[0] == productPao {
+			found = true
+			require.Equal(t, `'=HYPERLINK("http://example.com","Pão")`, record[1])
+			require.Equal(t, "'@SUM(A1)|bakery", record[8])
+			require.Equal(t, "'+weight=500g", record[10])
+			require.Equal(t, "3", record[6], "the numbers are left alone")
+		}
+	}
+	require.True(t, found)
+}
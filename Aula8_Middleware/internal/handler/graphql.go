@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"aula4/internal/utils"
+	"encoding/json"
+	"net/http"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+type GraphQLController struct {
+	Schema *graphql.Schema
+}
+
+func NewHandlerGraphQL(schema *graphql.Schema) *GraphQLController {
+	return &GraphQLController{
+		Schema: schema,
+	}
+}
+
+func (c *GraphQLController) Serve(w http.ResponseWriter, r *http.Request) {
+	var reqBody utils.RequestBodyGraphQL
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	response := c.Schema.Exec(r.Context(), reqBody.Query, reqBody.OperationName, reqBody.Variables)
+
+	body := utils.ResponseBodyGraphQL{Data: response.Data}
+	for _, err := range response.Errors {
+		body.Errors = append(body.Errors, utils.GraphQLError{Message: err.Message, Path: err.Path})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(body)
+}
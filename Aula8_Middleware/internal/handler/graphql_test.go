@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"aula4/internal/gql"
+	"aula4/internal/repository"
+	"aula4/internal/search"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func runGraphQL(t *testing.T, hd *GraphQLController, query string, variables map[string]any) utils.ResponseBodyGraphQL {
+	body, err := json.Marshal(utils.RequestBodyGraphQL{Query: query, Variables: variables})
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(string(body)))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(hd.Serve).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response utils.ResponseBodyGraphQL
+	err = json.NewDecoder(rr.Body).Decode(&response)
+	require.NoError(t, err, "could not decode response body")
+	return response
+}
+
+func TestGraphQLQueries(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		expected  string
+		hasErrors bool
+	}{
+		{
+			name:     "Filter by price",
+			query:    `{ products(filter: {minPrice: 11}) { total items { codeValue attributes { name value } } } }`,
+			expected: `{"products":{"total":1,"items":[{"codeValue":"SHIRT-L","attributes":[{"name":"size","value":"L"}]}]}}`,
+		},
+		{
+			name:     "Pagination",
+			query:    `{ products(page: 2, pageSize: 2) { total page pageSize items { codeValue } } }`,
+			expected: `{"products":{"total":3,"page":2,"pageSize":2,"items":[{"codeValue":"SHIRT-M"}]}}`,
+		},
+		{
+			name:      "Invalid page size",
+			query:     `{ products(pageSize: 1000) { total } }`,
+			hasErrors: true,
+		},
+		{
+			name:      "Product with its variants",
+			query:     `query($id: ID!) { product(id: $id) { codeValue variants { codeValue } } }`,
+			variables: map[string]any{"id": parentShirt},
+			expected:  `{"product":{"codeValue":"SHIRT","variants":[{"codeValue":"SHIRT-L"},{"codeValue":"SHIRT-M"}]}}`,
+		},
+		{
+			name:     "Product not found",
+			query:    `{ product(id: "684963bb-7172-48ad-aecd-cdca3f0df099") { id } }`,
+			expected: `{"product":null}`,
+		},
+		{
+			name:      "Too deep",
+			query:     `{ products { items { variants { variants { variants { id } } } } } }`,
+			hasErrors: true,
+		},
+		{
+			name:      "Unknown field",
+			query:     `{ products { items { colour } } }`,
+			hasErrors: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := newVariantFixture()
+			productService := service.NewServiceProducts(&mockRepo)
+			schema, err := gql.NewSchema(&productService)
+			require.NoError(t, err)
+
+			response := runGraphQL(t, NewHandlerGraphQL(schema), tt.query, tt.variables)
+			if tt.hasErrors {
+				require.NotEmpty(t, response.Errors)
+				return
+			}
+
+			require.Empty(t, response.Errors)
+			require.JSONEq(t, tt.expected, string(response.Data))
+		})
+	}
+}
+
+func TestGraphQLTextFilterCountsEveryHit(t *testing.T) {
+	mockRepo := repository.NewRepositoryProductsMock()
+	for i := 0; i < search.MaxPageSize+50; i++ {
+		product := streamProduct(fmt.Sprintf("TEA-%d", i))
+		product.Id = uuid.NewString()
+		mockRepo.Products[product.Id] = &product
+	}
+	productService := service.NewServiceProducts(&mockRepo)
+	schema, err := gql.NewSchema(&productService)
+	require.NoError(t, err)
+
+	response := runGraphQL(t, NewHandlerGraphQL(schema), `{ products(filter: {text: "tea"}, page: 2, pageSize: 100) { total items { id } } }`, nil)
+	require.Empty(t, response.Errors)
+
+	var data struct {
+		Products struct {
+			Total int
+			Items []struct{ Id string }
+		}
+	}
+	require.NoError(t, json.Unmarshal(response.Data, &data))
+	require.Equal(t, search.MaxPageSize+50, data.Products.Total)
+	require.Len(t, data.Products.Items, 50)
+}
+
+func TestGraphQLQuote(t *testing.T) {
+	mockRepo := newVariantFixture()
+	productService := service.NewServiceProducts(&mockRepo)
+	schema, err := gql.NewSchema(&productService)
+	require.NoError(t, err)
+
+	response := runGraphQL(t, NewHandlerGraphQL(schema), `{ quote(ids: ["`+variantL+`"]) { totalPrice products { codeValue } } }`, nil)
+	require.Empty(t, response.Errors)
+
+	var data struct {
+		Quote struct {
+			TotalPrice float64
+			Products   []struct{ CodeValue string }
+		}
+	}
+	require.NoError(t, json.Unmarshal(response.Data, &data))
+	require.InDelta(t, 12.0*service.TaxLessThanTen, data.Quote.TotalPrice, 0.0001)
+	require.Len(t, data.Quote.Products, 1)
+}
+
+func TestGraphQLMutations(t *testing.T) {
+	mockRepo := newVariantFixture()
+	productService := service.NewServiceProducts(&mockRepo)
+	schema, err := gql.NewSchema(&productService)
+	require.NoError(t, err)
+	hd := NewHandlerGraphQL(schema)
+
+	input := map[string]any{
+		"name":       "Cap",
+		"quantity":   3,
+		"codeValue":  "CAP",
+		"expiration": "01/01/2030",
+		"price":      5.5,
+		"attributes": []map[string]any{{"name": "color", "value": "red"}},
+	}
+
+	response := runGraphQL(t, hd, `mutation($input: ProductInput!) { createProduct(input: $input) { id codeValue } }`, map[string]any{"input": input})
+	require.Empty(t, response.Errors)
+
+	var created struct {
+		CreateProduct struct{ Id, CodeValue string }
+	}
+	require.NoError(t, json.Unmarshal(response.Data, &created))
+	require.Equal(t, "CAP", created.CreateProduct.CodeValue)
+	require.Contains(t, mockRepo.Products, created.CreateProduct.Id)
+
+	response = runGraphQL(t, hd, `mutation($input: ProductInput!) { createProduct(input: $input) { id } }`, map[string]any{"input": input})
+	require.NotEmpty(t, response.Errors, "code_value must stay unique")
+
+	input["price"] = 7.0
+	response = runGraphQL(t, hd, `mutation($id: ID!, $input: ProductInput!) { updateProduct(id: $id, input: $input) { price } }`,
+		map[string]any{"id": created.CreateProduct.Id, "input": input})
+	require.Empty(t, response.Errors)
+	require.JSONEq(t, `{"updateProduct":{"price":7}}`, string(response.Data))
+
+	response = runGraphQL(t, hd, `mutation($id: ID!) { deleteProduct(id: $id) }`, map[string]any{"id": created.CreateProduct.Id})
+	require.Empty(t, response.Errors)
+	require.NotContains(t, mockRepo.Products, created.CreateProduct.Id)
+}
+
+func TestGraphQLInvalidBody(t *testing.T) {
+	schema, err := gql.NewSchema(nil)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query":`))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandlerGraphQL(schema).Serve).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
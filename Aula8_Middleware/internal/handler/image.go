@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+)
+
+// ImageCacheControl lets every cache keep an image for a year, the content of an image id never changes
+const ImageCacheControl = "max-age=31536000, immutable"
+
+// MaxImageUploadBytes is the body limit of an upload, the image and the multipart framing
+const MaxImageUploadBytes = service.MaxImageBytes + 64<<10
+
+// UploadImage stores the "image" file of a multipart form, "primary=true" makes it the primary image
+func (c *ProductController) UploadImage(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseMultipartForm(service.MaxImageBytes); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.ResponseWithError(w, errors.New("image is too large"), http.StatusRequestEntityTooLarge)
+			return
+		}
+		utils.ResponseWithError(w, errors.New("invalid multipart form: "+err.Error()), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	primary := false
+	if value := r.FormValue("primary"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.ResponseWithError(w, errors.New("primary must be true or false"), http.StatusBadRequest)
+			return
+		}
+		primary = parsed
+	}
+
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		utils.ResponseWithError(w, errors.New("the image field is required"), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, service.MaxImageBytes+1))
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	image, err := c.Service.AddImage(idStr, content, primary)
+	if err != nil {
+		utils.ResponseWithError(w, err, imageErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", utils.ImageURL("/products", idStr, image.Id))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(utils.ToImageData("/products", idStr, image))
+}
+
+func (c *ProductController) GetImage(w http.ResponseWriter, r *http.Request) {
+	c.serveImage(w, r, false)
+}
+
+func (c *ProductController) GetThumbnail(w http.ResponseWriter, r *http.Request) {
+	c.serveImage(w, r, true)
+}
+
+// GetPublishedImage serves the images of the products of the public catalogue only
+func (c *ProductController) GetPublishedImage(w http.ResponseWriter, r *http.Request) {
+	if c.checkPublished(w, r) {
+		c.serveImage(w, r, false)
+	}
+}
+
+func (c *ProductController) GetPublishedThumbnail(w http.ResponseWriter, r *http.Request) {
+	if c.checkPublished(w, r) {
+		c.serveImage(w, r, true)
+	}
+}
+
+// ReorderImages sets the display order of the images
+func (c *ProductController) ReorderImages(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var body utils.RequestBodyImageOrder
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.ResponseWithError(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+
+	images, err := c.Service.ReorderImages(idStr, body.Ids)
+	if err != nil {
+		utils.ResponseWithError(w, err, imageErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(imagesData(idStr, images))
+}
+
+// SetPrimaryImage makes the image the primary one of its product
+func (c *ProductController) SetPrimaryImage(w http.ResponseWriter, r *http.Request) {
+	idStr, imageIdStr, ok := imageParams(w, r)
+	if !ok {
+		return
+	}
+
+	images, err := c.Service.SetPrimaryImage(idStr, imageIdStr)
+	if err != nil {
+		utils.ResponseWithError(w, err, imageErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(imagesData(idStr, images))
+}
+
+func (c *ProductController) DeleteImage(w http.ResponseWriter, r *http.Request) {
+	idStr, imageIdStr, ok := imageParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := c.Service.DeleteImage(idStr, imageIdStr); err != nil {
+		utils.ResponseWithError(w, err, imageErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *ProductController) serveImage(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	idStr, imageIdStr, ok := imageParams(w, r)
+	if !ok {
+		return
+	}
+
+	image, content, err := c.Service.GetImage(idStr, imageIdStr, thumbnail)
+	if err != nil {
+		utils.ResponseWithError(w, err, imageErrorStatus(err))
+		return
+	}
+	defer content.Close()
+
+	contentType, etag := image.Content_type, `"`+image.Id+`"`
+	if thumbnail {
+		contentType, etag = service.ThumbnailContentType, `"`+image.Id+`-thumbnail"`
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", ImageCacheControl)
+	if match := r.Header.Get("If-None-Match"); match != "" && strings.Contains(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, content)
+}
+
+// checkPublished answers 404 for the products outside the public catalogue
+func (c *ProductController) checkPublished(w http.ResponseWriter, r *http.Request) bool {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return false
+	}
+
+	if _, err := c.Service.GetPublishedById(idStr); err != nil {
+		if err.Error() == "product not found" {
+			utils.ResponseWithError(w, err, http.StatusNotFound)
+		} else {
+			utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		}
+		return false
+	}
+
+	return true
+}
+
+func imageParams(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return "", "", false
+	}
+
+	imageIdStr := chi.URLParam(r, "imageId")
+	if err := utils.ValidateUUID(imageIdStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return "", "", false
+	}
+
+	return idStr, imageIdStr, true
+}
+
+func imagesData(productId string, images []storage.Image) []utils.ImageData {
+	data := utils.ToImagesData("/products", productId, images)
+	if data == nil {
+		data = []utils.ImageData{}
+	}
+	return data
+}
+
+func imageErrorStatus(err error) int {
+	message := err.Error()
+	switch {
+	case message == "product not found" || message == "image not found":
+		return http.StatusNotFound
+	case message == "image is too large":
+		return http.StatusRequestEntityTooLarge
+	case strings.HasPrefix(message, "unsupported image type"):
+		return http.StatusUnsupportedMediaType
+	case strings.HasPrefix(message, "invalid image") || message == "image order must list every image once":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"aula4/internal/blob"
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+const imageProduct = "684963bb-7172-48ad-aecd-cdca3f0df050"
+
+func newImageFixture(t *testing.T) (repository.MockRepository, *blob.FileStore, *chi.Mux) {
+	mockRepo := repository.NewRepositoryProductsMock()
+	mockRepo.Products[imageProduct] = &storage.Product{
+		Id: imageProduct, Name: "Product A", Quantity: 2, Code_value: "IMG",
+		Is_published: boolPtr(true), Expiration: "01/01/2030", Price: 10,
+	}
+
+	blobs := blob.NewFileStore(t.TempDir())
+	productService := service.NewServiceProducts(&mockRepo)
+	productService.Blobs = blobs
+	hd := NewHandlerProducts(&productService)
+
+	rt := chi.NewRouter()
+	rt.Get("/products", hd.GetAll)
+	rt.Get("/products/{id}", hd.GetById)
+	rt.Put("/products/{id}", hd.Update)
+	rt.Post("/products/{id}/images", hd.UploadImage)
+	rt.Put("/products/{id}/images/order", hd.ReorderImages)
+	rt.Get("/products/{id}/images/{imageId}", hd.GetImage)
+	rt.Get("/products/{id}/images/{imageId}/thumbnail", hd.GetThumbnail)
+	rt.Put("/products/{id}/images/{imageId}/primary", hd.SetPrimaryImage)
+	rt.Delete("/products/{id}/images/{imageId}", hd.DeleteImage)
+	rt.Get("/catalogue/{id}/images/{imageId}", hd.GetPublishedImage)
+	return mockRepo, blobs, rt
+}
+
+func newPNG(t *testing.T, width int, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func uploadImage(t *testing.T, rt http.Handler, productId string, content []byte, primary string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if content != nil {
+		part, err := form.CreateFormFile("image", "image.png")
+		require.NoError(t, err)
+		part.Write(content)
+	}
+	if primary != "" {
+		form.WriteField("primary", primary)
+	}
+	require.NoError(t, form.Close())
+
+	req, _ := http.NewRequest("POST", "/products/"+productId+"/images", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rr := httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestUploadImage(t *testing.T) {
+	tests := []struct {
+		name         string
+		productId    string
+		content      []byte
+		primary      string
+		expectedCode int
+	}{
+		{name: "PNG", productId: imageProduct, content: newPNG(t, 400, 300), expectedCode: http.StatusCreated},
+		{name: "Not an image", productId: imageProduct, content: []byte("just some text"), expectedCode: http.StatusUnsupportedMediaType},
+		{name: "Broken PNG", productId: imageProduct, content: newPNG(t, 40, 30)[:60], expectedCode: http.StatusBadRequest},
+		{name: "Too large", productId: imageProduct, content: append(newPNG(t, 4, 4), make([]byte, service.MaxImageBytes)...), expectedCode: http.StatusRequestEntityTooLarge},
+		{name: "Missing image field", productId: imageProduct, expectedCode: http.StatusBadRequest},
+		{name: "Invalid primary", productId: imageProduct, content: newPNG(t, 4, 4), primary: "maybe", expectedCode: http.StatusBadRequest},
+		{name: "Unknown product", productId: "684963bb-7172-48ad-aecd-cdca3f0df099", content: newPNG(t, 4, 4), expectedCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo, _, rt := newImageFixture(t)
+
+			rr := uploadImage(t, rt, tt.productId, tt.content, tt.primary)
+			require.Equal(t, tt.expectedCode, rr.Code, rr.Body.String())
+			if tt.expectedCode != http.StatusCreated {
+				require.Empty(t, mockRepo.Products[imageProduct].Images)
+				return
+			}
+
+			var data utils.ImageData
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &data))
+			require.Equal(t, "image/png", data.Content_type)
+			require.Equal(t, 400, data.Width)
+			require.Equal(t, 300, data.Height)
+			require.True(t, data.Primary, "the first image is the primary one")
+			require.Equal(t, "/products/"+imageProduct+"/images/"+data.Id, data.Url)
+			require.Equal(t, data.Url, rr.Header().Get("Location"))
+			require.Len(t, mockRepo.Products[imageProduct].Images, 1)
+		})
+	}
+}
+
+func TestReadsSendTheImageURLs(t *testing.T) {
+	mockRepo, _, rt := newImageFixture(t)
+	variant := "684963bb-7172-48ad-aecd-cdca3f0df0a1"
+	mockRepo.Products[variant] = &storage.Product{
+		Id: variant, Parent_id: imageProduct, Name: "Product A, M", Quantity: 1, Code_value: "IMG-M",
+		Is_published: boolPtr(true), Expiration: "01/01/2030", Price: 10,
+		Images: []storage.Image{{Id: "684963bb-7172-48ad-aecd-cdca3f0df0a2", Content_type: "image/png"}},
+	}
+
+	rr := uploadImage(t, rt, imageProduct, newPNG(t, 40, 30), "")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var uploaded utils.ImageData
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &uploaded))
+
+	rr = serve(rt, "GET", "/products/"+imageProduct, "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var product utils.ProductWithVariantsView
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &product))
+	require.Equal(t, []utils.ImageData{uploaded}, product.Images)
+	require.Len(t, product.Variants, 1)
+	require.Equal(t, "/products/"+variant+"/images/684963bb-7172-48ad-aecd-cdca3f0df0a2/thumbnail", product.Variants[0].Images[0].Thumbnail_url)
+
+	rr = serve(rt, "GET", "/products", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var products []utils.ProductView
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &products))
+	require.Len(t, products, 2)
+	for _, listed := range products {
+		require.Len(t, listed.Images, 1)
+		require.Equal(t, utils.ImageURL("/products", listed.Id, listed.Images[0].Id), listed.Images[0].Url)
+	}
+}
+
+func TestGetImageAndThumbnail(t *testing.T) {
+	_, _, rt := newImageFixture(t)
+	content := newPNG(t, 400, 300)
+
+	rr := uploadImage(t, rt, imageProduct, content, "")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var data utils.ImageData
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &data))
+
+	req, _ := http.NewRequest("GET", data.Url, nil)
+	rr = httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+	require.Equal(t, ImageCacheControl, rr.Header().Get("Cache-Control"))
+	require.Equal(t, content, rr.Body.Bytes())
+
+	req, _ = http.NewRequest("GET", data.Url, nil)
+	req.Header.Set("If-None-Match", rr.Header().Get("ETag"))
+	rr = httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotModified, rr.Code)
+
+	req, _ = http.NewRequest("GET", data.Thumbnail_url, nil)
+	rr = httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, service.ThumbnailContentType, rr.Header().Get("Content-Type"))
+	thumbnail, err := png.DecodeConfig(rr.Body)
+	require.NoError(t, err)
+	require.Equal(t, service.ThumbnailSize, thumbnail.Width)
+	require.Equal(t, 150, thumbnail.Height)
+
+	req, _ = http.NewRequest("GET", "/catalogue/"+imageProduct+"/images/"+data.Id, nil)
+	rr = httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	req, _ = http.NewRequest("GET", "/products/"+imageProduct+"/images/684963bb-7172-48ad-aecd-cdca3f0df099", nil)
+	rr = httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestPublishedImageOfHiddenProduct(t *testing.T) {
+	mockRepo, _, rt := newImageFixture(t)
+
+	rr := uploadImage(t, rt, imageProduct, newPNG(t, 4, 4), "")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	imageId := mockRepo.Products[imageProduct].Images[0].Id
+	mockRepo.Products[imageProduct].Is_published = boolPtr(false)
+
+	req, _ := http.NewRequest("GET", "/catalogue/"+imageProduct+"/images/"+imageId, nil)
+	rr = httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestManageImages(t *testing.T) {
+	mockRepo, blobs, rt := newImageFixture(t)
+
+	var ids []string
+	for _, primary := range []string{"", "false", "true"} {
+		rr := uploadImage(t, rt, imageProduct, newPNG(t, 8, 8), primary)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var data utils.ImageData
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &data))
+		ids = append(ids, data.Id)
+	}
+	primaries := func() []bool {
+		var flags []bool
+		for _, img := range mockRepo.Products[imageProduct].Images {
+			flags = append(flags, img.Primary)
+		}
+		return flags
+	}
+	require.Equal(t, []bool{false, false, true}, primaries(), "primary=true takes over")
+
+	// an update of the product keeps its images
+	update := `{"name":"Product AA","quantity":2,"code_value":"IMG","is_published":true,"expiration":"01/01/2030","price":12}`
+	req, _ := http.NewRequest("PUT", "/products/"+imageProduct, strings.NewReader(update))
+	rr := httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Len(t, mockRepo.Products[imageProduct].Images, 3)
+
+	for _, order := range []string{`{"ids":["` + ids[0] + `"]}`, `{"ids":["` + ids[0] + `","` + ids[0] + `","` + ids[1] + `"]}`} {
+		req, _ = http.NewRequest("PUT", "/products/"+imageProduct+"/images/order", strings.NewReader(order))
+		rr = httptest.NewRecorder()
+		rt.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusBadRequest, rr.Code, order)
+	}
+
+	order := `{"ids":["` + ids[2] + `","` + ids[0] + `","` + ids[1] + `"]}`
+	req, _ = http.NewRequest("PUT", "/products/"+imageProduct+"/images/order", strings.NewReader(order))
+	rr = httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var images []utils.ImageData
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &images))
+	require.Equal(t, ids[2], images[0].Id)
+	require.Equal(t, ids[0], images[1].Id)
+
+	req, _ = http.NewRequest("PUT", "/products/"+imageProduct+"/images/"+ids[1]+"/primary", nil)
+	rr = httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, []bool{false, false, true}, primaries())
+
+	req, _ = http.NewRequest("DELETE", "/products/"+imageProduct+"/images/"+ids[1], nil)
+	rr = httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	require.Equal(t, []bool{true, false}, primaries(), "the first remaining image becomes primary")
+
+	_, err := blobs.Get(storage.ImageKey(imageProduct, ids[1]))
+	require.True(t, errors.Is(err, blob.ErrNotFound))
+	_, err = blobs.Get(storage.ThumbnailKey(imageProduct, ids[1]))
+	require.True(t, errors.Is(err, blob.ErrNotFound))
+
+	data := utils.ToData(mockRepo.Products[imageProduct])
+	require.Len(t, data.Images, 2)
+	require.Equal(t, "/products/"+imageProduct+"/images/"+ids[2]+"/thumbnail", data.Images[0].Thumbnail_url)
+}
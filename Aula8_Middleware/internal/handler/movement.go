@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+type MovementController struct {
+	Service service.MovementService
+}
+
+func NewHandlerMovements(service service.MovementService) *MovementController {
+	return &MovementController{
+		Service: service,
+	}
+}
+
+func (c *MovementController) Create(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var reqBody utils.RequestBodyMovement
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	movement, err := c.Service.Record(storage.Movement{
+		Product_id: idStr,
+		Type:       reqBody.Type,
+		Quantity:   reqBody.Quantity,
+		Reason:     reqBody.Reason,
+		Actor:      reqBody.Actor,
+	})
+	if err != nil {
+		switch err.Error() {
+		case "product not found":
+			utils.ResponseWithError(w, err, http.StatusNotFound)
+		case "not enough stock":
+			utils.ResponseWithError(w, err, http.StatusConflict)
+		default:
+			utils.ResponseWithError(w, err, http.StatusBadRequest)
+		}
+		return
+	}
+
+	utils.RespondWithMovement(w, &movement, http.StatusCreated, utils.MessageMovementRecorded)
+}
+
+func (c *MovementController) GetAll(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	ledger, err := c.Service.GetLedger(idStr)
+	if err != nil {
+		if err.Error() == "product not found" {
+			utils.ResponseWithError(w, err, http.StatusNotFound)
+		} else {
+			utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	data := utils.LedgerData{
+		Product_id: idStr,
+		Quantity:   ledger.Quantity,
+		Balance:    ledger.Balance,
+		Reconciled: ledger.Reconciled,
+		Movements:  []utils.MovementData{},
+	}
+	for _, movement := range ledger.Movements {
+		data.Movements = append(data.Movements, utils.ToMovementData(movement))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
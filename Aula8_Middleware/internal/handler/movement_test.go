@@ -0,0 +1,282 @@
+package handler
+
+import (
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const stockedProduct = "684963bb-7172-48ad-aecd-cdca3f0df030"
+
+func newMovementRouter(mockRepo *repository.MockRepository, mockMovementRepo *repository.MockMovementRepository) *chi.Mux {
+	productService := service.NewServiceProducts(mockRepo)
+	productService.Movements = mockMovementRepo
+	movementService := service.NewServiceMovements(mockMovementRepo, mockRepo)
+
+	productHandler := NewHandlerProducts(&productService)
+	movementHandler := NewHandlerMovements(&movementService)
+
+	rt := chi.NewRouter()
+	rt.Get("/products/low-stock", productHandler.GetLowStock)
+	rt.Put("/products/{id}", productHandler.UpdateOrCreate)
+	rt.Get("/products/{id}/movements", movementHandler.GetAll)
+	rt.Post("/products/{id}/movements", movementHandler.Create)
+	return rt
+}
+
+func newStockedProduct() *storage.Product {
+	return &storage.Product{
+		Id:                  stockedProduct,
+		Name:                "Coffee",
+		Quantity:            10,
+		Code_value:          "COF",
+		Is_published:        boolPtr(true),
+		Expiration:          "01/01/2030",
+		Price:               5.0,
+		Low_stock_threshold: 3,
+	}
+}
+
+func TestCreateMovement(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectedCode     int
+		expectedQuantity int
+	}{
+		{
+			name:             "Receipt",
+			body:             `{"type":"receipt","quantity":5,"reason":"supplier delivery","actor":"ana"}`,
+			expectedCode:     http.StatusCreated,
+			expectedQuantity: 15,
+		},
+		{
+			name:             "Sale",
+			body:             `{"type":"sale","quantity":4,"actor":"shop"}`,
+			expectedCode:     http.StatusCreated,
+			expectedQuantity: 6,
+		},
+		{
+			name:             "Negative adjustment",
+			body:             `{"type":"adjustment","quantity":-2,"reason":"broken","actor":"ana"}`,
+			expectedCode:     http.StatusCreated,
+			expectedQuantity: 8,
+		},
+		{
+			name:             "Sale beyond stock",
+			body:             `{"type":"sale","quantity":11,"actor":"shop"}`,
+			expectedCode:     http.StatusConflict,
+			expectedQuantity: 10,
+		},
+		{
+			name:             "Negative receipt",
+			body:             `{"type":"receipt","quantity":-1,"actor":"ana"}`,
+			expectedCode:     http.StatusBadRequest,
+			expectedQuantity: 10,
+		},
+		{
+			name:             "Unknown type",
+			body:             `{"type":"theft","quantity":1,"actor":"ana"}`,
+			expectedCode:     http.StatusBadRequest,
+			expectedQuantity: 10,
+		},
+		{
+			name:             "Missing actor",
+			body:             `{"type":"receipt","quantity":1}`,
+			expectedCode:     http.StatusBadRequest,
+			expectedQuantity: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := repository.NewRepositoryProductsMock()
+			mockRepo.Products[stockedProduct] = newStockedProduct()
+			mockMovementRepo := repository.NewRepositoryMovementsMock()
+			rt := newMovementRouter(&mockRepo, &mockMovementRepo)
+
+			req, _ := http.NewRequest("POST", "/products/"+stockedProduct+"/movements", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code, "handler returned wrong status code: %s", rr.Body.String())
+			require.Equal(t, tt.expectedQuantity, mockRepo.Products[stockedProduct].Quantity)
+		})
+	}
+}
+
+func TestGetMovementsReconciles(t *testing.T) {
+	mockRepo := repository.NewRepositoryProductsMock()
+	mockRepo.Products[stockedProduct] = newStockedProduct()
+	mockMovementRepo := repository.NewRepositoryMovementsMock()
+	rt := newMovementRouter(&mockRepo, &mockMovementRepo)
+
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{"POST", "/products/" + stockedProduct + "/movements", `{"type":"sale","quantity":3,"actor":"shop"}`},
+		{"POST", "/products/" + stockedProduct + "/movements", `{"type":"return","quantity":1,"actor":"shop"}`},
+		{"PUT", "/products/" + stockedProduct, `{"name":"Coffee","quantity":20,"code_value":"COF","is_published":true,"expiration":"01/01/2030","price":5}`},
+	}
+	for _, request := range requests {
+		req, _ := http.NewRequest(request.method, request.path, strings.NewReader(request.body))
+		rr := httptest.NewRecorder()
+		rt.ServeHTTP(rr, req)
+		require.Less(t, rr.Code, 300, "%s %s failed: %s", request.method, request.path, rr.Body.String())
+	}
+
+	req, _ := http.NewRequest("GET", "/products/"+stockedProduct+"/movements", nil)
+	rr := httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var ledger utils.LedgerData
+	err := json.NewDecoder(rr.Body).Decode(&ledger)
+	require.NoError(t, err, "could not decode response body")
+
+	require.Equal(t, 20, ledger.Quantity)
+	require.Equal(t, 20, ledger.Balance)
+	require.True(t, ledger.Reconciled)
+
+	var types []string
+	for _, movement := range ledger.Movements {
+		types = append(types, movement.Type)
+	}
+	require.Equal(t, []string{"adjustment", "sale", "return", "adjustment"}, types, "the opening balance must come first")
+	require.Equal(t, service.ActorSystem, ledger.Movements[0].Actor)
+	require.Equal(t, 12, ledger.Movements[3].Quantity)
+}
+
+func TestConcurrentWritesOpenTheLedgerOnce(t *testing.T) {
+	st := storage.NewStorageProducts()
+	st.Path = filepath.Join(t.TempDir(), "products.json")
+	// the product was stored before the ledger, its first write opens it
+	require.NoError(t, st.SaveProduct(newStockedProduct()))
+	rp := repository.NewRepositoryProducts(&st)
+	movementStorage := storage.NewStorageMovements()
+	movementStorage.Path = filepath.Join(t.TempDir(), "movements.json")
+	movementRepo := repository.NewRepositoryMovements(&movementStorage)
+
+	productService := service.NewServiceProducts(&rp)
+	productService.Movements = &movementRepo
+	movementService := service.NewServiceMovements(&movementRepo, &rp)
+	productHandler := NewHandlerProducts(&productService)
+	movementHandler := NewHandlerMovements(&movementService)
+	rt := chi.NewRouter()
+	rt.Patch("/products/{id}", productHandler.Update)
+	rt.Post("/products/{id}/movements", movementHandler.Create)
+	rt.Get("/products/{id}/movements", movementHandler.GetAll)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			rr := serve(rt, "PATCH", "/products/"+stockedProduct, `{"quantity":`+strings.Repeat("1", 1+i%2)+`}`)
+			assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		}(i)
+		go func() {
+			defer wg.Done()
+			rr := serve(rt, "POST", "/products/"+stockedProduct+"/movements", `{"type":"receipt","quantity":2,"actor":"shop"}`)
+			assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		}()
+	}
+	wg.Wait()
+
+	rr := serve(rt, "GET", "/products/"+stockedProduct+"/movements", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var ledger utils.LedgerData
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&ledger))
+	require.True(t, ledger.Reconciled, "the ledger has %d for a quantity of %d", ledger.Balance, ledger.Quantity)
+
+	openings := 0
+	for _, movement := range ledger.Movements {
+		if movement.Actor == service.ActorSystem {
+			openings++
+			require.Equal(t, 10, movement.Quantity, "the opening balance is the quantity before the first write")
+		}
+	}
+	require.Equal(t, 1, openings)
+}
+
+func TestGetLowStock(t *testing.T) {
+	mockRepo := repository.NewRepositoryProductsMock()
+	mockRepo.Products[stockedProduct] = newStockedProduct()
+	mockMovementRepo := repository.NewRepositoryMovementsMock()
+	rt := newMovementRouter(&mockRepo, &mockMovementRepo)
+
+	lowStock := func() []storage.Product {
+		req, _ := http.NewRequest("GET", "/products/low-stock", nil)
+		rr := httptest.NewRecorder()
+		rt.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var products []storage.Product
+		err := json.NewDecoder(rr.Body).Decode(&products)
+		require.NoError(t, err, "could not decode response body")
+		return products
+	}
+
+	require.Empty(t, lowStock())
+
+	req, _ := http.NewRequest("POST", "/products/"+stockedProduct+"/movements", strings.NewReader(`{"type":"sale","quantity":7,"actor":"shop"}`))
+	rr := httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	products := lowStock()
+	require.Len(t, products, 1)
+	require.Equal(t, stockedProduct, products[0].Id)
+}
+
+// failingLedger refuses every movement
+type failingLedger struct {
+	repository.MockMovementRepository
+}
+
+func (l *failingLedger) Create(movement storage.Movement) (storage.Movement, error) {
+	return storage.Movement{}, errors.New("ledger unavailable")
+}
+
+// eventRecorder keeps the types of the events it receives
+type eventRecorder []string
+
+func (r *eventRecorder) Publish(event service.Event) {
+	*r = append(*r, event.Type)
+}
+
+func TestSavedProductSurvivesALedgerFailure(t *testing.T) {
+	mockRepo := repository.NewRepositoryProductsMock()
+	productService := service.NewServiceProducts(&mockRepo)
+	productService.Movements = &failingLedger{}
+	var events eventRecorder
+	productService.Events = &events
+	rt := chi.NewRouter()
+	rt.Put("/products/{id}", NewHandlerProducts(&productService).UpdateOrCreate)
+
+	body := `{"name":"Coffee","quantity":10,"code_value":"COF","is_published":true,"expiration":"01/01/2030","price":5}`
+	for _, expectedCode := range []int{http.StatusCreated, http.StatusOK} {
+		// the retry of a create the ledger refused finds the product saved
+		rr := serve(rt, "PUT", "/products/"+stockedProduct, body)
+		require.Equal(t, expectedCode, rr.Code, rr.Body.String())
+	}
+
+	require.Contains(t, mockRepo.Products, stockedProduct)
+	require.Equal(t, []string{service.EventProductCreated}, []string(events), "the write is published")
+}
@@ -0,0 +1,868 @@
+package handler
+
+import (
+	"aula4/internal/middleware"
+	"aula4/internal/openapi"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"aula4/internal/webhook"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	OpenAPIPath = "/openapi.json"
+	DocsPath    = "/docs"
+
+	securityToken = "Token"
+)
+
+// NewOpenAPI documents every route registered by the API
+func NewOpenAPI() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "Products API",
+		Description: "Product catalogue. Every route but the catalogue requires the Token header and JSON bodies are validated against this spec. A tenant token, or the X-Tenant-ID header, selects the catalogue of a tenant.",
+		Version:     "1.0.0",
+	})
+
+	doc.AddSecurityScheme(securityToken, openapi.SecurityScheme{
+		Type: "apiKey",
+		Name: "Token",
+		In:   "header",
+	})
+
+	errorBody := utils.ResponseBodyProduct{}
+
+	imageUpload := &openapi.Schema{
+		Type: openapi.TypeObject,
+		Properties: map[string]*openapi.Schema{
+			"image":   {Type: openapi.TypeString, Format: "binary"},
+			"primary": {Type: openapi.TypeBoolean, Description: "make it the primary image, the first image of a product always is"},
+		},
+		Required: []string{"image"},
+	}
+
+	doc.Add(
+		openapi.Route{
+			Method:    http.MethodGet,
+			Path:      OpenAPIPath,
+			Summary:   "OpenAPI specification of this API",
+			Tags:      []string{"docs"},
+			Responses: map[int]any{http.StatusOK: &openapi.Schema{Type: openapi.TypeObject}},
+		},
+		openapi.Route{
+			Method:    http.MethodGet,
+			Path:      DocsPath,
+			Summary:   "Html page rendering the specification",
+			Tags:      []string{"docs"},
+			Responses: map[int]any{http.StatusOK: nil},
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/products",
+			Summary: "List all products, answering If-None-Match and If-Modified-Since with 304",
+			Tags:    []string{"products"},
+			Query: []openapi.Parameter{
+				{Name: "category", In: "query", Description: "only products of this category or of its subcategories", Schema: &openapi.Schema{Type: openapi.TypeString}},
+				{Name: "tag", In: "query", Description: "only products with this tag", Schema: &openapi.Schema{Type: openapi.TypeString}},
+			},
+			Responses: map[int]any{
+				http.StatusOK:                  []utils.ProductView{},
+				http.StatusNotModified:         nil,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/products/{id}",
+			Summary: "Get a product by id, answering If-None-Match and If-Modified-Since with 304",
+			Tags:    []string{"products"},
+			Responses: map[int]any{
+				http.StatusOK:                  utils.ProductWithVariantsView{},
+				http.StatusNotModified:         nil,
+				http.StatusBadRequest:          errorBody,
+				http.StatusNotFound:            errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/products/by-code/{code}",
+			Summary: "Get the product of a scanned code, a UPC-A, EAN-13 or GTIN-14 finding the product of the same item",
+			Tags:    []string{"products"},
+			Responses: map[int]any{
+				http.StatusOK:                  utils.ProductWithVariantsView{},
+				http.StatusNotModified:         nil,
+				http.StatusBadRequest:          errorBody,
+				http.StatusNotFound:            errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/products/{id}/barcode",
+			Summary: "Draw the barcode of the code_value, EAN-13 for ean13 and upca, ITF-14 for gtin14 and Code 128 otherwise",
+			Tags:    []string{"products"},
+			Query: []openapi.Parameter{
+				{Name: "format", In: "query", Description: "png by default", Schema: &openapi.Schema{Type: openapi.TypeString, Enum: []string{"png", "svg"}}},
+				{Name: "scale", In: "query", Description: "width in pixels of the narrowest bar, from 1 to " + strconv.Itoa(MaxBarcodeScale) + ", " + strconv.Itoa(DefaultBarcodeScale) + " by default", Schema: &openapi.Schema{Type: openapi.TypeInteger}},
+			},
+			Responses: map[int]any{
+				http.StatusOK:                  &openapi.Schema{Type: openapi.TypeString, Format: "binary", Description: "image/png or image/svg+xml"},
+				http.StatusBadRequest:          errorBody,
+				http.StatusNotFound:            errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/products/{id}/prices",
+			Summary: "Price timeline of a product: the past changes, the price in effect and the scheduled prices",
+			Tags:    []string{"pricing"},
+			Responses: map[int]any{
+				http.StatusOK:                  utils.PriceTimelineData{},
+				http.StatusBadRequest:          errorBody,
+				http.StatusNotFound:            errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodPost,
+			Path:    "/products/{id}/prices",
+			Summary: "Schedule a price from a future date on, the scheduler applies it once it is due",
+			Tags:    []string{"pricing"},
+			Body:    utils.RequestBodyPrice{},
+			Responses: map[int]any{
+				http.StatusCreated:               utils.PriceChangeData{},
+				http.StatusBadRequest:            errorBody,
+				http.StatusNotFound:              errorBody,
+				http.StatusConflict:              errorBody,
+				http.StatusRequestEntityTooLarge: errorBody,
+				http.StatusUnsupportedMediaType:  errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodDelete,
+			Path:    "/products/{id}/prices/{priceId}",
+			Summary: "Cancel a scheduled price",
+			Tags:    []string{"pricing"},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          errorBody,
+				http.StatusNotFound:            errorBody,
+				http.StatusConflict:            errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/products/search",
+			Summary: "List products more expensive than price, or rank the products matching q",
+			Tags:    []string{"products"},
+			Query: []openapi.Parameter{
+				{Name: "price", In: "query", Description: "required without q, the response is then a list of products", Schema: &openapi.Schema{Type: openapi.TypeNumber}},
+				{Name: "q", In: "query", Description: "words to find in the name or the code, accents and case are ignored, the last letters and small typos may be missing", Schema: &openapi.Schema{Type: openapi.TypeString}},
+				{Name: "page", In: "query", Description: "page of the q results, starting at 1", Schema: &openapi.Schema{Type: openapi.TypeInteger}},
+				{Name: "page_size", In: "query", Description: "q results per page, 20 by default and 100 at most", Schema: &openapi.Schema{Type: openapi.TypeInteger}},
+			},
+			Responses: map[int]any{
+				http.StatusOK:                  utils.ResponseBodySearch{},
+				http.StatusBadRequest:          errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/products/consumer_price",
+			Summary: "Quote the consumer price of a list of products",
+			Tags:    []string{"products"},
+			Query: []openapi.Parameter{
+				{Name: "list", In: "query", Description: "comma separated product or variant ids, every stock unit when empty", Schema: &openapi.Schema{Type: openapi.TypeString}},
+				{Name: "at", In: "query", Description: "date of the prices of the quote, now when empty", Schema: &openapi.Schema{Type: openapi.TypeString, Format: "date-time"}},
+			},
+			Responses: map[int]any{
+				http.StatusOK:         utils.ResponseBodyTotalPrice{},
+				http.StatusBadRequest: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/products/tags",
+			Summary: "Number of products of every tag",
+			Tags:    []string{"products"},
+			Responses: map[int]any{
+				http.StatusOK:                  openapi.MapOf(0),
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/products/low-stock",
+			Summary: "List the products whose quantity reached their low stock threshold",
+			Tags:    []string{"inventory"},
+			Responses: map[int]any{
+				http.StatusOK:                  []utils.ProductView{},
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/products/schedule",
+			Summary: "List the pending publish_at and unpublish_at changes, the earliest first",
+			Tags:    []string{"publishing"},
+			Responses: map[int]any{
+				http.StatusOK:                  []utils.ScheduledChangeData{},
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/catalogue",
+			Summary: "List the products published now and not expired, without a token, on the public router",
+			Tags:    []string{"publishing"},
+			Responses: map[int]any{
+				http.StatusOK:                  []utils.PublicData{},
+				http.StatusNotModified:         nil,
+				http.StatusNotAcceptable:       errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/catalogue/{id}",
+			Summary: "Get a product published now and not expired, without a token, on the public router",
+			Tags:    []string{"publishing"},
+			Responses: map[int]any{
+				http.StatusOK:                  utils.PublicData{},
+				http.StatusNotModified:         nil,
+				http.StatusBadRequest:          errorBody,
+				http.StatusNotFound:            errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+		},
+		openapi.Route{
+			Method:          http.MethodPost,
+			Path:            "/products/{id}/images",
+			Summary:         "Upload an image of the product, PNG, JPEG or GIF up to 5 MiB, and generate its thumbnail",
+			Tags:            []string{"images"},
+			Body:            imageUpload,
+			BodyContentType: openapi.ContentTypeMultipart,
+			MaxBodyBytes:    MaxImageUploadBytes,
+			Responses: map[int]any{
+				http.StatusCreated:               utils.ImageData{},
+				http.StatusBadRequest:            errorBody,
+				http.StatusNotFound:              errorBody,
+				http.StatusRequestEntityTooLarge: errorBody,
+				http.StatusUnsupportedMediaType:  errorBody,
+				http.StatusInternalServerError:   errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodPut,
+			Path:    "/products/{id}/images/order",
+			Summary: "Set the display order of the images, listing every image once",
+			Tags:    []string{"images"},
+			Body:    utils.RequestBodyImageOrder{},
+			Responses: map[int]any{
+				http.StatusOK:                  []utils.ImageData{},
+				http.StatusBadRequest:          errorBody,
+				http.StatusNotFound:            errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/products/{id}/images/{imageId}",
+			Summary: "Download an image",
+			Tags:    []string{"images"},
+			Responses: map[int]any{
+				http.StatusOK:          &openapi.Schema{Type: openapi.TypeString, Format: "binary", Description: "the image in its uploaded format"},
+				http.StatusNotModified: nil,
+				http.StatusBadRequest:  errorBody,
+				http.StatusNotFound:    errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/products/{id}/images/{imageId}/thumbnail",
+			Summary: "Download the PNG thumbnail of an image",
+			Tags:    []string{"images"},
+			Responses: map[int]any{
+				http.StatusOK:          &openapi.Schema{Type: openapi.TypeString, Format: "binary", Description: "image/png"},
+				http.StatusNotModified: nil,
+				http.StatusBadRequest:  errorBody,
+				http.StatusNotFound:    errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodPut,
+			Path:    "/products/{id}/images/{imageId}/primary",
+			Summary: "Make the image the primary one of the product",
+			Tags:    []string{"images"},
+			Responses: map[int]any{
+				http.StatusOK:                  []utils.ImageData{},
+				http.StatusBadRequest:          errorBody,
+				http.StatusNotFound:            errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodDelete,
+			Path:    "/products/{id}/images/{imageId}",
+			Summary: "Delete an image and its thumbnail",
+			Tags:    []string{"images"},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          errorBody,
+				http.StatusNotFound:            errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/catalogue/{id}/images/{imageId}",
+			Summary: "Download an image of a product of the public catalogue, without a token",
+			Tags:    []string{"publishing"},
+			Responses: map[int]any{
+				http.StatusOK:          &openapi.Schema{Type: openapi.TypeString, Format: "binary", Description: "the image in its uploaded format"},
+				http.StatusNotModified: nil,
+				http.StatusBadRequest:  errorBody,
+				http.StatusNotFound:    errorBody,
+			},
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/catalogue/{id}/images/{imageId}/thumbnail",
+			Summary: "Download the PNG thumbnail of an image of a product of the public catalogue, without a token",
+			Tags:    []string{"publishing"},
+			Responses: map[int]any{
+				http.StatusOK:          &openapi.Schema{Type: openapi.TypeString, Format: "binary", Description: "image/png"},
+				http.StatusNotModified: nil,
+				http.StatusBadRequest:  errorBody,
+				http.StatusNotFound:    errorBody,
+			},
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/products/stream",
+			Summary: "Server-sent events of the product changes, resumed with the Last-Event-ID header",
+			Tags:    []string{"products"},
+			Query: []openapi.Parameter{
+				{Name: "product", In: "query", Description: "comma separated product ids, their variants included", Schema: &openapi.Schema{Type: openapi.TypeString}},
+				{Name: "fields", In: "query", Description: "comma separated fields, only the product.updated events changing one of them", Schema: &openapi.Schema{Type: openapi.TypeString}},
+			},
+			Responses: map[int]any{
+				http.StatusOK:                  &openapi.Schema{Type: openapi.TypeString, Description: "text/event-stream of " + strings.Join(service.EventTypes, ", ") + " events"},
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/products/{id}/movements",
+			Summary: "Inventory ledger of a product reconciled against its quantity",
+			Tags:    []string{"inventory"},
+			Responses: map[int]any{
+				http.StatusOK:                  utils.LedgerData{},
+				http.StatusBadRequest:          errorBody,
+				http.StatusNotFound:            errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodPost,
+			Path:    "/products/{id}/movements",
+			Summary: "Record a receipt, sale, adjustment or return and apply it to the stock",
+			Tags:    []string{"inventory"},
+			Body:    utils.RequestBodyMovement{},
+			Responses: map[int]any{
+				http.StatusCreated:               utils.ResponseBodyMovement{},
+				http.StatusBadRequest:            errorBody,
+				http.StatusNotFound:              errorBody,
+				http.StatusConflict:              errorBody,
+				http.StatusRequestEntityTooLarge: errorBody,
+				http.StatusUnsupportedMediaType:  errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/products/{id}/variants",
+			Summary: "List the variants of a product",
+			Tags:    []string{"products"},
+			Responses: map[int]any{
+				http.StatusOK:                  []utils.ProductView{},
+				http.StatusBadRequest:          errorBody,
+				http.StatusNotFound:            errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodPost,
+			Path:    "/products/{id}/variants",
+			Summary: "Create a variant of a product, inheriting the fields left empty",
+			Tags:    []string{"products"},
+			Body:    utils.RequestBodyVariant{},
+			Responses: map[int]any{
+				http.StatusCreated:               utils.ResponseBodyProduct{},
+				http.StatusBadRequest:            errorBody,
+				http.StatusNotFound:              errorBody,
+				http.StatusRequestEntityTooLarge: errorBody,
+				http.StatusUnsupportedMediaType:  errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodPost,
+			Path:    "/products",
+			Summary: "Create a product",
+			Tags:    []string{"products"},
+			Body:    utils.RequestBodyProduct{},
+			Responses: map[int]any{
+				http.StatusCreated:               utils.ResponseBodyProduct{},
+				http.StatusBadRequest:            errorBody,
+				http.StatusRequestEntityTooLarge: errorBody,
+				http.StatusUnsupportedMediaType:  errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodPut,
+			Path:    "/products/{id}",
+			Summary: "Replace a product, creating it when it does not exist",
+			Tags:    []string{"products"},
+			Body:    utils.RequestBodyProduct{},
+			Responses: map[int]any{
+				http.StatusOK:                    utils.ResponseBodyProduct{},
+				http.StatusCreated:               utils.ResponseBodyProduct{},
+				http.StatusBadRequest:            errorBody,
+				http.StatusRequestEntityTooLarge: errorBody,
+				http.StatusUnsupportedMediaType:  errorBody,
+				http.StatusInternalServerError:   errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodPatch,
+			Path:    "/products/{id}",
+			Summary: "Update some fields of a product",
+			Tags:    []string{"products"},
+			Body:    openapi.Partial(utils.RequestBodyProduct{}),
+			Responses: map[int]any{
+				http.StatusOK:                    utils.ResponseBodyProduct{},
+				http.StatusBadRequest:            errorBody,
+				http.StatusNotFound:              errorBody,
+				http.StatusRequestEntityTooLarge: errorBody,
+				http.StatusUnsupportedMediaType:  errorBody,
+				http.StatusInternalServerError:   errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodDelete,
+			Path:    "/products/{id}",
+			Summary: "Delete a product",
+			Tags:    []string{"products"},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          errorBody,
+				http.StatusNotFound:            errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/categories",
+			Summary: "List all categories",
+			Tags:    []string{"categories"},
+			Responses: map[int]any{
+				http.StatusOK:                  []utils.CategoryData{},
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/categories/tree",
+			Summary: "Category hierarchy with the product count, stock and value of every branch",
+			Tags:    []string{"categories"},
+			Responses: map[int]any{
+				http.StatusOK:                  []utils.CategoryTreeData{},
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/categories/{id}",
+			Summary: "Get a category by id",
+			Tags:    []string{"categories"},
+			Responses: map[int]any{
+				http.StatusOK:         utils.CategoryData{},
+				http.StatusBadRequest: errorBody,
+				http.StatusNotFound:   errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/categories/{id}/summary",
+			Summary: "Product count, stock and value of a category and its subcategories",
+			Tags:    []string{"categories"},
+			Responses: map[int]any{
+				http.StatusOK:         utils.CategorySummaryData{},
+				http.StatusBadRequest: errorBody,
+				http.StatusNotFound:   errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodPost,
+			Path:    "/categories",
+			Summary: "Create a category, optionally under a parent",
+			Tags:    []string{"categories"},
+			Body:    utils.RequestBodyCategory{},
+			Responses: map[int]any{
+				http.StatusCreated:    utils.ResponseBodyCategory{},
+				http.StatusBadRequest: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodPut,
+			Path:    "/categories/{id}",
+			Summary: "Rename or move a category",
+			Tags:    []string{"categories"},
+			Body:    utils.RequestBodyCategory{},
+			Responses: map[int]any{
+				http.StatusOK:         utils.ResponseBodyCategory{},
+				http.StatusBadRequest: errorBody,
+				http.StatusNotFound:   errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodDelete,
+			Path:    "/categories/{id}",
+			Summary: "Delete a category without subcategories or products",
+			Tags:    []string{"categories"},
+			Responses: map[int]any{
+				http.StatusNoContent:  nil,
+				http.StatusBadRequest: errorBody,
+				http.StatusNotFound:   errorBody,
+				http.StatusConflict:   errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/suppliers",
+			Summary: "List the suppliers",
+			Tags:    []string{"purchasing"},
+			Responses: map[int]any{
+				http.StatusOK:                  []utils.SupplierData{},
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/suppliers/{id}",
+			Summary: "Get a supplier by id",
+			Tags:    []string{"purchasing"},
+			Responses: map[int]any{
+				http.StatusOK:                  utils.SupplierData{},
+				http.StatusBadRequest:          errorBody,
+				http.StatusNotFound:            errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodPost,
+			Path:    "/suppliers",
+			Summary: "Create a supplier",
+			Tags:    []string{"purchasing"},
+			Body:    utils.RequestBodySupplier{},
+			Responses: map[int]any{
+				http.StatusCreated:               utils.ResponseBodySupplier{},
+				http.StatusBadRequest:            errorBody,
+				http.StatusRequestEntityTooLarge: errorBody,
+				http.StatusUnsupportedMediaType:  errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodPut,
+			Path:    "/suppliers/{id}",
+			Summary: "Replace the details of a supplier",
+			Tags:    []string{"purchasing"},
+			Body:    utils.RequestBodySupplier{},
+			Responses: map[int]any{
+				http.StatusOK:                    utils.ResponseBodySupplier{},
+				http.StatusBadRequest:            errorBody,
+				http.StatusNotFound:              errorBody,
+				http.StatusRequestEntityTooLarge: errorBody,
+				http.StatusUnsupportedMediaType:  errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodDelete,
+			Path:    "/suppliers/{id}",
+			Summary: "Delete a supplier without open purchase orders",
+			Tags:    []string{"purchasing"},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          errorBody,
+				http.StatusNotFound:            errorBody,
+				http.StatusConflict:            errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/purchase-orders",
+			Summary: "List the purchase orders by creation date",
+			Tags:    []string{"purchasing"},
+			Query: []openapi.Parameter{
+				{Name: "status", In: "query", Description: "only the orders with this status", Schema: &openapi.Schema{Type: openapi.TypeString, Enum: PurchaseOrderStatuses}},
+				{Name: "supplier", In: "query", Description: "only the orders of this supplier", Schema: &openapi.Schema{Type: openapi.TypeString}},
+				{Name: "product", In: "query", Description: "only the orders with a line for this product", Schema: &openapi.Schema{Type: openapi.TypeString}},
+			},
+			Responses: map[int]any{
+				http.StatusOK:                  []utils.PurchaseOrderData{},
+				http.StatusBadRequest:          errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/purchase-orders/inbound",
+			Summary: "Quantities ordered and not received yet, by product",
+			Tags:    []string{"purchasing"},
+			Query: []openapi.Parameter{
+				{Name: "product", In: "query", Description: "only this product", Schema: &openapi.Schema{Type: openapi.TypeString}},
+			},
+			Responses: map[int]any{
+				http.StatusOK:                  []utils.InboundData{},
+				http.StatusBadRequest:          errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/purchase-orders/{id}",
+			Summary: "Get a purchase order by id",
+			Tags:    []string{"purchasing"},
+			Responses: map[int]any{
+				http.StatusOK:                  utils.PurchaseOrderData{},
+				http.StatusBadRequest:          errorBody,
+				http.StatusNotFound:            errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodPost,
+			Path:    "/purchase-orders",
+			Summary: "Open a purchase order of a supplier, one line per product",
+			Tags:    []string{"purchasing"},
+			Body:    utils.RequestBodyPurchaseOrder{},
+			Responses: map[int]any{
+				http.StatusCreated:               utils.ResponseBodyPurchaseOrder{},
+				http.StatusBadRequest:            errorBody,
+				http.StatusRequestEntityTooLarge: errorBody,
+				http.StatusUnsupportedMediaType:  errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodPost,
+			Path:    "/purchase-orders/{id}/receive",
+			Summary: "Receive a delivery, recording a receipt movement per line. Without lines the whole outstanding quantity is received",
+			Tags:    []string{"purchasing"},
+			Body:    utils.RequestBodyReceipt{},
+			Responses: map[int]any{
+				http.StatusOK:                    utils.ResponseBodyPurchaseOrder{},
+				http.StatusBadRequest:            errorBody,
+				http.StatusNotFound:              errorBody,
+				http.StatusConflict:              errorBody,
+				http.StatusRequestEntityTooLarge: errorBody,
+				http.StatusUnsupportedMediaType:  errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodPost,
+			Path:    "/purchase-orders/{id}/cancel",
+			Summary: "Cancel the outstanding quantities of a purchase order",
+			Tags:    []string{"purchasing"},
+			Responses: map[int]any{
+				http.StatusOK:         utils.ResponseBodyPurchaseOrder{},
+				http.StatusBadRequest: errorBody,
+				http.StatusNotFound:   errorBody,
+				http.StatusConflict:   errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/webhooks",
+			Summary: "List the webhook subscribers",
+			Tags:    []string{"webhooks"},
+			Responses: map[int]any{
+				http.StatusOK:                  []utils.SubscriberData{},
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodPost,
+			Path:    "/webhooks",
+			Summary: "Subscribe a url to product.created, product.updated, product.deleted or stock.low. Deliveries are signed with HMAC-SHA256 in X-Webhook-Signature",
+			Tags:    []string{"webhooks"},
+			Body:    utils.RequestBodySubscriber{},
+			Responses: map[int]any{
+				http.StatusCreated:               utils.ResponseBodySubscriber{},
+				http.StatusBadRequest:            errorBody,
+				http.StatusRequestEntityTooLarge: errorBody,
+				http.StatusUnsupportedMediaType:  errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodDelete,
+			Path:    "/webhooks/{id}",
+			Summary: "Unsubscribe",
+			Tags:    []string{"webhooks"},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          errorBody,
+				http.StatusNotFound:            errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/webhooks/deliveries",
+			Summary: "Log of the recent delivery attempts",
+			Tags:    []string{"webhooks"},
+			Query: []openapi.Parameter{
+				{Name: "subscriber", In: "query", Description: "only the attempts made to this subscriber", Schema: &openapi.Schema{Type: openapi.TypeString}},
+			},
+			Responses: map[int]any{
+				http.StatusOK: []webhook.Delivery{},
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/webhooks/dead-letters",
+			Summary: "Deliveries that failed every attempt",
+			Tags:    []string{"webhooks"},
+			Responses: map[int]any{
+				http.StatusOK: []webhook.DeadLetter{},
+			},
+			Security: securityToken,
+		},
+		openapi.Route{
+			Method:  http.MethodPost,
+			Path:    "/graphql",
+			Summary: "Run a GraphQL query or mutation over the products, the schema is served by introspection",
+			Tags:    []string{"graphql"},
+			Body:    utils.RequestBodyGraphQL{},
+			Responses: map[int]any{
+				http.StatusOK:                    utils.ResponseBodyGraphQL{},
+				http.StatusBadRequest:            errorBody,
+				http.StatusRequestEntityTooLarge: errorBody,
+				http.StatusUnsupportedMediaType:  errorBody,
+			},
+			Security: securityToken,
+		},
+	)
+
+	documentIdempotencyKey(doc)
+	documentFormats(doc, map[string]any{
+		"/products":                utils.ProductsXML{},
+		"/products/search":         utils.ResponseBodySearch{},
+		"/products/consumer_price": utils.ResponseBodyTotalPrice{},
+	})
+
+	return doc
+}
+
+// documentFormats adds the CSV and XML representations of the listings, mapping their
+// path to the XML body, see utils.Negotiate
+func documentFormats(doc *openapi.Document, xmlBodies map[string]any) {
+	for path, xmlBody := range xmlBodies {
+		op, _ := doc.Operation(http.MethodGet, path)
+
+		ok := op.Responses[strconv.Itoa(http.StatusOK)]
+		ok.Description += ", as JSON, CSV or XML following the Accept header, gzip compressed when accepted"
+		ok.Content[utils.ContentTypeCSV] = openapi.MediaType{Schema: &openapi.Schema{Type: openapi.TypeString}}
+		ok.Content[utils.ContentTypeXML] = openapi.MediaType{Schema: openapi.SchemaOf(xmlBody)}
+		op.Responses[strconv.Itoa(http.StatusOK)] = ok
+
+		errorResponse, found := op.Responses[strconv.Itoa(http.StatusBadRequest)]
+		if !found {
+			errorResponse = op.Responses[strconv.Itoa(http.StatusInternalServerError)]
+		}
+		op.Responses[strconv.Itoa(http.StatusNotAcceptable)] = openapi.Response{
+			Description: http.StatusText(http.StatusNotAcceptable),
+			Content:     errorResponse.Content,
+		}
+	}
+}
+
+// documentIdempotencyKey adds the Idempotency-Key header to the POST and PATCH routes
+// protected by the token, see middleware.Idempotency
+func documentIdempotencyKey(doc *openapi.Document) {
+	for _, item := range doc.Paths {
+		for method, op := range item {
+			if method != "post" && method != "patch" || len(op.Security) == 0 {
+				continue
+			}
+
+			op.Parameters = append(op.Parameters, openapi.Parameter{
+				Name:        middleware.HeaderIdempotencyKey,
+				In:          "header",
+				Description: "retries with the same key and body replay the first response",
+				Schema:      &openapi.Schema{Type: openapi.TypeString},
+			})
+			// the error body is the one of the bad requests of the route
+			errorResponse := op.Responses[strconv.Itoa(http.StatusBadRequest)]
+			for _, status := range []int{http.StatusConflict, http.StatusUnprocessableEntity} {
+				if _, ok := op.Responses[strconv.Itoa(status)]; !ok {
+					op.Responses[strconv.Itoa(status)] = openapi.Response{Description: http.StatusText(status), Content: errorResponse.Content}
+				}
+			}
+		}
+	}
+}
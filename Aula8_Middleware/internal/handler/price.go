@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+// GetPrices returns the price timeline of the product: the past changes, the price in effect
+// and the scheduled prices
+func (c *ProductController) GetPrices(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	timeline, err := c.Service.GetPriceTimeline(idStr)
+	if err != nil {
+		utils.ResponseWithError(w, err, priceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(toPriceTimelineData(timeline))
+}
+
+// SchedulePrice sets the price of the product from a future date on, the scheduler of the
+// API copies it into the price once it is due
+func (c *ProductController) SchedulePrice(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var body utils.RequestBodyPrice
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.ResponseWithError(w, errors.New("invalid request body"), http.StatusBadRequest)
+		return
+	}
+
+	change, err := c.Service.SchedulePrice(idStr, body.Price, body.Effective_at)
+	if err != nil {
+		utils.ResponseWithError(w, err, priceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(utils.ToPriceChangeData(change, service.PriceStatusScheduled))
+}
+
+// CancelPrice removes a scheduled price
+func (c *ProductController) CancelPrice(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	priceIdStr := chi.URLParam(r, "priceId")
+	if err := utils.ValidateUUID(priceIdStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := c.Service.CancelPrice(idStr, priceIdStr); err != nil {
+		utils.ResponseWithError(w, err, priceErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func toPriceTimelineData(timeline service.PriceTimeline) utils.PriceTimelineData {
+	data := utils.PriceTimelineData{
+		Product_id: timeline.Product.Id,
+		Price:      timeline.Price,
+		Prices:     []utils.PriceChangeData{},
+	}
+	for _, point := range timeline.Points {
+		data.Prices = append(data.Prices, utils.ToPriceChangeData(point.PriceChange, point.Status))
+	}
+	return data
+}
+
+func priceErrorStatus(err error) int {
+	switch err.Error() {
+	case "product not found", "price not found":
+		return http.StatusNotFound
+	case "a price is already scheduled at that time", "only scheduled prices can be cancelled":
+		return http.StatusConflict
+	case "price must be greater than zero", "effective_at must be in the future":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+const productPriced = "684963bb-7172-48ad-aecd-cdca3f0df060"
+
+// newPriceFixture has a product priced 10 before the timeline was kept, the clock of the
+// service is at scheduleNow until the test moves it
+func newPriceFixture() (repository.MockRepository, *service.ServiceProducts, *time.Time, *chi.Mux) {
+	mockRepo := repository.NewRepositoryProductsMock()
+	mockRepo.Products[productPriced] = &storage.Product{
+		Id: productPriced, Name: "Product PRICED", Quantity: 5, Code_value: "PRICED",
+		Is_published: boolPtr(true), Expiration: "01/01/2030", Price: 10,
+	}
+
+	now := scheduleNow
+	productService := service.NewServiceProducts(&mockRepo)
+	productService.Now = func() time.Time { return now }
+	hd := NewHandlerProducts(&productService)
+
+	rt := chi.NewRouter()
+	rt.Post("/products", hd.Create)
+	rt.Patch("/products/{id}", hd.Update)
+	rt.Get("/products/consumer_price", hd.ConsumerPrice)
+	rt.Get("/products/{id}/prices", hd.GetPrices)
+	rt.Post("/products/{id}/prices", hd.SchedulePrice)
+	rt.Delete("/products/{id}/prices/{priceId}", hd.CancelPrice)
+	return mockRepo, &productService, &now, rt
+}
+
+func getPrices(t *testing.T, rt http.Handler, productId string) utils.PriceTimelineData {
+	rr := serve(rt, "GET", "/products/"+productId+"/prices", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var timeline utils.PriceTimelineData
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &timeline))
+	return timeline
+}
+
+func quoteAt(t *testing.T, rt http.Handler, productId string, at time.Time) float64 {
+	rr := serve(rt, "GET", "/products/consumer_price?list="+productId+"&at="+url.QueryEscape(at.Format(time.RFC3339)), "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var body utils.ResponseBodyTotalPrice
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	require.Len(t, body.Products, 1)
+	require.InDelta(t, body.TotalPrice/service.TaxLessThanTen, body.Products[0].Price, 1e-9, "the products carry the quoted price")
+	return body.TotalPrice
+}
+
+func statuses(timeline utils.PriceTimelineData) []string {
+	var list []string
+	for _, change := range timeline.Prices {
+		list = append(list, change.Status)
+	}
+	return list
+}
+
+func TestPriceHistory(t *testing.T) {
+	_, _, now, rt := newPriceFixture()
+
+	timeline := getPrices(t, rt, productPriced)
+	require.Equal(t, 10.0, timeline.Price)
+	require.Empty(t, timeline.Prices, "no change was recorded yet")
+
+	rr := serve(rt, "PATCH", "/products/"+productPriced, `{"price":12}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	timeline = getPrices(t, rt, productPriced)
+	require.Equal(t, 12.0, timeline.Price)
+	require.Equal(t, []string{service.PriceStatusPast, service.PriceStatusCurrent}, statuses(timeline))
+	require.Equal(t, 10.0, timeline.Prices[0].Price)
+	require.True(t, timeline.Prices[0].Effective_at.IsZero(), "the price known before the timeline was in effect since ever")
+	require.Equal(t, scheduleNow, timeline.Prices[1].Effective_at)
+
+	*now = scheduleNow.Add(time.Hour)
+	for _, patch := range []string{`{"name":"Product PRICED 2"}`, `{"price":12}`} {
+		rr = serve(rt, "PATCH", "/products/"+productPriced, patch)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	}
+	require.Len(t, getPrices(t, rt, productPriced).Prices, 2, "only a new price is recorded")
+
+	rr = serve(rt, "PATCH", "/products/"+productPriced, `{"price":15}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	timeline = getPrices(t, rt, productPriced)
+	require.Equal(t, []string{service.PriceStatusPast, service.PriceStatusPast, service.PriceStatusCurrent}, statuses(timeline))
+
+	require.InDelta(t, 10*service.TaxLessThanTen, quoteAt(t, rt, productPriced, scheduleNow.Add(-time.Minute)), 1e-9)
+	require.InDelta(t, 12*service.TaxLessThanTen, quoteAt(t, rt, productPriced, scheduleNow.Add(30*time.Minute)), 1e-9)
+	require.InDelta(t, 15*service.TaxLessThanTen, quoteAt(t, rt, productPriced, *now), 1e-9)
+
+	rr = serve(rt, "GET", "/products/consumer_price?list="+productPriced+"&at=yesterday", "")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestQuoteBeforeTheFirstPrice(t *testing.T) {
+	_, _, _, rt := newPriceFixture()
+
+	rr := serve(rt, "POST", "/products", `{"name":"New","quantity":1,"code_value":"NEW","is_published":true,"expiration":"01/01/2030","price":8}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var created utils.ResponseBodyProduct
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+
+	timeline := getPrices(t, rt, created.Data.Id)
+	require.Equal(t, []string{service.PriceStatusCurrent}, statuses(timeline), "a new product starts its timeline")
+
+	require.InDelta(t, 8*service.TaxLessThanTen, quoteAt(t, rt, created.Data.Id, scheduleNow), 1e-9)
+	rr = serve(rt, "GET", "/products/consumer_price?list="+created.Data.Id+"&at="+url.QueryEscape(scheduleNow.Add(-time.Hour).Format(time.RFC3339)), "")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Contains(t, rr.Body.String(), "has no price at")
+}
+
+func TestSchedulePrice(t *testing.T) {
+	mockRepo, productService, now, rt := newPriceFixture()
+	tomorrow := scheduleNow.Add(24 * time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name         string
+		productId    string
+		body         string
+		expectedCode int
+	}{
+		{name: "Scheduled", productId: productPriced, body: `{"price":20,"effective_at":"` + tomorrow + `"}`, expectedCode: http.StatusCreated},
+		{name: "Same time", productId: productPriced, body: `{"price":21,"effective_at":"` + tomorrow + `"}`, expectedCode: http.StatusConflict},
+		{name: "In the past", productId: productPriced, body: `{"price":20,"effective_at":"2025-05-01T00:00:00Z"}`, expectedCode: http.StatusBadRequest},
+		{name: "Zero price", productId: productPriced, body: `{"price":0,"effective_at":"2030-01-01T00:00:00Z"}`, expectedCode: http.StatusBadRequest},
+		{name: "Unknown product", productId: "684963bb-7172-48ad-aecd-cdca3f0df099", body: `{"price":20,"effective_at":"2030-01-01T00:00:00Z"}`, expectedCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rr := serve(rt, "POST", "/products/"+tt.productId+"/prices", tt.body)
+		require.Equal(t, tt.expectedCode, rr.Code, tt.name+": "+rr.Body.String())
+	}
+
+	timeline := getPrices(t, rt, productPriced)
+	require.Equal(t, 10.0, timeline.Price)
+	require.Equal(t, []string{service.PriceStatusCurrent, service.PriceStatusScheduled}, statuses(timeline))
+	require.InDelta(t, 20*service.TaxLessThanTen, quoteAt(t, rt, productPriced, scheduleNow.Add(48*time.Hour)), 1e-9)
+	require.InDelta(t, 10*service.TaxLessThanTen, quoteAt(t, rt, productPriced, scheduleNow), 1e-9)
+
+	*now = scheduleNow.Add(25 * time.Hour)
+	require.Equal(t, 20.0, getPrices(t, rt, productPriced).Price, "a due price is in effect before the scheduler runs")
+
+	applied, err := productService.ApplySchedule()
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	require.Equal(t, service.ScheduleActionPrice, applied[0].Action)
+	require.Equal(t, 20.0, mockRepo.Products[productPriced].Price)
+	require.Len(t, mockRepo.Products[productPriced].Prices, 2, "applying a price does not record it again")
+
+	applied, err = productService.ApplySchedule()
+	require.NoError(t, err)
+	require.Empty(t, applied)
+
+	rr := serve(rt, "POST", "/products/"+productPriced+"/prices", `{"price":25,"effective_at":"2030-01-01T00:00:00Z"}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var scheduled utils.PriceChangeData
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &scheduled))
+
+	timeline = getPrices(t, rt, productPriced)
+	past := timeline.Prices[1]
+	require.Equal(t, service.PriceStatusCurrent, past.Status)
+
+	rr = serve(rt, "DELETE", "/products/"+productPriced+"/prices/"+past.Id, "")
+	require.Equal(t, http.StatusConflict, rr.Code)
+	rr = serve(rt, "DELETE", "/products/"+productPriced+"/prices/684963bb-7172-48ad-aecd-cdca3f0df099", "")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	rr = serve(rt, "DELETE", "/products/"+productPriced+"/prices/"+scheduled.Id, "")
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	require.Len(t, getPrices(t, rt, productPriced).Prices, 2)
+}
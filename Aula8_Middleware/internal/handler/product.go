@@ -2,15 +2,23 @@ package handler
 
 import (
 	"aula4/internal/repository/storage"
+	"aula4/internal/search"
 	"aula4/internal/service"
 	"aula4/internal/utils"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
 )
 
+// HeaderTotalPrice carries the total of GET /products/consumer_price in every media type
+const HeaderTotalPrice = "X-Total-Price"
+
 type ProductController struct {
 	Service service.Service
 }
@@ -37,9 +45,17 @@ func (c *ProductController) Create(w http.ResponseWriter, r *http.Request) {
 		Name:         reqBody.Name,
 		Quantity:     reqBody.Quantity,
 		Code_value:   reqBody.Code_value,
+		Code_format:  reqBody.Code_format,
 		Is_published: reqBody.Is_published,
 		Expiration:   reqBody.Expiration,
 		Price:        reqBody.Price,
+		Category_id:  reqBody.Category_id,
+		Tags:         utils.NormalizeTags(reqBody.Tags),
+		Attributes:   reqBody.Attributes,
+
+		Low_stock_threshold: reqBody.Low_stock_threshold,
+		Publish_at:          reqBody.Publish_at,
+		Unpublish_at:        reqBody.Unpublish_at,
 	}
 
 	productServ, err := c.Service.Create(product)
@@ -62,6 +78,7 @@ func (c *ProductController) UpdateOrCreate(w http.ResponseWriter, r *http.Reques
 	err := utils.ValidateUUID(idStr)
 	if err != nil {
 		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
 	}
 
 	product := storage.Product{
@@ -69,9 +86,17 @@ func (c *ProductController) UpdateOrCreate(w http.ResponseWriter, r *http.Reques
 		Name:         reqBody.Name,
 		Quantity:     reqBody.Quantity,
 		Code_value:   reqBody.Code_value,
+		Code_format:  reqBody.Code_format,
 		Is_published: reqBody.Is_published,
 		Expiration:   reqBody.Expiration,
 		Price:        reqBody.Price,
+		Category_id:  reqBody.Category_id,
+		Tags:         utils.NormalizeTags(reqBody.Tags),
+		Attributes:   reqBody.Attributes,
+
+		Low_stock_threshold: reqBody.Low_stock_threshold,
+		Publish_at:          reqBody.Publish_at,
+		Unpublish_at:        reqBody.Unpublish_at,
 	}
 
 	productServ, err := c.Service.Update(product)
@@ -79,7 +104,7 @@ func (c *ProductController) UpdateOrCreate(w http.ResponseWriter, r *http.Reques
 		if err.Error() == "product not found" {
 			productServ, err = c.Service.Create(product)
 			if err != nil {
-				utils.ResponseWithError(w, err, http.StatusInternalServerError)
+				utils.ResponseWithError(w, err, http.StatusBadRequest)
 				return
 			}
 
@@ -99,6 +124,7 @@ func (c *ProductController) Update(w http.ResponseWriter, r *http.Request) {
 	err := utils.ValidateUUID(idStr)
 	if err != nil {
 		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
 	}
 
 	_, err = c.Service.GetById(idStr)
@@ -115,9 +141,11 @@ func (c *ProductController) Update(w http.ResponseWriter, r *http.Request) {
 
 	product, err := c.Service.Patch(idStr, updates)
 	if err != nil {
-		switch err.Error() {
-		case "product not found":
+		switch {
+		case err.Error() == "product not found":
 			utils.ResponseWithError(w, err, http.StatusNotFound)
+		case errors.Is(err, service.ErrInvalidPatch):
+			utils.ResponseWithError(w, err, http.StatusBadRequest)
 		default:
 			utils.ResponseWithError(w, err, http.StatusInternalServerError)
 		}
@@ -132,6 +160,7 @@ func (c *ProductController) Delete(w http.ResponseWriter, r *http.Request) {
 	err := utils.ValidateUUID(idStr)
 	if err != nil {
 		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
 	}
 
 	if _, err := c.Service.GetById(idStr); err != nil {
@@ -139,9 +168,14 @@ func (c *ProductController) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// another request may delete the product between the check and the delete
 	err = c.Service.Delete(idStr)
 	if err != nil {
-		utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		if err.Error() == "product not found" {
+			utils.ResponseWithError(w, err, http.StatusNotFound)
+		} else {
+			utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -149,15 +183,30 @@ func (c *ProductController) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (c *ProductController) GetAll(w http.ResponseWriter, r *http.Request) {
-	products, err := c.Service.GetAll()
+	contentType, err := utils.Negotiate(r.Header.Get("Accept"))
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusNotAcceptable)
+		return
+	}
+
+	filter := service.ProductFilter{
+		CategoryId: r.URL.Query().Get("category"),
+		Tag:        r.URL.Query().Get("tag"),
+	}
+
+	var products []*storage.Product
+	if filter.CategoryId != "" || filter.Tag != "" {
+		products, err = c.Service.GetByFilter(filter)
+	} else {
+		products, err = c.Service.GetAll()
+	}
 	if err != nil {
 		utils.ResponseWithError(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(products)
+	utils.SetValidators(w, products...)
+	utils.RespondFormatted(w, contentType, http.StatusOK, productsFormatted(products, utils.ToProductViews(products)))
 }
 
 func (c *ProductController) GetById(w http.ResponseWriter, r *http.Request) {
@@ -165,6 +214,7 @@ func (c *ProductController) GetById(w http.ResponseWriter, r *http.Request) {
 	err := utils.ValidateUUID(idStr)
 	if err != nil {
 		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
 	}
 
 	var product *storage.Product
@@ -178,12 +228,36 @@ func (c *ProductController) GetById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the product may be deleted between the two reads
+	variants, err := c.Service.GetVariants(idStr)
+	if err != nil {
+		if err.Error() == "product not found" {
+			utils.ResponseWithError(w, err, http.StatusNotFound)
+		} else {
+			utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.SetValidators(w, append([]*storage.Product{product}, variants...)...)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(product)
+	json.NewEncoder(w).Encode(utils.ProductWithVariants{Product: product, Variants: variants})
 }
 
+// Search lists the products more expensive than ?price=, or ranks the products matching ?q=
 func (c *ProductController) Search(w http.ResponseWriter, r *http.Request) {
+	contentType, err := utils.Negotiate(r.Header.Get("Accept"))
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusNotAcceptable)
+		return
+	}
+
+	if r.URL.Query().Has("q") {
+		c.fullTextSearch(w, r, contentType)
+		return
+	}
+
 	priceStr := r.URL.Query().Get("price")
 	price, err := strconv.ParseFloat(priceStr, 64)
 	if err != nil {
@@ -197,12 +271,64 @@ func (c *ProductController) Search(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(products)
+	utils.RespondFormatted(w, contentType, http.StatusOK, productsFormatted(products, utils.ToProductViews(products)))
+}
+
+func (c *ProductController) fullTextSearch(w http.ResponseWriter, r *http.Request, contentType string) {
+	query := search.Query{Text: r.URL.Query().Get("q")}
+
+	for name, value := range map[string]*int{"page": &query.Page, "page_size": &query.PageSize} {
+		raw := r.URL.Query().Get(name)
+		if raw == "" {
+			continue
+		}
+
+		number, err := strconv.Atoi(raw)
+		if err != nil || number < 1 {
+			utils.ResponseWithError(w, fmt.Errorf("invalid %s", name), http.StatusBadRequest)
+			return
+		}
+		*value = number
+	}
+
+	result, err := c.Service.FullTextSearch(query)
+	if err != nil {
+		if errors.Is(err, search.ErrEmptyQuery) {
+			utils.ResponseWithError(w, err, http.StatusBadRequest)
+			return
+		}
+		utils.ResponseWithError(w, errors.New("could not retrieve products"), http.StatusInternalServerError)
+		return
+	}
+
+	body := utils.ResponseBodySearch{
+		Query:     query.Text,
+		Total:     result.Total,
+		Page:      result.Page,
+		Page_size: result.PageSize,
+		Results:   []utils.SearchHitData{},
+	}
+	records := [][]string{append([]string{"score"}, utils.DataCSVHeader...)}
+	for _, hit := range result.Hits {
+		hitData := utils.SearchHitData{
+			Score:      hit.Score,
+			Highlights: hit.Highlights,
+			Data:       utils.ToData(hit.Product),
+		}
+		body.Results = append(body.Results, hitData)
+		records = append(records, append([]string{strconv.FormatFloat(hit.Score, 'f', -1, 64)}, utils.DataCSVRecord(hitData.Data)...))
+	}
+
+	utils.RespondFormatted(w, contentType, http.StatusOK, utils.Formatted{JSON: body, XML: body, CSV: records})
 }
 
 func (c *ProductController) ConsumerPrice(w http.ResponseWriter, r *http.Request) {
+	contentType, err := utils.Negotiate(r.Header.Get("Accept"))
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusNotAcceptable)
+		return
+	}
+
 	listIds := r.URL.Query().Get("list")
 
 	var ids []string
@@ -210,7 +336,16 @@ func (c *ProductController) ConsumerPrice(w http.ResponseWriter, r *http.Request
 		ids = strings.Split(listIds, ",")
 	}
 
-	totalPrice, products, err := c.Service.GetTotalPrice(ids)
+	var at time.Time
+	if value := r.URL.Query().Get("at"); value != "" {
+		at, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			utils.ResponseWithError(w, errors.New("invalid at, use RFC 3339, e.g. 2030-01-02T15:04:05Z"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	totalPrice, products, err := c.Service.GetTotalPrice(ids, at)
 	if err != nil {
 		utils.ResponseWithError(w, err, http.StatusBadRequest)
 		return
@@ -218,15 +353,7 @@ func (c *ProductController) ConsumerPrice(w http.ResponseWriter, r *http.Request
 
 	var productsResponse []*utils.Data
 	for _, product := range products {
-		dt := utils.Data{
-			Id:           product.Id,
-			Name:         product.Name,
-			Code_value:   product.Code_value,
-			Is_published: *product.Is_published,
-			Expiration:   product.Expiration,
-			Quantity:     product.Quantity,
-			Price:        product.Price,
-		}
+		dt := utils.ToData(product)
 
 		productsResponse = append(productsResponse, &dt)
 	}
@@ -236,7 +363,105 @@ func (c *ProductController) ConsumerPrice(w http.ResponseWriter, r *http.Request
 		TotalPrice: totalPrice,
 	}
 
+	// the CSV rows are the products, the total goes in a header
+	w.Header().Set(HeaderTotalPrice, strconv.FormatFloat(totalPrice, 'f', -1, 64))
+	records := productsFormatted(products, nil).CSV
+	utils.RespondFormatted(w, contentType, http.StatusOK, utils.Formatted{JSON: body, XML: body, CSV: records})
+}
+
+// productsFormatted writes a list of products as JSON, in their storage form, and as
+// CSV and XML records of utils.Data
+func productsFormatted(products []*storage.Product, jsonBody any) utils.Formatted {
+	xmlBody := utils.ProductsXML{Products: []utils.Data{}}
+	records := [][]string{utils.DataCSVHeader}
+	for _, product := range products {
+		data := utils.ToData(product)
+		xmlBody.Products = append(xmlBody.Products, data)
+		records = append(records, utils.DataCSVRecord(data))
+	}
+
+	return utils.Formatted{JSON: jsonBody, XML: xmlBody, CSV: records}
+}
+
+func (c *ProductController) GetLowStock(w http.ResponseWriter, r *http.Request) {
+	products, err := c.Service.GetLowStock()
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(utils.ToProductViews(products))
+}
+
+func (c *ProductController) GetTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := c.Service.GetTags()
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tags)
+}
+
+func (c *ProductController) GetVariants(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	variants, err := c.Service.GetVariants(idStr)
+	if err != nil {
+		if err.Error() == "product not found" {
+			utils.ResponseWithError(w, err, http.StatusNotFound)
+		} else {
+			utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(body)
+	json.NewEncoder(w).Encode(utils.ToProductViews(variants))
+}
+
+func (c *ProductController) CreateVariant(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var reqBody utils.RequestBodyVariant
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	variant := storage.Product{
+		Name:         reqBody.Name,
+		Quantity:     reqBody.Quantity,
+		Code_value:   reqBody.Code_value,
+		Code_format:  reqBody.Code_format,
+		Is_published: reqBody.Is_published,
+		Expiration:   reqBody.Expiration,
+		Price:        reqBody.Price,
+		Attributes:   reqBody.Attributes,
+	}
+
+	productServ, err := c.Service.CreateVariant(idStr, variant)
+	if err != nil {
+		if err.Error() == "product not found" {
+			utils.ResponseWithError(w, err, http.StatusNotFound)
+		} else {
+			utils.ResponseWithError(w, err, http.StatusBadRequest)
+		}
+		return
+	}
+
+	utils.RespondWithProduct(w, &productServ, http.StatusCreated, utils.MessageProductCreated)
 }
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"aula4/internal/barcode"
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+// The seeds of the fuzz targets are in testdata/fuzz, run them longer with e.g.
+// go test ./internal/handler -run '^$' -fuzz FuzzPatchProduct -fuzztime 30s
+
+const (
+	fuzzProduct = "684963bb-7172-48ad-aecd-cdca3f0df070"
+	fuzzOther   = "684963bb-7172-48ad-aecd-cdca3f0df071"
+)
+
+// requireProductInvariants checks what every stored product keeps whatever the requests were
+func requireProductInvariants(t *testing.T, products []*storage.Product) {
+	keys := make(map[string]string)
+	for _, product := range products {
+		require.GreaterOrEqual(t, product.Quantity, 0, "quantity of %s", product.Id)
+		require.Greater(t, product.Price, 0.0, "price of %s", product.Id)
+		require.NoError(t, utils.ValidateDate(product.Expiration), "expiration of %s", product.Id)
+		require.NotNil(t, product.Is_published, "is_published of %s", product.Id)
+
+		key := barcode.Key(product.Code_format, product.Code_value)
+		other, found := keys[key]
+		require.False(t, found, "%s and %s share the code %q", product.Id, other, product.Code_value)
+		keys[key] = product.Id
+	}
+}
+
+func FuzzCreateProduct(f *testing.F) {
+	f.Add(`{"name":"Coffee","quantity":1,"code_value":"COF","is_published":true,"expiration":"01/01/2030","price":5}`)
+	f.Add(`{"name":"Coffee","quantity":1,"code_value":"EXISTING","expiration":"01/01/2030","price":5}`)
+	f.Add(`{"name":"Coffee","quantity":1,"code_value":"4006381333931","code_format":"ean13","expiration":"01/01/2030","price":5}`)
+
+	f.Fuzz(func(t *testing.T, body string) {
+		mockRepo := repository.NewRepositoryProductsMock()
+		mockRepo.Products[fuzzOther] = &storage.Product{
+			Id: fuzzOther, Name: "Other", Quantity: 1, Code_value: "EXISTING",
+			Is_published: boolPtr(true), Expiration: "01/01/2030", Price: 1,
+		}
+		productService := service.NewServiceProducts(&mockRepo)
+		rt := chi.NewRouter()
+		rt.Post("/products", NewHandlerProducts(&productService).Create)
+
+		rr := serve(rt, "POST", "/products", body)
+		require.Contains(t, []int{http.StatusCreated, http.StatusBadRequest}, rr.Code, rr.Body.String())
+
+		var response utils.ResponseBodyProduct
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response), "the errors are JSON too")
+		require.Equal(t, rr.Code != http.StatusCreated, response.Error)
+
+		if rr.Code != http.StatusCreated {
+			require.Len(t, mockRepo.Products, 1, "a refused product is not stored")
+			return
+		}
+		require.NotEmpty(t, response.Data.Name)
+		require.NotEmpty(t, response.Data.Code_value)
+
+		var products []*storage.Product
+		for _, product := range mockRepo.Products {
+			products = append(products, product)
+		}
+		requireProductInvariants(t, products)
+	})
+}
+
+// FuzzPatchProduct patches a product of a JSON storage through the real repository, a refused
+// patch leaves it as it was and a field left out of the patch keeps its value
+func FuzzPatchProduct(f *testing.F) {
+	f.Add(`{"name":"Coffee 2"}`)
+	f.Add(`{"quantity":0}`)
+	f.Add(`{"code_value":"OTHER"}`)
+	f.Add(`{"price":7.5,"is_published":false}`)
+
+	f.Fuzz(func(t *testing.T, body string) {
+		st := storage.NewStorageProducts()
+		st.Path = filepath.Join(t.TempDir(), "products.json")
+		rp := repository.NewRepositoryProducts(&st)
+		for _, product := range []*storage.Product{
+			{Id: fuzzProduct, Name: "Coffee", Quantity: 5, Code_value: "4006381333931", Code_format: barcode.FormatEAN13, Is_published: boolPtr(true), Expiration: "01/01/2030", Price: 5},
+			{Id: fuzzOther, Name: "Other", Quantity: 1, Code_value: "OTHER", Is_published: boolPtr(false), Expiration: "01/01/2030", Price: 1},
+		} {
+			require.NoError(t, st.SaveProduct(product))
+		}
+		before, err := rp.GetById(fuzzProduct)
+		require.NoError(t, err)
+
+		productService := service.NewServiceProducts(&rp)
+		rt := chi.NewRouter()
+		rt.Patch("/products/{id}", NewHandlerProducts(&productService).Update)
+
+		rr := serve(rt, "PATCH", "/products/"+fuzzProduct, body)
+		require.Contains(t, []int{http.StatusOK, http.StatusBadRequest}, rr.Code, rr.Body.String())
+		var response utils.ResponseBodyProduct
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response), "the errors are JSON too")
+
+		after, err := rp.GetById(fuzzProduct)
+		require.NoError(t, err)
+		products, err := rp.GetAll()
+		require.NoError(t, err)
+		requireProductInvariants(t, products)
+
+		if rr.Code != http.StatusOK {
+			require.Equal(t, before, after, "a refused patch changes nothing")
+			return
+		}
+
+		// the handler reads the first JSON value, the validator of the API refuses the rest
+		var updates map[string]interface{}
+		require.NoError(t, json.NewDecoder(strings.NewReader(body)).Decode(&updates))
+		if _, ok := updates["quantity"]; !ok {
+			require.Equal(t, before.Quantity, after.Quantity, "quantity is kept")
+		}
+		if _, ok := updates["is_published"]; !ok {
+			require.Equal(t, before.Is_published, after.Is_published, "is_published is kept")
+		}
+		if _, ok := updates["price"]; !ok {
+			require.Equal(t, before.Price, after.Price, "price is kept")
+			require.True(t, slices.Equal(before.Prices, after.Prices), "the price timeline is kept")
+		}
+	})
+}
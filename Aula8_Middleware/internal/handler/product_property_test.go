@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"aula4/internal/barcode"
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"aula4/internal/search"
+	"aula4/internal/service"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+// The properties run random sequences of requests through testing/quick, a failure prints
+// the sequence that broke the property
+
+// propertyCodes are the codes the sequences pick from, the GTINs are spelled in the ways
+// that read as the same product
+var propertyCodes = []struct {
+	Format string
+	Value  string
+}{
+	{"", "COF"},
+	{"", "TEA"},
+	{barcode.FormatEAN13, "4006381333931"},
+	{barcode.FormatGTIN14, "04006381333931"},
+	{barcode.FormatUPCA, "036000291452"},
+	{barcode.FormatEAN13, "0036000291452"},
+	{barcode.FormatUPCA, "0360-0029-1452"},
+	{barcode.FormatEAN13, "0 036000 291452"},
+}
+
+// propertyStep is a request of a sequence, Op picks the request and Product and Code index
+// the products created so far and propertyCodes
+type propertyStep struct {
+	Op       uint8
+	Product  uint8
+	Code     uint8
+	Quantity int8
+}
+
+func (propertyStep) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(propertyStep{
+		Op:       uint8(r.Intn(256)),
+		Product:  uint8(r.Intn(256)),
+		Code:     uint8(r.Intn(len(propertyCodes))),
+		Quantity: int8(r.Intn(41) - 20),
+	})
+}
+
+// newPropertyRouter serves the product and movement routes of a JSON storage kept in an index
+func newPropertyRouter(t *testing.T) (*chi.Mux, *service.ServiceProducts, *repository.RepositoryProducts) {
+	st := storage.NewStorageProducts()
+	st.Path = filepath.Join(t.TempDir(), "products.json")
+	rp := repository.NewRepositoryProducts(&st)
+	index := search.NewIndex()
+	irp, err := search.NewIndexedRepository(&rp, index)
+	require.NoError(t, err)
+
+	mockMovementRepo := repository.NewRepositoryMovementsMock()
+	productService := service.NewServiceProducts(&irp)
+	productService.Index = index
+	productService.Movements = &mockMovementRepo
+	movementService := service.NewServiceMovements(&mockMovementRepo, &irp)
+
+	productHandler := NewHandlerProducts(&productService)
+	movementHandler := NewHandlerMovements(&movementService)
+
+	rt := chi.NewRouter()
+	rt.Post("/products", productHandler.Create)
+	rt.Put("/products/{id}", productHandler.UpdateOrCreate)
+	rt.Patch("/products/{id}", productHandler.Update)
+	rt.Post("/products/{id}/movements", movementHandler.Create)
+	return rt, &productService, &rp
+}
+
+func allProducts(t *testing.T, rp *repository.RepositoryProducts) []*storage.Product {
+	products, err := rp.GetAll()
+	if err != nil {
+		require.EqualError(t, err, "no Products")
+	}
+	return products
+}
+
+func propertyBody(name string, code int, quantity int) string {
+	return fmt.Sprintf(`{"name":%q,"quantity":%d,"code_value":%q,"code_format":%q,"is_published":true,"expiration":"01/01/2030","price":5}`,
+		name, quantity, propertyCodes[code].Value, propertyCodes[code].Format)
+}
+
+func createdId(t *testing.T, body []byte) string {
+	var response struct {
+		Data struct{ Id string }
+	}
+	require.NoError(t, json.Unmarshal(body, &response))
+	return response.Data.Id
+}
+
+func TestPropertyCodeValueIsUnique(t *testing.T) {
+	property := func(steps []propertyStep) bool {
+		rt, productService, rp := newPropertyRouter(t)
+
+		var ids []string
+		for i, step := range steps {
+			code := int(step.Code)
+			switch {
+			case len(ids) == 0 || step.Op%3 == 0:
+				rr := serve(rt, "POST", "/products", propertyBody(fmt.Sprintf("Product %d", i), code, 1))
+				require.Contains(t, []int{http.StatusCreated, http.StatusBadRequest}, rr.Code, rr.Body.String())
+				if rr.Code == http.StatusCreated {
+					ids = append(ids, createdId(t, rr.Body.Bytes()))
+				}
+			case step.Op%3 == 1:
+				patch := fmt.Sprintf(`{"code_value":%q,"code_format":%q}`, propertyCodes[code].Value, propertyCodes[code].Format)
+				rr := serve(rt, "PATCH", "/products/"+ids[int(step.Product)%len(ids)], patch)
+				require.Contains(t, []int{http.StatusOK, http.StatusBadRequest}, rr.Code, rr.Body.String())
+			default:
+				rr := serve(rt, "PUT", "/products/"+ids[int(step.Product)%len(ids)], propertyBody("Replaced", code, 1))
+				require.Contains(t, []int{http.StatusOK, http.StatusBadRequest}, rr.Code, rr.Body.String())
+			}
+
+			products := allProducts(t, rp)
+			requireProductInvariants(t, products)
+			for _, product := range products {
+				found, err := productService.GetByCode(product.Code_value)
+				require.NoError(t, err)
+				require.Equal(t, product.Id, found.Id, "the code %q finds its product", product.Code_value)
+			}
+		}
+		return true
+	}
+
+	require.NoError(t, quick.Check(property, &quick.Config{MaxCount: 30}))
+}
+
+func TestPropertyQuantityIsNeverNegative(t *testing.T) {
+	movementTypes := []string{storage.MovementReceipt, storage.MovementSale, storage.MovementAdjustment, storage.MovementReturn}
+
+	property := func(steps []propertyStep) bool {
+		rt, _, rp := newPropertyRouter(t)
+		rr := serve(rt, "POST", "/products", propertyBody("Coffee", 0, 3))
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		id := createdId(t, rr.Body.Bytes())
+
+		for _, step := range steps {
+			before, err := rp.GetById(id)
+			require.NoError(t, err)
+
+			if step.Op%2 == 0 {
+				rr = serve(rt, "PATCH", "/products/"+id, fmt.Sprintf(`{"quantity":%d}`, step.Quantity))
+			} else {
+				movementType := movementTypes[int(step.Op/2)%len(movementTypes)]
+				body := fmt.Sprintf(`{"type":%q,"quantity":%d,"actor":"ana"}`, movementType, step.Quantity)
+				rr = serve(rt, "POST", "/products/"+id+"/movements", body)
+			}
+
+			after, err := rp.GetById(id)
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, after.Quantity, 0, rr.Body.String())
+			if rr.Code >= http.StatusBadRequest {
+				require.Equal(t, before.Quantity, after.Quantity, "a refused request keeps the quantity")
+			}
+		}
+		return true
+	}
+
+	require.NoError(t, quick.Check(property, &quick.Config{MaxCount: 50}))
+}
+
+// propertyTier is a pricing tier with a small quantity and tax, so the sequences often make
+// tiers the validation accepts
+type propertyTier struct {
+	MinQuantity uint8
+	Tax         uint8
+}
+
+func tiersOf(taxAtZero uint8, tiers []propertyTier) []service.PricingTier {
+	pricing := []service.PricingTier{{MinQuantity: 0, Tax: 0.5 + float64(taxAtZero%20)/10}}
+	for _, tier := range tiers {
+		pricing = append(pricing, service.PricingTier{
+			MinQuantity: 1 + int(tier.MinQuantity%30),
+			Tax:         0.5 + float64(tier.Tax%20)/10,
+		})
+	}
+	return pricing
+}
+
+func TestPropertyTotalPriceGrowsWithQuantity(t *testing.T) {
+	const stock = 40
+
+	accepted := 0
+	property := func(price uint16, taxAtZero uint8, tiers []propertyTier) bool {
+		pricing := tiersOf(taxAtZero, tiers)
+		if service.ValidatePricingTiers(pricing) != nil {
+			return true
+		}
+		accepted++
+
+		mockRepo := repository.NewRepositoryProductsMock()
+		product := newStockedProduct()
+		product.Quantity = stock
+		product.Price = 0.01 + float64(price)/100
+		mockRepo.Products[product.Id] = product
+
+		productService := service.NewServiceProducts(&mockRepo)
+		productService.Pricing = pricing
+
+		previous := 0.0
+		for quantity := 1; quantity <= stock; quantity++ {
+			ids := strings.Split(strings.Repeat(stockedProduct+",", quantity), ",")[:quantity]
+			total, _, err := productService.GetTotalPrice(ids, scheduleNow)
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, total, previous*(1-1e-9), "%d units with %v", quantity, pricing)
+			previous = total
+		}
+		return true
+	}
+
+	require.NoError(t, quick.Check(property, &quick.Config{MaxCount: 300}))
+	require.NotZero(t, accepted, "some of the random tiers are valid")
+}
@@ -540,6 +540,80 @@ func TestPatchProduct(t *testing.T) {
 			expectedCode: http.StatusNotFound,
 			expectedName: "",
 		},
+		{
+			name:      "Negative quantity",
+			productID: "684963bb-7172-48ad-aecd-cdca3f0df012",
+			patchData: map[string]interface{}{
+				"quantity": -1,
+			},
+			initialData: map[string]*storage.Product{
+				"684963bb-7172-48ad-aecd-cdca3f0df012": {
+					Id:           "684963bb-7172-48ad-aecd-cdca3f0df012",
+					Name:         "Product A",
+					Quantity:     5,
+					Code_value:   "123yy",
+					Is_published: boolPtr(true),
+					Expiration:   "01/01/2025",
+					Price:        10.0,
+				},
+			},
+			expectedErr:  errors.New("quantity must not be negative"),
+			expectedCode: http.StatusBadRequest,
+			expectedName: "",
+		},
+		{
+			name:      "Price not positive",
+			productID: "684963bb-7172-48ad-aecd-cdca3f0df012",
+			patchData: map[string]interface{}{
+				"price": 0.0,
+			},
+			initialData: map[string]*storage.Product{
+				"684963bb-7172-48ad-aecd-cdca3f0df012": {
+					Id:           "684963bb-7172-48ad-aecd-cdca3f0df012",
+					Name:         "Product A",
+					Quantity:     5,
+					Code_value:   "123yy",
+					Is_published: boolPtr(true),
+					Expiration:   "01/01/2025",
+					Price:        10.0,
+				},
+			},
+			expectedErr:  errors.New("price must be greater than zero"),
+			expectedCode: http.StatusBadRequest,
+			expectedName: "",
+		},
+		{
+			name:      "Invalid expiration",
+			productID: "684963bb-7172-48ad-aecd-cdca3f0df012",
+			patchData: map[string]interface{}{
+				"expiration": "2025-01-01",
+			},
+			initialData: map[string]*storage.Product{
+				"684963bb-7172-48ad-aecd-cdca3f0df012": {
+					Id:           "684963bb-7172-48ad-aecd-cdca3f0df012",
+					Name:         "Product A",
+					Quantity:     5,
+					Code_value:   "123yy",
+					Is_published: boolPtr(true),
+					Expiration:   "01/01/2025",
+					Price:        10.0,
+				},
+			},
+			expectedErr:  errors.New("invalid date. The format must be DD/MM/YYYY"),
+			expectedCode: http.StatusBadRequest,
+			expectedName: "",
+		},
+		{
+			name:      "Invalid id",
+			productID: "684963bb",
+			patchData: map[string]interface{}{
+				"name": "Product AA",
+			},
+			initialData:  map[string]*storage.Product{},
+			expectedErr:  errors.New("invalid UUID"),
+			expectedCode: http.StatusBadRequest,
+			expectedName: "",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi"
+)
+
+// PurchaseOrderStatuses are the values of the status filter of GET /purchase-orders
+var PurchaseOrderStatuses = []string{
+	storage.PurchaseOrderOpen,
+	storage.PurchaseOrderPartiallyReceived,
+	storage.PurchaseOrderReceived,
+	storage.PurchaseOrderCancelled,
+}
+
+type PurchaseOrderController struct {
+	Service service.PurchaseOrderService
+}
+
+func NewHandlerPurchaseOrders(service service.PurchaseOrderService) *PurchaseOrderController {
+	return &PurchaseOrderController{
+		Service: service,
+	}
+}
+
+func (c *PurchaseOrderController) Create(w http.ResponseWriter, r *http.Request) {
+	var reqBody utils.RequestBodyPurchaseOrder
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	order := storage.PurchaseOrder{
+		Supplier_id: reqBody.Supplier_id,
+		Reference:   reqBody.Reference,
+		Expected_at: reqBody.Expected_at,
+	}
+	for _, line := range reqBody.Lines {
+		order.Lines = append(order.Lines, storage.PurchaseOrderLine{
+			Product_id: line.Product_id,
+			Quantity:   line.Quantity,
+			Unit_cost:  line.Unit_cost,
+		})
+	}
+
+	order, err := c.Service.Create(order)
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondWithPurchaseOrder(w, &order, http.StatusCreated, utils.MessagePurchaseOrderCreated)
+}
+
+// GetAll lists the orders, filtered by the status, supplier and product query parameters
+func (c *PurchaseOrderController) GetAll(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := service.PurchaseOrderFilter{
+		Status:      query.Get("status"),
+		Supplier_id: query.Get("supplier"),
+		Product_id:  query.Get("product"),
+	}
+
+	if filter.Status != "" && !slices.Contains(PurchaseOrderStatuses, filter.Status) {
+		utils.ResponseWithError(w, errors.New("status must be one of "+strings.Join(PurchaseOrderStatuses, ", ")), http.StatusBadRequest)
+		return
+	}
+	for _, id := range []string{filter.Supplier_id, filter.Product_id} {
+		if id == "" {
+			continue
+		}
+		if err := utils.ValidateUUID(id); err != nil {
+			utils.ResponseWithError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	orders, err := c.Service.GetAll(filter)
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	data := []utils.PurchaseOrderData{}
+	for _, order := range orders {
+		data = append(data, utils.ToPurchaseOrderData(order))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (c *PurchaseOrderController) GetById(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	order, err := c.Service.GetById(idStr)
+	if err != nil {
+		if err.Error() == "purchase order not found" {
+			utils.ResponseWithError(w, err, http.StatusNotFound)
+		} else {
+			utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(utils.ToPurchaseOrderData(order))
+}
+
+// Receive adds the delivered quantities to the stock, the whole outstanding quantity
+// when the body lists no lines
+func (c *PurchaseOrderController) Receive(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var reqBody utils.RequestBodyReceipt
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var lines []service.ReceiptLine
+	for _, line := range reqBody.Lines {
+		lines = append(lines, service.ReceiptLine{Product_id: line.Product_id, Quantity: line.Quantity})
+	}
+
+	order, err := c.Service.Receive(idStr, lines, reqBody.Actor)
+	if err != nil {
+		utils.ResponseWithError(w, err, purchaseOrderErrorStatus(err))
+		return
+	}
+
+	utils.RespondWithPurchaseOrder(w, &order, http.StatusOK, utils.MessagePurchaseOrderReceived)
+}
+
+// Cancel closes an order, the quantities received so far stay in stock
+func (c *PurchaseOrderController) Cancel(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	order, err := c.Service.Cancel(idStr)
+	if err != nil {
+		utils.ResponseWithError(w, err, purchaseOrderErrorStatus(err))
+		return
+	}
+
+	utils.RespondWithPurchaseOrder(w, &order, http.StatusOK, utils.MessagePurchaseOrderCancelled)
+}
+
+// GetInbound reports the quantities ordered and not received yet by product, only the
+// product of the product query parameter when set
+func (c *PurchaseOrderController) GetInbound(w http.ResponseWriter, r *http.Request) {
+	productId := r.URL.Query().Get("product")
+	if productId != "" {
+		if err := utils.ValidateUUID(productId); err != nil {
+			utils.ResponseWithError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := c.Service.GetInbound(productId)
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	data := []utils.InboundData{}
+	for _, inbound := range report {
+		data = append(data, toInboundData(inbound))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+func toInboundData(inbound service.InboundQuantity) utils.InboundData {
+	data := utils.InboundData{
+		Product_id: inbound.Product_id,
+		Quantity:   inbound.Quantity,
+		Orders:     []utils.InboundOrderData{},
+	}
+
+	for _, order := range inbound.Orders {
+		data.Orders = append(data.Orders, utils.InboundOrderData{
+			Order_id:    order.Order_id,
+			Supplier_id: order.Supplier_id,
+			Quantity:    order.Quantity,
+			Expected_at: order.Expected_at,
+		})
+	}
+
+	return data
+}
+
+// purchaseOrderErrorStatus answers 409 for the orders already closed and the products
+// deleted since they were ordered
+func purchaseOrderErrorStatus(err error) int {
+	message := err.Error()
+	switch {
+	case message == "purchase order not found":
+		return http.StatusNotFound
+	case strings.HasPrefix(message, "purchase order is ") || message == "product not found" || strings.HasPrefix(message, "product has variants"):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,365 @@
+package handler
+
+import (
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	supplierAcme   = "684963bb-7172-48ad-aecd-cdca3f0df060"
+	supplierUnused = "684963bb-7172-48ad-aecd-cdca3f0df061"
+)
+
+type purchasingFixture struct {
+	products  repository.MockRepository
+	movements repository.MockMovementRepository
+	suppliers repository.MockSupplierRepository
+	orders    repository.MockPurchaseOrderRepository
+}
+
+// newPurchasingFixture has the shirt with its variants, the stocked coffee and two suppliers
+func newPurchasingFixture() *purchasingFixture {
+	f := &purchasingFixture{
+		products:  newVariantFixture(),
+		movements: repository.NewRepositoryMovementsMock(),
+		suppliers: repository.NewRepositorySuppliersMock(),
+		orders:    repository.NewRepositoryPurchaseOrdersMock(),
+	}
+	f.products.Products[stockedProduct] = newStockedProduct()
+	f.suppliers.Suppliers[supplierAcme] = &storage.Supplier{Id: supplierAcme, Name: "Acme", Lead_time_days: 5}
+	f.suppliers.Suppliers[supplierUnused] = &storage.Supplier{Id: supplierUnused, Name: "Unused"}
+	return f
+}
+
+func (f *purchasingFixture) router() *chi.Mux {
+	movementService := service.NewServiceMovements(&f.movements, &f.products)
+	supplierService := service.NewServiceSuppliers(&f.suppliers)
+	supplierService.Orders = &f.orders
+	orderService := service.NewServicePurchaseOrders(&f.orders, &f.suppliers, &f.products, &movementService)
+
+	hs := NewHandlerSuppliers(&supplierService)
+	ho := NewHandlerPurchaseOrders(&orderService)
+
+	rt := chi.NewRouter()
+	rt.Post("/suppliers", hs.Create)
+	rt.Delete("/suppliers/{id}", hs.Delete)
+	rt.Get("/purchase-orders", ho.GetAll)
+	rt.Get("/purchase-orders/inbound", ho.GetInbound)
+	rt.Post("/purchase-orders", ho.Create)
+	rt.Post("/purchase-orders/{id}/receive", ho.Receive)
+	rt.Post("/purchase-orders/{id}/cancel", ho.Cancel)
+	return rt
+}
+
+func serve(rt http.Handler, method string, path string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	return rr
+}
+
+func createOrder(t *testing.T, rt http.Handler, body string) utils.PurchaseOrderData {
+	rr := serve(rt, "POST", "/purchase-orders", body)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	var response utils.ResponseBodyPurchaseOrder
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	return *response.Data
+}
+
+func TestCreateSupplier(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{name: "Valid", body: `{"name":"Acme","email":"orders@acme.test","lead_time_days":3}`, expectedCode: http.StatusCreated},
+		{name: "Missing name", body: `{"name":" "}`, expectedCode: http.StatusBadRequest},
+		{name: "Invalid email", body: `{"name":"Acme","email":"acme"}`, expectedCode: http.StatusBadRequest},
+		{name: "Negative lead time", body: `{"name":"Acme","lead_time_days":-1}`, expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := serve(newPurchasingFixture().router(), "POST", "/suppliers", tt.body)
+			require.Equal(t, tt.expectedCode, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+func TestCreatePurchaseOrder(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "Valid",
+			body:         `{"supplier_id":"` + supplierAcme + `","lines":[{"product_id":"` + stockedProduct + `","quantity":5,"unit_cost":2.5},{"product_id":"` + variantM + `","quantity":3}]}`,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "Unknown supplier",
+			body:         `{"supplier_id":"684963bb-7172-48ad-aecd-cdca3f0df099","lines":[{"product_id":"` + stockedProduct + `","quantity":5}]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "No lines",
+			body:         `{"supplier_id":"` + supplierAcme + `","lines":[]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Unknown product",
+			body:         `{"supplier_id":"` + supplierAcme + `","lines":[{"product_id":"684963bb-7172-48ad-aecd-cdca3f0df099","quantity":5}]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Parent of variants",
+			body:         `{"supplier_id":"` + supplierAcme + `","lines":[{"product_id":"` + parentShirt + `","quantity":5}]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Product ordered twice",
+			body:         `{"supplier_id":"` + supplierAcme + `","lines":[{"product_id":"` + stockedProduct + `","quantity":5},{"product_id":"` + stockedProduct + `","quantity":1}]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Zero quantity",
+			body:         `{"supplier_id":"` + supplierAcme + `","lines":[{"product_id":"` + stockedProduct + `","quantity":0}]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPurchasingFixture()
+			rr := serve(f.router(), "POST", "/purchase-orders", tt.body)
+			require.Equal(t, tt.expectedCode, rr.Code, rr.Body.String())
+
+			if tt.expectedCode == http.StatusCreated {
+				var response utils.ResponseBodyPurchaseOrder
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+				require.Equal(t, storage.PurchaseOrderOpen, response.Data.Status)
+				require.Equal(t, 12.5, response.Data.Total)
+				require.Equal(t, 5, response.Data.Lines[0].Outstanding)
+			} else {
+				require.Empty(t, f.orders.Orders)
+			}
+		})
+	}
+}
+
+func TestReceivePurchaseOrder(t *testing.T) {
+	f := newPurchasingFixture()
+	rt := f.router()
+	order := createOrder(t, rt, `{"supplier_id":"`+supplierAcme+`","lines":[{"product_id":"`+stockedProduct+`","quantity":5},{"product_id":"`+variantM+`","quantity":3}]}`)
+	receive := "/purchase-orders/" + order.Id + "/receive"
+	variantStock := f.products.Products[variantM].Quantity
+
+	for _, body := range []string{
+		`{"actor":"alice","lines":[{"product_id":"` + stockedProduct + `","quantity":6}]}`,
+		`{"actor":"alice","lines":[{"product_id":"` + variantL + `","quantity":1}]}`,
+		`{"actor":"alice","lines":[{"product_id":"` + stockedProduct + `","quantity":1},{"product_id":"` + stockedProduct + `","quantity":1}]}`,
+		`{"lines":[{"product_id":"` + stockedProduct + `","quantity":1}]}`,
+	} {
+		rr := serve(rt, "POST", receive, body)
+		require.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+	require.Equal(t, 10, f.products.Products[stockedProduct].Quantity, "a refused delivery changes nothing")
+
+	rr := serve(rt, "POST", receive, `{"actor":"alice","lines":[{"product_id":"`+stockedProduct+`","quantity":2}]}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var response utils.ResponseBodyPurchaseOrder
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Equal(t, storage.PurchaseOrderPartiallyReceived, response.Data.Status)
+	require.Equal(t, 12, f.products.Products[stockedProduct].Quantity)
+
+	// the receipt is in the ledger, after the opening balance
+	movements, _ := f.movements.GetByProduct(stockedProduct)
+	require.Len(t, movements, 2)
+	require.Equal(t, storage.MovementReceipt, movements[1].Type)
+	require.Equal(t, 2, movements[1].Quantity)
+	require.Equal(t, "alice", movements[1].Actor)
+	require.Equal(t, "purchase order "+order.Id, movements[1].Reason)
+
+	rr = serve(rt, "GET", "/purchase-orders/inbound", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var inbound []utils.InboundData
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &inbound))
+	require.Len(t, inbound, 2)
+	require.Equal(t, variantM, inbound[0].Product_id)
+	require.Equal(t, 3, inbound[0].Quantity)
+	require.Equal(t, stockedProduct, inbound[1].Product_id)
+	require.Equal(t, 3, inbound[1].Quantity)
+	require.Equal(t, order.Id, inbound[1].Orders[0].Order_id)
+
+	// without lines the rest of the order is received
+	rr = serve(rt, "POST", receive, `{"actor":"alice"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Equal(t, storage.PurchaseOrderReceived, response.Data.Status)
+	require.NotNil(t, response.Data.Closed_at)
+	require.Equal(t, 15, f.products.Products[stockedProduct].Quantity)
+	require.Equal(t, variantStock+3, f.products.Products[variantM].Quantity)
+
+	rr = serve(rt, "POST", receive, `{"actor":"alice"}`)
+	require.Equal(t, http.StatusConflict, rr.Code)
+	rr = serve(rt, "POST", "/purchase-orders/"+order.Id+"/cancel", "")
+	require.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = serve(rt, "GET", "/purchase-orders/inbound", "")
+	require.Equal(t, "[]\n", rr.Body.String())
+}
+
+func TestCancelPurchaseOrder(t *testing.T) {
+	f := newPurchasingFixture()
+	rt := f.router()
+	first := createOrder(t, rt, `{"supplier_id":"`+supplierAcme+`","lines":[{"product_id":"`+stockedProduct+`","quantity":5}]}`)
+	second := createOrder(t, rt, `{"supplier_id":"`+supplierAcme+`","lines":[{"product_id":"`+stockedProduct+`","quantity":4}]}`)
+
+	rr := serve(rt, "GET", "/purchase-orders/inbound?product="+stockedProduct, "")
+	var inbound []utils.InboundData
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &inbound))
+	require.Equal(t, 9, inbound[0].Quantity)
+	require.Len(t, inbound[0].Orders, 2)
+
+	rr = serve(rt, "DELETE", "/suppliers/"+supplierAcme, "")
+	require.Equal(t, http.StatusConflict, rr.Code, "the supplier has open orders")
+
+	rr = serve(rt, "POST", "/purchase-orders/"+first.Id+"/cancel", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var response utils.ResponseBodyPurchaseOrder
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Equal(t, storage.PurchaseOrderCancelled, response.Data.Status)
+	require.Equal(t, 0, response.Data.Lines[0].Outstanding)
+
+	rr = serve(rt, "GET", "/purchase-orders/inbound?product="+stockedProduct, "")
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &inbound))
+	require.Equal(t, 4, inbound[0].Quantity)
+
+	rr = serve(rt, "GET", "/purchase-orders?status=open", "")
+	var orders []utils.PurchaseOrderData
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &orders))
+	require.Len(t, orders, 1)
+	require.Equal(t, second.Id, orders[0].Id)
+
+	rr = serve(rt, "GET", "/purchase-orders?status=lost", "")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = serve(rt, "DELETE", "/suppliers/"+supplierUnused, "")
+	require.Equal(t, http.StatusNoContent, rr.Code)
+}
+
+func TestReceiveRetryAfterAFailedReceipt(t *testing.T) {
+	f := newPurchasingFixture()
+	rt := f.router()
+	order := createOrder(t, rt, `{"supplier_id":"`+supplierAcme+`","lines":[{"product_id":"`+stockedProduct+`","quantity":5},{"product_id":"`+variantM+`","quantity":3}]}`)
+	receive := "/purchase-orders/" + order.Id + "/receive"
+
+	// the receipt of the coffee is recorded, the one of the variant gone since fails
+	delete(f.products.Products, variantM)
+	for i := 0; i < 2; i++ {
+		rr := serve(rt, "POST", receive, `{"actor":"alice"}`)
+		require.Equal(t, http.StatusConflict, rr.Code, rr.Body.String())
+		require.Equal(t, 15, f.products.Products[stockedProduct].Quantity, "a retry does not receive the coffee again")
+
+		stored := f.orders.Orders[order.Id]
+		require.Equal(t, storage.PurchaseOrderPartiallyReceived, stored.Status)
+		require.Equal(t, 5, stored.Lines[0].Received)
+		require.Equal(t, 0, stored.Lines[1].Received, "the failed receipt is released")
+	}
+}
+
+func TestConcurrentReceiptsFitTheOrder(t *testing.T) {
+	dir := t.TempDir()
+	productStorage := storage.NewStorageProducts()
+	productStorage.Path = filepath.Join(dir, "products.json")
+	require.NoError(t, productStorage.SaveProduct(newStockedProduct()))
+	movementStorage := storage.NewStorageMovements()
+	movementStorage.Path = filepath.Join(dir, "movements.json")
+	orderStorage := storage.NewStoragePurchaseOrders()
+	orderStorage.Path = filepath.Join(dir, "purchase_orders.json")
+
+	products := repository.NewRepositoryProducts(&productStorage)
+	movements := repository.NewRepositoryMovements(&movementStorage)
+	orders := repository.NewRepositoryPurchaseOrders(&orderStorage)
+	suppliers := repository.NewRepositorySuppliersMock()
+	suppliers.Suppliers[supplierAcme] = &storage.Supplier{Id: supplierAcme, Name: "Acme"}
+
+	movementService := service.NewServiceMovements(&movements, &products)
+	orderService := service.NewServicePurchaseOrders(&orders, &suppliers, &products, &movementService)
+	ho := NewHandlerPurchaseOrders(&orderService)
+	rt := chi.NewRouter()
+	rt.Post("/purchase-orders", ho.Create)
+	rt.Post("/purchase-orders/{id}/receive", ho.Receive)
+	rt.Post("/purchase-orders/{id}/cancel", ho.Cancel)
+
+	// ten receipts of one unit compete for the five ordered
+	order := createOrder(t, rt, `{"supplier_id":"`+supplierAcme+`","lines":[{"product_id":"`+stockedProduct+`","quantity":5}]}`)
+	var accepted atomic.Int32
+	race(10, func(int) {
+		rr := serve(rt, "POST", "/purchase-orders/"+order.Id+"/receive", `{"actor":"alice","lines":[{"product_id":"`+stockedProduct+`","quantity":1}]}`)
+		if rr.Code == http.StatusOK {
+			accepted.Add(1)
+		}
+	})
+
+	stored, err := orders.GetById(order.Id)
+	require.NoError(t, err)
+	require.Equal(t, 5, int(accepted.Load()))
+	require.Equal(t, 5, stored.Lines[0].Received)
+	require.Equal(t, storage.PurchaseOrderReceived, stored.Status)
+	product, err := products.GetById(stockedProduct)
+	require.NoError(t, err)
+	require.Equal(t, 15, product.Quantity, "the stock has the receipts of the order, no more")
+
+	// a delivery and a cancel of the same order, only one of them closes it
+	order = createOrder(t, rt, `{"supplier_id":"`+supplierAcme+`","lines":[{"product_id":"`+stockedProduct+`","quantity":5}]}`)
+	var codes [2]int
+	race(2, func(i int) {
+		path := []string{"/receive", "/cancel"}[i]
+		codes[i] = serve(rt, "POST", "/purchase-orders/"+order.Id+path, `{"actor":"alice"}`).Code
+	})
+
+	require.ElementsMatch(t, []int{http.StatusOK, http.StatusConflict}, codes[:])
+	stored, err = orders.GetById(order.Id)
+	require.NoError(t, err)
+	product, err = products.GetById(stockedProduct)
+	require.NoError(t, err)
+	if codes[0] == http.StatusOK {
+		require.Equal(t, storage.PurchaseOrderReceived, stored.Status)
+		require.Equal(t, 20, product.Quantity)
+	} else {
+		require.Equal(t, storage.PurchaseOrderCancelled, stored.Status)
+		require.Equal(t, 15, product.Quantity)
+	}
+}
+
+// race runs n calls of fn at once and waits for them
+func race(n int, fn func(i int)) {
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			fn(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+}
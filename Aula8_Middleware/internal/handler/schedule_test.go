@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	productLive     = "684963bb-7172-48ad-aecd-cdca3f0df040"
+	productHidden   = "684963bb-7172-48ad-aecd-cdca3f0df041"
+	productLaunch   = "684963bb-7172-48ad-aecd-cdca3f0df042"
+	productWithdraw = "684963bb-7172-48ad-aecd-cdca3f0df043"
+	productExpired  = "684963bb-7172-48ad-aecd-cdca3f0df044"
+)
+
+var scheduleNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// newScheduleFixture has a published product, an unpublished one, one published an hour
+// ago by a change the scheduler did not apply yet, one unpublished tomorrow and a published
+// one that expired yesterday
+func newScheduleFixture() (repository.MockRepository, *service.ServiceProducts) {
+	mockRepo := repository.NewRepositoryProductsMock()
+
+	products := []*storage.Product{
+		{Id: productLive, Code_value: "LIVE", Is_published: boolPtr(true)},
+		{Id: productHidden, Code_value: "HIDDEN", Is_published: boolPtr(false)},
+		{Id: productLaunch, Code_value: "LAUNCH", Is_published: boolPtr(false), Publish_at: timePtr(scheduleNow.Add(-time.Hour))},
+		{Id: productWithdraw, Code_value: "WITHDRAW", Is_published: boolPtr(true), Unpublish_at: timePtr(scheduleNow.Add(24 * time.Hour))},
+	}
+	for _, product := range products {
+		product.Name = "Product " + product.Code_value
+		product.Quantity = 1
+		product.Expiration = "01/01/2030"
+		product.Price = 10
+		mockRepo.Products[product.Id] = product
+	}
+	mockRepo.Products[productExpired] = &storage.Product{
+		Id: productExpired, Code_value: "EXPIRED", Name: "Product EXPIRED", Quantity: 1,
+		Is_published: boolPtr(true), Expiration: "31/05/2025", Price: 10,
+	}
+
+	productService := service.NewServiceProducts(&mockRepo)
+	productService.Now = func() time.Time { return scheduleNow }
+	return mockRepo, &productService
+}
+
+func newCatalogueRouter(productService *service.ServiceProducts) *chi.Mux {
+	hd := NewHandlerProducts(productService)
+
+	rt := chi.NewRouter()
+	rt.Get("/catalogue", hd.GetPublished)
+	rt.Get("/catalogue/{id}", hd.GetPublishedById)
+	rt.Get("/products/schedule", hd.GetSchedule)
+	rt.Patch("/products/{id}", hd.Update)
+	return rt
+}
+
+func TestCatalogueShowsPublishedProducts(t *testing.T) {
+	_, productService := newScheduleFixture()
+	rt := newCatalogueRouter(productService)
+
+	req, _ := http.NewRequest("GET", "/catalogue", nil)
+	rr := httptest.NewRecorder()
+	rt.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var products []utils.PublicData
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&products))
+
+	var codes []string
+	for _, product := range products {
+		codes = append(codes, product.Code_value)
+	}
+	sort.Strings(codes)
+	require.Equal(t, []string{"LAUNCH", "LIVE", "WITHDRAW"}, codes, "a due change counts before the scheduler applies it")
+
+	tests := []struct {
+		name         string
+		id           string
+		expectedCode int
+	}{
+		{name: "Published", id: productLive, expectedCode: http.StatusOK},
+		{name: "Unpublished", id: productHidden, expectedCode: http.StatusNotFound},
+		{name: "Expired", id: productExpired, expectedCode: http.StatusNotFound},
+		{name: "Missing", id: "684963bb-7172-48ad-aecd-cdca3f0df099", expectedCode: http.StatusNotFound},
+		{name: "Invalid id", id: "not-an-id", expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/catalogue/"+tt.id, nil)
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, req)
+			require.Equal(t, tt.expectedCode, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+func TestApplySchedule(t *testing.T) {
+	mockRepo, productService := newScheduleFixture()
+	rt := newCatalogueRouter(productService)
+
+	getSchedule := func() []utils.ScheduledChangeData {
+		req, _ := http.NewRequest("GET", "/products/schedule", nil)
+		rr := httptest.NewRecorder()
+		rt.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var changes []utils.ScheduledChangeData
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&changes))
+		return changes
+	}
+
+	changes := getSchedule()
+	require.Len(t, changes, 2)
+	require.Equal(t, "LAUNCH", changes[0].Code_value)
+	require.Equal(t, service.ScheduleActionPublish, changes[0].Action)
+	require.True(t, changes[0].Due)
+	require.Equal(t, "WITHDRAW", changes[1].Code_value)
+	require.False(t, changes[1].Due)
+
+	applied, err := productService.ApplySchedule()
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	require.True(t, *mockRepo.Products[productLaunch].Is_published)
+	require.Nil(t, mockRepo.Products[productLaunch].Publish_at, "an applied change is cleared")
+	require.Len(t, getSchedule(), 1)
+
+	productService.Now = func() time.Time { return scheduleNow.Add(48 * time.Hour) }
+
+	applied, err = productService.ApplySchedule()
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	require.False(t, *mockRepo.Products[productWithdraw].Is_published)
+	require.Empty(t, getSchedule())
+
+	applied, err = productService.ApplySchedule()
+	require.NoError(t, err)
+	require.Empty(t, applied, "nothing is left to apply")
+}
+
+// rescheduledRepository moves the window of a product once the scheduler read the products,
+// like an admin patching it meanwhile
+type rescheduledRepository struct {
+	*repository.MockRepository
+	id          string
+	rescheduled bool
+}
+
+func (r *rescheduledRepository) GetAll() ([]*storage.Product, error) {
+	products, err := r.MockRepository.GetAll()
+	if !r.rescheduled {
+		r.rescheduled = true
+		product := *r.Products[r.id]
+		product.Publish_at = timePtr(scheduleNow.Add(24 * time.Hour))
+		r.Products[r.id] = &product
+	}
+	return products, err
+}
+
+func TestApplyScheduleKeepsAChangedWindow(t *testing.T) {
+	mockRepo, _ := newScheduleFixture()
+	productService := service.NewServiceProducts(&rescheduledRepository{MockRepository: &mockRepo, id: productLaunch})
+	productService.Now = func() time.Time { return scheduleNow }
+
+	applied, err := productService.ApplySchedule()
+	require.NoError(t, err)
+	require.Empty(t, applied, "the window that fired was moved before the write")
+	require.False(t, *mockRepo.Products[productLaunch].Is_published)
+	require.Equal(t, scheduleNow.Add(24*time.Hour), *mockRepo.Products[productLaunch].Publish_at)
+}
+
+func TestQuotePublishes(t *testing.T) {
+	mockRepo, productService := newScheduleFixture()
+
+	_, _, err := productService.GetTotalPrice([]string{productHidden}, scheduleNow)
+	require.NoError(t, err)
+	require.True(t, *mockRepo.Products[productHidden].Is_published, "a quoted product goes into the catalogue")
+}
+
+func TestPatchSchedule(t *testing.T) {
+	tests := []struct {
+		name         string
+		id           string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "Schedule a window",
+			id:           productHidden,
+			body:         `{"publish_at":"2025-07-01T09:00:00Z","unpublish_at":"2025-07-31T18:00:00-03:00"}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Window closing before it opens",
+			id:           productWithdraw,
+			body:         `{"publish_at":"2025-06-03T09:00:00Z"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Invalid time",
+			id:           productHidden,
+			body:         `{"publish_at":"01/07/2025"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Clear a change",
+			id:           productWithdraw,
+			body:         `{"unpublish_at":null}`,
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo, productService := newScheduleFixture()
+			rt := newCatalogueRouter(productService)
+
+			req, _ := http.NewRequest("PATCH", "/products/"+tt.id, strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, req)
+			require.Equal(t, tt.expectedCode, rr.Code, rr.Body.String())
+
+			if tt.name == "Clear a change" {
+				require.Nil(t, mockRepo.Products[tt.id].Unpublish_at)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"aula4/internal/search"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	productPao    = "684963bb-7172-48ad-aecd-cdca3f0df030"
+	productPaoDoc = "684963bb-7172-48ad-aecd-cdca3f0df031"
+	productCafe   = "684963bb-7172-48ad-aecd-cdca3f0df032"
+)
+
+func newSearchFixture(t *testing.T) (*ProductController, *service.ServiceProducts) {
+	mockRepo := repository.NewRepositoryProductsMock()
+	for id, product := range map[string]storage.Product{
+		productPao:    {Name: "Pão de Açúcar", Code_value: "PAO-001", Price: 3},
+		productPaoDoc: {Name: "Pão doce", Code_value: "PAO-002", Price: 4},
+		productCafe:   {Name: "Café Pilão", Code_value: "CAF-100", Price: 12},
+	} {
+		product.Id = id
+		product.Quantity = 5
+		product.Is_published = boolPtr(true)
+		product.Expiration = "01/01/2030"
+		mockRepo.Products[id] = &product
+	}
+
+	index := search.NewIndex()
+	indexedRepo, err := search.NewIndexedRepository(&mockRepo, index)
+	require.NoError(t, err)
+
+	productService := service.NewServiceProducts(&indexedRepo)
+	productService.Index = index
+	return NewHandlerProducts(&productService), &productService
+}
+
+func searchProducts(controller *ProductController, query string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/products/search?"+query, nil)
+	rr := httptest.NewRecorder()
+	controller.Search(rr, req)
+	return rr
+}
+
+func TestFullTextSearch(t *testing.T) {
+	tests := []struct {
+		name         string
+		q            string
+		expectedIds  []string
+		expectedCode int
+	}{
+		{
+			name:         "Accents are folded",
+			q:            "pao",
+			expectedIds:  []string{productPao, productPaoDoc},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Every word must match",
+			q:            "pão açucar",
+			expectedIds:  []string{productPao},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Prefix",
+			q:            "caf",
+			expectedIds:  []string{productCafe},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Typo",
+			q:            "pilao cafr",
+			expectedIds:  []string{productCafe},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Code without separators",
+			q:            "caf100",
+			expectedIds:  []string{productCafe},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "No match",
+			q:            "leite",
+			expectedIds:  []string{},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "No searchable words",
+			q:            "--",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller, _ := newSearchFixture(t)
+
+			rr := searchProducts(controller, "q="+url.QueryEscape(tt.q))
+			require.Equal(t, tt.expectedCode, rr.Code, rr.Body.String())
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var body utils.ResponseBodySearch
+			err := json.NewDecoder(rr.Body).Decode(&body)
+			require.NoError(t, err)
+
+			ids := []string{}
+			for _, result := range body.Results {
+				ids = append(ids, result.Data.Id)
+			}
+			require.ElementsMatch(t, tt.expectedIds, ids)
+			require.Equal(t, len(tt.expectedIds), body.Total)
+		})
+	}
+}
+
+func TestFullTextSearchRanking(t *testing.T) {
+	controller, _ := newSearchFixture(t)
+
+	rr := searchProducts(controller, "q=p%C3%A3o+a")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var body utils.ResponseBodySearch
+	err := json.NewDecoder(rr.Body).Decode(&body)
+	require.NoError(t, err)
+	require.Len(t, body.Results, 1)
+	require.Equal(t, "<em>Pão</em> de <em>Açúcar</em>", body.Results[0].Highlights["name"])
+
+	rr = searchProducts(controller, "q=pao+001")
+	err = json.NewDecoder(rr.Body).Decode(&body)
+	require.NoError(t, err)
+	require.Equal(t, productPao, body.Results[0].Data.Id)
+	require.Equal(t, "<em>PAO</em>-<em>001</em>", body.Results[0].Highlights["code_value"])
+
+	rr = searchProducts(controller, "q=pao&page=2&page_size=1")
+	err = json.NewDecoder(rr.Body).Decode(&body)
+	require.NoError(t, err)
+	require.Equal(t, 2, body.Total)
+	require.Equal(t, 2, body.Page)
+	require.Len(t, body.Results, 1)
+	require.Greater(t, body.Results[0].Score, 0.0)
+
+	rr = searchProducts(controller, "q=pao&page=0")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestFullTextSearchEscapesTheHighlights(t *testing.T) {
+	controller, productService := newSearchFixture(t)
+
+	_, err := productService.Create(storage.Product{
+		Name:         `<script>alert("tea")</script> & Tea`,
+		Quantity:     5,
+		Code_value:   "TEA-<b>",
+		Is_published: boolPtr(true),
+		Expiration:   "01/01/2030",
+		Price:        3,
+	})
+	require.NoError(t, err)
+
+	rr := searchProducts(controller, "q=tea")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var body utils.ResponseBodySearch
+	err = json.NewDecoder(rr.Body).Decode(&body)
+	require.NoError(t, err)
+	require.Len(t, body.Results, 1)
+	require.Equal(t, "&lt;script&gt;alert(&#34;<em>tea</em>&#34;)&lt;/script&gt; &amp; <em>Tea</em>", body.Results[0].Highlights["name"])
+	require.Equal(t, "<em>TEA</em>-&lt;b&gt;", body.Results[0].Highlights["code_value"])
+}
+
+func TestFullTextSearchFollowsWrites(t *testing.T) {
+	controller, productService := newSearchFixture(t)
+
+	product, err := productService.GetById(productCafe)
+	require.NoError(t, err)
+
+	renamed := *product
+	renamed.Name = "Café Melitta"
+	_, err = productService.Update(renamed)
+	require.NoError(t, err)
+
+	rr := searchProducts(controller, "q=melitta")
+	require.Contains(t, rr.Body.String(), productCafe)
+	rr = searchProducts(controller, "q=pilao")
+	require.NotContains(t, rr.Body.String(), productCafe)
+
+	err = productService.Delete(productPao)
+	require.NoError(t, err)
+
+	rr = searchProducts(controller, "q=acucar")
+	require.NotContains(t, rr.Body.String(), productPao)
+
+	rr = searchProducts(controller, "price=10")
+	require.Equal(t, http.StatusOK, rr.Code, "the price search keeps working")
+	require.Contains(t, rr.Body.String(), productCafe)
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"aula4/internal/service"
+	"aula4/internal/stream"
+	"aula4/internal/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultHeartbeat is the interval of the keep-alive comments of the stream
+	DefaultHeartbeat = 15 * time.Second
+
+	// streamRetry tells EventSource clients how long to wait before reconnecting, in milliseconds
+	streamRetry = 3000
+)
+
+type StreamController struct {
+	Broker *stream.Broker
+	// Heartbeat is the interval of the keep-alive comments, DefaultHeartbeat when zero
+	Heartbeat time.Duration
+}
+
+func NewHandlerStream(broker *stream.Broker, heartbeat time.Duration) *StreamController {
+	if heartbeat <= 0 {
+		heartbeat = DefaultHeartbeat
+	}
+
+	return &StreamController{
+		Broker:    broker,
+		Heartbeat: heartbeat,
+	}
+}
+
+// Stream serves the product events as text/event-stream. Clients filter them with
+// ?product=<id,...> and ?fields=<field,...> and resume with the Last-Event-ID header.
+func (c *StreamController) Stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		utils.ResponseWithError(w, errors.New("streaming is not supported"), http.StatusInternalServerError)
+		return
+	}
+
+	filter := stream.Filter{
+		ProductIds: splitList(r.URL.Query().Get("product")),
+		Fields:     splitList(r.URL.Query().Get("fields")),
+	}
+
+	lastId, err := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+	resume := err == nil
+
+	backlog, messages, cancel := c.Broker.Subscribe(lastId, resume, filter)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+	for _, message := range backlog {
+		writeMessage(w, message)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(c.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+			writeMessage(w, message)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func writeMessage(w http.ResponseWriter, message stream.Message) {
+	data, err := json.Marshal(service.ToEventData(message.Event))
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", message.Id, message.Event.Type, data)
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
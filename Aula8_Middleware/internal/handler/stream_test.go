@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/stream"
+	"aula4/internal/utils"
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type sseEvent struct {
+	id      string
+	event   string
+	data    string
+	comment string
+}
+
+// sseClient reads the events of GET /products/stream one at a time
+type sseClient struct {
+	reader *bufio.Reader
+}
+
+func connectStream(t *testing.T, server *httptest.Server, query string, lastEventId string) *sseClient {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/products/stream"+query, nil)
+	if lastEventId != "" {
+		req.Header.Set("Last-Event-ID", lastEventId)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	client := &sseClient{reader: bufio.NewReader(resp.Body)}
+	retry := client.next(t)
+	require.Empty(t, retry.event, "the stream starts with the retry interval")
+	return client
+}
+
+func (c *sseClient) next(t *testing.T) sseEvent {
+	var event sseEvent
+	for {
+		line, err := c.reader.ReadString('\n')
+		require.NoError(t, err)
+
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return event
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "":
+			event.comment = value
+		case "id":
+			event.id = value
+		case "event":
+			event.event = value
+		case "data":
+			event.data = value
+		}
+	}
+}
+
+func newStreamServer(t *testing.T, heartbeat time.Duration) (*httptest.Server, *stream.Broker, *service.ServiceProducts) {
+	broker := stream.NewBroker(10)
+	mockRepo := repository.NewRepositoryProductsMock()
+	productService := service.NewServiceProducts(&mockRepo)
+	productService.Events = broker
+
+	streamHandler := NewHandlerStream(broker, heartbeat)
+	server := httptest.NewServer(http.HandlerFunc(streamHandler.Stream))
+	t.Cleanup(server.Close)
+
+	return server, broker, &productService
+}
+
+func waitForClients(t *testing.T, broker *stream.Broker, clients int) {
+	require.Eventually(t, func() bool { return broker.Clients() == clients }, time.Second, time.Millisecond)
+}
+
+func streamProduct(code string) storage.Product {
+	return storage.Product{
+		Name:         "Tea",
+		Quantity:     5,
+		Code_value:   code,
+		Is_published: boolPtr(true),
+		Expiration:   "01/01/2030",
+		Price:        3.0,
+	}
+}
+
+func TestStreamLiveEvents(t *testing.T) {
+	server, broker, productService := newStreamServer(t, time.Minute)
+	client := connectStream(t, server, "", "")
+	waitForClients(t, broker, 1)
+
+	product, err := productService.Create(streamProduct("TEA"))
+	require.NoError(t, err)
+
+	event := client.next(t)
+	require.Equal(t, "1", event.id)
+	require.Equal(t, service.EventProductCreated, event.event)
+
+	var data utils.EventData
+	err = json.Unmarshal([]byte(event.data), &data)
+	require.NoError(t, err)
+	require.Equal(t, product.Id, data.Data.Id)
+
+	err = productService.Delete(product.Id)
+	require.NoError(t, err)
+
+	event = client.next(t)
+	require.Equal(t, "2", event.id)
+	require.Equal(t, service.EventProductDeleted, event.event)
+}
+
+func TestStreamFilters(t *testing.T) {
+	server, broker, productService := newStreamServer(t, time.Minute)
+
+	watched, err := productService.Create(streamProduct("TEA"))
+	require.NoError(t, err)
+	other, err := productService.Create(streamProduct("COFFEE"))
+	require.NoError(t, err)
+
+	client := connectStream(t, server, "?product="+watched.Id+"&fields=price", "")
+	waitForClients(t, broker, 1)
+
+	other.Price = 10
+	_, err = productService.Update(other)
+	require.NoError(t, err)
+
+	watched.Quantity = 1
+	_, err = productService.Update(watched)
+	require.NoError(t, err)
+
+	watched.Price = 4
+	_, err = productService.Update(watched)
+	require.NoError(t, err)
+
+	event := client.next(t)
+	require.Equal(t, service.EventProductUpdated, event.event)
+
+	var data utils.EventData
+	err = json.Unmarshal([]byte(event.data), &data)
+	require.NoError(t, err)
+	require.Equal(t, watched.Id, data.Data.Id)
+	// the new price goes into the timeline too
+	require.Equal(t, []string{"price", "prices"}, data.Changed, "only the price change of the watched product matches")
+}
+
+func TestStreamResume(t *testing.T) {
+	server, _, productService := newStreamServer(t, time.Minute)
+
+	for _, code := range []string{"A", "B", "C"} {
+		_, err := productService.Create(streamProduct(code))
+		require.NoError(t, err)
+	}
+
+	client := connectStream(t, server, "", "1")
+
+	require.Equal(t, "2", client.next(t).id)
+	require.Equal(t, "3", client.next(t).id)
+}
+
+func TestStreamHeartbeat(t *testing.T) {
+	server, _, _ := newStreamServer(t, 10*time.Millisecond)
+	client := connectStream(t, server, "", "")
+
+	require.Equal(t, "heartbeat", client.next(t).comment)
+}
+
+func TestBrokerDropsSlowClients(t *testing.T) {
+	broker := stream.NewBroker(1000)
+	_, messages, cancel := broker.Subscribe(0, false, stream.Filter{})
+	defer cancel()
+
+	for i := 0; i < 100; i++ {
+		broker.Publish(service.NewEvent(service.EventProductCreated, streamProduct("A")))
+	}
+
+	require.Equal(t, 0, broker.Clients(), "a client that does not read is disconnected")
+
+	received := 0
+	for range messages {
+		received++
+	}
+	require.Less(t, received, 100)
+}
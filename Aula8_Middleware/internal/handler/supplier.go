@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+type SupplierController struct {
+	Service service.SupplierService
+}
+
+func NewHandlerSuppliers(service service.SupplierService) *SupplierController {
+	return &SupplierController{
+		Service: service,
+	}
+}
+
+func (c *SupplierController) Create(w http.ResponseWriter, r *http.Request) {
+	var reqBody utils.RequestBodySupplier
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	supplier, err := c.Service.Create(storage.Supplier{
+		Name:           reqBody.Name,
+		Email:          reqBody.Email,
+		Phone:          reqBody.Phone,
+		Lead_time_days: reqBody.Lead_time_days,
+	})
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondWithSupplier(w, &supplier, http.StatusCreated, utils.MessageSupplierCreated)
+}
+
+func (c *SupplierController) Update(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var reqBody utils.RequestBodySupplier
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	supplier, err := c.Service.Update(storage.Supplier{
+		Id:             idStr,
+		Name:           reqBody.Name,
+		Email:          reqBody.Email,
+		Phone:          reqBody.Phone,
+		Lead_time_days: reqBody.Lead_time_days,
+	})
+	if err != nil {
+		if err.Error() == "supplier not found" {
+			utils.ResponseWithError(w, err, http.StatusNotFound)
+			return
+		}
+
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondWithSupplier(w, &supplier, http.StatusOK, utils.MessageSupplierUpdated)
+}
+
+func (c *SupplierController) Delete(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := c.Service.Delete(idStr); err != nil {
+		switch err.Error() {
+		case "supplier not found":
+			utils.ResponseWithError(w, err, http.StatusNotFound)
+		case "supplier has open purchase orders":
+			utils.ResponseWithError(w, err, http.StatusConflict)
+		default:
+			utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.RespondWithSupplier(w, nil, http.StatusNoContent, utils.MessageSupplierDeleted)
+}
+
+func (c *SupplierController) GetAll(w http.ResponseWriter, r *http.Request) {
+	suppliers, err := c.Service.GetAll()
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	data := []utils.SupplierData{}
+	for _, supplier := range suppliers {
+		data = append(data, utils.ToSupplierData(supplier))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (c *SupplierController) GetById(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	supplier, err := c.Service.GetById(idStr)
+	if err != nil {
+		if err.Error() == "supplier not found" {
+			utils.ResponseWithError(w, err, http.StatusNotFound)
+		} else {
+			utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(utils.ToSupplierData(supplier))
+}
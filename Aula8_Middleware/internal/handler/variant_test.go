@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	parentShirt = "684963bb-7172-48ad-aecd-cdca3f0df020"
+	variantM    = "684963bb-7172-48ad-aecd-cdca3f0df021"
+	variantL    = "684963bb-7172-48ad-aecd-cdca3f0df022"
+)
+
+func newVariantFixture() repository.MockRepository {
+	mockRepo := repository.NewRepositoryProductsMock()
+
+	mockRepo.Products[parentShirt] = &storage.Product{
+		Id:           parentShirt,
+		Name:         "Shirt",
+		Quantity:     1,
+		Code_value:   "SHIRT",
+		Is_published: boolPtr(true),
+		Expiration:   "01/01/2030",
+		Price:        10.0,
+	}
+	mockRepo.Products[variantM] = &storage.Product{
+		Id:           variantM,
+		Name:         "Shirt",
+		Quantity:     4,
+		Code_value:   "SHIRT-M",
+		Is_published: boolPtr(true),
+		Expiration:   "01/01/2030",
+		Price:        10.0,
+		Parent_id:    parentShirt,
+		Attributes:   map[string]string{"size": "M"},
+	}
+	mockRepo.Products[variantL] = &storage.Product{
+		Id:           variantL,
+		Name:         "Shirt",
+		Quantity:     2,
+		Code_value:   "SHIRT-L",
+		Is_published: boolPtr(true),
+		Expiration:   "01/01/2030",
+		Price:        12.0,
+		Parent_id:    parentShirt,
+		Attributes:   map[string]string{"size": "L"},
+	}
+
+	return mockRepo
+}
+
+func TestCreateVariant(t *testing.T) {
+	tests := []struct {
+		name         string
+		parentID     string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "Successful creation",
+			parentID:     parentShirt,
+			body:         `{"code_value":"SHIRT-XL","quantity":3,"price":14,"attributes":{"size":"XL"}}`,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "Duplicated code_value",
+			parentID:     parentShirt,
+			body:         `{"code_value":"SHIRT-M","quantity":3,"price":14,"attributes":{"size":"M"}}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Variant of a variant",
+			parentID:     variantM,
+			body:         `{"code_value":"SHIRT-M-RED","quantity":3,"price":14,"attributes":{"color":"red"}}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Parent not found",
+			parentID:     "684963bb-7172-48ad-aecd-cdca3f0df099",
+			body:         `{"code_value":"SHIRT-XL","quantity":3,"price":14,"attributes":{"size":"XL"}}`,
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := newVariantFixture()
+			productService := service.NewServiceProducts(&mockRepo)
+			productHandler := NewHandlerProducts(&productService)
+
+			rt := chi.NewRouter()
+			rt.Post("/products/{id}/variants", productHandler.CreateVariant)
+
+			req, _ := http.NewRequest("POST", "/products/"+tt.parentID+"/variants", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code, "handler returned wrong status code: %s", rr.Body.String())
+
+			if tt.expectedCode == http.StatusCreated {
+				var response utils.ResponseBodyProduct
+				err := json.NewDecoder(rr.Body).Decode(&response)
+				require.NoError(t, err, "could not decode response body")
+
+				require.Equal(t, parentShirt, response.Data.Parent_id)
+				require.Equal(t, "Shirt", response.Data.Name, "name must be inherited from the parent")
+				require.Equal(t, "01/01/2030", response.Data.Expiration, "expiration must be inherited from the parent")
+				require.Equal(t, map[string]string{"size": "XL"}, response.Data.Attributes)
+			}
+		})
+	}
+}
+
+func TestGetByIdEmbedsVariants(t *testing.T) {
+	mockRepo := newVariantFixture()
+	productService := service.NewServiceProducts(&mockRepo)
+	productHandler := NewHandlerProducts(&productService)
+
+	req, _ := http.NewRequest("GET", "/products/"+parentShirt, nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(productHandler.GetById).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var response struct {
+		storage.Product
+		Variants []*storage.Product
+	}
+	err := json.NewDecoder(rr.Body).Decode(&response)
+	require.NoError(t, err, "could not decode response body")
+
+	require.Equal(t, parentShirt, response.Id)
+	require.Len(t, response.Variants, 2)
+}
+
+func TestConsumerPriceOfVariants(t *testing.T) {
+	tests := []struct {
+		name          string
+		list          string
+		expectedCode  int
+		expectedTotal float64
+	}{
+		{
+			name:          "Variant",
+			list:          variantL,
+			expectedCode:  http.StatusOK,
+			expectedTotal: 12.0 * service.TaxLessThanTen,
+		},
+		{
+			name:         "Parent with variants",
+			list:         parentShirt,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:          "Every stock unit skips the parent",
+			list:          "",
+			expectedCode:  http.StatusOK,
+			expectedTotal: 22.0 * service.TaxLessThanTen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := newVariantFixture()
+			productService := service.NewServiceProducts(&mockRepo)
+			productHandler := NewHandlerProducts(&productService)
+
+			req, _ := http.NewRequest("GET", "/products/consumer_price?list="+tt.list, nil)
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(productHandler.ConsumerPrice).ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code, "handler returned wrong status code: %s", rr.Body.String())
+
+			if tt.expectedCode == http.StatusOK {
+				var response utils.ResponseBodyTotalPrice
+				err := json.NewDecoder(rr.Body).Decode(&response)
+				require.NoError(t, err, "could not decode response body")
+				require.InDelta(t, tt.expectedTotal, response.TotalPrice, 0.0001)
+			}
+		})
+	}
+}
+
+func TestDeleteProductWithVariants(t *testing.T) {
+	mockRepo := newVariantFixture()
+	productService := service.NewServiceProducts(&mockRepo)
+	productHandler := NewHandlerProducts(&productService)
+
+	req, _ := http.NewRequest("DELETE", "/products/"+parentShirt, nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(productHandler.Delete).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	require.Empty(t, mockRepo.Products, "variants must be deleted with their parent")
+}
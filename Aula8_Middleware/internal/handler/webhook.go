@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"aula4/internal/webhook"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+type WebhookController struct {
+	Service    service.WebhookService
+	Dispatcher *webhook.Dispatcher
+}
+
+func NewHandlerWebhooks(service service.WebhookService, dispatcher *webhook.Dispatcher) *WebhookController {
+	return &WebhookController{
+		Service:    service,
+		Dispatcher: dispatcher,
+	}
+}
+
+func (c *WebhookController) Create(w http.ResponseWriter, r *http.Request) {
+	var reqBody utils.RequestBodySubscriber
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	subscriber, err := c.Service.Subscribe(storage.Subscriber{
+		Url:    reqBody.Url,
+		Events: reqBody.Events,
+		Secret: reqBody.Secret,
+	})
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	data := utils.ToSubscriberData(&subscriber)
+	data.Secret = subscriber.Secret
+
+	utils.RespondWithSubscriber(w, &data, http.StatusCreated, utils.MessageSubscriberCreated)
+}
+
+func (c *WebhookController) GetAll(w http.ResponseWriter, r *http.Request) {
+	subscribers, err := c.Service.GetAll()
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	data := []utils.SubscriberData{}
+	for _, subscriber := range subscribers {
+		data = append(data, utils.ToSubscriberData(subscriber))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (c *WebhookController) Delete(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(idStr); err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := c.Service.Delete(idStr); err != nil {
+		if err.Error() == "subscriber not found" {
+			utils.ResponseWithError(w, err, http.StatusNotFound)
+		} else {
+			utils.ResponseWithError(w, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.RespondWithSubscriber(w, nil, http.StatusNoContent, utils.MessageSubscriberDeleted)
+}
+
+func (c *WebhookController) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	deliveries := c.Dispatcher.Deliveries(r.URL.Query().Get("subscriber"))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+func (c *WebhookController) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	deadLetters := c.Dispatcher.DeadLetters()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(deadLetters)
+}
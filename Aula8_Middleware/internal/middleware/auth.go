@@ -1,12 +1,15 @@
 package middleware
 
 import (
+	"aula4/internal/tenant"
 	"aula4/internal/utils"
 	"errors"
 	"net/http"
 	"os"
 )
 
+// ValidateToken checks the Token header against the token of the tenant of the request,
+// or the TOKEN of the environment for the tenants without one
 func ValidateToken(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		token := r.Header.Get("Token")
@@ -15,7 +18,12 @@ func ValidateToken(next http.Handler) http.Handler {
 			return
 		}
 
-		if token != os.Getenv("TOKEN") {
+		expected := os.Getenv("TOKEN")
+		if t, ok := tenant.FromContext(r.Context()); ok && t.Token != "" {
+			expected = t.Token
+		}
+
+		if token != expected {
 			utils.ResponseWithError(w, errors.New("Unauthorized"), http.StatusUnauthorized)
 			return
 		}
@@ -0,0 +1,267 @@
+package middleware
+
+import (
+	"aula4/internal/utils"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+	DefaultIdempotencyTTL    = 24 * time.Hour
+	// DefaultIdempotencyMaxEntries bounds the memory of the keys sent within a TTL
+	DefaultIdempotencyMaxEntries = 10000
+	maxIdempotencyKeyLength      = 255
+	idempotencyKeySeparator      = "\x00"
+)
+
+var (
+	ErrIdempotencyKeyTooLong    = errors.New("Idempotency-Key is too long")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this Idempotency-Key is in progress")
+	ErrIdempotencyKeyReused     = errors.New("Idempotency-Key was already used with a different request")
+	ErrIdempotencyBodyTooLarge  = errors.New("request body is too large")
+)
+
+// storedResponse is the first response given to a key, replayed on retries
+type storedResponse struct {
+	fingerprint [sha256.Size]byte
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+// Idempotency replays the first response of the POST and PATCH requests sent with the
+// same Idempotency-Key header by the same client, so a client can retry them safely.
+// The keys are kept in memory for TTL, at most MaxEntries of them: the oldest key is
+// forgotten first.
+type Idempotency struct {
+	TTL        time.Duration
+	MaxEntries int
+	// Now returns the current time, time.Now when nil
+	Now func() time.Time
+
+	mu        sync.Mutex
+	responses map[string]*storedResponse
+	// expiries lists the reserved keys by expiry, they all live for TTL so it is the reserve order
+	expiries []expiry
+}
+
+type expiry struct {
+	key     string
+	expires time.Time
+}
+
+// NewIdempotency keeps the responses for ttl, DefaultIdempotencyTTL when zero, and at most
+// DefaultIdempotencyMaxEntries of them
+func NewIdempotency(ttl time.Duration) *Idempotency {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+
+	return &Idempotency{
+		TTL:        ttl,
+		MaxEntries: DefaultIdempotencyMaxEntries,
+		responses:  make(map[string]*storedResponse),
+	}
+}
+
+func (i *Idempotency) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(HeaderIdempotencyKey)
+		if key == "" || r.Method != http.MethodPost && r.Method != http.MethodPatch {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			utils.ResponseWithError(w, ErrIdempotencyKeyTooLong, http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				utils.ResponseWithError(w, ErrIdempotencyBodyTooLarge, http.StatusRequestEntityTooLarge)
+				return
+			}
+			utils.ResponseWithError(w, errors.New("could not read the request body"), http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// the method and the path are part of the key, the body is only compared
+		storeKey := client(r) + idempotencyKeySeparator + r.Method + " " + r.URL.Path + idempotencyKeySeparator + key
+		fingerprint := sha256.Sum256(body)
+
+		stored, first, err := i.reserve(storeKey, fingerprint)
+		if err != nil {
+			status := http.StatusConflict
+			if errors.Is(err, ErrIdempotencyKeyReused) {
+				status = http.StatusUnprocessableEntity
+			}
+			utils.ResponseWithError(w, err, status)
+			return
+		}
+
+		if !first {
+			for name, values := range stored.header {
+				w.Header()[name] = values
+			}
+			w.Header().Set(HeaderIdempotentReplayed, "true")
+			w.WriteHeader(stored.status)
+			w.Write(stored.body)
+			return
+		}
+
+		// a handler that panics releases the key, the retries are not refused as in progress
+		completed := false
+		defer func() {
+			if !completed {
+				i.release(storeKey)
+			}
+		}()
+
+		recorder := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		i.complete(storeKey, recorder)
+		completed = true
+	})
+}
+
+// reserve returns the stored response of the key, or reserves the key for the caller when first is true
+func (i *Idempotency) reserve(key string, fingerprint [sha256.Size]byte) (stored storedResponse, first bool, err error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := i.now()
+	i.removeExpired(now)
+
+	response, ok := i.responses[key]
+	if !ok {
+		i.removeOldest()
+		expires := now.Add(i.TTL)
+		i.responses[key] = &storedResponse{fingerprint: fingerprint, expires: expires}
+		i.expiries = append(i.expiries, expiry{key: key, expires: expires})
+		return storedResponse{}, true, nil
+	}
+
+	if response.fingerprint != fingerprint {
+		return storedResponse{}, false, ErrIdempotencyKeyReused
+	}
+	if !response.done {
+		return storedResponse{}, false, ErrIdempotencyKeyInProgress
+	}
+
+	return *response, false, nil
+}
+
+// complete stores the response, server errors release the key so the request can be retried
+func (i *Idempotency) complete(key string, recorder *recordingWriter) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if recorder.status >= http.StatusInternalServerError {
+		delete(i.responses, key)
+		return
+	}
+
+	// the key was forgotten for a newer one while the request ran
+	response, ok := i.responses[key]
+	if !ok {
+		return
+	}
+	response.done = true
+	response.status = recorder.status
+	response.header = recorder.Header().Clone()
+	response.body = recorder.body.Bytes()
+}
+
+// release forgets the key of a request that did not complete
+func (i *Idempotency) release(key string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if response, ok := i.responses[key]; ok && !response.done {
+		delete(i.responses, key)
+	}
+}
+
+// removeExpired must be called with i.mu held. It stops at the first key that has not expired,
+// a released key reserved again has its own later entry in i.expiries.
+func (i *Idempotency) removeExpired(now time.Time) {
+	for len(i.expiries) > 0 && !now.Before(i.expiries[0].expires) {
+		first := i.expiries[0]
+		i.expiries = i.expiries[1:]
+
+		if response, ok := i.responses[first.key]; ok && response.expires.Equal(first.expires) {
+			delete(i.responses, first.key)
+		}
+	}
+}
+
+// removeOldest must be called with i.mu held. It forgets the oldest keys until there is room
+// for one more, skipping the entries of i.expiries whose key was already released.
+func (i *Idempotency) removeOldest() {
+	for len(i.responses) >= i.MaxEntries && len(i.expiries) > 0 {
+		first := i.expiries[0]
+		i.expiries = i.expiries[1:]
+
+		if response, ok := i.responses[first.key]; ok && response.expires.Equal(first.expires) {
+			delete(i.responses, first.key)
+		}
+	}
+}
+
+func (i *Idempotency) now() time.Time {
+	if i.Now == nil {
+		return time.Now()
+	}
+	return i.Now()
+}
+
+// client identifies the caller by its token, by its address when it has none
+func client(r *http.Request) string {
+	if token := r.Header.Get("Token"); token != "" {
+		sum := sha256.Sum256([]byte(token))
+		return string(sum[:])
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// recordingWriter copies the response it writes so it can be replayed
+type recordingWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(status int) {
+	if !rw.wroteHeader {
+		rw.status = status
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
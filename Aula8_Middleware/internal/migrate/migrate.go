@@ -0,0 +1,99 @@
+package migrate
+
+import (
+	"aula4/internal/repository/storage"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	ErrDestinationNotEmpty = errors.New("the destination already has products")
+	ErrVerificationFailed  = errors.New("the destination does not match the source")
+)
+
+// Summary is the count and the checksum of the products of a storage
+type Summary struct {
+	Count    int
+	Checksum string
+}
+
+// Report compares the source of a copy with its destination once written
+type Report struct {
+	Source      Summary
+	Destination Summary
+}
+
+// Checksum is the sha256 of the products as JSON sorted by id, so it does not depend
+// on the order a storage returns them in
+func Checksum(products []*storage.Product) (string, error) {
+	sorted := append([]*storage.Product(nil), products...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Id < sorted[j].Id })
+
+	hash := sha256.New()
+	encoder := json.NewEncoder(hash)
+	for _, product := range sorted {
+		if err := encoder.Encode(product); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Summarize reads every product of st
+func Summarize(st storage.Storage) (Summary, error) {
+	products, err := st.ReadAllProductsToFile()
+	if err != nil {
+		return Summary{}, err
+	}
+	return summarize(products)
+}
+
+// Copy writes the products of from into to and reads them back to verify the count and
+// the checksum. A destination with products is only replaced when replace is set.
+func Copy(from storage.Storage, to storage.Storage, replace bool) (Report, error) {
+	products, err := from.ReadAllProductsToFile()
+	if err != nil {
+		return Report{}, fmt.Errorf("could not read the source: %w", err)
+	}
+
+	var report Report
+	if report.Source, err = summarize(products); err != nil {
+		return report, err
+	}
+
+	existing, err := to.ReadAllProductsToFile()
+	if err != nil {
+		return report, fmt.Errorf("could not read the destination: %w", err)
+	}
+	if len(existing) > 0 && !replace {
+		return report, fmt.Errorf("%w: %d products", ErrDestinationNotEmpty, len(existing))
+	}
+
+	if err := to.WriteProductsToFile(products); err != nil {
+		return report, fmt.Errorf("could not write the destination: %w", err)
+	}
+
+	if report.Destination, err = Summarize(to); err != nil {
+		return report, fmt.Errorf("could not read the destination back: %w", err)
+	}
+	if report.Destination.Count != report.Source.Count {
+		return report, fmt.Errorf("%w: %d products written, %d read back", ErrVerificationFailed, report.Source.Count, report.Destination.Count)
+	}
+	if report.Destination.Checksum != report.Source.Checksum {
+		return report, fmt.Errorf("%w: checksum %s, expected %s", ErrVerificationFailed, report.Destination.Checksum, report.Source.Checksum)
+	}
+
+	return report, nil
+}
+
+func summarize(products []*storage.Product) (Summary, error) {
+	checksum, err := Checksum(products)
+	if err != nil {
+		return Summary{}, err
+	}
+	return Summary{Count: len(products), Checksum: checksum}, nil
+}
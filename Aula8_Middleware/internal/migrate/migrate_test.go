@@ -0,0 +1,193 @@
+package migrate
+
+import (
+	"aula4/internal/repository/storage"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func newSQLite(t *testing.T, version int) *sql.DB {
+	db, err := sql.Open(storage.SQLiteDriver, filepath.Join(t.TempDir(), "products.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = storage.MigrateSQLite(db, version)
+	require.NoError(t, err)
+	return db
+}
+
+func newJSON(t *testing.T) *storage.StorageProducts {
+	st := storage.NewStorageProducts()
+	st.Path = filepath.Join(t.TempDir(), "products.json")
+	return &st
+}
+
+func sampleProducts() []*storage.Product {
+	publishAt := time.Date(2031, 1, 1, 9, 0, 0, 0, time.FixedZone("", -3*60*60))
+	published := true
+	unpublished := false
+	return []*storage.Product{
+		{
+			Id: "684963bb-7172-48ad-aecd-cdca3f0df001", Name: "Shirt", Quantity: 0, Code_value: "SHIRT",
+			Is_published: &published, Expiration: "01/01/2030", Price: 10.1, Tags: []string{"clothes"},
+			Version: 3, Updated_at: time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC),
+			Prices: []storage.PriceChange{
+				{Id: "684963bb-7172-48ad-aecd-cdca3f0df101", Price: 9, Created_at: time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC)},
+				{Id: "684963bb-7172-48ad-aecd-cdca3f0df102", Price: 10.1, Effective_at: time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC), Created_at: time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC)},
+			},
+		},
+		{
+			Id: "684963bb-7172-48ad-aecd-cdca3f0df002", Name: "Shirt", Quantity: 4, Code_value: "SHIRT-M", Code_format: "internal",
+			Is_published: &unpublished, Expiration: "01/01/2030", Price: 12, Parent_id: "684963bb-7172-48ad-aecd-cdca3f0df001",
+			Attributes: map[string]string{"size": "M"}, Tags: []string{}, Low_stock_threshold: 2, Category_id: "cat",
+		},
+		{
+			Id: "684963bb-7172-48ad-aecd-cdca3f0df003", Name: "Legacy", Quantity: 1, Code_value: "OLD",
+			Expiration: "01/01/2030", Price: 0.1 + 0.2, Publish_at: &publishAt,
+		},
+	}
+}
+
+func TestCopyRoundTrip(t *testing.T) {
+	jsonSource := newJSON(t)
+	require.NoError(t, jsonSource.WriteProductsToFile(sampleProducts()))
+
+	sqlite := storage.NewStorageProductsSQLite(newSQLite(t, storage.LatestSQLiteSchemaVersion()))
+	report, err := Copy(jsonSource, &sqlite, false)
+	require.NoError(t, err)
+	require.Equal(t, 3, report.Destination.Count)
+	require.Equal(t, report.Source, report.Destination)
+
+	jsonDestination := newJSON(t)
+	back, err := Copy(&sqlite, jsonDestination, false)
+	require.NoError(t, err)
+	require.Equal(t, report.Source.Checksum, back.Destination.Checksum, "JSON to SQLite and back keeps every field")
+
+	products, err := jsonDestination.ReadAllProductsToFile()
+	require.NoError(t, err)
+	require.Equal(t, sampleProducts(), products)
+
+	_, err = Copy(jsonSource, &sqlite, false)
+	require.ErrorIs(t, err, ErrDestinationNotEmpty)
+
+	_, err = Copy(jsonSource, &sqlite, true)
+	require.NoError(t, err)
+}
+
+// lossyStorage drops the last product it writes
+type lossyStorage struct {
+	storage.StorageProductsSQLite
+}
+
+func (s *lossyStorage) WriteProductsToFile(productList []*storage.Product) error {
+	return s.StorageProductsSQLite.WriteProductsToFile(productList[:len(productList)-1])
+}
+
+func TestCopyVerifies(t *testing.T) {
+	source := newJSON(t)
+	require.NoError(t, source.WriteProductsToFile(sampleProducts()))
+
+	destination := &lossyStorage{storage.NewStorageProductsSQLite(newSQLite(t, storage.LatestSQLiteSchemaVersion()))}
+	_, err := Copy(source, destination, false)
+	require.ErrorIs(t, err, ErrVerificationFailed)
+}
+
+func TestChecksumIgnoresOrder(t *testing.T) {
+	products := sampleProducts()
+	reversed := []*storage.Product{products[2], products[1], products[0]}
+
+	first, err := Checksum(products)
+	require.NoError(t, err)
+	second, err := Checksum(reversed)
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+
+	products[0].Quantity++
+	changed, err := Checksum(products)
+	require.NoError(t, err)
+	require.NotEqual(t, first, changed)
+}
+
+func TestSQLiteMigrations(t *testing.T) {
+	db := newSQLite(t, 0)
+	latest := storage.LatestSQLiteSchemaVersion()
+
+	for version := 1; version <= latest; version++ {
+		applied, err := storage.MigrateSQLite(db, version)
+		require.NoError(t, err)
+		require.Len(t, applied, 1)
+
+		current, err := storage.SQLiteSchemaVersion(db)
+		require.NoError(t, err)
+		require.Equal(t, version, current)
+	}
+	require.NoError(t, storage.CheckSQLiteSchema(db))
+
+	st := storage.NewStorageProductsSQLite(db)
+	require.NoError(t, st.WriteProductsToFile(sampleProducts()))
+
+	applied, err := storage.MigrateSQLite(db, 1)
+	require.NoError(t, err)
+	require.Len(t, applied, latest-1)
+	require.ErrorIs(t, storage.CheckSQLiteSchema(db), storage.ErrSQLiteSchemaOutdated)
+
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT count(*) FROM products`).Scan(&count))
+	require.Equal(t, 3, count, "going down keeps the rows of the remaining columns")
+
+	_, err = storage.MigrateSQLite(db, latest)
+	require.NoError(t, err)
+
+	_, err = storage.MigrateSQLite(db, 0)
+	require.NoError(t, err)
+	_, err = db.Exec(`SELECT 1 FROM products`)
+	require.Error(t, err, "version 0 has no table")
+
+	_, err = storage.MigrateSQLite(db, latest+1)
+	require.Error(t, err)
+}
+
+func TestSQLiteRefusesNewerSchema(t *testing.T) {
+	db := newSQLite(t, storage.LatestSQLiteSchemaVersion())
+	_, err := db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, storage.LatestSQLiteSchemaVersion()+1))
+	require.NoError(t, err)
+
+	_, err = storage.MigrateSQLite(db, storage.LatestSQLiteSchemaVersion())
+	require.ErrorIs(t, err, storage.ErrSQLiteSchemaTooNew)
+	require.ErrorIs(t, storage.CheckSQLiteSchema(db), storage.ErrSQLiteSchemaTooNew)
+}
+
+func TestSQLiteStorage(t *testing.T) {
+	st := storage.NewStorageProductsSQLite(newSQLite(t, storage.LatestSQLiteSchemaVersion()))
+	product := sampleProducts()[2]
+
+	require.NoError(t, st.SaveProduct(product))
+	require.Equal(t, 1, product.Version)
+	require.EqualError(t, st.SaveProduct(product), "product already exists")
+
+	found, err := st.ReadProductById(product.Id)
+	require.NoError(t, err)
+	require.Equal(t, product.Code_value, found.Code_value)
+
+	found.Quantity = 7
+	require.NoError(t, st.UpdateProduct(found))
+	require.Equal(t, 2, found.Version)
+
+	unchanged := *found
+	require.NoError(t, st.UpdateProduct(&unchanged))
+	require.Equal(t, 2, unchanged.Version, "a write that changes nothing keeps the version")
+
+	require.NoError(t, st.DeleteProduct(product.Id))
+	require.EqualError(t, st.DeleteProduct(product.Id), "product not found")
+	require.EqualError(t, st.UpdateProduct(product), "product not found")
+
+	missing, err := st.ReadProductById(product.Id)
+	require.NoError(t, err)
+	require.Nil(t, missing)
+}
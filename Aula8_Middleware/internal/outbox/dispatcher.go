@@ -0,0 +1,193 @@
+package outbox
+
+import (
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Dispatcher reads the outbox in the background and sends every entry to the sinks.
+// Delivery is at least once: an entry stays in the outbox until every sink accepted it,
+// and a sink can receive an event again if the process stops between the send and the
+// write of the progress.
+type Dispatcher struct {
+	Storage storage.OutboxStorage
+	Sinks   []Sink
+	// Interval between two reads of the outbox, 1s when zero
+	Interval time.Duration
+
+	mu      sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+	running bool
+}
+
+func NewDispatcher(st storage.OutboxStorage, interval time.Duration, sinks ...Sink) *Dispatcher {
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	return &Dispatcher{Storage: st, Sinks: sinks, Interval: interval}
+}
+
+// Start reads the outbox every Interval until Stop
+func (d *Dispatcher) Start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.running {
+		return
+	}
+	d.running = true
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+
+	go func() {
+		defer close(d.done)
+
+		ticker := time.NewTicker(d.Interval)
+		defer ticker.Stop()
+
+		for {
+			if err := d.Dispatch(); err != nil {
+				log.Printf("outbox: %v", err)
+			}
+
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop waits for the running dispatch and makes a last one so the entries written
+// before the call are not left behind
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	if !d.running {
+		d.mu.Unlock()
+		return
+	}
+	d.running = false
+	close(d.stop)
+	d.mu.Unlock()
+
+	<-d.done
+	if err := d.Dispatch(); err != nil {
+		log.Printf("outbox: %v", err)
+	}
+}
+
+// Dispatch sends the pending entries in order. A sink that fails gets no later entry in
+// this dispatch, so it never receives the events of a product out of order, and the next
+// dispatch resumes it from that entry. The other sinks keep receiving the entries.
+func (d *Dispatcher) Dispatch() error {
+	entries, err := d.Storage.ReadOutbox()
+	if err != nil {
+		return err
+	}
+
+	failed := make(map[string]bool)
+	var errs []error
+	for _, entry := range entries {
+		events := ToEvents(*entry)
+
+		sent := false
+		for _, sink := range d.Sinks {
+			if failed[sink.Name()] || slices.Contains(entry.Dispatched, sink.Name()) {
+				continue
+			}
+
+			if err := sendAll(sink, events); err != nil {
+				failed[sink.Name()] = true
+				errs = append(errs, fmt.Errorf("sink %s failed on entry %s: %w", sink.Name(), entry.Id, err))
+				continue
+			}
+			entry.Dispatched = append(entry.Dispatched, sink.Name())
+			sent = true
+		}
+
+		if !d.dispatchedToAll(entry) {
+			if sent {
+				if err := d.Storage.UpdateOutboxEntry(entry); err != nil {
+					return errors.Join(append(errs, err)...)
+				}
+			}
+			continue
+		}
+
+		if err := d.Storage.DeleteOutboxEntry(entry.Id); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func sendAll(sink Sink, events []service.Event) error {
+	for _, event := range events {
+		if err := sink.Send(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) dispatchedToAll(entry *storage.OutboxEntry) bool {
+	for _, sink := range d.Sinks {
+		if !slices.Contains(entry.Dispatched, sink.Name()) {
+			return false
+		}
+	}
+	return true
+}
+
+// ToEvents returns the events of an outbox entry. The event ids derive from the entry id
+// so they are the same every time the entry is sent.
+func ToEvents(entry storage.OutboxEntry) []service.Event {
+	event := service.Event{Id: entry.Id, Type: entry.Type, Occurred_at: entry.Created_at}
+
+	switch entry.Type {
+	case storage.OutboxProductDeleted:
+		if entry.Before != nil {
+			event.Product = *entry.Before
+		}
+		return []service.Event{event}
+	case storage.OutboxProductUpdated:
+		if entry.Product == nil {
+			return nil
+		}
+		event.Product = *entry.Product
+		if entry.Before != nil {
+			event.Changed = service.ChangedFields(*entry.Before, *entry.Product)
+		}
+	default:
+		if entry.Product == nil {
+			return nil
+		}
+		event.Product = *entry.Product
+	}
+
+	var events []service.Event
+	if entry.Type != storage.OutboxProductUpdated || entry.Before == nil || len(event.Changed) != 0 {
+		events = append(events, event)
+	}
+
+	if service.IsLowStock(entry.Product) && (entry.Before == nil || !service.IsLowStock(entry.Before)) {
+		events = append(events, service.Event{
+			Id:          entry.Id + ":" + service.EventStockLow,
+			Type:        service.EventStockLow,
+			Product:     *entry.Product,
+			Occurred_at: entry.Created_at,
+		})
+	}
+
+	return events
+}
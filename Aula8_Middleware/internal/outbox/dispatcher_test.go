@@ -0,0 +1,222 @@
+package outbox
+
+import (
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// flakySink fails the first failures sends and records every event it accepted
+type flakySink struct {
+	mu       sync.Mutex
+	failures int
+	events   []service.Event
+}
+
+func (s *flakySink) Name() string {
+	return "flaky"
+}
+
+func (s *flakySink) Send(event service.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("unavailable")
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func newTestStorage(t *testing.T) *storage.StorageProducts {
+	st := storage.NewStorageProducts()
+	st.Path = filepath.Join(t.TempDir(), "products.json")
+	st.Outbox = true
+	return &st
+}
+
+func newProduct() storage.Product {
+	isPublished := true
+	return storage.Product{
+		Name:                "Coffee",
+		Quantity:            10,
+		Code_value:          "COF",
+		Is_published:        &isPublished,
+		Expiration:          "01/01/2030",
+		Price:               5.0,
+		Low_stock_threshold: 3,
+	}
+}
+
+func TestOutboxIsWrittenWithTheProduct(t *testing.T) {
+	st := newTestStorage(t)
+	rp := repository.NewRepositoryProducts(st)
+	productService := service.NewServiceProducts(&rp)
+
+	product, err := productService.Create(newProduct())
+	require.NoError(t, err)
+
+	product.Price = 6
+	_, err = productService.Update(product)
+	require.NoError(t, err)
+
+	err = productService.Delete(product.Id)
+	require.NoError(t, err)
+
+	entries, err := st.ReadOutbox()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, storage.OutboxProductCreated, entries[0].Type)
+	require.Equal(t, storage.OutboxProductUpdated, entries[1].Type)
+	require.Equal(t, 5.0, entries[1].Before.Price)
+	require.Equal(t, 6.0, entries[1].Product.Price)
+	require.Equal(t, storage.OutboxProductDeleted, entries[2].Type)
+	require.Nil(t, entries[2].Product)
+
+	products, err := st.ReadAllProductsToFile()
+	require.NoError(t, err)
+	require.Empty(t, products)
+}
+
+func TestStorageReadsTheLegacyFormat(t *testing.T) {
+	st := newTestStorage(t)
+	err := os.WriteFile(st.Path, []byte(`[{"Id":"1","Name":"Tea"}]`), 0644)
+	require.NoError(t, err)
+
+	products, err := st.ReadAllProductsToFile()
+	require.NoError(t, err)
+	require.Len(t, products, 1)
+
+	entries, err := st.ReadOutbox()
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	st.Outbox = false
+	err = st.DeleteProduct("1")
+	require.NoError(t, err)
+
+	raw, err := os.ReadFile(st.Path)
+	require.NoError(t, err)
+	require.JSONEq(t, `[]`, string(raw), "without the outbox the file keeps the list format")
+}
+
+func TestDispatchAtLeastOnce(t *testing.T) {
+	st := newTestStorage(t)
+	product := newProduct()
+	product.Id = "1"
+	require.NoError(t, st.SaveProduct(&product))
+
+	reliable := NewChannelSink("channel", 10, 0)
+	flaky := &flakySink{failures: 1}
+	dispatcher := NewDispatcher(st, 0, reliable, flaky)
+
+	err := dispatcher.Dispatch()
+	require.Error(t, err)
+
+	entries, err := st.ReadOutbox()
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "the entry stays until every sink accepted it")
+	require.Equal(t, []string{"channel"}, entries[0].Dispatched)
+
+	err = dispatcher.Dispatch()
+	require.NoError(t, err)
+
+	entries, err = st.ReadOutbox()
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	require.Len(t, reliable.Events, 1, "a sink that accepted the entry does not receive it again")
+	require.Len(t, flaky.events, 1)
+	received := <-reliable.Events
+	require.Equal(t, received.Id, flaky.events[0].Id, "the event id is the idempotency key")
+}
+
+func TestDispatchPastAFailingSink(t *testing.T) {
+	st := newTestStorage(t)
+	for _, id := range []string{"1", "2"} {
+		product := newProduct()
+		product.Id = id
+		product.Code_value = "COF" + id
+		require.NoError(t, st.SaveProduct(&product))
+	}
+
+	flaky := &flakySink{failures: 1}
+	reliable := NewChannelSink("channel", 10, 0)
+	dispatcher := NewDispatcher(st, 0, flaky, reliable)
+
+	err := dispatcher.Dispatch()
+	require.ErrorContains(t, err, "sink flaky failed")
+	require.Len(t, reliable.Events, 2, "the other sinks receive every entry")
+	require.Empty(t, flaky.events, "the failed sink gets no later entry, its events stay in order")
+
+	entries, err := st.ReadOutbox()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		require.Equal(t, []string{"channel"}, entry.Dispatched)
+	}
+
+	require.NoError(t, dispatcher.Dispatch())
+	entries, err = st.ReadOutbox()
+	require.NoError(t, err)
+	require.Empty(t, entries)
+	require.Len(t, flaky.events, 2)
+	require.Equal(t, "1", flaky.events[0].Product.Id)
+	require.Equal(t, "2", flaky.events[1].Product.Id)
+	require.Len(t, reliable.Events, 2, "a sink that accepted an entry does not receive it again")
+}
+
+func TestToEvents(t *testing.T) {
+	before := newProduct()
+	after := newProduct()
+	after.Quantity = 2
+	created := time.Now().UTC()
+
+	events := ToEvents(storage.OutboxEntry{Id: "e1", Type: storage.OutboxProductUpdated, Before: &before, Product: &after, Created_at: created})
+	require.Len(t, events, 2)
+	require.Equal(t, "e1", events[0].Id)
+	require.Equal(t, []string{"quantity"}, events[0].Changed)
+	require.Equal(t, service.EventStockLow, events[1].Type)
+	require.Equal(t, "e1:stock.low", events[1].Id)
+
+	events = ToEvents(storage.OutboxEntry{Id: "e2", Type: storage.OutboxProductUpdated, Before: &after, Product: &after})
+	require.Empty(t, events, "a write that changed nothing has no event")
+
+	events = ToEvents(storage.OutboxEntry{Id: "e3", Type: storage.OutboxProductDeleted, Before: &before})
+	require.Len(t, events, 1)
+	require.Equal(t, "COF", events[0].Product.Code_value)
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink := NewFileSink(path)
+
+	for _, eventType := range []string{service.EventProductCreated, service.EventProductDeleted} {
+		require.NoError(t, sink.Send(service.NewEvent(eventType, newProduct())))
+	}
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var types []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var data utils.EventData
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &data))
+		types = append(types, data.Type)
+	}
+	require.Equal(t, []string{service.EventProductCreated, service.EventProductDeleted}, types)
+}
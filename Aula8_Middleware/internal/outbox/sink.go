@@ -0,0 +1,117 @@
+package outbox
+
+import (
+	"aula4/internal/service"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Sink receives the events read from the outbox. Send may be called again with an event
+// it already received, receivers use the event id as idempotency key.
+type Sink interface {
+	// Name identifies the sink in the dispatch progress of the outbox entries, it must not change
+	Name() string
+	Send(event service.Event) error
+}
+
+// LogSink writes a line per event to the standard logger
+type LogSink struct{}
+
+func (LogSink) Name() string {
+	return "log"
+}
+
+func (LogSink) Send(event service.Event) error {
+	log.Printf("outbox: %s %s product %s", event.Id, event.Type, event.Product.Id)
+	return nil
+}
+
+// FileSink appends the events to a file as json lines
+type FileSink struct {
+	mu   sync.Mutex
+	Path string
+}
+
+func NewFileSink(path string) *FileSink {
+	return &FileSink{Path: path}
+}
+
+func (s *FileSink) Name() string {
+	return "file"
+}
+
+func (s *FileSink) Send(event service.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	err = json.NewEncoder(file).Encode(service.ToEventData(event))
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// PublisherSink hands the events to a service.Publisher, such as the webhook dispatcher
+// or the stream broker, which deliver them on their own
+type PublisherSink struct {
+	SinkName  string
+	Publisher service.Publisher
+}
+
+func NewPublisherSink(name string, publisher service.Publisher) PublisherSink {
+	return PublisherSink{SinkName: name, Publisher: publisher}
+}
+
+func (s PublisherSink) Name() string {
+	return s.SinkName
+}
+
+func (s PublisherSink) Send(event service.Event) error {
+	s.Publisher.Publish(event)
+	return nil
+}
+
+// ChannelSink sends the events to an in-process channel, it fails when the channel is
+// not read within Timeout so the event is sent again on the next dispatch
+type ChannelSink struct {
+	SinkName string
+	Events   chan service.Event
+	Timeout  time.Duration
+}
+
+// NewChannelSink buffers size events, the timeout is 1s when zero
+func NewChannelSink(name string, size int, timeout time.Duration) ChannelSink {
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+
+	return ChannelSink{SinkName: name, Events: make(chan service.Event, size), Timeout: timeout}
+}
+
+func (s ChannelSink) Name() string {
+	return s.SinkName
+}
+
+func (s ChannelSink) Send(event service.Event) error {
+	timer := time.NewTimer(s.Timeout)
+	defer timer.Stop()
+
+	select {
+	case s.Events <- event:
+		return nil
+	case <-timer.C:
+		return errors.New("channel sink is full")
+	}
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"aula4/internal/repository/storage"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+type RepositoryCategories struct {
+	Storage storage.CategoryStorage
+}
+
+func NewRepositoryCategories(storage storage.CategoryStorage) RepositoryCategories {
+	return RepositoryCategories{
+		Storage: storage,
+	}
+}
+
+func (r *RepositoryCategories) GetById(id string) (*storage.Category, error) {
+	category, err := r.Storage.ReadCategoryById(id)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, errors.New("category not found")
+	}
+	return category, nil
+}
+
+func (r *RepositoryCategories) GetAll() ([]*storage.Category, error) {
+	return r.Storage.ReadAllCategoriesToFile()
+}
+
+func (r *RepositoryCategories) Create(category storage.Category) (storage.Category, error) {
+	id := uuid.New()
+	category.Id = id.String()
+
+	if err := r.Storage.SaveCategory(&category); err != nil {
+		return storage.Category{}, err
+	}
+
+	return category, nil
+}
+
+func (r *RepositoryCategories) Update(category storage.Category) (storage.Category, error) {
+	if err := r.Storage.UpdateCategory(&category); err != nil {
+		return storage.Category{}, err
+	}
+
+	return category, nil
+}
+
+func (r *RepositoryCategories) Delete(id string) error {
+	return r.Storage.DeleteCategory(id)
+}
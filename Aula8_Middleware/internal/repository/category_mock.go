@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"aula4/internal/repository/storage"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+type MockCategoryRepository struct {
+	Categories map[string]*storage.Category
+}
+
+func NewRepositoryCategoriesMock() MockCategoryRepository {
+	return MockCategoryRepository{
+		Categories: make(map[string]*storage.Category),
+	}
+}
+
+func (m *MockCategoryRepository) GetById(id string) (*storage.Category, error) {
+	if category, exists := m.Categories[id]; exists {
+		return category, nil
+	}
+	return nil, errors.New("category not found")
+}
+
+func (m *MockCategoryRepository) GetAll() ([]*storage.Category, error) {
+	var categories []*storage.Category
+	for _, category := range m.Categories {
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+func (m *MockCategoryRepository) Create(category storage.Category) (storage.Category, error) {
+	if category.Id == "" {
+		category.Id = uuid.New().String()
+	}
+	m.Categories[category.Id] = &category
+	return category, nil
+}
+
+func (m *MockCategoryRepository) Update(category storage.Category) (storage.Category, error) {
+	if _, exists := m.Categories[category.Id]; exists {
+		m.Categories[category.Id] = &category
+		return category, nil
+	}
+
+	return storage.Category{}, errors.New("category not found")
+}
+
+func (m *MockCategoryRepository) Delete(id string) error {
+	if _, exists := m.Categories[id]; exists {
+		delete(m.Categories, id)
+		return nil
+	}
+	return errors.New("category not found")
+}
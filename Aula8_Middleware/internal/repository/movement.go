@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"aula4/internal/repository/storage"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RepositoryMovements struct {
+	Storage storage.MovementStorage
+}
+
+func NewRepositoryMovements(storage storage.MovementStorage) RepositoryMovements {
+	return RepositoryMovements{
+		Storage: storage,
+	}
+}
+
+func (r *RepositoryMovements) GetAll() ([]*storage.Movement, error) {
+	return r.Storage.ReadAllMovementsToFile()
+}
+
+// GetByProduct returns the movements of a product in the order they were recorded
+func (r *RepositoryMovements) GetByProduct(productId string) ([]*storage.Movement, error) {
+	movements, err := r.Storage.ReadAllMovementsToFile()
+	if err != nil {
+		return nil, err
+	}
+
+	productMovements := []*storage.Movement{}
+	for _, movement := range movements {
+		if movement.Product_id == productId {
+			productMovements = append(productMovements, movement)
+		}
+	}
+
+	return productMovements, nil
+}
+
+func (r *RepositoryMovements) Create(movement storage.Movement) (storage.Movement, error) {
+	id := uuid.New()
+	movement.Id = id.String()
+	if movement.Created_at.IsZero() {
+		movement.Created_at = time.Now().UTC()
+	}
+
+	if err := r.Storage.SaveMovement(&movement); err != nil {
+		return storage.Movement{}, err
+	}
+
+	return movement, nil
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"aula4/internal/repository/storage"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type MockMovementRepository struct {
+	Movements []*storage.Movement
+}
+
+func NewRepositoryMovementsMock() MockMovementRepository {
+	return MockMovementRepository{}
+}
+
+func (m *MockMovementRepository) GetAll() ([]*storage.Movement, error) {
+	return m.Movements, nil
+}
+
+func (m *MockMovementRepository) GetByProduct(productId string) ([]*storage.Movement, error) {
+	movements := []*storage.Movement{}
+	for _, movement := range m.Movements {
+		if movement.Product_id == productId {
+			movements = append(movements, movement)
+		}
+	}
+	return movements, nil
+}
+
+func (m *MockMovementRepository) Create(movement storage.Movement) (storage.Movement, error) {
+	movement.Id = uuid.New().String()
+	if movement.Created_at.IsZero() {
+		movement.Created_at = time.Now().UTC()
+	}
+	m.Movements = append(m.Movements, &movement)
+	return movement, nil
+}
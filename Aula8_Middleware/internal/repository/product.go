@@ -4,6 +4,7 @@ import (
 	"aula4/internal/repository/storage"
 	"aula4/internal/utils"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -42,11 +43,19 @@ func (r *RepositoryProducts) GetAll() ([]*storage.Product, error) {
 	return products, nil
 }
 
+// Create and Update check the code_value is unique in the same atomic write as the product,
+// two concurrent requests cannot both pass the check
 func (r *RepositoryProducts) Create(product storage.Product) (storage.Product, error) {
 	id := uuid.New()
 	product.Id = id.String()
 
-	if err := r.Storage.SaveProduct(&product); err != nil {
+	err := r.Storage.Atomic(func(tx storage.Storage) error {
+		if err := checkUniqueCode(tx, product); err != nil {
+			return err
+		}
+		return tx.SaveProduct(&product)
+	})
+	if err != nil {
 		return storage.Product{}, err
 	}
 
@@ -54,58 +63,149 @@ func (r *RepositoryProducts) Create(product storage.Product) (storage.Product, e
 }
 
 func (r *RepositoryProducts) Update(product storage.Product) (storage.Product, error) {
-	if err := r.Storage.UpdateProduct(&product); err != nil {
+	err := r.Storage.Atomic(func(tx storage.Storage) error {
+		if err := checkUniqueCode(tx, product); err != nil {
+			return err
+		}
+		return tx.UpdateProduct(&product)
+	})
+	if err != nil {
 		return storage.Product{}, err
 	}
 
 	return product, nil
 }
 
-func (r *RepositoryProducts) Patch(id string, updates map[string]interface{}) (*storage.Product, error) {
-	product, err := r.GetById(id)
+// Modify runs change on the stored product and writes it, no other write of the storage comes
+// between the read and the write. The product stays as it was when change returns an error.
+// The storage is locked while change runs, change must not call the repository.
+func (r *RepositoryProducts) Modify(id string, change func(product *storage.Product) error) (*storage.Product, error) {
+	var modified *storage.Product
+	err := r.Storage.Atomic(func(tx storage.Storage) error {
+		product, err := tx.ReadProductById(id)
+		if err != nil {
+			return err
+		}
+		if product == nil {
+			return errors.New("product not found")
+		}
+
+		before := *product
+		if err := change(product); err != nil {
+			return err
+		}
+		product.Id = id
+
+		// the codes already stored are unique, only a changed one is checked
+		if product.Code_value != before.Code_value || product.Code_format != before.Code_format {
+			if err := checkUniqueCode(tx, *product); err != nil {
+				return err
+			}
+		}
+
+		modified = product
+		return tx.UpdateProduct(product)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	return modified, nil
+}
+
+func (r *RepositoryProducts) Patch(id string, updates map[string]interface{}) (*storage.Product, error) {
+	return r.Modify(id, func(product *storage.Product) error {
+		return ApplyPatch(product, updates)
+	})
+}
+
+func checkUniqueCode(tx storage.Storage, product storage.Product) error {
+	products, err := tx.ReadAllProductsToFile()
+	if err != nil {
+		return err
+	}
+
+	return utils.CheckUniqueCodeValue(products, product)
+}
+
+// ApplyPatch sets the fields of the updates on the product, the first invalid one is returned.
+// The services use it in Modify to patch a product they check in the same write.
+func ApplyPatch(product *storage.Product, updates map[string]interface{}) error {
 	if name, ok := updates["name"].(string); ok {
 		product.Name = name
 	}
-	if quantity, err := ToInt(updates["quantity"]); err == nil {
+	if value, ok := updates["quantity"]; ok {
+		quantity, err := ToInt(value)
+		if err != nil {
+			return err
+		}
+		if quantity < 0 {
+			return errors.New("quantity must not be negative")
+		}
 		product.Quantity = quantity
 	}
-	if codeValue, ok := updates["code_value"].(string); ok {
-		products, err := r.GetAll()
-		if err != nil {
-			if err.Error() != "no Products" {
-				return nil, err
-			}
+	// the code and its format change together, a new format can make two codes the same
+	codeValue, codeOk := updates["code_value"].(string)
+	codeFormat, formatOk := updates["code_format"].(string)
+	if codeOk || formatOk {
+		if codeOk {
+			product.Code_value = codeValue
 		}
-
-		if err := utils.CheckUniqueCodeValue(products, *product); err != nil {
-			return nil, err
+		if formatOk {
+			product.Code_format = codeFormat
 		}
-
-		product.Code_value = codeValue
 	}
-	if isPublished, err := ToBool(updates["is_published"]); err == nil {
+	if value, ok := updates["is_published"]; ok {
+		isPublished, err := ToBool(value)
+		if err != nil {
+			return err
+		}
 		product.Is_published = isPublished
 	}
 	if expiration, ok := updates["expiration"].(string); ok {
-		if err := utils.ValidateDate(product.Expiration); err != nil {
-			return nil, err
+		if err := utils.ValidateDate(expiration); err != nil {
+			return err
 		}
 
 		product.Expiration = expiration
 	}
 	if price, ok := updates["price"].(float64); ok {
+		if price <= 0 {
+			return errors.New("price must be greater than zero")
+		}
 		product.Price = price
 	}
-
-	if err := r.Storage.UpdateProduct(product); err != nil {
-		return nil, err
+	// the timeline of the price comes from the service, never from a request body
+	if prices, ok := updates["prices"].([]storage.PriceChange); ok {
+		product.Prices = prices
+	}
+	if categoryId, ok := updates["category_id"].(string); ok {
+		product.Category_id = categoryId
+	}
+	if tags, err := ToStrings(updates["tags"]); err == nil {
+		product.Tags = utils.NormalizeTags(tags)
+	}
+	if attributes, err := ToStringMap(updates["attributes"]); err == nil {
+		product.Attributes = attributes
+	}
+	if updates["low_stock_threshold"] != nil {
+		threshold, err := ToInt(updates["low_stock_threshold"])
+		if err != nil {
+			return err
+		}
+		product.Low_stock_threshold = threshold
+	}
+	for field, target := range map[string]**time.Time{"publish_at": &product.Publish_at, "unpublish_at": &product.Unpublish_at} {
+		if value, ok := updates[field]; ok {
+			t, err := ToTime(value)
+			if err != nil {
+				return err
+			}
+			*target = t
+		}
 	}
 
-	return product, nil
+	return nil
 }
 
 func (r *RepositoryProducts) Delete(id string) error {
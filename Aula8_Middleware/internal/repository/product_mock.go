@@ -3,6 +3,9 @@ package repository
 import (
 	"aula4/internal/repository/storage"
 	"errors"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type MockRepository struct {
@@ -36,12 +39,18 @@ func (m *MockRepository) GetAll() ([]*storage.Product, error) {
 }
 
 func (m *MockRepository) Create(product storage.Product) (storage.Product, error) {
+	if product.Id == "" {
+		product.Id = uuid.New().String()
+	}
+	product.Version = 1
+	product.Updated_at = time.Now().UTC()
 	m.Products[product.Id] = &product
 	return product, nil
 }
 
 func (m *MockRepository) Update(product storage.Product) (storage.Product, error) {
-	if _, exists := m.Products[product.Id]; exists {
+	if before, exists := m.Products[product.Id]; exists {
+		storage.Stamp(&product, before)
 		m.Products[product.Id] = &product
 		return product, nil
 	}
@@ -49,8 +58,26 @@ func (m *MockRepository) Update(product storage.Product) (storage.Product, error
 	return storage.Product{}, errors.New("product not found")
 }
 
+func (m *MockRepository) Modify(id string, change func(product *storage.Product) error) (*storage.Product, error) {
+	before, exists := m.Products[id]
+	if !exists {
+		return nil, errors.New("product not found")
+	}
+
+	product := *before
+	if err := change(&product); err != nil {
+		return nil, err
+	}
+	product.Id = id
+
+	storage.Stamp(&product, before)
+	m.Products[id] = &product
+	return &product, nil
+}
+
 func (m *MockRepository) Patch(id string, updates map[string]interface{}) (*storage.Product, error) {
 	if product, exists := m.Products[id]; exists {
+		before := *product
 		if name, ok := updates["name"].(string); ok {
 			product.Name = name
 		}
@@ -60,6 +87,9 @@ func (m *MockRepository) Patch(id string, updates map[string]interface{}) (*stor
 		if codeValue, ok := updates["code_value"].(string); ok {
 			product.Code_value = codeValue
 		}
+		if codeFormat, ok := updates["code_format"].(string); ok {
+			product.Code_format = codeFormat
+		}
 		if isPublished, ok := updates["is_published"].(bool); ok {
 			product.Is_published = &isPublished
 		}
@@ -69,7 +99,32 @@ func (m *MockRepository) Patch(id string, updates map[string]interface{}) (*stor
 		if price, ok := updates["price"].(float64); ok {
 			product.Price = price
 		}
+		if prices, ok := updates["prices"].([]storage.PriceChange); ok {
+			product.Prices = prices
+		}
+		if categoryId, ok := updates["category_id"].(string); ok {
+			product.Category_id = categoryId
+		}
+		if tags, ok := updates["tags"].([]string); ok {
+			product.Tags = tags
+		}
+		if attributes, ok := updates["attributes"].(map[string]string); ok {
+			product.Attributes = attributes
+		}
+		if threshold, ok := updates["low_stock_threshold"].(int); ok {
+			product.Low_stock_threshold = threshold
+		}
+		for field, target := range map[string]**time.Time{"publish_at": &product.Publish_at, "unpublish_at": &product.Unpublish_at} {
+			if value, ok := updates[field]; ok {
+				t, err := ToTime(value)
+				if err != nil {
+					return nil, err
+				}
+				*target = t
+			}
+		}
 
+		storage.Stamp(product, &before)
 		return product, nil
 	}
 
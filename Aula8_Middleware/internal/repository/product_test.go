@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"aula4/internal/repository/storage"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func newStorages(t *testing.T) map[string]storage.Storage {
+	st := storage.NewStorageProducts()
+	st.Path = filepath.Join(t.TempDir(), "products.json")
+
+	db, err := sql.Open(storage.SQLiteDriver, filepath.Join(t.TempDir(), "products.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	_, err = storage.MigrateSQLite(db, storage.LatestSQLiteSchemaVersion())
+	require.NoError(t, err)
+	sqlite := storage.NewStorageProductsSQLite(db)
+
+	return map[string]storage.Storage{"JSON": &st, "SQLite": &sqlite}
+}
+
+func newProduct(name string, code string) storage.Product {
+	isPublished := true
+	return storage.Product{Name: name, Quantity: 0, Code_value: code, Is_published: &isPublished, Expiration: "01/01/2030", Price: 1}
+}
+
+// concurrently runs fn from n goroutines at once and returns their errors
+func concurrently(n int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	const writers = 20
+
+	for name, st := range newStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			rp := NewRepositoryProducts(st)
+
+			errs := concurrently(writers, func(i int) error {
+				_, err := rp.Create(newProduct(fmt.Sprintf("Product %d", i), "SAME"))
+				return err
+			})
+			created := 0
+			for _, err := range errs {
+				if err == nil {
+					created++
+					continue
+				}
+				require.EqualError(t, err, "the code_value must be unique")
+			}
+			require.Equal(t, 1, created, "a single product gets the code")
+
+			counter, err := rp.Create(newProduct("Counter", "COUNTER"))
+			require.NoError(t, err)
+			errs = concurrently(writers, func(i int) error {
+				_, err := rp.Modify(counter.Id, func(product *storage.Product) error {
+					product.Quantity++
+					return nil
+				})
+				return err
+			})
+			require.NoError(t, errors.Join(errs...))
+
+			counted, err := rp.GetById(counter.Id)
+			require.NoError(t, err)
+			require.Equal(t, writers, counted.Quantity, "no increment is lost")
+			require.Equal(t, 1+writers, counted.Version)
+
+			other, err := rp.Create(newProduct("Other", "OTHER"))
+			require.NoError(t, err)
+			ids := []string{counter.Id, other.Id}
+			errs = concurrently(writers, func(i int) error {
+				_, err := rp.Patch(ids[i%2], map[string]interface{}{"code_value": "TAKEN"})
+				return err
+			})
+			for _, err := range errs {
+				if err != nil {
+					require.EqualError(t, err, "the code_value must be unique")
+				}
+			}
+
+			products, err := rp.GetAll()
+			require.NoError(t, err)
+			codes := make(map[string]bool)
+			for _, product := range products {
+				require.False(t, codes[product.Code_value], "the code %q is repeated", product.Code_value)
+				codes[product.Code_value] = true
+			}
+			require.True(t, codes["TAKEN"], "one of the patches takes the code")
+		})
+	}
+}
+
+func TestUpdateKeepsTheOrder(t *testing.T) {
+	for name, st := range newStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			rp := NewRepositoryProducts(st)
+
+			var ids []string
+			for _, code := range []string{"FIRST", "SECOND", "THIRD"} {
+				product, err := rp.Create(newProduct(code, code))
+				require.NoError(t, err)
+				ids = append(ids, product.Id)
+			}
+
+			first, err := rp.GetById(ids[0])
+			require.NoError(t, err)
+			first.Quantity = 7
+			_, err = rp.Update(*first)
+			require.NoError(t, err)
+
+			products, err := st.ReadAllProductsToFile()
+			require.NoError(t, err)
+			var order []string
+			for _, product := range products {
+				order = append(order, product.Id)
+			}
+			require.Equal(t, ids, order, "an updated product keeps its place")
+			require.Equal(t, 7, products[0].Quantity)
+		})
+	}
+}
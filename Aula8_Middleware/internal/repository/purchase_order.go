@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"aula4/internal/repository/storage"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RepositoryPurchaseOrders struct {
+	Storage storage.PurchaseOrderStorage
+}
+
+func NewRepositoryPurchaseOrders(storage storage.PurchaseOrderStorage) RepositoryPurchaseOrders {
+	return RepositoryPurchaseOrders{
+		Storage: storage,
+	}
+}
+
+func (r *RepositoryPurchaseOrders) GetById(id string) (*storage.PurchaseOrder, error) {
+	order, err := r.Storage.ReadPurchaseOrderById(id)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.New("purchase order not found")
+	}
+	return order, nil
+}
+
+func (r *RepositoryPurchaseOrders) GetAll() ([]*storage.PurchaseOrder, error) {
+	return r.Storage.ReadAllPurchaseOrdersToFile()
+}
+
+func (r *RepositoryPurchaseOrders) Create(order storage.PurchaseOrder) (storage.PurchaseOrder, error) {
+	id := uuid.New()
+	order.Id = id.String()
+	if order.Created_at.IsZero() {
+		order.Created_at = time.Now().UTC()
+	}
+
+	if err := r.Storage.SavePurchaseOrder(&order); err != nil {
+		return storage.PurchaseOrder{}, err
+	}
+
+	return order, nil
+}
+
+func (r *RepositoryPurchaseOrders) Update(order storage.PurchaseOrder) (storage.PurchaseOrder, error) {
+	if err := r.Storage.UpdatePurchaseOrder(&order); err != nil {
+		return storage.PurchaseOrder{}, err
+	}
+
+	return order, nil
+}
+
+// Modify runs change on the stored order and writes it, no other write of the orders comes
+// between the read and the write. change must not call the repository.
+func (r *RepositoryPurchaseOrders) Modify(id string, change func(order *storage.PurchaseOrder) error) (*storage.PurchaseOrder, error) {
+	return r.Storage.ModifyPurchaseOrder(id, change)
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"aula4/internal/repository/storage"
+	"errors"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type MockPurchaseOrderRepository struct {
+	Orders map[string]*storage.PurchaseOrder
+}
+
+func NewRepositoryPurchaseOrdersMock() MockPurchaseOrderRepository {
+	return MockPurchaseOrderRepository{
+		Orders: make(map[string]*storage.PurchaseOrder),
+	}
+}
+
+func (m *MockPurchaseOrderRepository) GetById(id string) (*storage.PurchaseOrder, error) {
+	if order, exists := m.Orders[id]; exists {
+		return order, nil
+	}
+	return nil, errors.New("purchase order not found")
+}
+
+// GetAll returns the orders by creation date, like the storage which appends them
+func (m *MockPurchaseOrderRepository) GetAll() ([]*storage.PurchaseOrder, error) {
+	var orders []*storage.PurchaseOrder
+	for _, order := range m.Orders {
+		orders = append(orders, order)
+	}
+	sort.SliceStable(orders, func(i, j int) bool {
+		if orders[i].Created_at.Equal(orders[j].Created_at) {
+			return orders[i].Id < orders[j].Id
+		}
+		return orders[i].Created_at.Before(orders[j].Created_at)
+	})
+	return orders, nil
+}
+
+func (m *MockPurchaseOrderRepository) Create(order storage.PurchaseOrder) (storage.PurchaseOrder, error) {
+	if order.Id == "" {
+		order.Id = uuid.New().String()
+	}
+	if order.Created_at.IsZero() {
+		order.Created_at = time.Now().UTC()
+	}
+	m.Orders[order.Id] = &order
+	return order, nil
+}
+
+func (m *MockPurchaseOrderRepository) Update(order storage.PurchaseOrder) (storage.PurchaseOrder, error) {
+	if _, exists := m.Orders[order.Id]; exists {
+		m.Orders[order.Id] = &order
+		return order, nil
+	}
+
+	return storage.PurchaseOrder{}, errors.New("purchase order not found")
+}
+
+func (m *MockPurchaseOrderRepository) Modify(id string, change func(order *storage.PurchaseOrder) error) (*storage.PurchaseOrder, error) {
+	before, exists := m.Orders[id]
+	if !exists {
+		return nil, errors.New("purchase order not found")
+	}
+
+	order := *before
+	order.Lines = slices.Clone(before.Lines)
+	if err := change(&order); err != nil {
+		return nil, err
+	}
+	order.Id = id
+
+	m.Orders[id] = &order
+	return &order, nil
+}
@@ -8,5 +8,45 @@ type Repository interface {
 	Create(product storage.Product) (storage.Product, error)
 	Update(product storage.Product) (storage.Product, error)
 	Patch(id string, updates map[string]interface{}) (*storage.Product, error)
+	// Modify is the read-modify-write of a product, see RepositoryProducts.Modify
+	Modify(id string, change func(product *storage.Product) error) (*storage.Product, error)
 	Delete(id string) error
 }
+
+type CategoryRepository interface {
+	GetById(id string) (*storage.Category, error)
+	GetAll() ([]*storage.Category, error)
+	Create(category storage.Category) (storage.Category, error)
+	Update(category storage.Category) (storage.Category, error)
+	Delete(id string) error
+}
+
+type MovementRepository interface {
+	GetAll() ([]*storage.Movement, error)
+	GetByProduct(productId string) ([]*storage.Movement, error)
+	Create(movement storage.Movement) (storage.Movement, error)
+}
+
+type SubscriberRepository interface {
+	GetById(id string) (*storage.Subscriber, error)
+	GetAll() ([]*storage.Subscriber, error)
+	Create(subscriber storage.Subscriber) (storage.Subscriber, error)
+	Delete(id string) error
+}
+
+type SupplierRepository interface {
+	GetById(id string) (*storage.Supplier, error)
+	GetAll() ([]*storage.Supplier, error)
+	Create(supplier storage.Supplier) (storage.Supplier, error)
+	Update(supplier storage.Supplier) (storage.Supplier, error)
+	Delete(id string) error
+}
+
+type PurchaseOrderRepository interface {
+	GetById(id string) (*storage.PurchaseOrder, error)
+	GetAll() ([]*storage.PurchaseOrder, error)
+	Create(order storage.PurchaseOrder) (storage.PurchaseOrder, error)
+	Update(order storage.PurchaseOrder) (storage.PurchaseOrder, error)
+	// Modify is the read-modify-write of an order, see RepositoryPurchaseOrders.Modify
+	Modify(id string, change func(order *storage.PurchaseOrder) error) (*storage.PurchaseOrder, error)
+}
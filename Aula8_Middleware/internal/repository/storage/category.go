@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	localFileCategoriesJson = "../../docs/db/json/categories.json"
+)
+
+type Category struct {
+	Id        string
+	Name      string
+	Parent_id string
+}
+
+type StorageCategories struct {
+	mu sync.Mutex
+	// Path of the json file, localFileCategoriesJson when empty
+	Path string
+	// Tenant keeps the data of a tenant in its own file next to Path, see TenantPath
+	Tenant string
+}
+
+func NewStorageCategories() StorageCategories {
+	return StorageCategories{}
+}
+
+func (s *StorageCategories) ReadAllCategoriesToFile() ([]*Category, error) {
+	var categoryList []*Category
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			file, err = os.Create(s.path())
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+
+			initialData := []Category{}
+			writer := json.NewEncoder(file)
+			if err := writer.Encode(initialData); err != nil {
+				return nil, err
+			}
+
+			return categoryList, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := json.NewDecoder(file)
+	err = reader.Decode(&categoryList)
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return categoryList, nil
+}
+
+func (s *StorageCategories) ReadCategoryById(id string) (*Category, error) {
+	categories, err := s.ReadAllCategoriesToFile()
+	if err != nil {
+		return nil, err
+	}
+	for _, category := range categories {
+		if category.Id == id {
+			return category, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *StorageCategories) SaveCategory(category *Category) error {
+	categories, err := s.ReadAllCategoriesToFile()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range categories {
+		if c.Id == category.Id {
+			return errors.New("category already exists")
+		}
+	}
+
+	categories = append(categories, category)
+	return s.WriteCategoriesToFile(categories)
+}
+
+func (s *StorageCategories) UpdateCategory(updatedCategory *Category) error {
+	categories, err := s.ReadAllCategoriesToFile()
+	if err != nil {
+		return err
+	}
+
+	for i, category := range categories {
+		if category.Id == updatedCategory.Id {
+			categories[i] = updatedCategory
+			return s.WriteCategoriesToFile(categories)
+		}
+	}
+
+	return errors.New("category not found")
+}
+
+func (s *StorageCategories) DeleteCategory(id string) error {
+	categories, err := s.ReadAllCategoriesToFile()
+	if err != nil {
+		return err
+	}
+
+	for i, category := range categories {
+		if category.Id == id {
+			categories = append(categories[:i], categories[i+1:]...)
+			return s.WriteCategoriesToFile(categories)
+		}
+	}
+
+	return errors.New("category not found")
+}
+
+func (s *StorageCategories) WriteCategoriesToFile(categoryList []*Category) error {
+	file, err := os.OpenFile(s.path(), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := json.NewEncoder(file)
+	return writer.Encode(categoryList)
+}
+
+func (s *StorageCategories) path() string {
+	if s.Path == "" {
+		return TenantPath(localFileCategoriesJson, s.Tenant)
+	}
+	return TenantPath(s.Path, s.Tenant)
+}
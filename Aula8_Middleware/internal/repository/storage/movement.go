@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	localFileMovementsJson = "../../docs/db/json/movements.json"
+)
+
+const (
+	MovementReceipt    = "receipt"
+	MovementSale       = "sale"
+	MovementAdjustment = "adjustment"
+	MovementReturn     = "return"
+)
+
+type Movement struct {
+	Id         string
+	Product_id string
+	Type       string
+	// Quantity is the signed change applied to the stock of the product
+	Quantity   int
+	Reason     string
+	Actor      string
+	Created_at time.Time
+}
+
+type StorageMovements struct {
+	mu sync.Mutex
+	// Path of the json file, localFileMovementsJson when empty
+	Path string
+	// Tenant keeps the data of a tenant in its own file next to Path, see TenantPath
+	Tenant string
+}
+
+func NewStorageMovements() StorageMovements {
+	return StorageMovements{}
+}
+
+func (s *StorageMovements) ReadAllMovementsToFile() ([]*Movement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.readMovements()
+}
+
+func (s *StorageMovements) readMovements() ([]*Movement, error) {
+	var movementList []*Movement
+
+	file, err := os.Open(s.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			file, err = os.Create(s.path())
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+
+			initialData := []Movement{}
+			writer := json.NewEncoder(file)
+			if err := writer.Encode(initialData); err != nil {
+				return nil, err
+			}
+
+			return movementList, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := json.NewDecoder(file)
+	err = reader.Decode(&movementList)
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return movementList, nil
+}
+
+// SaveMovement appends a movement, the ledger is never rewritten
+func (s *StorageMovements) SaveMovement(movement *Movement) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	movements, err := s.readMovements()
+	if err != nil {
+		return err
+	}
+
+	movements = append(movements, movement)
+	return s.writeMovements(movements)
+}
+
+func (s *StorageMovements) WriteMovementsToFile(movementList []*Movement) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.writeMovements(movementList)
+}
+
+func (s *StorageMovements) writeMovements(movementList []*Movement) error {
+	file, err := os.OpenFile(s.path(), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := json.NewEncoder(file)
+	return writer.Encode(movementList)
+}
+
+func (s *StorageMovements) path() string {
+	if s.Path == "" {
+		return TenantPath(localFileMovementsJson, s.Tenant)
+	}
+	return TenantPath(s.Path, s.Tenant)
+}
@@ -0,0 +1,24 @@
+package storage
+
+import "time"
+
+// The outbox entry types match the names of the events they become
+const (
+	OutboxProductCreated = "product.created"
+	OutboxProductUpdated = "product.updated"
+	OutboxProductDeleted = "product.deleted"
+)
+
+// OutboxEntry records a product write, it is saved in the same file write as the product
+type OutboxEntry struct {
+	// Id is the idempotency key of the events of the entry
+	Id   string
+	Type string
+	// Before is the product as it was, nil for created products
+	Before *Product
+	// Product is the product as it was written, nil for deleted products
+	Product    *Product
+	Created_at time.Time
+	// Dispatched lists the sinks that acknowledged the entry
+	Dispatched []string
+}
@@ -1,11 +1,16 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"io"
 	"os"
+	"reflect"
 	"sync"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 const (
@@ -13,17 +18,83 @@ const (
 )
 
 type Product struct {
-	Id           string
-	Name         string
-	Quantity     int
-	Code_value   string
+	Id         string
+	Name       string
+	Quantity   int
+	Code_value string
+	// Code_format is the format Code_value was validated in, see barcode.Formats, empty for a free code
+	Code_format  string
 	Is_published *bool
 	Expiration   string
 	Price        float64
+	Category_id  string
+	Tags         []string
+	// Parent_id is the product this one is a variant of, empty for standalone and parent products
+	Parent_id  string
+	Attributes map[string]string
+	// Low_stock_threshold lists the product in the low stock report once its quantity drops to it, zero disables it
+	Low_stock_threshold int
+	// Publish_at and Unpublish_at schedule a change of Is_published, the scheduler of the API
+	// applies them and clears them once they are due
+	Publish_at   *time.Time
+	Unpublish_at *time.Time
+	// Images are in display order, their content is kept in a blob store
+	Images []Image
+	// Prices is the timeline of Price, the past changes and the scheduled ones sorted by Effective_at.
+	// The scheduler of the API copies the due scheduled prices into Price.
+	Prices []PriceChange
+	// Version starts at 1 and grows with every write that changes the product, Updated_at is the time of that write.
+	// The storage sets both.
+	Version    int
+	Updated_at time.Time
+}
+
+// Image describes an image of a product, the blob store keeps the image under
+// ImageKey and its thumbnail under ThumbnailKey
+type Image struct {
+	Id           string
+	Content_type string
+	Size         int64
+	Width        int
+	Height       int
+	// Primary is the image shown first in the listings, a product has at most one
+	Primary    bool
+	Created_at time.Time
+}
+
+// PriceChange sets the price of a product from Effective_at on
+type PriceChange struct {
+	Id           string
+	Price        float64
+	Effective_at time.Time
+	Created_at   time.Time
+}
+
+// ImageKey is the blob key of an image of the product
+func ImageKey(productId string, imageId string) string {
+	return "products/" + productId + "/" + imageId
+}
+
+// ThumbnailKey is the blob key of the thumbnail of an image of the product
+func ThumbnailKey(productId string, imageId string) string {
+	return "products/" + productId + "/" + imageId + "-thumbnail"
 }
 
 type StorageProducts struct {
 	mu sync.Mutex
+	// Path of the json file, localFileJson when empty
+	Path string
+	// Tenant keeps the data of a tenant in its own file next to Path, see TenantPath
+	Tenant string
+	// Outbox records an OutboxEntry for every product write in the same file write,
+	// the file then holds a productsDocument instead of a list of products
+	Outbox bool
+}
+
+// productsDocument is the file format when the outbox is enabled
+type productsDocument struct {
+	Products []*Product
+	Outbox   []*OutboxEntry
 }
 
 func NewStorageProducts() StorageProducts {
@@ -31,46 +102,103 @@ func NewStorageProducts() StorageProducts {
 }
 
 func (s *StorageProducts) ReadAllProductsToFile() ([]*Product, error) {
-	var productList []*Product
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.readProducts()
+}
 
+func (s *StorageProducts) ReadProductById(id string) (*Product, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	file, err := os.Open(localFileJson)
-	if err != nil {
-		if os.IsNotExist(err) {
-			file, err = os.Create(localFileJson)
-			if err != nil {
-				return nil, err
-			}
-			defer file.Close()
-
-			initialData := []Product{}
-			writer := json.NewEncoder(file)
-			if err := writer.Encode(initialData); err != nil {
-				return nil, err
-			}
-
-			return productList, nil
-		}
-		return nil, err
-	}
-	defer file.Close()
+	return s.readProductById(id)
+}
+
+func (s *StorageProducts) SaveProduct(product *Product) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.saveProduct(product)
+}
+
+func (s *StorageProducts) UpdateProduct(updatedProduct *Product) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updateProduct(updatedProduct)
+}
 
-	reader := json.NewDecoder(file)
-	err = reader.Decode(&productList)
+func (s *StorageProducts) DeleteProduct(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deleteProduct(id)
+}
+
+func (s *StorageProducts) WriteProductsToFile(productList []*Product) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.writeProducts(productList)
+}
+
+// Atomic runs fn holding the lock of the storage, the reads and writes fn makes through tx
+// see no write of another caller in between
+func (s *StorageProducts) Atomic(fn func(tx Storage) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return fn(lockedProducts{s})
+}
+
+// lockedProducts is the storage as Atomic hands it to fn, the lock is already held
+type lockedProducts struct {
+	s *StorageProducts
+}
+
+func (l lockedProducts) ReadAllProductsToFile() ([]*Product, error) {
+	return l.s.readProducts()
+}
+
+func (l lockedProducts) WriteProductsToFile(productList []*Product) error {
+	return l.s.writeProducts(productList)
+}
+
+func (l lockedProducts) ReadProductById(id string) (*Product, error) {
+	return l.s.readProductById(id)
+}
+
+func (l lockedProducts) SaveProduct(product *Product) error {
+	return l.s.saveProduct(product)
+}
+
+func (l lockedProducts) UpdateProduct(updatedProduct *Product) error {
+	return l.s.updateProduct(updatedProduct)
+}
+
+func (l lockedProducts) DeleteProduct(id string) error {
+	return l.s.deleteProduct(id)
+}
+
+// Atomic of an Atomic runs in the lock already held
+func (l lockedProducts) Atomic(fn func(tx Storage) error) error {
+	return fn(l)
+}
+
+// the methods below must be called with s.mu held
+
+func (s *StorageProducts) readProducts() ([]*Product, error) {
+	document, err := s.readDocument()
 	if err != nil {
-		if err == io.EOF {
-			return nil, nil
-		}
 		return nil, err
 	}
 
-	return productList, nil
+	return document.Products, nil
 }
 
-func (s *StorageProducts) ReadProductById(id string) (*Product, error) {
-	products, err := s.ReadAllProductsToFile()
+func (s *StorageProducts) readProductById(id string) (*Product, error) {
+	products, err := s.readProducts()
 	if err != nil {
 		return nil, err
 	}
@@ -82,61 +210,214 @@ func (s *StorageProducts) ReadProductById(id string) (*Product, error) {
 	return nil, nil
 }
 
-func (s *StorageProducts) SaveProduct(product *Product) error {
-	products, err := s.ReadAllProductsToFile()
+func (s *StorageProducts) saveProduct(product *Product) error {
+	document, err := s.readDocument()
 	if err != nil {
 		return err
 	}
 
-	for _, p := range products {
+	for _, p := range document.Products {
 		if p.Id == product.Id {
 			return errors.New("product already exists")
 		}
 	}
 
-	products = append(products, product)
-	return s.WriteProductsToFile(products)
+	product.Version = 1
+	product.Updated_at = time.Now().UTC()
+
+	document.Products = append(document.Products, product)
+	s.addOutboxEntry(document, OutboxProductCreated, nil, product)
+	return s.writeDocument(document)
 }
 
-func (s *StorageProducts) UpdateProduct(updatedProduct *Product) error {
-	products, err := s.ReadAllProductsToFile()
+func (s *StorageProducts) updateProduct(updatedProduct *Product) error {
+	document, err := s.readDocument()
 	if err != nil {
 		return err
 	}
 
-	for i, product := range products {
+	for i, product := range document.Products {
 		if product.Id == updatedProduct.Id {
-			products[i] = updatedProduct
-			return s.WriteProductsToFile(products)
+			Stamp(updatedProduct, product)
+			document.Products[i] = updatedProduct
+			s.addOutboxEntry(document, OutboxProductUpdated, product, updatedProduct)
+			return s.writeDocument(document)
 		}
 	}
 
 	return errors.New("product not found")
 }
 
-func (s *StorageProducts) DeleteProduct(id string) error {
-	products, err := s.ReadAllProductsToFile()
+func (s *StorageProducts) deleteProduct(id string) error {
+	document, err := s.readDocument()
 	if err != nil {
 		return err
 	}
 
-	for i, product := range products {
+	for i, product := range document.Products {
 		if product.Id == id {
-			products = append(products[:i], products[i+1:]...)
-			return s.WriteProductsToFile(products)
+			document.Products = append(document.Products[:i], document.Products[i+1:]...)
+			s.addOutboxEntry(document, OutboxProductDeleted, product, nil)
+			return s.writeDocument(document)
 		}
 	}
 
 	return errors.New("product not found")
 }
 
-func (s *StorageProducts) WriteProductsToFile(productList []*Product) error {
-	file, err := os.OpenFile(localFileJson, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+func (s *StorageProducts) writeProducts(productList []*Product) error {
+	document, err := s.readDocument()
+	if err != nil {
+		return err
+	}
+
+	document.Products = productList
+	return s.writeDocument(document)
+}
+
+// ReadOutbox returns the entries that were not dispatched to every sink yet, oldest first
+func (s *StorageProducts) ReadOutbox() ([]*OutboxEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	document, err := s.readDocument()
+	if err != nil {
+		return nil, err
+	}
+
+	return document.Outbox, nil
+}
+
+// UpdateOutboxEntry saves the dispatch progress of an entry
+func (s *StorageProducts) UpdateOutboxEntry(entry *OutboxEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	document, err := s.readDocument()
+	if err != nil {
+		return err
+	}
+
+	for i, e := range document.Outbox {
+		if e.Id == entry.Id {
+			document.Outbox[i] = entry
+			return s.writeDocument(document)
+		}
+	}
+
+	return errors.New("outbox entry not found")
+}
+
+// DeleteOutboxEntry removes an entry once every sink acknowledged it
+func (s *StorageProducts) DeleteOutboxEntry(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	document, err := s.readDocument()
+	if err != nil {
+		return err
+	}
+
+	for i, e := range document.Outbox {
+		if e.Id == id {
+			document.Outbox = append(document.Outbox[:i], document.Outbox[i+1:]...)
+			return s.writeDocument(document)
+		}
+	}
+
+	return errors.New("outbox entry not found")
+}
+
+// Stamp sets the version of a new write of before: the same when nothing changed, the next one otherwise
+func Stamp(product *Product, before *Product) {
+	product.Version = before.Version
+	product.Updated_at = before.Updated_at
+	if reflect.DeepEqual(product, before) {
+		return
+	}
+
+	product.Version = before.Version + 1
+	product.Updated_at = time.Now().UTC()
+}
+
+func (s *StorageProducts) path() string {
+	if s.Path == "" {
+		return TenantPath(localFileJson, s.Tenant)
+	}
+	return TenantPath(s.Path, s.Tenant)
+}
+
+func (s *StorageProducts) addOutboxEntry(document *productsDocument, entryType string, before, after *Product) {
+	if !s.Outbox {
+		return
+	}
+
+	document.Outbox = append(document.Outbox, &OutboxEntry{
+		Id:         uuid.New().String(),
+		Type:       entryType,
+		Before:     before,
+		Product:    after,
+		Created_at: time.Now().UTC(),
+	})
+}
+
+// readDocument reads both file formats, it must be called with s.mu held
+func (s *StorageProducts) readDocument() (*productsDocument, error) {
+	document := &productsDocument{}
+
+	raw, err := os.ReadFile(s.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return document, s.writeDocument(document)
+		}
+		return nil, err
+	}
+
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return document, nil
+	}
+
+	if raw[0] == '[' {
+		err = json.Unmarshal(raw, &document.Products)
+	} else {
+		err = json.Unmarshal(raw, document)
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return document, nil
+}
+
+// writeDocument replaces the file through a rename so a crash never leaves it half
+// written, it must be called with s.mu held
+func (s *StorageProducts) writeDocument(document *productsDocument) error {
+	tmp := s.path() + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
 	writer := json.NewEncoder(file)
-	return writer.Encode(productList)
+	if s.Outbox {
+		err = writer.Encode(document)
+	} else {
+		if document.Products == nil {
+			document.Products = []*Product{}
+		}
+		err = writer.Encode(document.Products)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, s.path())
 }
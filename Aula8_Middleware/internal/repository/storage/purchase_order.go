@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	localFilePurchaseOrdersJson = "../../docs/db/json/purchase_orders.json"
+)
+
+const (
+	PurchaseOrderOpen              = "open"
+	PurchaseOrderPartiallyReceived = "partially_received"
+	PurchaseOrderReceived          = "received"
+	PurchaseOrderCancelled         = "cancelled"
+)
+
+type PurchaseOrderLine struct {
+	Product_id string
+	Quantity   int
+	// Received is the part of Quantity already added to the stock of the product
+	Received  int
+	Unit_cost float64
+}
+
+type PurchaseOrder struct {
+	Id          string
+	Supplier_id string
+	// Reference is the number of the order on the supplier side, optional
+	Reference   string
+	Status      string
+	Lines       []PurchaseOrderLine
+	Expected_at *time.Time
+	Created_at  time.Time
+	// Closed_at is set when the order is received in full or cancelled
+	Closed_at *time.Time
+}
+
+type StoragePurchaseOrders struct {
+	mu sync.Mutex
+	// Path of the json file, localFilePurchaseOrdersJson when empty
+	Path string
+	// Tenant keeps the data of a tenant in its own file next to Path, see TenantPath
+	Tenant string
+}
+
+func NewStoragePurchaseOrders() StoragePurchaseOrders {
+	return StoragePurchaseOrders{}
+}
+
+func (s *StoragePurchaseOrders) ReadAllPurchaseOrdersToFile() ([]*PurchaseOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.readPurchaseOrders()
+}
+
+func (s *StoragePurchaseOrders) readPurchaseOrders() ([]*PurchaseOrder, error) {
+	var orderList []*PurchaseOrder
+
+	file, err := os.Open(s.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			file, err = os.Create(s.path())
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+
+			initialData := []PurchaseOrder{}
+			writer := json.NewEncoder(file)
+			if err := writer.Encode(initialData); err != nil {
+				return nil, err
+			}
+
+			return orderList, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := json.NewDecoder(file)
+	err = reader.Decode(&orderList)
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return orderList, nil
+}
+
+func (s *StoragePurchaseOrders) ReadPurchaseOrderById(id string) (*PurchaseOrder, error) {
+	orders, err := s.ReadAllPurchaseOrdersToFile()
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		if order.Id == id {
+			return order, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *StoragePurchaseOrders) SavePurchaseOrder(order *PurchaseOrder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orders, err := s.readPurchaseOrders()
+	if err != nil {
+		return err
+	}
+
+	for _, o := range orders {
+		if o.Id == order.Id {
+			return errors.New("purchase order already exists")
+		}
+	}
+
+	orders = append(orders, order)
+	return s.writePurchaseOrders(orders)
+}
+
+func (s *StoragePurchaseOrders) UpdatePurchaseOrder(updatedOrder *PurchaseOrder) error {
+	_, err := s.ModifyPurchaseOrder(updatedOrder.Id, func(order *PurchaseOrder) error {
+		*order = *updatedOrder
+		return nil
+	})
+	return err
+}
+
+// ModifyPurchaseOrder runs change on the stored order and writes it holding the lock, so the
+// check and the write of change cannot interleave with another write. An error of change
+// leaves the order as it was.
+func (s *StoragePurchaseOrders) ModifyPurchaseOrder(id string, change func(order *PurchaseOrder) error) (*PurchaseOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orders, err := s.readPurchaseOrders()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, order := range orders {
+		if order.Id == id {
+			if err := change(order); err != nil {
+				return nil, err
+			}
+			order.Id = id
+			orders[i] = order
+			return order, s.writePurchaseOrders(orders)
+		}
+	}
+
+	return nil, errors.New("purchase order not found")
+}
+
+func (s *StoragePurchaseOrders) WritePurchaseOrdersToFile(orderList []*PurchaseOrder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.writePurchaseOrders(orderList)
+}
+
+func (s *StoragePurchaseOrders) writePurchaseOrders(orderList []*PurchaseOrder) error {
+	file, err := os.OpenFile(s.path(), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := json.NewEncoder(file)
+	return writer.Encode(orderList)
+}
+
+func (s *StoragePurchaseOrders) path() string {
+	if s.Path == "" {
+		return TenantPath(localFilePurchaseOrdersJson, s.Tenant)
+	}
+	return TenantPath(s.Path, s.Tenant)
+}
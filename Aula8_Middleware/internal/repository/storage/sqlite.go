@@ -0,0 +1,323 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+const sqliteProductColumns = `id, name, quantity, code_value, is_published, expiration, price,
+	category_id, tags, parent_id, attributes, low_stock_threshold, version, updated_at, publish_at, unpublish_at, images, code_format, prices`
+
+const sqliteProductPlaceholders = `?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?`
+
+// StorageProductsSQLite keeps the products in a SQLite database with the schema of
+// LatestSQLiteSchemaVersion, the slices and maps are stored as JSON
+type StorageProductsSQLite struct {
+	mu sync.Mutex
+	db *sql.DB
+}
+
+func NewStorageProductsSQLite(db *sql.DB) StorageProductsSQLite {
+	return StorageProductsSQLite{db: db}
+}
+
+func (s *StorageProductsSQLite) ReadAllProductsToFile() ([]*Product, error) {
+	rows, err := s.db.Query(`SELECT ` + sqliteProductColumns + ` FROM products ORDER BY rowid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*Product
+	for rows.Next() {
+		product, err := scanSQLiteProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	return products, rows.Err()
+}
+
+func (s *StorageProductsSQLite) ReadProductById(id string) (*Product, error) {
+	row := s.db.QueryRow(`SELECT `+sqliteProductColumns+` FROM products WHERE id = ?`, id)
+	product, err := scanSQLiteProduct(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return product, err
+}
+
+// WriteProductsToFile replaces every product in one transaction
+func (s *StorageProductsSQLite) WriteProductsToFile(productList []*Product) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.writeProducts(productList)
+}
+
+func (s *StorageProductsSQLite) writeProducts(productList []*Product) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM products`); err != nil {
+		return err
+	}
+	for _, product := range productList {
+		if err := insertSQLiteProduct(tx, product); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *StorageProductsSQLite) SaveProduct(product *Product) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.saveProduct(product)
+}
+
+func (s *StorageProductsSQLite) saveProduct(product *Product) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRow(`SELECT 1 FROM products WHERE id = ?`, product.Id).Scan(&exists)
+	if err == nil {
+		return errors.New("product already exists")
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	saved := *product
+	saved.Version = 1
+	saved.Updated_at = time.Now().UTC()
+	if err := insertSQLiteProduct(tx, &saved); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	product.Version = saved.Version
+	product.Updated_at = saved.Updated_at
+	return nil
+}
+
+func (s *StorageProductsSQLite) UpdateProduct(updatedProduct *Product) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updateProduct(updatedProduct)
+}
+
+func (s *StorageProductsSQLite) updateProduct(updatedProduct *Product) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := scanSQLiteProduct(tx.QueryRow(`SELECT `+sqliteProductColumns+` FROM products WHERE id = ?`, updatedProduct.Id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.New("product not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	Stamp(updatedProduct, before)
+	// updated in place, the rowid and so the position of the product in the listings are kept
+	values, err := sqliteProductValues(updatedProduct)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE products SET (`+sqliteProductColumns+`) = (`+sqliteProductPlaceholders+`) WHERE id = ?`,
+		append(values, updatedProduct.Id)...)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *StorageProductsSQLite) DeleteProduct(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deleteProduct(id)
+}
+
+func (s *StorageProductsSQLite) deleteProduct(id string) error {
+	result, err := s.db.Exec(`DELETE FROM products WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New("product not found")
+	}
+	return nil
+}
+
+// Atomic runs fn holding the write lock of the storage, the writes of other callers wait for fn.
+// The reads are not locked, another reader may see the writes fn made before it returns.
+func (s *StorageProductsSQLite) Atomic(fn func(tx Storage) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return fn(lockedProductsSQLite{s})
+}
+
+// lockedProductsSQLite is the storage as Atomic hands it to fn, the lock is already held
+type lockedProductsSQLite struct {
+	s *StorageProductsSQLite
+}
+
+func (l lockedProductsSQLite) ReadAllProductsToFile() ([]*Product, error) {
+	return l.s.ReadAllProductsToFile()
+}
+
+func (l lockedProductsSQLite) WriteProductsToFile(productList []*Product) error {
+	return l.s.writeProducts(productList)
+}
+
+func (l lockedProductsSQLite) ReadProductById(id string) (*Product, error) {
+	return l.s.ReadProductById(id)
+}
+
+func (l lockedProductsSQLite) SaveProduct(product *Product) error {
+	return l.s.saveProduct(product)
+}
+
+func (l lockedProductsSQLite) UpdateProduct(updatedProduct *Product) error {
+	return l.s.updateProduct(updatedProduct)
+}
+
+func (l lockedProductsSQLite) DeleteProduct(id string) error {
+	return l.s.deleteProduct(id)
+}
+
+func (l lockedProductsSQLite) Atomic(fn func(tx Storage) error) error {
+	return fn(l)
+}
+
+func insertSQLiteProduct(tx *sql.Tx, product *Product) error {
+	values, err := sqliteProductValues(product)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO products (`+sqliteProductColumns+`) VALUES (`+sqliteProductPlaceholders+`)`, values...)
+	return err
+}
+
+// sqliteProductValues returns the values of the product in the order of sqliteProductColumns
+func sqliteProductValues(product *Product) ([]any, error) {
+	tags, err := json.Marshal(product.Tags)
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := json.Marshal(product.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	images, err := json.Marshal(product.Images)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := json.Marshal(product.Prices)
+	if err != nil {
+		return nil, err
+	}
+
+	var updatedAt string
+	if !product.Updated_at.IsZero() {
+		updatedAt = product.Updated_at.Format(time.RFC3339Nano)
+	}
+
+	return []any{
+		product.Id, product.Name, product.Quantity, product.Code_value, product.Is_published, product.Expiration, product.Price,
+		product.Category_id, string(tags), product.Parent_id, string(attributes), product.Low_stock_threshold, product.Version, updatedAt,
+		sqliteTime(product.Publish_at), sqliteTime(product.Unpublish_at), string(images), product.Code_format, string(prices),
+	}, nil
+}
+
+type sqliteScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSQLiteProduct(row sqliteScanner) (*Product, error) {
+	var product Product
+	var isPublished sql.NullBool
+	var tags, attributes, images, prices, updatedAt string
+	var publishAt, unpublishAt sql.NullString
+
+	err := row.Scan(&product.Id, &product.Name, &product.Quantity, &product.Code_value, &isPublished, &product.Expiration, &product.Price,
+		&product.Category_id, &tags, &product.Parent_id, &attributes, &product.Low_stock_threshold, &product.Version, &updatedAt,
+		&publishAt, &unpublishAt, &images, &product.Code_format, &prices)
+	if err != nil {
+		return nil, err
+	}
+
+	if isPublished.Valid {
+		product.Is_published = &isPublished.Bool
+	}
+	if err := json.Unmarshal([]byte(tags), &product.Tags); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(attributes), &product.Attributes); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(images), &product.Images); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(prices), &product.Prices); err != nil {
+		return nil, err
+	}
+	if updatedAt != "" {
+		if product.Updated_at, err = time.Parse(time.RFC3339Nano, updatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	if product.Publish_at, err = parseSQLiteTime(publishAt); err != nil {
+		return nil, err
+	}
+	if product.Unpublish_at, err = parseSQLiteTime(unpublishAt); err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// sqliteTime stores an optional time as RFC 3339 text, NULL when it is not set
+func sqliteTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: t.Format(time.RFC3339Nano), Valid: true}
+}
+
+func parseSQLiteTime(value sql.NullString) (*time.Time, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// SQLiteDriver is the database/sql driver name of github.com/mattn/go-sqlite3, the
+// binaries using the SQLite storage import the driver
+const SQLiteDriver = "sqlite3"
+
+var (
+	ErrSQLiteSchemaTooNew   = errors.New("the database schema is newer than this build understands")
+	ErrSQLiteSchemaOutdated = errors.New("the database schema is outdated, run the migrations")
+)
+
+// SQLiteMigration moves the schema from Version-1 to Version with Up, and back with Down
+type SQLiteMigration struct {
+	Version     int
+	Description string
+	Up          string
+	Down        string
+}
+
+// SQLiteMigrations are the schema versions in order, the version of a database is kept
+// in PRAGMA user_version. A migration is never changed once released, a new one is added.
+var SQLiteMigrations = []SQLiteMigration{
+	{
+		Version:     1,
+		Description: "products",
+		Up: `CREATE TABLE products (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			quantity INTEGER NOT NULL,
+			code_value TEXT NOT NULL,
+			is_published INTEGER,
+			expiration TEXT NOT NULL,
+			price REAL NOT NULL
+		)`,
+		Down: `DROP TABLE products`,
+	},
+	{
+		Version:     2,
+		Description: "categories and tags",
+		Up: `ALTER TABLE products ADD COLUMN category_id TEXT NOT NULL DEFAULT '';
+			ALTER TABLE products ADD COLUMN tags TEXT NOT NULL DEFAULT 'null'`,
+		Down: `ALTER TABLE products DROP COLUMN tags;
+			ALTER TABLE products DROP COLUMN category_id`,
+	},
+	{
+		Version:     3,
+		Description: "variants and low stock thresholds",
+		Up: `ALTER TABLE products ADD COLUMN parent_id TEXT NOT NULL DEFAULT '';
+			ALTER TABLE products ADD COLUMN attributes TEXT NOT NULL DEFAULT 'null';
+			ALTER TABLE products ADD COLUMN low_stock_threshold INTEGER NOT NULL DEFAULT 0`,
+		Down: `ALTER TABLE products DROP COLUMN low_stock_threshold;
+			ALTER TABLE products DROP COLUMN attributes;
+			ALTER TABLE products DROP COLUMN parent_id`,
+	},
+	{
+		Version:     4,
+		Description: "product versions",
+		Up: `ALTER TABLE products ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE products ADD COLUMN updated_at TEXT NOT NULL DEFAULT ''`,
+		Down: `ALTER TABLE products DROP COLUMN updated_at;
+			ALTER TABLE products DROP COLUMN version`,
+	},
+	{
+		Version:     5,
+		Description: "code_value index",
+		Up:          `CREATE INDEX products_code_value ON products (code_value)`,
+		Down:        `DROP INDEX products_code_value`,
+	},
+	{
+		Version:     6,
+		Description: "publishing windows",
+		Up: `ALTER TABLE products ADD COLUMN publish_at TEXT;
+			ALTER TABLE products ADD COLUMN unpublish_at TEXT`,
+		Down: `ALTER TABLE products DROP COLUMN unpublish_at;
+			ALTER TABLE products DROP COLUMN publish_at`,
+	},
+	{
+		Version:     7,
+		Description: "product images",
+		Up:          `ALTER TABLE products ADD COLUMN images TEXT NOT NULL DEFAULT 'null'`,
+		Down:        `ALTER TABLE products DROP COLUMN images`,
+	},
+	{
+		Version:     8,
+		Description: "code formats",
+		Up:          `ALTER TABLE products ADD COLUMN code_format TEXT NOT NULL DEFAULT ''`,
+		Down:        `ALTER TABLE products DROP COLUMN code_format`,
+	},
+	{
+		Version:     9,
+		Description: "price history",
+		Up:          `ALTER TABLE products ADD COLUMN prices TEXT NOT NULL DEFAULT 'null'`,
+		Down:        `ALTER TABLE products DROP COLUMN prices`,
+	},
+}
+
+// LatestSQLiteSchemaVersion is the schema used by StorageProductsSQLite
+func LatestSQLiteSchemaVersion() int {
+	return SQLiteMigrations[len(SQLiteMigrations)-1].Version
+}
+
+// SQLiteSchemaVersion returns the version of the schema, 0 for an empty database
+func SQLiteSchemaVersion(db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRow(`PRAGMA user_version`).Scan(&version)
+	return version, err
+}
+
+// CheckSQLiteSchema fails unless the database has the schema of this build
+func CheckSQLiteSchema(db *sql.DB) error {
+	version, err := SQLiteSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case version > LatestSQLiteSchemaVersion():
+		return fmt.Errorf("%w: version %d, latest known %d", ErrSQLiteSchemaTooNew, version, LatestSQLiteSchemaVersion())
+	case version < LatestSQLiteSchemaVersion():
+		return fmt.Errorf("%w: version %d, latest known %d", ErrSQLiteSchemaOutdated, version, LatestSQLiteSchemaVersion())
+	}
+	return nil
+}
+
+// MigrateSQLite runs the up or down steps from the current version of the schema to target,
+// each step in its own transaction. It refuses to touch a schema newer than the latest migration.
+func MigrateSQLite(db *sql.DB, target int) ([]SQLiteMigration, error) {
+	current, err := SQLiteSchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	latest := LatestSQLiteSchemaVersion()
+	if current > latest {
+		return nil, fmt.Errorf("%w: version %d, latest known %d", ErrSQLiteSchemaTooNew, current, latest)
+	}
+	if target < 0 || target > latest {
+		return nil, fmt.Errorf("unknown schema version %d, the versions go from 0 to %d", target, latest)
+	}
+
+	var applied []SQLiteMigration
+	for _, migration := range SQLiteMigrations {
+		if migration.Version <= current || migration.Version > target {
+			continue
+		}
+		if err := runSQLiteMigration(db, migration.Up, migration.Version); err != nil {
+			return applied, fmt.Errorf("migration %d up: %w", migration.Version, err)
+		}
+		applied = append(applied, migration)
+	}
+
+	for i := len(SQLiteMigrations) - 1; i >= 0; i-- {
+		migration := SQLiteMigrations[i]
+		if migration.Version > current || migration.Version <= target {
+			continue
+		}
+		if err := runSQLiteMigration(db, migration.Down, migration.Version-1); err != nil {
+			return applied, fmt.Errorf("migration %d down: %w", migration.Version, err)
+		}
+		applied = append(applied, migration)
+	}
+
+	return applied, nil
+}
+
+func runSQLiteMigration(db *sql.DB, statements string, version int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(statements); err != nil {
+		return err
+	}
+	// PRAGMA does not take parameters, version is an int
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, version)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
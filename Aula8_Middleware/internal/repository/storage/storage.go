@@ -1,5 +1,6 @@
 package storage
 
+// Storage keeps the products, each method is atomic and Atomic groups several of them
 type Storage interface {
 	ReadAllProductsToFile() ([]*Product, error)
 	WriteProductsToFile(productList []*Product) error
@@ -8,4 +9,61 @@ type Storage interface {
 	SaveProduct(product *Product) error
 	UpdateProduct(updatedProduct *Product) error
 	DeleteProduct(id string) error
+
+	// Atomic runs fn with the writes of the storage locked, a read, check and write made
+	// through tx cannot interleave with another write
+	Atomic(fn func(tx Storage) error) error
+}
+
+type CategoryStorage interface {
+	ReadAllCategoriesToFile() ([]*Category, error)
+	WriteCategoriesToFile(categoryList []*Category) error
+
+	ReadCategoryById(id string) (*Category, error)
+	SaveCategory(category *Category) error
+	UpdateCategory(updatedCategory *Category) error
+	DeleteCategory(id string) error
+}
+
+type MovementStorage interface {
+	ReadAllMovementsToFile() ([]*Movement, error)
+	WriteMovementsToFile(movementList []*Movement) error
+
+	SaveMovement(movement *Movement) error
+}
+
+type SubscriberStorage interface {
+	ReadAllSubscribersToFile() ([]*Subscriber, error)
+	WriteSubscribersToFile(subscriberList []*Subscriber) error
+
+	ReadSubscriberById(id string) (*Subscriber, error)
+	SaveSubscriber(subscriber *Subscriber) error
+	DeleteSubscriber(id string) error
+}
+
+type OutboxStorage interface {
+	ReadOutbox() ([]*OutboxEntry, error)
+	UpdateOutboxEntry(entry *OutboxEntry) error
+	DeleteOutboxEntry(id string) error
+}
+
+type SupplierStorage interface {
+	ReadAllSuppliersToFile() ([]*Supplier, error)
+	WriteSuppliersToFile(supplierList []*Supplier) error
+
+	ReadSupplierById(id string) (*Supplier, error)
+	SaveSupplier(supplier *Supplier) error
+	UpdateSupplier(updatedSupplier *Supplier) error
+	DeleteSupplier(id string) error
+}
+
+type PurchaseOrderStorage interface {
+	ReadAllPurchaseOrdersToFile() ([]*PurchaseOrder, error)
+	WritePurchaseOrdersToFile(orderList []*PurchaseOrder) error
+
+	ReadPurchaseOrderById(id string) (*PurchaseOrder, error)
+	SavePurchaseOrder(order *PurchaseOrder) error
+	UpdatePurchaseOrder(updatedOrder *PurchaseOrder) error
+	// ModifyPurchaseOrder is the read-modify-write of an order, see StoragePurchaseOrders.ModifyPurchaseOrder
+	ModifyPurchaseOrder(id string, change func(order *PurchaseOrder) error) (*PurchaseOrder, error)
 }
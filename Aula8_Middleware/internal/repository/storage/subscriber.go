@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	localFileSubscribersJson = "../../docs/db/json/subscribers.json"
+)
+
+type Subscriber struct {
+	Id     string
+	Url    string
+	Events []string
+	// Secret signs the deliveries with HMAC-SHA256
+	Secret     string
+	Created_at time.Time
+}
+
+type StorageSubscribers struct {
+	mu sync.Mutex
+	// Path of the json file, localFileSubscribersJson when empty
+	Path string
+	// Tenant keeps the data of a tenant in its own file next to Path, see TenantPath
+	Tenant string
+}
+
+func NewStorageSubscribers() StorageSubscribers {
+	return StorageSubscribers{}
+}
+
+func (s *StorageSubscribers) ReadAllSubscribersToFile() ([]*Subscriber, error) {
+	var subscriberList []*Subscriber
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			file, err = os.Create(s.path())
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+
+			initialData := []Subscriber{}
+			writer := json.NewEncoder(file)
+			if err := writer.Encode(initialData); err != nil {
+				return nil, err
+			}
+
+			return subscriberList, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := json.NewDecoder(file)
+	err = reader.Decode(&subscriberList)
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return subscriberList, nil
+}
+
+func (s *StorageSubscribers) ReadSubscriberById(id string) (*Subscriber, error) {
+	subscribers, err := s.ReadAllSubscribersToFile()
+	if err != nil {
+		return nil, err
+	}
+	for _, subscriber := range subscribers {
+		if subscriber.Id == id {
+			return subscriber, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *StorageSubscribers) SaveSubscriber(subscriber *Subscriber) error {
+	subscribers, err := s.ReadAllSubscribersToFile()
+	if err != nil {
+		return err
+	}
+
+	for _, sb := range subscribers {
+		if sb.Id == subscriber.Id {
+			return errors.New("subscriber already exists")
+		}
+	}
+
+	subscribers = append(subscribers, subscriber)
+	return s.WriteSubscribersToFile(subscribers)
+}
+
+func (s *StorageSubscribers) DeleteSubscriber(id string) error {
+	subscribers, err := s.ReadAllSubscribersToFile()
+	if err != nil {
+		return err
+	}
+
+	for i, subscriber := range subscribers {
+		if subscriber.Id == id {
+			subscribers = append(subscribers[:i], subscribers[i+1:]...)
+			return s.WriteSubscribersToFile(subscribers)
+		}
+	}
+
+	return errors.New("subscriber not found")
+}
+
+func (s *StorageSubscribers) WriteSubscribersToFile(subscriberList []*Subscriber) error {
+	file, err := os.OpenFile(s.path(), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := json.NewEncoder(file)
+	return writer.Encode(subscriberList)
+}
+
+func (s *StorageSubscribers) path() string {
+	if s.Path == "" {
+		return TenantPath(localFileSubscribersJson, s.Tenant)
+	}
+	return TenantPath(s.Path, s.Tenant)
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	localFileSuppliersJson = "../../docs/db/json/suppliers.json"
+)
+
+type Supplier struct {
+	Id    string
+	Name  string
+	Email string
+	Phone string
+	// Lead_time_days is the usual delay between an order and its delivery
+	Lead_time_days int
+}
+
+type StorageSuppliers struct {
+	mu sync.Mutex
+	// Path of the json file, localFileSuppliersJson when empty
+	Path string
+	// Tenant keeps the data of a tenant in its own file next to Path, see TenantPath
+	Tenant string
+}
+
+func NewStorageSuppliers() StorageSuppliers {
+	return StorageSuppliers{}
+}
+
+func (s *StorageSuppliers) ReadAllSuppliersToFile() ([]*Supplier, error) {
+	var supplierList []*Supplier
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			file, err = os.Create(s.path())
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+
+			initialData := []Supplier{}
+			writer := json.NewEncoder(file)
+			if err := writer.Encode(initialData); err != nil {
+				return nil, err
+			}
+
+			return supplierList, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := json.NewDecoder(file)
+	err = reader.Decode(&supplierList)
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return supplierList, nil
+}
+
+func (s *StorageSuppliers) ReadSupplierById(id string) (*Supplier, error) {
+	suppliers, err := s.ReadAllSuppliersToFile()
+	if err != nil {
+		return nil, err
+	}
+	for _, supplier := range suppliers {
+		if supplier.Id == id {
+			return supplier, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *StorageSuppliers) SaveSupplier(supplier *Supplier) error {
+	suppliers, err := s.ReadAllSuppliersToFile()
+	if err != nil {
+		return err
+	}
+
+	for _, sp := range suppliers {
+		if sp.Id == supplier.Id {
+			return errors.New("supplier already exists")
+		}
+	}
+
+	suppliers = append(suppliers, supplier)
+	return s.WriteSuppliersToFile(suppliers)
+}
+
+func (s *StorageSuppliers) UpdateSupplier(updatedSupplier *Supplier) error {
+	suppliers, err := s.ReadAllSuppliersToFile()
+	if err != nil {
+		return err
+	}
+
+	for i, supplier := range suppliers {
+		if supplier.Id == updatedSupplier.Id {
+			suppliers[i] = updatedSupplier
+			return s.WriteSuppliersToFile(suppliers)
+		}
+	}
+
+	return errors.New("supplier not found")
+}
+
+func (s *StorageSuppliers) DeleteSupplier(id string) error {
+	suppliers, err := s.ReadAllSuppliersToFile()
+	if err != nil {
+		return err
+	}
+
+	for i, supplier := range suppliers {
+		if supplier.Id == id {
+			suppliers = append(suppliers[:i], suppliers[i+1:]...)
+			return s.WriteSuppliersToFile(suppliers)
+		}
+	}
+
+	return errors.New("supplier not found")
+}
+
+func (s *StorageSuppliers) WriteSuppliersToFile(supplierList []*Supplier) error {
+	file, err := os.OpenFile(s.path(), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := json.NewEncoder(file)
+	return writer.Encode(supplierList)
+}
+
+func (s *StorageSuppliers) path() string {
+	if s.Path == "" {
+		return TenantPath(localFileSuppliersJson, s.Tenant)
+	}
+	return TenantPath(s.Path, s.Tenant)
+}
@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// DefaultTenant is the tenant of the requests that name none, its files keep their path
+const DefaultTenant = "default"
+
+// TenantPath is the file of the tenant next to path: products.json becomes
+// products.shop-a.json for the tenant shop-a
+func TenantPath(path string, tenant string) string {
+	if tenant == "" || tenant == DefaultTenant {
+		return path
+	}
+
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + tenant + ext
+}
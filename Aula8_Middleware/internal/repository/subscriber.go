@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"aula4/internal/repository/storage"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RepositorySubscribers struct {
+	Storage storage.SubscriberStorage
+}
+
+func NewRepositorySubscribers(storage storage.SubscriberStorage) RepositorySubscribers {
+	return RepositorySubscribers{
+		Storage: storage,
+	}
+}
+
+func (r *RepositorySubscribers) GetById(id string) (*storage.Subscriber, error) {
+	subscriber, err := r.Storage.ReadSubscriberById(id)
+	if err != nil {
+		return nil, err
+	}
+	if subscriber == nil {
+		return nil, errors.New("subscriber not found")
+	}
+	return subscriber, nil
+}
+
+func (r *RepositorySubscribers) GetAll() ([]*storage.Subscriber, error) {
+	return r.Storage.ReadAllSubscribersToFile()
+}
+
+func (r *RepositorySubscribers) Create(subscriber storage.Subscriber) (storage.Subscriber, error) {
+	id := uuid.New()
+	subscriber.Id = id.String()
+	subscriber.Created_at = time.Now().UTC()
+
+	if err := r.Storage.SaveSubscriber(&subscriber); err != nil {
+		return storage.Subscriber{}, err
+	}
+
+	return subscriber, nil
+}
+
+func (r *RepositorySubscribers) Delete(id string) error {
+	return r.Storage.DeleteSubscriber(id)
+}
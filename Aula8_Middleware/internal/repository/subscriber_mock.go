@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"aula4/internal/repository/storage"
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// MockSubscriberRepository is safe for concurrent use, deliveries read it from other goroutines
+type MockSubscriberRepository struct {
+	mu          sync.Mutex
+	Subscribers map[string]*storage.Subscriber
+}
+
+func NewRepositorySubscribersMock() MockSubscriberRepository {
+	return MockSubscriberRepository{
+		Subscribers: make(map[string]*storage.Subscriber),
+	}
+}
+
+func (m *MockSubscriberRepository) GetById(id string) (*storage.Subscriber, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if subscriber, exists := m.Subscribers[id]; exists {
+		return subscriber, nil
+	}
+	return nil, errors.New("subscriber not found")
+}
+
+func (m *MockSubscriberRepository) GetAll() ([]*storage.Subscriber, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var subscribers []*storage.Subscriber
+	for _, subscriber := range m.Subscribers {
+		subscribers = append(subscribers, subscriber)
+	}
+	return subscribers, nil
+}
+
+func (m *MockSubscriberRepository) Create(subscriber storage.Subscriber) (storage.Subscriber, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if subscriber.Id == "" {
+		subscriber.Id = uuid.New().String()
+	}
+	m.Subscribers[subscriber.Id] = &subscriber
+	return subscriber, nil
+}
+
+func (m *MockSubscriberRepository) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.Subscribers[id]; exists {
+		delete(m.Subscribers, id)
+		return nil
+	}
+	return errors.New("subscriber not found")
+}
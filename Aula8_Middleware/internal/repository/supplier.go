@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"aula4/internal/repository/storage"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+type RepositorySuppliers struct {
+	Storage storage.SupplierStorage
+}
+
+func NewRepositorySuppliers(storage storage.SupplierStorage) RepositorySuppliers {
+	return RepositorySuppliers{
+		Storage: storage,
+	}
+}
+
+func (r *RepositorySuppliers) GetById(id string) (*storage.Supplier, error) {
+	supplier, err := r.Storage.ReadSupplierById(id)
+	if err != nil {
+		return nil, err
+	}
+	if supplier == nil {
+		return nil, errors.New("supplier not found")
+	}
+	return supplier, nil
+}
+
+func (r *RepositorySuppliers) GetAll() ([]*storage.Supplier, error) {
+	return r.Storage.ReadAllSuppliersToFile()
+}
+
+func (r *RepositorySuppliers) Create(supplier storage.Supplier) (storage.Supplier, error) {
+	id := uuid.New()
+	supplier.Id = id.String()
+
+	if err := r.Storage.SaveSupplier(&supplier); err != nil {
+		return storage.Supplier{}, err
+	}
+
+	return supplier, nil
+}
+
+func (r *RepositorySuppliers) Update(supplier storage.Supplier) (storage.Supplier, error) {
+	if err := r.Storage.UpdateSupplier(&supplier); err != nil {
+		return storage.Supplier{}, err
+	}
+
+	return supplier, nil
+}
+
+func (r *RepositorySuppliers) Delete(id string) error {
+	return r.Storage.DeleteSupplier(id)
+}
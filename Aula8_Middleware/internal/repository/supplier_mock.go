@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"aula4/internal/repository/storage"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+type MockSupplierRepository struct {
+	Suppliers map[string]*storage.Supplier
+}
+
+func NewRepositorySuppliersMock() MockSupplierRepository {
+	return MockSupplierRepository{
+		Suppliers: make(map[string]*storage.Supplier),
+	}
+}
+
+func (m *MockSupplierRepository) GetById(id string) (*storage.Supplier, error) {
+	if supplier, exists := m.Suppliers[id]; exists {
+		return supplier, nil
+	}
+	return nil, errors.New("supplier not found")
+}
+
+func (m *MockSupplierRepository) GetAll() ([]*storage.Supplier, error) {
+	var suppliers []*storage.Supplier
+	for _, supplier := range m.Suppliers {
+		suppliers = append(suppliers, supplier)
+	}
+	return suppliers, nil
+}
+
+func (m *MockSupplierRepository) Create(supplier storage.Supplier) (storage.Supplier, error) {
+	if supplier.Id == "" {
+		supplier.Id = uuid.New().String()
+	}
+	m.Suppliers[supplier.Id] = &supplier
+	return supplier, nil
+}
+
+func (m *MockSupplierRepository) Update(supplier storage.Supplier) (storage.Supplier, error) {
+	if _, exists := m.Suppliers[supplier.Id]; exists {
+		m.Suppliers[supplier.Id] = &supplier
+		return supplier, nil
+	}
+
+	return storage.Supplier{}, errors.New("supplier not found")
+}
+
+func (m *MockSupplierRepository) Delete(id string) error {
+	if _, exists := m.Suppliers[id]; exists {
+		delete(m.Suppliers, id)
+		return nil
+	}
+	return errors.New("supplier not found")
+}
@@ -3,9 +3,15 @@ package repository
 import (
 	"encoding/json"
 	"errors"
+	"time"
 )
 
+// ToBool and ToInt refuse nil, a JSON null would otherwise read as false and 0
 func ToBool(value interface{}) (*bool, error) {
+	if value == nil {
+		return nil, errors.New("invalid type for bool conversion")
+	}
+
 	bytes, err := json.Marshal(value)
 	if err != nil {
 		return nil, err
@@ -19,6 +25,10 @@ func ToBool(value interface{}) (*bool, error) {
 }
 
 func ToInt(value interface{}) (int, error) {
+	if value == nil {
+		return 0, errors.New("invalid type for int conversion")
+	}
+
 	bytes, err := json.Marshal(value)
 	if err != nil {
 		return 0, err
@@ -30,3 +40,57 @@ func ToInt(value interface{}) (int, error) {
 	}
 	return result, nil
 }
+
+func ToStrings(value interface{}) ([]string, error) {
+	if value == nil {
+		return nil, errors.New("invalid type for []string conversion")
+	}
+
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	if err := json.Unmarshal(bytes, &result); err != nil {
+		return nil, errors.New("invalid type for []string conversion")
+	}
+	return result, nil
+}
+
+func ToStringMap(value interface{}) (map[string]string, error) {
+	if value == nil {
+		return nil, errors.New("invalid type for map[string]string conversion")
+	}
+
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]string
+	if err := json.Unmarshal(bytes, &result); err != nil {
+		return nil, errors.New("invalid type for map[string]string conversion")
+	}
+	return result, nil
+}
+
+// ToTime reads an RFC 3339 string, null gives a nil time so a patch can clear it
+func ToTime(value interface{}) (*time.Time, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case time.Time:
+		return &v, nil
+	case *time.Time:
+		return v, nil
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, errors.New("invalid time, the format must be RFC 3339, e.g. 2025-01-31T09:00:00Z")
+		}
+		return &t, nil
+	default:
+		return nil, errors.New("invalid type for time conversion")
+	}
+}
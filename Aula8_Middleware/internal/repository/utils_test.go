@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// FuzzConversions reads the values of a decoded patch like Patch does: a conversion either fails
+// or returns the value it was given, never a zero value made up for null or a wrong type
+func FuzzConversions(f *testing.F) {
+	f.Add(`5`)
+	f.Add(`true`)
+	f.Add(`["a","b"]`)
+	f.Add(`{"size":"M"}`)
+	f.Add(`"2030-01-01T09:00:00Z"`)
+
+	f.Fuzz(func(t *testing.T, raw string) {
+		var value interface{}
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			return
+		}
+
+		if i, err := ToInt(value); err == nil {
+			number, ok := value.(float64)
+			require.True(t, ok, "%s is not a number", raw)
+			require.Equal(t, number, float64(i))
+		}
+
+		if b, err := ToBool(value); err == nil {
+			boolean, ok := value.(bool)
+			require.True(t, ok, "%s is not a bool", raw)
+			require.Equal(t, boolean, *b)
+		}
+
+		if strings, err := ToStrings(value); err == nil {
+			list, ok := value.([]interface{})
+			require.True(t, ok, "%s is not a list", raw)
+			require.Len(t, strings, len(list))
+		}
+
+		if m, err := ToStringMap(value); err == nil {
+			object, ok := value.(map[string]interface{})
+			require.True(t, ok, "%s is not an object", raw)
+			require.Len(t, m, len(object))
+		}
+
+		if tm, err := ToTime(value); err == nil && tm != nil {
+			_, ok := value.(string)
+			require.True(t, ok, "%s is not a time", raw)
+		}
+	})
+}
@@ -0,0 +1,376 @@
+package search
+
+import (
+	"aula4/internal/barcode"
+	"aula4/internal/repository/storage"
+	"errors"
+	"html"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	FieldName       = "name"
+	FieldCode_value = "code_value"
+
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+
+	highlightStart = "<em>"
+	highlightEnd   = "</em>"
+)
+
+// Match weights: an exact term ranks above a prefix, a prefix above a typo
+const (
+	weightExact  = 1.0
+	weightPrefix = 0.7
+	weightFuzzy  = 0.5
+)
+
+// fields are the indexed fields with their boost, a code matches more precisely than a name
+var fields = []struct {
+	name  string
+	boost float64
+	value func(product *storage.Product) string
+}{
+	{FieldName, 1.0, func(product *storage.Product) string { return product.Name }},
+	{FieldCode_value, 1.5, func(product *storage.Product) string { return product.Code_value }},
+}
+
+var ErrEmptyQuery = errors.New("query has no searchable terms")
+
+type Query struct {
+	Text string
+	// Page starts at 1, 1 when zero
+	Page int
+	// PageSize is DefaultPageSize when zero and at most MaxPageSize
+	PageSize int
+}
+
+type Hit struct {
+	Product *storage.Product
+	Score   float64
+	// Highlights maps the matching fields to their value with the matched words in <em> tags
+	Highlights map[string]string
+}
+
+type Result struct {
+	Total    int
+	Page     int
+	PageSize int
+	Hits     []Hit
+}
+
+// Index is an in-memory inverted index over the name and the code of the products
+type Index struct {
+	mu sync.RWMutex
+	// postings maps a term to the products holding it, with the fields it appears in
+	postings map[string]map[string][]string
+	products map[string]*storage.Product
+	terms    map[string][]string
+	// codes maps the barcode.Key of a code_value to the products holding it
+	codes map[string][]string
+	// sorted lists the terms for prefix matching, nil when a write changed them
+	sorted []string
+}
+
+func NewIndex() *Index {
+	return &Index{
+		postings: make(map[string]map[string][]string),
+		products: make(map[string]*storage.Product),
+		terms:    make(map[string][]string),
+		codes:    make(map[string][]string),
+	}
+}
+
+// Rebuild replaces the content of the index with the products
+func (x *Index) Rebuild(products []*storage.Product) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.postings = make(map[string]map[string][]string)
+	x.products = make(map[string]*storage.Product)
+	x.terms = make(map[string][]string)
+	x.codes = make(map[string][]string)
+	x.sorted = nil
+
+	for _, product := range products {
+		product := *product
+		x.add(&product)
+	}
+}
+
+// Add indexes the product, replacing the previous version with the same id
+func (x *Index) Add(product storage.Product) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.remove(product.Id)
+	x.add(&product)
+}
+
+func (x *Index) Remove(id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.remove(id)
+}
+
+// Len returns the number of indexed products
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	return len(x.products)
+}
+
+// LookupCode returns a copy of the product whose code_value has the barcode.Key
+func (x *Index) LookupCode(key string) (*storage.Product, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	ids := x.codes[key]
+	if len(ids) == 0 {
+		return nil, false
+	}
+
+	product := *x.products[ids[0]]
+	return &product, true
+}
+
+// Search returns the products matching every word of the query, the best scores first.
+// A word matches a term equal to it, a term it is the prefix of, or a term a few typos away.
+func (x *Index) Search(query Query) (Result, error) {
+	words := tokenize(query.Text)
+	if len(words) == 0 {
+		return Result{}, ErrEmptyQuery
+	}
+
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = DefaultPageSize
+	}
+	query.PageSize = min(query.PageSize, MaxPageSize)
+
+	sorted := x.sortedTerms()
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	var (
+		scores  map[string]float64
+		matched = make(map[string]map[string]bool)
+	)
+	for _, word := range words {
+		wordScores := make(map[string]float64)
+		for term, weight := range x.expand(word.term, sorted) {
+			idf := math.Log(1 + float64(len(x.products))/float64(len(x.postings[term])))
+			for id, termFields := range x.postings[term] {
+				best := 0.0
+				for _, field := range fields {
+					if contains(termFields, field.name) {
+						best = math.Max(best, weight*field.boost*idf)
+					}
+				}
+				wordScores[id] = math.Max(wordScores[id], best)
+
+				if matched[id] == nil {
+					matched[id] = make(map[string]bool)
+				}
+				matched[id][term] = true
+			}
+		}
+
+		// every word must match
+		if scores == nil {
+			scores = wordScores
+			continue
+		}
+		for id := range scores {
+			if _, ok := wordScores[id]; !ok {
+				delete(scores, id)
+				continue
+			}
+			scores[id] += wordScores[id]
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{Product: x.products[id], Score: math.Round(score*1000) / 1000})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].Product.Name != hits[j].Product.Name {
+			return hits[i].Product.Name < hits[j].Product.Name
+		}
+		return hits[i].Product.Id < hits[j].Product.Id
+	})
+
+	result := Result{Total: len(hits), Page: query.Page, PageSize: query.PageSize, Hits: []Hit{}}
+	start := (query.Page - 1) * query.PageSize
+	if start >= len(hits) {
+		return result, nil
+	}
+
+	for _, hit := range hits[start:min(start+query.PageSize, len(hits))] {
+		product := *hit.Product
+		hit.Product = &product
+		hit.Highlights = highlight(&product, matched[product.Id])
+		result.Hits = append(result.Hits, hit)
+	}
+
+	return result, nil
+}
+
+// sortedTerms returns the indexed terms in order, sorting them again after a write
+func (x *Index) sortedTerms() []string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.sorted == nil {
+		x.sorted = make([]string, 0, len(x.postings))
+		for term := range x.postings {
+			x.sorted = append(x.sorted, term)
+		}
+		sort.Strings(x.sorted)
+	}
+	return x.sorted
+}
+
+// expand returns the indexed terms a query word matches with the weight of the match,
+// sorted are the indexed terms in order
+func (x *Index) expand(word string, sorted []string) map[string]float64 {
+	terms := make(map[string]float64)
+
+	if _, ok := x.postings[word]; ok {
+		terms[word] = weightExact
+	}
+
+	for i := sort.SearchStrings(sorted, word); i < len(sorted) && strings.HasPrefix(sorted[i], word); i++ {
+		if _, ok := x.postings[sorted[i]]; ok && sorted[i] != word {
+			terms[sorted[i]] = weightPrefix
+		}
+	}
+
+	if edits := maxEdits(word); edits > 0 {
+		for term := range x.postings {
+			if _, ok := terms[term]; ok {
+				continue
+			}
+			if d := distance(word, term, edits); d <= edits {
+				terms[term] = weightFuzzy / float64(d)
+			}
+		}
+	}
+
+	return terms
+}
+
+// add must be called with x.mu held
+func (x *Index) add(product *storage.Product) {
+	x.products[product.Id] = product
+	x.sorted = nil
+
+	key := barcode.Key(product.Code_format, product.Code_value)
+	x.codes[key] = append(x.codes[key], product.Id)
+
+	for _, field := range fields {
+		value := field.value(product)
+
+		terms := []string{}
+		for _, t := range tokenize(value) {
+			terms = append(terms, t.term)
+		}
+		// codes are also found without their separators, e.g. "abc123" for "ABC-123"
+		if field.name == FieldCode_value && len(terms) > 1 {
+			terms = append(terms, strings.Join(terms, ""))
+		}
+
+		for _, term := range terms {
+			postings, ok := x.postings[term]
+			if !ok {
+				postings = make(map[string][]string)
+				x.postings[term] = postings
+			}
+			if !contains(postings[product.Id], field.name) {
+				postings[product.Id] = append(postings[product.Id], field.name)
+			}
+			if !contains(x.terms[product.Id], term) {
+				x.terms[product.Id] = append(x.terms[product.Id], term)
+			}
+		}
+	}
+}
+
+// remove must be called with x.mu held
+func (x *Index) remove(id string) {
+	if _, ok := x.products[id]; !ok {
+		return
+	}
+
+	key := barcode.Key(x.products[id].Code_format, x.products[id].Code_value)
+	x.codes[key] = slices.DeleteFunc(x.codes[key], func(other string) bool { return other == id })
+	if len(x.codes[key]) == 0 {
+		delete(x.codes, key)
+	}
+
+	for _, term := range x.terms[id] {
+		delete(x.postings[term], id)
+		if len(x.postings[term]) == 0 {
+			delete(x.postings, term)
+		}
+	}
+
+	delete(x.terms, id)
+	delete(x.products, id)
+	x.sorted = nil
+}
+
+// highlight wraps the words of the fields that matched one of the terms. The text of the
+// product is HTML escaped, only the highlight tags are markup.
+func highlight(product *storage.Product, terms map[string]bool) map[string]string {
+	highlights := make(map[string]string)
+
+	for _, field := range fields {
+		value := field.value(product)
+
+		var (
+			builder strings.Builder
+			last    int
+			found   bool
+		)
+		for _, t := range tokenize(value) {
+			if !terms[t.term] {
+				continue
+			}
+			builder.WriteString(html.EscapeString(value[last:t.start]))
+			builder.WriteString(highlightStart + html.EscapeString(value[t.start:t.end]) + highlightEnd)
+			last = t.end
+			found = true
+		}
+
+		if found {
+			builder.WriteString(html.EscapeString(value[last:]))
+			highlights[field.name] = builder.String()
+		}
+	}
+
+	return highlights
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
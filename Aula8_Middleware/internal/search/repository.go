@@ -0,0 +1,81 @@
+package search
+
+import (
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"sync"
+)
+
+// IndexedRepository keeps the index in sync with every successful write of the repository.
+// The writes are serialized so the index sees them in the order the storage made them.
+type IndexedRepository struct {
+	repository.Repository
+	Index *Index
+	mu    sync.Mutex
+}
+
+// NewIndexedRepository indexes the products already stored in the repository
+func NewIndexedRepository(rp repository.Repository, index *Index) (IndexedRepository, error) {
+	products, err := rp.GetAll()
+	if err != nil && err.Error() != "no Products" {
+		return IndexedRepository{}, err
+	}
+
+	index.Rebuild(products)
+	return IndexedRepository{Repository: rp, Index: index}, nil
+}
+
+func (r *IndexedRepository) Create(product storage.Product) (storage.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	created, err := r.Repository.Create(product)
+	if err == nil {
+		r.Index.Add(created)
+	}
+	return created, err
+}
+
+func (r *IndexedRepository) Update(product storage.Product) (storage.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	updated, err := r.Repository.Update(product)
+	if err == nil {
+		r.Index.Add(updated)
+	}
+	return updated, err
+}
+
+func (r *IndexedRepository) Patch(id string, updates map[string]interface{}) (*storage.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	patched, err := r.Repository.Patch(id, updates)
+	if err == nil && patched != nil {
+		r.Index.Add(*patched)
+	}
+	return patched, err
+}
+
+func (r *IndexedRepository) Modify(id string, change func(product *storage.Product) error) (*storage.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modified, err := r.Repository.Modify(id, change)
+	if err == nil && modified != nil {
+		r.Index.Add(*modified)
+	}
+	return modified, err
+}
+
+func (r *IndexedRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.Repository.Delete(id)
+	if err == nil {
+		r.Index.Remove(id)
+	}
+	return err
+}
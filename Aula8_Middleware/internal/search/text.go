@@ -0,0 +1,119 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// folding maps the accented letters of Portuguese and Spanish names to their base letter
+var folding = map[rune]rune{
+	'á': 'a', 'à': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'í': 'i', 'ì': 'i', 'î': 'i', 'ï': 'i',
+	'ó': 'o', 'ò': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o',
+	'ú': 'u', 'ù': 'u', 'û': 'u', 'ü': 'u',
+	'ç': 'c', 'ñ': 'n',
+}
+
+// token is a word of a text with its position, in bytes, in the original text
+type token struct {
+	term  string
+	start int
+	end   int
+}
+
+// Fold lower cases the text and removes the accents, "Pão de Açúcar" becomes "pao de acucar"
+func Fold(text string) string {
+	return strings.Map(foldRune, text)
+}
+
+func foldRune(r rune) rune {
+	r = unicode.ToLower(r)
+	if folded, ok := folding[r]; ok {
+		return folded
+	}
+	return r
+}
+
+// tokenize splits the text in words of letters and digits and folds them
+func tokenize(text string) []token {
+	var (
+		tokens  []token
+		builder strings.Builder
+		start   = -1
+	)
+
+	flush := func(end int) {
+		if start >= 0 {
+			tokens = append(tokens, token{term: builder.String(), start: start, end: end})
+			builder.Reset()
+			start = -1
+		}
+	}
+
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			builder.WriteRune(foldRune(r))
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+
+	return tokens
+}
+
+// maxEdits is the edit distance a query term of this length tolerates
+func maxEdits(term string) int {
+	switch n := len([]rune(term)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// distance is the Levenshtein distance between two terms, max+1 as soon as it exceeds max
+func distance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > max {
+		return max + 1
+	}
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		best := current[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			best = min(best, current[j])
+		}
+		if best > max {
+			return max + 1
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"aula4/internal/barcode"
+	"aula4/internal/repository/storage"
+	"errors"
+	"maps"
+)
+
+// normalizeCode validates the code_value of the product in its code_format and stores it normalized
+func (s *ServiceProducts) normalizeCode(product *storage.Product) error {
+	code, err := barcode.Normalize(product.Code_format, product.Code_value, s.CodePattern)
+	if err != nil {
+		return err
+	}
+
+	product.Code_value = code
+	return nil
+}
+
+// normalizeCodePatch validates the code of a patched product, the missing one of code_value and
+// code_format being the current one. The updates are copied before the normalized code is set.
+func (s *ServiceProducts) normalizeCodePatch(before *storage.Product, updates map[string]interface{}) (map[string]interface{}, error) {
+	codeValue, codeOk := updates["code_value"].(string)
+	codeFormat, formatOk := updates["code_format"].(string)
+	if !codeOk && !formatOk {
+		return updates, nil
+	}
+	if before != nil {
+		if !codeOk {
+			codeValue = before.Code_value
+		}
+		if !formatOk {
+			codeFormat = before.Code_format
+		}
+	}
+
+	code, err := barcode.Normalize(codeFormat, codeValue, s.CodePattern)
+	if err != nil {
+		return nil, err
+	}
+
+	updates = maps.Clone(updates)
+	updates["code_value"] = code
+	return updates, nil
+}
+
+// GetByCode returns the product of a scanned code: "4006381333931", "04006381333931" and
+// the UPC-A "012345678905" of "0012345678905" all find the same product
+func (s *ServiceProducts) GetByCode(code string) (*storage.Product, error) {
+	keys := barcode.LookupKeys(code)
+
+	if s.Index != nil {
+		for _, key := range keys {
+			if product, ok := s.Index.LookupCode(key); ok {
+				return product, nil
+			}
+		}
+		return nil, errors.New("product not found")
+	}
+
+	products, err := getAllProducts(s.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		for _, product := range products {
+			if barcode.Key(product.Code_format, product.Code_value) == key {
+				return product, nil
+			}
+		}
+	}
+
+	return nil, errors.New("product not found")
+}
+
+// GetBarcode returns the symbol of the code_value of the product, in the symbology of its code_format
+func (s *ServiceProducts) GetBarcode(id string) (barcode.Symbol, error) {
+	product, err := s.Repository.GetById(id)
+	if err != nil {
+		return barcode.Symbol{}, err
+	}
+
+	symbol, err := barcode.Encode(product.Code_format, product.Code_value)
+	if err != nil {
+		return barcode.Symbol{}, errors.New("the code_value cannot be drawn: " + err.Error())
+	}
+
+	return symbol, nil
+}
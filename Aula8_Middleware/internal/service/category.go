@@ -0,0 +1,277 @@
+package service
+
+import (
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"errors"
+	"sort"
+	"strings"
+)
+
+type CategorySummary struct {
+	// Count is the number of products in the category and its descendants,
+	// counting the variants of a product instead of the product itself
+	Count int
+	// Stock is the sum of the quantities of those products
+	Stock int
+	// Value is the sum of price * quantity of those products
+	Value float64
+}
+
+type CategoryNode struct {
+	Category *storage.Category
+	Summary  CategorySummary
+	Children []*CategoryNode
+}
+
+type ServiceCategories struct {
+	Repository repository.CategoryRepository
+	Products   repository.Repository
+	// Events receives the category events, nil when nobody listens
+	Events Publisher
+}
+
+func NewServiceCategories(repository repository.CategoryRepository, products repository.Repository) ServiceCategories {
+	return ServiceCategories{
+		Repository: repository,
+		Products:   products,
+	}
+}
+
+func (s *ServiceCategories) GetAll() ([]*storage.Category, error) {
+	return s.Repository.GetAll()
+}
+
+func (s *ServiceCategories) GetById(id string) (*storage.Category, error) {
+	return s.Repository.GetById(id)
+}
+
+func (s *ServiceCategories) Create(category storage.Category) (storage.Category, error) {
+	if err := s.validate(category); err != nil {
+		return storage.Category{}, err
+	}
+
+	created, err := s.Repository.Create(category)
+	if err != nil {
+		return storage.Category{}, err
+	}
+
+	publishCategory(s.Events, EventCategoryCreated, created)
+	return created, nil
+}
+
+func (s *ServiceCategories) Update(category storage.Category) (storage.Category, error) {
+	if _, err := s.Repository.GetById(category.Id); err != nil {
+		return storage.Category{}, err
+	}
+
+	if err := s.validate(category); err != nil {
+		return storage.Category{}, err
+	}
+
+	updated, err := s.Repository.Update(category)
+	if err != nil {
+		return storage.Category{}, err
+	}
+
+	publishCategory(s.Events, EventCategoryUpdated, updated)
+	return updated, nil
+}
+
+func (s *ServiceCategories) Delete(id string) error {
+	categories, err := s.Repository.GetAll()
+	if err != nil {
+		return err
+	}
+
+	for _, category := range categories {
+		if category.Parent_id == id {
+			return errors.New("category has subcategories")
+		}
+	}
+
+	products, err := getAllProducts(s.Products)
+	if err != nil {
+		return err
+	}
+
+	for _, product := range products {
+		if product.Category_id == id {
+			return errors.New("category has products")
+		}
+	}
+
+	category, err := s.Repository.GetById(id)
+	if err != nil {
+		return err
+	}
+	if err := s.Repository.Delete(id); err != nil {
+		return err
+	}
+
+	publishCategory(s.Events, EventCategoryDeleted, *category)
+	return nil
+}
+
+// GetTree returns the root categories with their descendants and product aggregates
+func (s *ServiceCategories) GetTree() ([]*CategoryNode, error) {
+	categories, err := s.Repository.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := getAllProducts(s.Products)
+	if err != nil {
+		return nil, err
+	}
+	products = StockUnits(products)
+
+	nodes := make(map[string]*CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.Id] = &CategoryNode{Category: category}
+	}
+
+	for _, product := range products {
+		if node, ok := nodes[product.Category_id]; ok {
+			node.Summary.add(product)
+		}
+	}
+
+	var roots []*CategoryNode
+	for _, category := range categories {
+		node := nodes[category.Id]
+		if parent, ok := nodes[category.Parent_id]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	sortNodes(roots)
+	for _, root := range roots {
+		root.accumulate()
+	}
+
+	return roots, nil
+}
+
+// GetSummary aggregates the products of a category and its descendants
+func (s *ServiceCategories) GetSummary(id string) (CategorySummary, error) {
+	if _, err := s.Repository.GetById(id); err != nil {
+		return CategorySummary{}, err
+	}
+
+	categories, err := s.Repository.GetAll()
+	if err != nil {
+		return CategorySummary{}, err
+	}
+
+	products, err := getAllProducts(s.Products)
+	if err != nil {
+		return CategorySummary{}, err
+	}
+	products = StockUnits(products)
+
+	ids := CategoryDescendants(categories, id)
+
+	var summary CategorySummary
+	for _, product := range products {
+		if ids[product.Category_id] {
+			summary.add(product)
+		}
+	}
+
+	return summary, nil
+}
+
+func (s *ServiceCategories) validate(category storage.Category) error {
+	if strings.TrimSpace(category.Name) == "" {
+		return errors.New("name is required")
+	}
+
+	if category.Parent_id == "" {
+		return nil
+	}
+
+	if category.Parent_id == category.Id {
+		return errors.New("a category cannot be its own parent")
+	}
+
+	if _, err := s.Repository.GetById(category.Parent_id); err != nil {
+		return errors.New("parent category not found")
+	}
+
+	if category.Id == "" {
+		return nil
+	}
+
+	categories, err := s.Repository.GetAll()
+	if err != nil {
+		return err
+	}
+
+	if CategoryDescendants(categories, category.Id)[category.Parent_id] {
+		return errors.New("a category cannot be moved under its own descendant")
+	}
+
+	return nil
+}
+
+// CategoryDescendants returns the ids of the category and of every category below it
+func CategoryDescendants(categories []*storage.Category, id string) map[string]bool {
+	children := make(map[string][]string)
+	for _, category := range categories {
+		children[category.Parent_id] = append(children[category.Parent_id], category.Id)
+	}
+
+	ids := map[string]bool{id: true}
+	pending := []string{id}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+
+		for _, child := range children[current] {
+			if !ids[child] {
+				ids[child] = true
+				pending = append(pending, child)
+			}
+		}
+	}
+
+	return ids
+}
+
+func (c *CategorySummary) add(product *storage.Product) {
+	c.Count++
+	c.Stock += product.Quantity
+	c.Value += product.Price * float64(product.Quantity)
+}
+
+// accumulate adds the aggregates of the children to the node
+func (n *CategoryNode) accumulate() CategorySummary {
+	sortNodes(n.Children)
+	for _, child := range n.Children {
+		summary := child.accumulate()
+		n.Summary.Count += summary.Count
+		n.Summary.Stock += summary.Stock
+		n.Summary.Value += summary.Value
+	}
+
+	return n.Summary
+}
+
+func sortNodes(nodes []*CategoryNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Category.Name < nodes[j].Category.Name
+	})
+}
+
+// getAllProducts treats the "no Products" error of the repository as an empty catalogue
+func getAllProducts(rp repository.Repository) ([]*storage.Product, error) {
+	products, err := rp.GetAll()
+	if err != nil && err.Error() != "no Products" {
+		return nil, err
+	}
+
+	return products, nil
+}
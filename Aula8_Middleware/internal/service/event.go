@@ -0,0 +1,169 @@
+package service
+
+import (
+	"aula4/internal/repository/storage"
+	"aula4/internal/utils"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventProductCreated = "product.created"
+	EventProductUpdated = "product.updated"
+	EventProductDeleted = "product.deleted"
+	EventStockLow       = "stock.low"
+)
+
+const (
+	EventCategoryCreated = "category.created"
+	EventCategoryUpdated = "category.updated"
+	EventCategoryDeleted = "category.deleted"
+)
+
+// EventTypes lists the product events the services emit, the ones the webhooks and the
+// stream deliver. The category events only reach the response caches.
+var EventTypes = []string{EventProductCreated, EventProductUpdated, EventProductDeleted, EventStockLow}
+
+// Event describes a change to a product, or to a category, after it was written
+type Event struct {
+	Id          string
+	Type        string
+	Product     storage.Product
+	Occurred_at time.Time
+	// Changed lists the json names of the fields a product.updated event changed
+	Changed []string
+	// Category is set by the category events instead of Product
+	Category *storage.Category
+}
+
+// Publisher receives the events of the services, it must not block the caller
+type Publisher interface {
+	Publish(event Event)
+}
+
+// Publishers fans the events out to several publishers
+type Publishers []Publisher
+
+func (p Publishers) Publish(event Event) {
+	for _, publisher := range p {
+		publisher.Publish(event)
+	}
+}
+
+func NewEvent(eventType string, product storage.Product) Event {
+	return Event{
+		Id:          uuid.New().String(),
+		Type:        eventType,
+		Product:     product,
+		Occurred_at: time.Now().UTC(),
+	}
+}
+
+func publish(publisher Publisher, eventType string, product storage.Product) {
+	if publisher == nil {
+		return
+	}
+
+	publisher.Publish(NewEvent(eventType, product))
+}
+
+// publishCategory emits a category event, the listings by category change with the tree
+func publishCategory(publisher Publisher, eventType string, category storage.Category) {
+	if publisher == nil {
+		return
+	}
+
+	publisher.Publish(Event{
+		Id:          uuid.New().String(),
+		Type:        eventType,
+		Occurred_at: time.Now().UTC(),
+		Category:    &category,
+	})
+}
+
+// publishChange emits product.updated with the changed fields, nothing when the write changed nothing
+func publishChange(publisher Publisher, before *storage.Product, product storage.Product) {
+	if publisher == nil {
+		return
+	}
+
+	event := NewEvent(EventProductUpdated, product)
+	if before != nil {
+		event.Changed = ChangedFields(*before, product)
+		if len(event.Changed) == 0 {
+			return
+		}
+	}
+
+	publisher.Publish(event)
+}
+
+// ChangedFields returns the json names of the fields that differ between two versions of a product
+func ChangedFields(before, after storage.Product) []string {
+	var changed []string
+	add := func(field string, differ bool) {
+		if differ {
+			changed = append(changed, field)
+		}
+	}
+
+	add("name", before.Name != after.Name)
+	add("quantity", before.Quantity != after.Quantity)
+	add("code_value", before.Code_value != after.Code_value)
+	add("code_format", before.Code_format != after.Code_format)
+	add("is_published", (before.Is_published != nil && *before.Is_published) != (after.Is_published != nil && *after.Is_published))
+	add("expiration", before.Expiration != after.Expiration)
+	add("price", before.Price != after.Price)
+	add("prices", !slices.EqualFunc(before.Prices, after.Prices, func(a, b storage.PriceChange) bool {
+		return a.Id == b.Id && a.Price == b.Price && a.Effective_at.Equal(b.Effective_at)
+	}))
+	add("category_id", before.Category_id != after.Category_id)
+	add("tags", !slices.Equal(before.Tags, after.Tags))
+	add("attributes", !maps.Equal(before.Attributes, after.Attributes))
+	add("low_stock_threshold", before.Low_stock_threshold != after.Low_stock_threshold)
+	add("publish_at", !equalTime(before.Publish_at, after.Publish_at))
+	add("unpublish_at", !equalTime(before.Unpublish_at, after.Unpublish_at))
+	add("images", !slices.EqualFunc(before.Images, after.Images, func(a, b storage.Image) bool {
+		// the content of an image never changes, its place and its primary flag do
+		return a.Id == b.Id && a.Primary == b.Primary
+	}))
+
+	return changed
+}
+
+// equalTime compares two optional times, nil only equals nil
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// ToEventData is the json representation of an event shared by webhooks and streams
+func ToEventData(event Event) utils.EventData {
+	return utils.EventData{
+		Id:          event.Id,
+		Type:        event.Type,
+		Occurred_at: event.Occurred_at,
+		Changed:     event.Changed,
+		Data:        utils.ToData(&event.Product),
+	}
+}
+
+// publishStockChange emits stock.low when a write takes the product into the low stock
+// report, before is the product as it was and nil for new products
+func publishStockChange(publisher Publisher, product storage.Product, before *storage.Product) {
+	if !IsLowStock(&product) || before != nil && IsLowStock(before) {
+		return
+	}
+
+	publish(publisher, EventStockLow, product)
+}
+
+// IsLowStock tells whether the quantity of the product reached its low stock threshold
+func IsLowStock(product *storage.Product) bool {
+	return product.Low_stock_threshold > 0 && product.Quantity <= product.Low_stock_threshold
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"aula4/internal/blob"
+	"aula4/internal/repository/storage"
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxImageBytes limits the size of an uploaded image
+	MaxImageBytes = 5 << 20
+	// MaxImagePixels refuses the images that would take too much memory once decoded
+	MaxImagePixels = 40_000_000
+	// ThumbnailSize is the longest side of a thumbnail, smaller images keep their size
+	ThumbnailSize = 200
+	// ThumbnailContentType is the format of every thumbnail
+	ThumbnailContentType = "image/png"
+)
+
+// ImageContentTypes are the accepted formats, detected from the content rather than
+// trusted from the upload
+var ImageContentTypes = []string{"image/png", "image/jpeg", "image/gif"}
+
+// AddImage stores the image and its thumbnail and appends it to the images of the product.
+// The first image of a product is its primary image.
+func (s *ServiceProducts) AddImage(productId string, content []byte, primary bool) (storage.Image, error) {
+	if s.Blobs == nil {
+		return storage.Image{}, errors.New("image storage is not configured")
+	}
+
+	if _, err := s.Repository.GetById(productId); err != nil {
+		return storage.Image{}, err
+	}
+
+	if len(content) > MaxImageBytes {
+		return storage.Image{}, errors.New("image is too large")
+	}
+	contentType := http.DetectContentType(content)
+	if !slices.Contains(ImageContentTypes, contentType) {
+		return storage.Image{}, errors.New("unsupported image type " + contentType)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return storage.Image{}, errors.New("invalid image: " + err.Error())
+	}
+	if config.Width*config.Height > MaxImagePixels {
+		return storage.Image{}, errors.New("image is too large")
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return storage.Image{}, errors.New("invalid image: " + err.Error())
+	}
+
+	var thumbnail bytes.Buffer
+	if err := png.Encode(&thumbnail, Thumbnail(decoded, ThumbnailSize)); err != nil {
+		return storage.Image{}, err
+	}
+
+	img := storage.Image{
+		Id:           uuid.New().String(),
+		Content_type: contentType,
+		Size:         int64(len(content)),
+		Width:        config.Width,
+		Height:       config.Height,
+		Created_at:   time.Now().UTC(),
+	}
+
+	if err := s.Blobs.Put(storage.ImageKey(productId, img.Id), bytes.NewReader(content)); err != nil {
+		return storage.Image{}, err
+	}
+	if err := s.Blobs.Put(storage.ThumbnailKey(productId, img.Id), &thumbnail); err != nil {
+		s.deleteImageBlobs(productId, img.Id)
+		return storage.Image{}, err
+	}
+
+	// the images are read again in the write, an image added meanwhile is kept
+	_, err = s.modify(productId, func(product *storage.Product) error {
+		img.Primary = primary || len(product.Images) == 0
+
+		var images []storage.Image
+		for _, existing := range product.Images {
+			existing.Primary = existing.Primary && !img.Primary
+			images = append(images, existing)
+		}
+		product.Images = append(images, img)
+		return nil
+	})
+	if err != nil {
+		s.deleteImageBlobs(productId, img.Id)
+		return storage.Image{}, err
+	}
+
+	return img, nil
+}
+
+// GetImage opens the image, or its thumbnail, the caller closes the reader
+func (s *ServiceProducts) GetImage(productId string, imageId string, thumbnail bool) (storage.Image, io.ReadCloser, error) {
+	if s.Blobs == nil {
+		return storage.Image{}, nil, errors.New("image storage is not configured")
+	}
+
+	product, err := s.Repository.GetById(productId)
+	if err != nil {
+		return storage.Image{}, nil, err
+	}
+
+	index := imageIndex(product.Images, imageId)
+	if index < 0 {
+		return storage.Image{}, nil, errors.New("image not found")
+	}
+
+	key := storage.ImageKey(productId, imageId)
+	if thumbnail {
+		key = storage.ThumbnailKey(productId, imageId)
+	}
+	content, err := s.Blobs.Get(key)
+	if errors.Is(err, blob.ErrNotFound) {
+		return storage.Image{}, nil, errors.New("image not found")
+	}
+	if err != nil {
+		return storage.Image{}, nil, err
+	}
+
+	return product.Images[index], content, nil
+}
+
+// ReorderImages puts the images in the order of ids, which lists every image once
+func (s *ServiceProducts) ReorderImages(productId string, ids []string) ([]storage.Image, error) {
+	product, err := s.modify(productId, func(product *storage.Product) error {
+		if len(ids) != len(product.Images) {
+			return errors.New("image order must list every image once")
+		}
+
+		var images []storage.Image
+		for _, id := range ids {
+			index := imageIndex(product.Images, id)
+			if index < 0 || imageIndex(images, id) >= 0 {
+				return errors.New("image order must list every image once")
+			}
+			images = append(images, product.Images[index])
+		}
+		product.Images = images
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return product.Images, nil
+}
+
+// SetPrimaryImage makes the image the primary one of the product
+func (s *ServiceProducts) SetPrimaryImage(productId string, imageId string) ([]storage.Image, error) {
+	product, err := s.modify(productId, func(product *storage.Product) error {
+		if imageIndex(product.Images, imageId) < 0 {
+			return errors.New("image not found")
+		}
+
+		var images []storage.Image
+		for _, img := range product.Images {
+			img.Primary = img.Id == imageId
+			images = append(images, img)
+		}
+		product.Images = images
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return product.Images, nil
+}
+
+// DeleteImage removes the image and its blobs, the first remaining image becomes primary
+// when the primary one is deleted
+func (s *ServiceProducts) DeleteImage(productId string, imageId string) error {
+	_, err := s.modify(productId, func(product *storage.Product) error {
+		index := imageIndex(product.Images, imageId)
+		if index < 0 {
+			return errors.New("image not found")
+		}
+
+		var images []storage.Image
+		for _, img := range product.Images {
+			if img.Id != imageId {
+				images = append(images, img)
+			}
+		}
+		if product.Images[index].Primary && len(images) > 0 {
+			images[0].Primary = true
+		}
+		product.Images = images
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.deleteImageBlobs(productId, imageId)
+	return nil
+}
+
+// deleteImageBlobs removes the blobs of an image, a failure leaves an orphan blob behind
+func (s *ServiceProducts) deleteImageBlobs(productId string, imageId string) {
+	if s.Blobs == nil {
+		return
+	}
+
+	s.Blobs.Delete(storage.ImageKey(productId, imageId))
+	s.Blobs.Delete(storage.ThumbnailKey(productId, imageId))
+}
+
+// deleteImages removes the blobs of every image of a deleted product
+func (s *ServiceProducts) deleteImages(product *storage.Product) {
+	for _, img := range product.Images {
+		s.deleteImageBlobs(product.Id, img.Id)
+	}
+}
+
+func imageIndex(images []storage.Image, imageId string) int {
+	return slices.IndexFunc(images, func(img storage.Image) bool { return img.Id == imageId })
+}
+
+// Thumbnail scales the image down so its longest side is size, averaging the pixels
+// that fall on each pixel of the thumbnail
+func Thumbnail(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	thumbWidth, thumbHeight := width, height
+	if width > size || height > size {
+		if width >= height {
+			thumbWidth, thumbHeight = size, max(1, height*size/width)
+		} else {
+			thumbWidth, thumbHeight = max(1, width*size/height), size
+		}
+	}
+
+	thumb := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for y := 0; y < thumbHeight; y++ {
+		y0 := bounds.Min.Y + y*height/thumbHeight
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*height/thumbHeight)
+
+		for x := 0; x < thumbWidth; x++ {
+			x0 := bounds.Min.X + x*width/thumbWidth
+			x1 := max(x0+1, bounds.Min.X+(x+1)*width/thumbWidth)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+
+			thumb.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+
+	return thumb
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"errors"
+	"log"
+)
+
+const (
+	// ActorSystem records the movements the ledger adds on its own, e.g. opening balances
+	ActorSystem = "system"
+	// ActorAPI records the quantity changes made through the product routes
+	ActorAPI = "api"
+)
+
+type Ledger struct {
+	Movements []*storage.Movement
+	// Balance is the sum of the movements
+	Balance int
+	// Quantity is the stock stored on the product
+	Quantity int
+	// Reconciled tells whether Balance and Quantity agree
+	Reconciled bool
+}
+
+type ServiceMovements struct {
+	Repository repository.MovementRepository
+	Products   repository.Repository
+	// Events receives product.updated and stock.low when a movement changes the stock, optional.
+	// The outbox of the products storage is preferred when the events must not be lost.
+	Events Publisher
+}
+
+func NewServiceMovements(repository repository.MovementRepository, products repository.Repository) ServiceMovements {
+	return ServiceMovements{
+		Repository: repository,
+		Products:   products,
+	}
+}
+
+// Record applies a movement to the stock of its product and appends it to the ledger. The stock
+// is checked, the movement appended and the quantity written with the product locked, so two
+// concurrent sales cannot both take the last unit.
+func (s *ServiceMovements) Record(movement storage.Movement) (storage.Movement, error) {
+	if _, err := s.Products.GetById(movement.Product_id); err != nil {
+		return storage.Movement{}, err
+	}
+
+	delta, err := MovementDelta(movement.Type, movement.Quantity)
+	if err != nil {
+		return storage.Movement{}, err
+	}
+
+	if movement.Actor == "" {
+		return storage.Movement{}, errors.New("actor is required")
+	}
+
+	products, err := getAllProducts(s.Products)
+	if err != nil {
+		return storage.Movement{}, err
+	}
+	if len(VariantsOf(products, movement.Product_id)) != 0 {
+		return storage.Movement{}, errors.New("product has variants, record the movement on one of them")
+	}
+
+	var before storage.Product
+	updated, err := s.Products.Modify(movement.Product_id, func(product *storage.Product) error {
+		before = *product
+		if product.Quantity+delta < 0 {
+			return errors.New("not enough stock")
+		}
+
+		if err := openLedger(s.Repository, product.Id, product.Quantity); err != nil {
+			return err
+		}
+
+		movement.Quantity = delta
+		created, err := s.Repository.Create(movement)
+		if err != nil {
+			return err
+		}
+		movement = created
+
+		product.Quantity += delta
+		return nil
+	})
+	if err != nil {
+		return storage.Movement{}, err
+	}
+
+	publishChange(s.Events, &before, *updated)
+	publishStockChange(s.Events, *updated, &before)
+
+	return movement, nil
+}
+
+// GetLedger returns the movements of a product reconciled against its quantity
+func (s *ServiceMovements) GetLedger(productId string) (Ledger, error) {
+	product, err := s.Products.GetById(productId)
+	if err != nil {
+		return Ledger{}, err
+	}
+
+	movements, err := s.Repository.GetByProduct(productId)
+	if err != nil {
+		return Ledger{}, err
+	}
+
+	ledger := Ledger{
+		Movements: movements,
+		Quantity:  product.Quantity,
+	}
+	for _, movement := range movements {
+		ledger.Balance += movement.Quantity
+	}
+	ledger.Reconciled = ledger.Balance == ledger.Quantity
+
+	return ledger, nil
+}
+
+// MovementDelta validates a movement and returns the signed change it makes to the stock.
+// Receipts, sales and returns take a positive quantity, adjustments a signed one.
+func MovementDelta(movementType string, quantity int) (int, error) {
+	switch movementType {
+	case storage.MovementReceipt, storage.MovementReturn:
+		if quantity <= 0 {
+			return 0, errors.New("quantity must be positive")
+		}
+		return quantity, nil
+	case storage.MovementSale:
+		if quantity <= 0 {
+			return 0, errors.New("quantity must be positive")
+		}
+		return -quantity, nil
+	case storage.MovementAdjustment:
+		if quantity == 0 {
+			return 0, errors.New("adjustment quantity must not be zero")
+		}
+		return quantity, nil
+	default:
+		return 0, errors.New("invalid movement type")
+	}
+}
+
+// recordStockChange keeps the ledger in step with quantities set through the product routes.
+// The write opens the ledger with openLedger while the product is locked, the adjustments then
+// add up to the quantity in whatever order they are recorded. The product is already saved, so
+// a failure is logged instead of failing the write, the ledger then reports it as not reconciled.
+func recordStockChange(movements repository.MovementRepository, productId string, before, after int, reason string) {
+	if movements == nil || before == after {
+		return
+	}
+
+	_, err := movements.Create(storage.Movement{
+		Product_id: productId,
+		Type:       storage.MovementAdjustment,
+		Quantity:   after - before,
+		Reason:     reason,
+		Actor:      ActorAPI,
+	})
+	if err != nil {
+		log.Printf("ledger: %s of product %s not recorded: %v", reason, productId, err)
+	}
+}
+
+// openLedger records the stock a product had before its first movement
+func openLedger(movements repository.MovementRepository, productId string, quantity int) error {
+	if movements == nil || quantity == 0 {
+		return nil
+	}
+
+	history, err := movements.GetByProduct(productId)
+	if err != nil {
+		return err
+	}
+	if len(history) != 0 {
+		return nil
+	}
+
+	_, err = movements.Create(storage.Movement{
+		Product_id: productId,
+		Type:       storage.MovementAdjustment,
+		Quantity:   quantity,
+		Reason:     "opening balance",
+		Actor:      ActorSystem,
+	})
+	return err
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"aula4/internal/repository/storage"
+	"errors"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	PriceStatusPast      = "past"
+	PriceStatusCurrent   = "current"
+	PriceStatusScheduled = "scheduled"
+)
+
+// PricePoint is a change of the price timeline with its status at the time of the request
+type PricePoint struct {
+	storage.PriceChange
+	Status string
+}
+
+// PriceTimeline is the price of a product in effect now, with its past and scheduled changes
+type PriceTimeline struct {
+	Product *storage.Product
+	Price   float64
+	Points  []PricePoint
+}
+
+// PriceAt returns the change of the timeline in effect at a date, the latest one not after it.
+// The due scheduled prices count before the scheduler stores them. A product without a timeline
+// always had its Price, ok is false for a date before the first change.
+func PriceAt(product *storage.Product, at time.Time) (storage.PriceChange, bool) {
+	if len(product.Prices) == 0 {
+		return storage.PriceChange{Price: product.Price}, true
+	}
+
+	var (
+		current storage.PriceChange
+		found   bool
+	)
+	for _, change := range product.Prices {
+		if change.Effective_at.After(at) {
+			continue
+		}
+		if !found || !change.Effective_at.Before(current.Effective_at) {
+			current, found = change, true
+		}
+	}
+
+	return current, found
+}
+
+// withPriceChange returns the timeline of before with price in effect from now on, false when
+// price already is the stored or the effective price
+func withPriceChange(before *storage.Product, price float64, now time.Time) ([]storage.PriceChange, bool) {
+	current, _ := PriceAt(before, now)
+	if price == before.Price || price == current.Price {
+		return before.Prices, false
+	}
+
+	return addPriceChange(before, storage.PriceChange{
+		Id:           uuid.New().String(),
+		Price:        price,
+		Effective_at: now,
+		Created_at:   now,
+	}, now), true
+}
+
+// addPriceChange inserts the change in the timeline of before. The price known before the
+// timeline was kept starts it, in effect since ever.
+func addPriceChange(before *storage.Product, change storage.PriceChange, now time.Time) []storage.PriceChange {
+	prices := slices.Clone(before.Prices)
+	if len(prices) == 0 {
+		prices = append(prices, storage.PriceChange{Id: uuid.New().String(), Price: before.Price, Created_at: now})
+	}
+	prices = append(prices, change)
+
+	sort.SliceStable(prices, func(i, j int) bool { return prices[i].Effective_at.Before(prices[j].Effective_at) })
+	return prices
+}
+
+// pricePatch adds the change of a patched price to the timeline, the updates are copied first
+func (s *ServiceProducts) pricePatch(before *storage.Product, updates map[string]interface{}) map[string]interface{} {
+	price, ok := updates["price"].(float64)
+	if !ok || before == nil {
+		return updates
+	}
+
+	prices, changed := withPriceChange(before, price, s.now().UTC())
+	if !changed {
+		return updates
+	}
+
+	updates = maps.Clone(updates)
+	updates["prices"] = prices
+	return updates
+}
+
+// GetPriceTimeline returns the price in effect and every change of the timeline, the earliest first
+func (s *ServiceProducts) GetPriceTimeline(id string) (PriceTimeline, error) {
+	product, err := s.Repository.GetById(id)
+	if err != nil {
+		return PriceTimeline{}, err
+	}
+
+	now := s.now()
+	timeline := PriceTimeline{Product: product, Price: product.Price, Points: []PricePoint{}}
+	current, ok := PriceAt(product, now)
+	if ok {
+		timeline.Price = current.Price
+	}
+
+	for _, change := range product.Prices {
+		status := PriceStatusPast
+		switch {
+		case change.Effective_at.After(now):
+			status = PriceStatusScheduled
+		case ok && change.Id == current.Id:
+			status = PriceStatusCurrent
+		}
+		timeline.Points = append(timeline.Points, PricePoint{PriceChange: change, Status: status})
+	}
+
+	return timeline, nil
+}
+
+// SchedulePrice sets the price of the product from a future date on
+func (s *ServiceProducts) SchedulePrice(id string, price float64, at time.Time) (storage.PriceChange, error) {
+	if price <= 0 {
+		return storage.PriceChange{}, errors.New("price must be greater than zero")
+	}
+
+	now := s.now().UTC()
+	if !at.After(now) {
+		return storage.PriceChange{}, errors.New("effective_at must be in the future")
+	}
+
+	change := storage.PriceChange{
+		Id:           uuid.New().String(),
+		Price:        price,
+		Effective_at: at.UTC(),
+		Created_at:   now,
+	}
+
+	_, err := s.modify(id, func(product *storage.Product) error {
+		for _, scheduled := range product.Prices {
+			if scheduled.Effective_at.Equal(at) {
+				return errors.New("a price is already scheduled at that time")
+			}
+		}
+
+		product.Prices = addPriceChange(product, change, now)
+		return nil
+	})
+	if err != nil {
+		return storage.PriceChange{}, err
+	}
+
+	return change, nil
+}
+
+// CancelPrice removes a scheduled price, the past ones stay in the timeline
+func (s *ServiceProducts) CancelPrice(id string, priceId string) error {
+	_, err := s.modify(id, func(product *storage.Product) error {
+		index := slices.IndexFunc(product.Prices, func(change storage.PriceChange) bool { return change.Id == priceId })
+		if index < 0 {
+			return errors.New("price not found")
+		}
+		if !product.Prices[index].Effective_at.After(s.now()) {
+			return errors.New("only scheduled prices can be cancelled")
+		}
+
+		product.Prices = slices.Delete(slices.Clone(product.Prices), index, index+1)
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+)
+
+// PricingTier applies Tax to the quotes of at least MinQuantity units
+type PricingTier struct {
+	MinQuantity int     `json:"min_quantity"`
+	Tax         float64 `json:"tax"`
+}
+
+// DefaultPricingTiers are the taxes of GetTotalPrice when ServiceProducts.Pricing is empty
+var DefaultPricingTiers = []PricingTier{
+	{MinQuantity: 0, Tax: TaxLessThanTen},
+	{MinQuantity: countProductMin, Tax: TaxBetweenTenAndTwenty},
+	{MinQuantity: countProductMax, Tax: TaxGreaterThanTwenty},
+}
+
+// ValidatePricingTiers needs a tier starting at zero, distinct minimum quantities and positive taxes.
+// A tier must not make a larger quote cheaper: the first quantity of a tier at its tax costs at
+// least the quantity before it at the tax of the previous tier, so the quote of a product grows
+// with its quantity.
+func ValidatePricingTiers(tiers []PricingTier) error {
+	if len(tiers) == 0 {
+		return nil
+	}
+
+	seen := make(map[int]bool)
+	for _, tier := range tiers {
+		if tier.MinQuantity < 0 {
+			return errors.New("pricing tier min_quantity must not be negative")
+		}
+		if tier.Tax <= 0 {
+			return errors.New("pricing tier tax must be positive")
+		}
+		if seen[tier.MinQuantity] {
+			return errors.New("pricing tiers must have distinct min_quantity")
+		}
+		seen[tier.MinQuantity] = true
+	}
+	if !seen[0] {
+		return errors.New("pricing tiers must start at min_quantity 0")
+	}
+
+	sorted := sortedTiers(tiers)
+	for i := 1; i < len(sorted); i++ {
+		quantity := float64(sorted[i].MinQuantity)
+		if quantity*sorted[i].Tax < (quantity-1)*sorted[i-1].Tax {
+			return errors.New("pricing tier at min_quantity " + strconv.Itoa(sorted[i].MinQuantity) + " makes a larger quote cheaper")
+		}
+	}
+
+	return nil
+}
+
+func sortedTiers(tiers []PricingTier) []PricingTier {
+	sorted := append([]PricingTier(nil), tiers...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].MinQuantity < sorted[j].MinQuantity })
+	return sorted
+}
+
+// TaxFor returns the tax of the tier with the highest MinQuantity not above quantity
+func TaxFor(tiers []PricingTier, quantity int) float64 {
+	if len(tiers) == 0 {
+		tiers = DefaultPricingTiers
+	}
+
+	sorted := sortedTiers(tiers)
+	tax := sorted[0].Tax
+	for _, tier := range sorted {
+		if tier.MinQuantity > quantity {
+			break
+		}
+		tax = tier.Tax
+	}
+	return tax
+}
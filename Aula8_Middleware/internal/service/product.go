@@ -1,9 +1,18 @@
 package service
 
 import (
+	"aula4/internal/blob"
 	"aula4/internal/repository"
 	"aula4/internal/repository/storage"
+	"aula4/internal/search"
 	"aula4/internal/utils"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 const (
@@ -19,6 +28,29 @@ const (
 
 type ServiceProducts struct {
 	Repository repository.Repository
+	// Categories validates category_id and resolves subcategories when filtering, optional
+	Categories repository.CategoryRepository
+	// Movements records the quantity changes made by Create, Update and Patch in the inventory ledger, optional
+	Movements repository.MovementRepository
+	// Events receives product.created, product.updated, product.deleted and stock.low after every successful write, optional.
+	// The outbox of the products storage is preferred when the events must not be lost.
+	Events Publisher
+	// Index answers FullTextSearch, when nil an index of every product is built for each search
+	Index *search.Index
+	// Now is the clock of the publishing schedule, time.Now when nil
+	Now func() time.Time
+	// Pricing are the taxes of GetTotalPrice by quoted quantity, DefaultPricingTiers when empty
+	Pricing []PricingTier
+	// Blobs keeps the images of the products and their thumbnails, the image methods fail when nil
+	Blobs blob.Store
+	// CodePattern validates the codes of the internal code_format, optional
+	CodePattern *regexp.Regexp
+}
+
+type ProductFilter struct {
+	// CategoryId matches products of the category and of its descendants
+	CategoryId string
+	Tag        string
 }
 
 func NewServiceProducts(repository repository.Repository) ServiceProducts {
@@ -44,7 +76,25 @@ func (s *ServiceProducts) SearchByPrice(price float64) ([]*storage.Product, erro
 	return filteredProducts, nil
 }
 
-func (s *ServiceProducts) GetTotalPrice(ids []string) (float64, []*storage.Product, error) {
+// FullTextSearch ranks the products whose name or code matches the words of the query
+func (s *ServiceProducts) FullTextSearch(query search.Query) (search.Result, error) {
+	index := s.Index
+	if index == nil {
+		products, err := getAllProducts(s.Repository)
+		if err != nil {
+			return search.Result{}, err
+		}
+
+		index = search.NewIndex()
+		index.Rebuild(products)
+	}
+
+	return index.Search(query)
+}
+
+// GetTotalPrice quotes the products at the price of a date, now when at is zero, with the
+// tax of the quoted quantity. The products are returned with the quoted price.
+func (s *ServiceProducts) GetTotalPrice(ids []string, at time.Time) (float64, []*storage.Product, error) {
 	var (
 		quantity int
 		products []*storage.Product
@@ -61,22 +111,26 @@ func (s *ServiceProducts) GetTotalPrice(ids []string) (float64, []*storage.Produ
 		return 0.0, nil, err
 	}
 
+	if at.IsZero() {
+		at = s.now()
+	}
+
 	var totalPrice float64
+	quoted := make([]*storage.Product, 0, len(products))
 	for _, product := range products {
-		totalPrice += product.Price
-	}
+		change, ok := PriceAt(product, at)
+		if !ok {
+			return 0.0, nil, errors.New("product ID:" + product.Id + " has no price at " + at.Format(time.RFC3339))
+		}
 
-	var tax float64
-	if quantity < countProductMin {
-		tax = TaxLessThanTen
-	} else if quantity >= countProductMin && quantity < countProductMax {
-		tax = TaxBetweenTenAndTwenty
-	} else {
-		tax = TaxGreaterThanTwenty
+		product := *product
+		product.Price = change.Price
+		totalPrice += product.Price
+		quoted = append(quoted, &product)
 	}
 
-	totalPrice = totalPrice * tax
-	return totalPrice, products, nil
+	totalPrice = totalPrice * TaxFor(s.Pricing, quantity)
+	return totalPrice, quoted, nil
 }
 
 func (s *ServiceProducts) GetAll() ([]*storage.Product, error) {
@@ -102,6 +156,10 @@ func (s *ServiceProducts) Create(product storage.Product) (storage.Product, erro
 		return storage.Product{}, err
 	}
 
+	if err := s.normalizeCode(&product); err != nil {
+		return storage.Product{}, err
+	}
+
 	products, err := s.Repository.GetAll()
 	if err != nil {
 		if err.Error() != "no Products" {
@@ -117,11 +175,27 @@ func (s *ServiceProducts) Create(product storage.Product) (storage.Product, erro
 		return storage.Product{}, err
 	}
 
+	if err := s.validateCategory(product.Category_id); err != nil {
+		return storage.Product{}, err
+	}
+
+	if err := ValidateSchedule(product.Publish_at, product.Unpublish_at); err != nil {
+		return storage.Product{}, err
+	}
+
+	now := s.now().UTC()
+	product.Prices = []storage.PriceChange{{Id: uuid.New().String(), Price: product.Price, Effective_at: now, Created_at: now}}
+
 	product, err = s.Repository.Create(product)
 	if err != nil {
 		return storage.Product{}, err
 	}
 
+	recordStockChange(s.Movements, product.Id, 0, product.Quantity, "product created")
+
+	publish(s.Events, EventProductCreated, product)
+	publishStockChange(s.Events, product, nil)
+
 	return product, nil
 }
 
@@ -130,6 +204,10 @@ func (s *ServiceProducts) Update(product storage.Product) (storage.Product, erro
 		return storage.Product{}, err
 	}
 
+	if err := s.normalizeCode(&product); err != nil {
+		return storage.Product{}, err
+	}
+
 	products, err := s.Repository.GetAll()
 	if err != nil {
 		if err.Error() != "no Products" {
@@ -145,27 +223,241 @@ func (s *ServiceProducts) Update(product storage.Product) (storage.Product, erro
 		return storage.Product{}, err
 	}
 
-	product, err = s.Repository.Update(product)
+	if err := s.validateCategory(product.Category_id); err != nil {
+		return storage.Product{}, err
+	}
+
+	if err := ValidateSchedule(product.Publish_at, product.Unpublish_at); err != nil {
+		return storage.Product{}, err
+	}
+
+	// a replaced variant stays under its parent, and the images have their own routes. They are
+	// read in the write so a concurrent image or price is not lost.
+	var before *storage.Product
+	updated, err := s.Repository.Modify(product.Id, func(current *storage.Product) error {
+		snapshot := *current
+		before = &snapshot
+
+		if product.Quantity != current.Quantity {
+			if err := openLedger(s.Movements, current.Id, current.Quantity); err != nil {
+				return err
+			}
+		}
+
+		product.Parent_id = current.Parent_id
+		product.Images = current.Images
+		product.Prices, _ = withPriceChange(current, product.Price, s.now().UTC())
+		*current = product
+		return nil
+	})
 	if err != nil {
 		return storage.Product{}, err
 	}
+	product = *updated
+
+	recordStockChange(s.Movements, product.Id, quantityOf(before), product.Quantity, "product replaced")
+
+	publishChange(s.Events, before, product)
+	publishStockChange(s.Events, product, before)
 
 	return product, nil
 }
 
+// ErrInvalidPatch is the refusal of a patch by a check of the product. The error of the check
+// keeps its message, errors.Is tells it from a failure of the storage.
+var ErrInvalidPatch = errors.New("invalid patch")
+
+type invalidPatchError struct {
+	err error
+}
+
+func (e invalidPatchError) Error() string { return e.err.Error() }
+
+func (e invalidPatchError) Unwrap() []error { return []error{e.err, ErrInvalidPatch} }
+
 func (s *ServiceProducts) Patch(id string, updates map[string]interface{}) (*storage.Product, error) {
-	product, err := s.Repository.Patch(id, updates)
+	if categoryId, ok := updates["category_id"].(string); ok {
+		if err := s.validateCategory(categoryId); err != nil {
+			return nil, invalidPatchError{err}
+		}
+	}
+
+	// the checks, the price timeline and the opening of the ledger read the product in the
+	// write, so a concurrent patch, movement or scheduled price is not lost
+	var before *storage.Product
+	product, err := s.Repository.Modify(id, func(current *storage.Product) error {
+		snapshot := *current
+		before = &snapshot
+
+		if err := validateSchedulePatch(current, updates); err != nil {
+			return invalidPatchError{err}
+		}
+
+		patch, err := s.normalizeCodePatch(current, updates)
+		if err != nil {
+			return invalidPatchError{err}
+		}
+		if err := repository.ApplyPatch(current, s.pricePatch(current, patch)); err != nil {
+			return invalidPatchError{err}
+		}
+
+		if current.Quantity != before.Quantity {
+			return openLedger(s.Movements, id, before.Quantity)
+		}
+		return nil
+	})
 	if err != nil {
+		// the repository checks the code against the other products in the write
+		if err.Error() == "the code_value must be unique" {
+			return nil, invalidPatchError{err}
+		}
 		return nil, err
 	}
+
+	recordStockChange(s.Movements, product.Id, quantityOf(before), product.Quantity, "product updated")
+
+	publishChange(s.Events, before, *product)
+	publishStockChange(s.Events, *product, before)
+
 	return product, nil
 }
 
 func (s *ServiceProducts) Delete(id string) error {
-	err := s.Repository.Delete(id)
+	products, err := getAllProducts(s.Repository)
+	if err != nil {
+		return err
+	}
+
+	for _, variant := range VariantsOf(products, id) {
+		if err := s.Repository.Delete(variant.Id); err != nil {
+			return err
+		}
+		s.deleteImages(variant)
+		publish(s.Events, EventProductDeleted, *variant)
+	}
+
+	before := s.snapshot(id)
+
+	err = s.Repository.Delete(id)
 	if err != nil {
 		return err
 	}
 
+	if before != nil {
+		s.deleteImages(before)
+		publish(s.Events, EventProductDeleted, *before)
+	}
+
+	return nil
+}
+
+func (s *ServiceProducts) GetByFilter(filter ProductFilter) ([]*storage.Product, error) {
+	products, err := getAllProducts(s.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	categoryIds := map[string]bool{filter.CategoryId: true}
+	if filter.CategoryId != "" && s.Categories != nil {
+		categories, err := s.Categories.GetAll()
+		if err != nil {
+			return nil, err
+		}
+		categoryIds = CategoryDescendants(categories, filter.CategoryId)
+	}
+
+	tag := strings.ToLower(strings.TrimSpace(filter.Tag))
+
+	filteredProducts := []*storage.Product{}
+	for _, product := range products {
+		if filter.CategoryId != "" && !categoryIds[product.Category_id] {
+			continue
+		}
+		if tag != "" && !slices.Contains(product.Tags, tag) {
+			continue
+		}
+
+		filteredProducts = append(filteredProducts, product)
+	}
+
+	return filteredProducts, nil
+}
+
+// GetLowStock lists the products whose quantity reached their low stock threshold
+func (s *ServiceProducts) GetLowStock() ([]*storage.Product, error) {
+	products, err := getAllProducts(s.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	lowStock := []*storage.Product{}
+	for _, product := range StockUnits(products) {
+		if IsLowStock(product) {
+			lowStock = append(lowStock, product)
+		}
+	}
+
+	return lowStock, nil
+}
+
+// GetTags counts the products of every tag
+func (s *ServiceProducts) GetTags() (map[string]int, error) {
+	products, err := getAllProducts(s.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]int)
+	for _, product := range products {
+		for _, tag := range product.Tags {
+			tags[tag]++
+		}
+	}
+
+	return tags, nil
+}
+
+func (s *ServiceProducts) validateCategory(categoryId string) error {
+	if categoryId == "" || s.Categories == nil {
+		return nil
+	}
+
+	if _, err := s.Categories.GetById(categoryId); err != nil {
+		return errors.New("category not found")
+	}
+
 	return nil
 }
+
+// snapshot copies the stored product, nil when it does not exist
+func (s *ServiceProducts) snapshot(id string) *storage.Product {
+	current, err := s.Repository.GetById(id)
+	if err != nil {
+		return nil
+	}
+
+	product := *current
+	return &product
+}
+
+// modify changes the stored product through Repository.Modify and publishes the change
+func (s *ServiceProducts) modify(id string, change func(product *storage.Product) error) (*storage.Product, error) {
+	var before storage.Product
+	product, err := s.Repository.Modify(id, func(product *storage.Product) error {
+		before = *product
+		return change(product)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publishChange(s.Events, &before, *product)
+	return product, nil
+}
+
+func quantityOf(product *storage.Product) int {
+	if product == nil {
+		return 0
+	}
+	return product.Quantity
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"errors"
+	"slices"
+	"sort"
+	"time"
+)
+
+// PurchaseOrderFilter selects the orders of GetAll, the empty fields match every order
+type PurchaseOrderFilter struct {
+	Status      string
+	Supplier_id string
+	Product_id  string
+}
+
+// ReceiptLine is the quantity of a product delivered for an order
+type ReceiptLine struct {
+	Product_id string
+	Quantity   int
+}
+
+// InboundOrder is the part of an order still to be delivered for a product
+type InboundOrder struct {
+	Order_id    string
+	Supplier_id string
+	Quantity    int
+	Expected_at *time.Time
+}
+
+// InboundQuantity is the quantity of a product ordered and not received yet
+type InboundQuantity struct {
+	Product_id string
+	Quantity   int
+	Orders     []InboundOrder
+}
+
+type ServicePurchaseOrders struct {
+	Repository repository.PurchaseOrderRepository
+	Suppliers  repository.SupplierRepository
+	Products   repository.Repository
+	// Movements records the receipts, so the stock, the ledger and the events of a delivery
+	// are the ones of any other receipt
+	Movements MovementService
+	// Now dates the orders, time.Now when nil
+	Now func() time.Time
+}
+
+func NewServicePurchaseOrders(repository repository.PurchaseOrderRepository, suppliers repository.SupplierRepository, products repository.Repository, movements MovementService) ServicePurchaseOrders {
+	return ServicePurchaseOrders{
+		Repository: repository,
+		Suppliers:  suppliers,
+		Products:   products,
+		Movements:  movements,
+	}
+}
+
+func (s *ServicePurchaseOrders) GetAll(filter PurchaseOrderFilter) ([]*storage.PurchaseOrder, error) {
+	orders, err := s.Repository.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := []*storage.PurchaseOrder{}
+	for _, order := range orders {
+		if filter.Status != "" && order.Status != filter.Status {
+			continue
+		}
+		if filter.Supplier_id != "" && order.Supplier_id != filter.Supplier_id {
+			continue
+		}
+		if filter.Product_id != "" && lineIndex(order.Lines, filter.Product_id) < 0 {
+			continue
+		}
+		filtered = append(filtered, order)
+	}
+
+	return filtered, nil
+}
+
+func (s *ServicePurchaseOrders) GetById(id string) (*storage.PurchaseOrder, error) {
+	return s.Repository.GetById(id)
+}
+
+// Create opens an order of a known supplier, every line ordering a different product
+// that holds stock, i.e. not a parent of variants
+func (s *ServicePurchaseOrders) Create(order storage.PurchaseOrder) (storage.PurchaseOrder, error) {
+	if _, err := s.Suppliers.GetById(order.Supplier_id); err != nil {
+		return storage.PurchaseOrder{}, err
+	}
+
+	if len(order.Lines) == 0 {
+		return storage.PurchaseOrder{}, errors.New("purchase order has no lines")
+	}
+
+	products, err := getAllProducts(s.Products)
+	if err != nil {
+		return storage.PurchaseOrder{}, err
+	}
+
+	lines := make([]storage.PurchaseOrderLine, 0, len(order.Lines))
+	for _, line := range order.Lines {
+		if line.Quantity <= 0 {
+			return storage.PurchaseOrder{}, errors.New("quantity must be positive")
+		}
+		if line.Unit_cost < 0 {
+			return storage.PurchaseOrder{}, errors.New("unit cost must not be negative")
+		}
+		if lineIndex(lines, line.Product_id) >= 0 {
+			return storage.PurchaseOrder{}, errors.New("product " + line.Product_id + " is ordered twice")
+		}
+		if _, err := s.Products.GetById(line.Product_id); err != nil {
+			return storage.PurchaseOrder{}, err
+		}
+		if len(VariantsOf(products, line.Product_id)) != 0 {
+			return storage.PurchaseOrder{}, errors.New("product has variants, order one of them")
+		}
+
+		line.Received = 0
+		lines = append(lines, line)
+	}
+
+	order.Lines = lines
+	order.Status = storage.PurchaseOrderOpen
+	order.Created_at = s.now().UTC()
+	order.Closed_at = nil
+
+	return s.Repository.Create(order)
+}
+
+// Receive adds a delivery to the stock through the movement ledger, one receipt per line.
+// Without lines the whole outstanding quantity of the order is received.
+func (s *ServicePurchaseOrders) Receive(id string, lines []ReceiptLine, actor string) (storage.PurchaseOrder, error) {
+	// the delivery is reserved on the order before the stock changes, two deliveries of a
+	// line cannot both fit in its outstanding quantity and a cancelled order stays closed
+	var receipts []ReceiptLine
+	order, err := s.Repository.Modify(id, func(order *storage.PurchaseOrder) error {
+		if !IsOrderPending(order) {
+			return errors.New("purchase order is " + order.Status)
+		}
+		if actor == "" {
+			return errors.New("actor is required")
+		}
+
+		receipts = lines
+		if len(receipts) == 0 {
+			for _, line := range order.Lines {
+				if outstanding := line.Quantity - line.Received; outstanding > 0 {
+					receipts = append(receipts, ReceiptLine{Product_id: line.Product_id, Quantity: outstanding})
+				}
+			}
+		}
+
+		// every line is checked before the first receipt, so a bad delivery changes nothing
+		seen := make(map[string]bool, len(receipts))
+		for _, receipt := range receipts {
+			index := lineIndex(order.Lines, receipt.Product_id)
+			if index < 0 {
+				return errors.New("product " + receipt.Product_id + " is not on the purchase order")
+			}
+			if seen[receipt.Product_id] {
+				return errors.New("product " + receipt.Product_id + " is received twice")
+			}
+			seen[receipt.Product_id] = true
+
+			if receipt.Quantity <= 0 {
+				return errors.New("quantity must be positive")
+			}
+			line := order.Lines[index]
+			if receipt.Quantity > line.Quantity-line.Received {
+				return errors.New("quantity exceeds the outstanding quantity")
+			}
+		}
+
+		for _, receipt := range receipts {
+			order.Lines[lineIndex(order.Lines, receipt.Product_id)].Received += receipt.Quantity
+		}
+		s.setReceivedStatus(order)
+		return nil
+	})
+	if err != nil {
+		return storage.PurchaseOrder{}, err
+	}
+
+	for i, receipt := range receipts {
+		_, err := s.Movements.Record(storage.Movement{
+			Product_id: receipt.Product_id,
+			Type:       storage.MovementReceipt,
+			Quantity:   receipt.Quantity,
+			Reason:     "purchase order " + order.Id,
+			Actor:      actor,
+		})
+		if err != nil {
+			// the receipts recorded stay on the order and the others are released, the order
+			// counts what reached the stock and a retry receives only the rest
+			return storage.PurchaseOrder{}, errors.Join(err, s.release(id, receipts[i:]))
+		}
+	}
+
+	return *order, nil
+}
+
+// Cancel closes an order, what was received stays in stock and the rest is no longer inbound
+func (s *ServicePurchaseOrders) Cancel(id string) (storage.PurchaseOrder, error) {
+	order, err := s.Repository.Modify(id, func(order *storage.PurchaseOrder) error {
+		if !IsOrderPending(order) {
+			return errors.New("purchase order is " + order.Status)
+		}
+
+		order.Status = storage.PurchaseOrderCancelled
+		now := s.now().UTC()
+		order.Closed_at = &now
+		return nil
+	})
+	if err != nil {
+		return storage.PurchaseOrder{}, err
+	}
+
+	return *order, nil
+}
+
+// GetInbound sums the quantities ordered and not received yet, by product id.
+// An empty productId reports every product.
+func (s *ServicePurchaseOrders) GetInbound(productId string) ([]InboundQuantity, error) {
+	orders, err := s.Repository.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	byProduct := make(map[string]*InboundQuantity)
+	for _, order := range orders {
+		if !IsOrderPending(order) {
+			continue
+		}
+
+		for _, line := range order.Lines {
+			outstanding := line.Quantity - line.Received
+			if outstanding <= 0 || productId != "" && line.Product_id != productId {
+				continue
+			}
+
+			inbound, ok := byProduct[line.Product_id]
+			if !ok {
+				inbound = &InboundQuantity{Product_id: line.Product_id}
+				byProduct[line.Product_id] = inbound
+			}
+			inbound.Quantity += outstanding
+			inbound.Orders = append(inbound.Orders, InboundOrder{
+				Order_id:    order.Id,
+				Supplier_id: order.Supplier_id,
+				Quantity:    outstanding,
+				Expected_at: order.Expected_at,
+			})
+		}
+	}
+
+	report := []InboundQuantity{}
+	for _, inbound := range byProduct {
+		report = append(report, *inbound)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Product_id < report[j].Product_id })
+
+	return report, nil
+}
+
+// IsOrderPending tells whether an order still waits for a delivery
+func IsOrderPending(order *storage.PurchaseOrder) bool {
+	return order.Status == storage.PurchaseOrderOpen || order.Status == storage.PurchaseOrderPartiallyReceived
+}
+
+// release takes back the receipts reserved by Receive and not recorded in the ledger
+func (s *ServicePurchaseOrders) release(id string, receipts []ReceiptLine) error {
+	_, err := s.Repository.Modify(id, func(order *storage.PurchaseOrder) error {
+		for _, receipt := range receipts {
+			if index := lineIndex(order.Lines, receipt.Product_id); index >= 0 {
+				order.Lines[index].Received -= receipt.Quantity
+			}
+		}
+		s.setReceivedStatus(order)
+		return nil
+	})
+	return err
+}
+
+// setReceivedStatus sets the status of the order from its received quantities, a cancelled
+// order stays cancelled
+func (s *ServicePurchaseOrders) setReceivedStatus(order *storage.PurchaseOrder) {
+	if order.Status == storage.PurchaseOrderCancelled {
+		return
+	}
+
+	complete, started := true, false
+	for _, line := range order.Lines {
+		complete = complete && line.Received >= line.Quantity
+		started = started || line.Received > 0
+	}
+
+	switch {
+	case complete:
+		order.Status = storage.PurchaseOrderReceived
+		now := s.now().UTC()
+		order.Closed_at = &now
+	case started:
+		order.Status = storage.PurchaseOrderPartiallyReceived
+		order.Closed_at = nil
+	default:
+		order.Status = storage.PurchaseOrderOpen
+		order.Closed_at = nil
+	}
+}
+
+func (s *ServicePurchaseOrders) now() time.Time {
+	if s.Now == nil {
+		return time.Now()
+	}
+	return s.Now()
+}
+
+func lineIndex(lines []storage.PurchaseOrderLine, productId string) int {
+	return slices.IndexFunc(lines, func(line storage.PurchaseOrderLine) bool { return line.Product_id == productId })
+}
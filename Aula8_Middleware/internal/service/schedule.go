@@ -0,0 +1,349 @@
+package service
+
+import (
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	ScheduleActionPublish   = "publish"
+	ScheduleActionUnpublish = "unpublish"
+	// ScheduleActionPrice is a scheduled price copied into Price, see PriceAt
+	ScheduleActionPrice = "price"
+)
+
+const DefaultSchedulerInterval = 10 * time.Second
+
+// ScheduledChange is a pending change of the visibility of a product
+type ScheduledChange struct {
+	Product *storage.Product
+	Action  string
+	At      time.Time
+	// Due is set by GetSchedule for the changes waiting for the next run of the scheduler
+	Due bool
+}
+
+// IsPublishedAt tells if the product is visible at now. The due changes count before the
+// scheduler stores them, the latest one wins and unpublishing wins a tie.
+func IsPublishedAt(product *storage.Product, now time.Time) bool {
+	published := product.Is_published != nil && *product.Is_published
+
+	var publishedAt time.Time
+	if product.Publish_at != nil && !product.Publish_at.After(now) {
+		published = true
+		publishedAt = *product.Publish_at
+	}
+	if product.Unpublish_at != nil && !product.Unpublish_at.After(now) && !product.Unpublish_at.Before(publishedAt) {
+		published = false
+	}
+
+	return published
+}
+
+// IsExpiredAt tells if the expiration day of the product is over at now, a product with an
+// invalid expiration counts as expired
+func IsExpiredAt(product *storage.Product, now time.Time) bool {
+	end, err := expirationEnd(product, now.Location())
+	if err != nil {
+		return true
+	}
+	return !now.Before(end)
+}
+
+// expirationEnd is the end of the expiration day of the product, the product is expired from then on
+func expirationEnd(product *storage.Product, location *time.Location) (time.Time, error) {
+	expiration, err := time.ParseInLocation("02/01/2006", product.Expiration, location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return expiration.AddDate(0, 0, 1), nil
+}
+
+// IsPublicAt tells if the product belongs in the public catalogue: published and not expired
+func IsPublicAt(product *storage.Product, now time.Time) bool {
+	return IsPublishedAt(product, now) && !IsExpiredAt(product, now)
+}
+
+// ScheduledChanges lists the changes still set on the product, due or not
+func ScheduledChanges(product *storage.Product) []ScheduledChange {
+	var changes []ScheduledChange
+	if product.Publish_at != nil {
+		changes = append(changes, ScheduledChange{Product: product, Action: ScheduleActionPublish, At: *product.Publish_at})
+	}
+	if product.Unpublish_at != nil {
+		changes = append(changes, ScheduledChange{Product: product, Action: ScheduleActionUnpublish, At: *product.Unpublish_at})
+	}
+	return changes
+}
+
+// ValidateSchedule refuses a window that closes before it opens
+func ValidateSchedule(publishAt *time.Time, unpublishAt *time.Time) error {
+	if publishAt != nil && unpublishAt != nil && !unpublishAt.After(*publishAt) {
+		return errors.New("unpublish_at must be after publish_at")
+	}
+	return nil
+}
+
+// NextChange returns the first time after now a product changes without a write: a scheduled
+// publication, unpublication or price, or the end of an expiration day. ok is false when no
+// product changes that way.
+func (s *ServiceProducts) NextChange(now time.Time) (next time.Time, ok bool) {
+	products, err := getAllProducts(s.Repository)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	consider := func(at time.Time) {
+		if at.After(now) && (!ok || at.Before(next)) {
+			next, ok = at, true
+		}
+	}
+	for _, product := range products {
+		for _, change := range ScheduledChanges(product) {
+			consider(change.At)
+		}
+		for _, change := range product.Prices {
+			consider(change.Effective_at)
+		}
+		if end, err := expirationEnd(product, now.Location()); err == nil {
+			consider(end)
+		}
+	}
+
+	return next, ok
+}
+
+// GetPublished lists the products of the public catalogue, published now and not expired
+func (s *ServiceProducts) GetPublished() ([]*storage.Product, error) {
+	products, err := getAllProducts(s.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	published := []*storage.Product{}
+	for _, product := range products {
+		if IsPublicAt(product, now) {
+			published = append(published, product)
+		}
+	}
+
+	return published, nil
+}
+
+// GetPublishedById returns the product when it is in the public catalogue, "product not found" otherwise
+func (s *ServiceProducts) GetPublishedById(id string) (*storage.Product, error) {
+	product, err := s.Repository.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsPublicAt(product, s.now()) {
+		return nil, errors.New("product not found")
+	}
+
+	return product, nil
+}
+
+// GetSchedule lists the pending changes of every product, the earliest first
+func (s *ServiceProducts) GetSchedule() ([]ScheduledChange, error) {
+	products, err := getAllProducts(s.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	changes := []ScheduledChange{}
+	for _, product := range products {
+		for _, change := range ScheduledChanges(product) {
+			change.Due = !change.At.After(now)
+			changes = append(changes, change)
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if !changes[i].At.Equal(changes[j].At) {
+			return changes[i].At.Before(changes[j].At)
+		}
+		return changes[i].Product.Code_value < changes[j].Product.Code_value
+	})
+
+	return changes, nil
+}
+
+// errNothingDue skips the write of a product whose changes were moved or applied since the read
+var errNothingDue = errors.New("nothing is due")
+
+// dueChanges lists the changes of the product the scheduler applies at now: the publishing
+// changes due and the scheduled price in effect when it is not the stored price yet
+func dueChanges(product *storage.Product, now time.Time) []ScheduledChange {
+	var due []ScheduledChange
+	for _, change := range ScheduledChanges(product) {
+		if !change.At.After(now) {
+			due = append(due, change)
+		}
+	}
+	if change, ok := PriceAt(product, now); ok && change.Price != product.Price {
+		due = append(due, ScheduledChange{Product: product, Action: ScheduleActionPrice, At: change.Effective_at})
+	}
+	return due
+}
+
+// ApplySchedule stores the visibility of the products with due changes and clears them, and
+// the due scheduled prices. The changes are read again in the write, so a window changed since
+// the read is left as it is. A product that fails does not hold back the others.
+func (s *ServiceProducts) ApplySchedule() ([]ScheduledChange, error) {
+	products, err := getAllProducts(s.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	var applied []ScheduledChange
+	var errs []error
+	for _, product := range products {
+		if len(dueChanges(product, now)) == 0 {
+			continue
+		}
+
+		var due []ScheduledChange
+		modified, err := s.modify(product.Id, func(current *storage.Product) error {
+			due = dueChanges(current, now)
+			if len(due) == 0 {
+				return errNothingDue
+			}
+
+			published := IsPublishedAt(current, now)
+			for _, change := range due {
+				switch change.Action {
+				case ScheduleActionPublish:
+					current.Publish_at = nil
+				case ScheduleActionUnpublish:
+					current.Unpublish_at = nil
+				case ScheduleActionPrice:
+					price, _ := PriceAt(current, now)
+					current.Price = price.Price
+				}
+			}
+			current.Is_published = &published
+			return nil
+		})
+		// a product deleted since the read has nothing left to apply
+		if err == errNothingDue || err != nil && err.Error() == "product not found" {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, errors.New(product.Id+": "+err.Error()))
+			continue
+		}
+
+		for _, change := range due {
+			change.Product = modified
+			applied = append(applied, change)
+		}
+	}
+
+	return applied, errors.Join(errs...)
+}
+
+func (s *ServiceProducts) now() time.Time {
+	if s.Now == nil {
+		return time.Now()
+	}
+	return s.Now()
+}
+
+// validateSchedulePatch checks the window a patch leaves on the product
+func validateSchedulePatch(before *storage.Product, updates map[string]interface{}) error {
+	var publishAt, unpublishAt *time.Time
+	if before != nil {
+		publishAt, unpublishAt = before.Publish_at, before.Unpublish_at
+	}
+
+	for field, target := range map[string]**time.Time{"publish_at": &publishAt, "unpublish_at": &unpublishAt} {
+		if value, ok := updates[field]; ok {
+			t, err := repository.ToTime(value)
+			if err != nil {
+				return err
+			}
+			*target = t
+		}
+	}
+
+	return ValidateSchedule(publishAt, unpublishAt)
+}
+
+// Scheduler applies the due publishing changes in the background
+type Scheduler struct {
+	Products *ServiceProducts
+	// Interval between two runs, DefaultSchedulerInterval when zero
+	Interval time.Duration
+
+	mu      sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+	running bool
+}
+
+func NewScheduler(products *ServiceProducts, interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = DefaultSchedulerInterval
+	}
+
+	return &Scheduler{Products: products, Interval: interval}
+}
+
+// Start applies the schedule every Interval until Stop
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return
+	}
+	s.running = true
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+
+		for {
+			changes, err := s.Products.ApplySchedule()
+			for _, change := range changes {
+				log.Printf("schedule: %s %s at %s", change.Action, change.Product.Id, change.At.Format(time.RFC3339))
+			}
+			if err != nil {
+				log.Printf("schedule: %v", err)
+			}
+
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop waits for the running pass
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	close(s.stop)
+	s.mu.Unlock()
+
+	<-s.done
+}
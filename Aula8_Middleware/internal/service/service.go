@@ -1,6 +1,12 @@
 package service
 
-import "aula4/internal/repository/storage"
+import (
+	"aula4/internal/barcode"
+	"aula4/internal/repository/storage"
+	"aula4/internal/search"
+	"io"
+	"time"
+)
 
 type Service interface {
 	GetAll() ([]*storage.Product, error)
@@ -10,5 +16,62 @@ type Service interface {
 	Patch(id string, updates map[string]interface{}) (*storage.Product, error)
 	Delete(id string) error
 	SearchByPrice(price float64) ([]*storage.Product, error)
-	GetTotalPrice(ids []string) (float64, []*storage.Product, error)
+	FullTextSearch(query search.Query) (search.Result, error)
+	GetTotalPrice(ids []string, at time.Time) (float64, []*storage.Product, error)
+	GetByFilter(filter ProductFilter) ([]*storage.Product, error)
+	GetTags() (map[string]int, error)
+	GetVariants(id string) ([]*storage.Product, error)
+	CreateVariant(parentId string, variant storage.Product) (storage.Product, error)
+	GetLowStock() ([]*storage.Product, error)
+	GetPublished() ([]*storage.Product, error)
+	GetPublishedById(id string) (*storage.Product, error)
+	GetSchedule() ([]ScheduledChange, error)
+	AddImage(productId string, content []byte, primary bool) (storage.Image, error)
+	GetImage(productId string, imageId string, thumbnail bool) (storage.Image, io.ReadCloser, error)
+	ReorderImages(productId string, ids []string) ([]storage.Image, error)
+	SetPrimaryImage(productId string, imageId string) ([]storage.Image, error)
+	DeleteImage(productId string, imageId string) error
+	GetByCode(code string) (*storage.Product, error)
+	GetBarcode(id string) (barcode.Symbol, error)
+	GetPriceTimeline(id string) (PriceTimeline, error)
+	SchedulePrice(id string, price float64, at time.Time) (storage.PriceChange, error)
+	CancelPrice(id string, priceId string) error
+}
+
+type CategoryService interface {
+	GetAll() ([]*storage.Category, error)
+	GetById(id string) (*storage.Category, error)
+	Create(category storage.Category) (storage.Category, error)
+	Update(category storage.Category) (storage.Category, error)
+	Delete(id string) error
+	GetTree() ([]*CategoryNode, error)
+	GetSummary(id string) (CategorySummary, error)
+}
+
+type MovementService interface {
+	Record(movement storage.Movement) (storage.Movement, error)
+	GetLedger(productId string) (Ledger, error)
+}
+
+type WebhookService interface {
+	GetAll() ([]*storage.Subscriber, error)
+	Subscribe(subscriber storage.Subscriber) (storage.Subscriber, error)
+	Delete(id string) error
+}
+
+type SupplierService interface {
+	GetAll() ([]*storage.Supplier, error)
+	GetById(id string) (*storage.Supplier, error)
+	Create(supplier storage.Supplier) (storage.Supplier, error)
+	Update(supplier storage.Supplier) (storage.Supplier, error)
+	Delete(id string) error
+}
+
+type PurchaseOrderService interface {
+	GetAll(filter PurchaseOrderFilter) ([]*storage.PurchaseOrder, error)
+	GetById(id string) (*storage.PurchaseOrder, error)
+	Create(order storage.PurchaseOrder) (storage.PurchaseOrder, error)
+	Receive(id string, lines []ReceiptLine, actor string) (storage.PurchaseOrder, error)
+	Cancel(id string) (storage.PurchaseOrder, error)
+	GetInbound(productId string) ([]InboundQuantity, error)
 }
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"errors"
+	"net/mail"
+	"strings"
+)
+
+type ServiceSuppliers struct {
+	Repository repository.SupplierRepository
+	// Orders keeps the suppliers of open purchase orders from being deleted, optional
+	Orders repository.PurchaseOrderRepository
+}
+
+func NewServiceSuppliers(repository repository.SupplierRepository) ServiceSuppliers {
+	return ServiceSuppliers{
+		Repository: repository,
+	}
+}
+
+func (s *ServiceSuppliers) GetAll() ([]*storage.Supplier, error) {
+	return s.Repository.GetAll()
+}
+
+func (s *ServiceSuppliers) GetById(id string) (*storage.Supplier, error) {
+	return s.Repository.GetById(id)
+}
+
+func (s *ServiceSuppliers) Create(supplier storage.Supplier) (storage.Supplier, error) {
+	if err := validateSupplier(supplier); err != nil {
+		return storage.Supplier{}, err
+	}
+
+	return s.Repository.Create(supplier)
+}
+
+func (s *ServiceSuppliers) Update(supplier storage.Supplier) (storage.Supplier, error) {
+	if _, err := s.Repository.GetById(supplier.Id); err != nil {
+		return storage.Supplier{}, err
+	}
+
+	if err := validateSupplier(supplier); err != nil {
+		return storage.Supplier{}, err
+	}
+
+	return s.Repository.Update(supplier)
+}
+
+// Delete refuses the suppliers of orders still waiting for a delivery, the closed orders
+// keep the id of their deleted supplier
+func (s *ServiceSuppliers) Delete(id string) error {
+	if _, err := s.Repository.GetById(id); err != nil {
+		return err
+	}
+
+	if s.Orders != nil {
+		orders, err := s.Orders.GetAll()
+		if err != nil {
+			return err
+		}
+		for _, order := range orders {
+			if order.Supplier_id == id && IsOrderPending(order) {
+				return errors.New("supplier has open purchase orders")
+			}
+		}
+	}
+
+	return s.Repository.Delete(id)
+}
+
+func validateSupplier(supplier storage.Supplier) error {
+	if strings.TrimSpace(supplier.Name) == "" {
+		return errors.New("name is required")
+	}
+	if supplier.Email != "" {
+		if _, err := mail.ParseAddress(supplier.Email); err != nil {
+			return errors.New("invalid email")
+		}
+	}
+	if supplier.Lead_time_days < 0 {
+		return errors.New("lead time must not be negative")
+	}
+	return nil
+}
@@ -26,16 +26,25 @@ func GetProductQuantity(service Service, ids []string) (int, []*storage.Product,
 			continue
 		}
 
+		variants, err := service.GetVariants(idStr)
+		if err != nil {
+			return 0.0, nil, err
+		}
+		if len(variants) != 0 {
+			return 0.0, nil, errors.New("product ID:" + idStr + " has variants, quote one of its variants")
+		}
+
 		mapProdQtd[idStr]++
 		if mapProdQtd[idStr] > product.Quantity {
 			return 0.0, nil, errors.New("not enough stock for product ID:" + idStr)
 		}
 
-		*product.Is_published = true
-		_, err = service.Update(*product)
+		// only is_published is written, a full update would put back what another request changed since the read
+		published, err := service.Patch(idStr, map[string]interface{}{"is_published": true})
 		if err != nil {
 			return 0.0, nil, err
 		}
+		product.Is_published = published.Is_published
 
 		quantity++
 		products = append(products, product)
@@ -54,6 +63,8 @@ func GetProductQuantityTotal(service Service) (int, []*storage.Product, error) {
 		return 0.0, nil, err
 	}
 
+	products = StockUnits(products)
+
 	var quantity int
 	for _, product := range products {
 		quantity += product.Quantity
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"aula4/internal/repository/storage"
+	"errors"
+)
+
+// GetVariants lists the variants of a parent product
+func (s *ServiceProducts) GetVariants(id string) ([]*storage.Product, error) {
+	if _, err := s.Repository.GetById(id); err != nil {
+		return nil, err
+	}
+
+	products, err := getAllProducts(s.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	return VariantsOf(products, id), nil
+}
+
+// CreateVariant adds a variant under the parent product. Name, expiration,
+// publication, category and tags are inherited from the parent when empty.
+func (s *ServiceProducts) CreateVariant(parentId string, variant storage.Product) (storage.Product, error) {
+	parent, err := s.Repository.GetById(parentId)
+	if err != nil {
+		return storage.Product{}, err
+	}
+
+	if parent.Parent_id != "" {
+		return storage.Product{}, errors.New("a variant cannot have variants")
+	}
+
+	if variant.Name == "" {
+		variant.Name = parent.Name
+	}
+	if variant.Expiration == "" {
+		variant.Expiration = parent.Expiration
+	}
+	if variant.Is_published == nil {
+		isPublished := parent.Is_published != nil && *parent.Is_published
+		variant.Is_published = &isPublished
+	}
+	if variant.Category_id == "" {
+		variant.Category_id = parent.Category_id
+	}
+	if variant.Tags == nil {
+		variant.Tags = parent.Tags
+	}
+	variant.Parent_id = parent.Id
+
+	return s.Create(variant)
+}
+
+// VariantsOf returns the products whose parent is id
+func VariantsOf(products []*storage.Product, id string) []*storage.Product {
+	variants := []*storage.Product{}
+	for _, product := range products {
+		if product.Parent_id == id {
+			variants = append(variants, product)
+		}
+	}
+
+	return variants
+}
+
+// StockUnits drops the parents that have variants, their stock lives in the variants
+func StockUnits(products []*storage.Product) []*storage.Product {
+	parents := make(map[string]bool)
+	for _, product := range products {
+		if product.Parent_id != "" {
+			parents[product.Parent_id] = true
+		}
+	}
+
+	units := []*storage.Product{}
+	for _, product := range products {
+		if !parents[product.Id] {
+			units = append(units, product)
+		}
+	}
+
+	return units
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"slices"
+)
+
+type ServiceWebhooks struct {
+	Repository repository.SubscriberRepository
+}
+
+func NewServiceWebhooks(repository repository.SubscriberRepository) ServiceWebhooks {
+	return ServiceWebhooks{
+		Repository: repository,
+	}
+}
+
+func (s *ServiceWebhooks) GetAll() ([]*storage.Subscriber, error) {
+	return s.Repository.GetAll()
+}
+
+// Subscribe registers a receiver, generating its secret when none is given
+func (s *ServiceWebhooks) Subscribe(subscriber storage.Subscriber) (storage.Subscriber, error) {
+	target, err := url.Parse(subscriber.Url)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return storage.Subscriber{}, errors.New("url must be an absolute http or https url")
+	}
+
+	if len(subscriber.Events) == 0 {
+		return storage.Subscriber{}, errors.New("at least one event is required")
+	}
+
+	var events []string
+	for _, event := range subscriber.Events {
+		if !slices.Contains(EventTypes, event) {
+			return storage.Subscriber{}, errors.New("unknown event " + event)
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	subscriber.Events = events
+
+	if subscriber.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return storage.Subscriber{}, err
+		}
+		subscriber.Secret = hex.EncodeToString(secret)
+	}
+
+	return s.Repository.Create(subscriber)
+}
+
+func (s *ServiceWebhooks) Delete(id string) error {
+	if _, err := s.Repository.GetById(id); err != nil {
+		return err
+	}
+
+	return s.Repository.Delete(id)
+}
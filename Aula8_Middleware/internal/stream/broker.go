@@ -0,0 +1,138 @@
+package stream
+
+import (
+	"aula4/internal/service"
+	"slices"
+	"sync"
+)
+
+// Message is an event with its position in the stream, sent as the SSE id
+type Message struct {
+	Id    uint64
+	Event service.Event
+}
+
+// Filter selects the events a client receives, the zero value selects every event
+type Filter struct {
+	// ProductIds matches the events of these products and of their variants
+	ProductIds []string
+	// Fields matches the product.updated events that changed one of these fields,
+	// other event types always match
+	Fields []string
+}
+
+func (f Filter) Match(event service.Event) bool {
+	if len(f.ProductIds) != 0 &&
+		!slices.Contains(f.ProductIds, event.Product.Id) &&
+		!(event.Product.Parent_id != "" && slices.Contains(f.ProductIds, event.Product.Parent_id)) {
+		return false
+	}
+
+	if len(f.Fields) != 0 && event.Type == service.EventProductUpdated {
+		for _, field := range event.Changed {
+			if slices.Contains(f.Fields, field) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+type client struct {
+	filter   Filter
+	messages chan Message
+}
+
+// Broker keeps the recent events in a bounded log and fans them out to the connected
+// clients. It implements service.Publisher.
+type Broker struct {
+	mu      sync.Mutex
+	size    int
+	buffer  int
+	log     []Message
+	last    uint64
+	clients map[*client]struct{}
+}
+
+// NewBroker keeps the last size events for resuming, 1000 when zero
+func NewBroker(size int) *Broker {
+	if size <= 0 {
+		size = 1000
+	}
+
+	return &Broker{
+		size:    size,
+		buffer:  64,
+		clients: make(map[*client]struct{}),
+	}
+}
+
+// Publish never blocks: a client that cannot keep up is disconnected and
+// resumes from the log when it reconnects with Last-Event-ID.
+func (b *Broker) Publish(event service.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.last++
+	message := Message{Id: b.last, Event: event}
+
+	b.log = append(b.log, message)
+	if len(b.log) > b.size {
+		b.log = b.log[len(b.log)-b.size:]
+	}
+
+	for c := range b.clients {
+		if !c.filter.Match(event) {
+			continue
+		}
+
+		select {
+		case c.messages <- message:
+		default:
+			delete(b.clients, c)
+			close(c.messages)
+		}
+	}
+}
+
+// Subscribe connects a client. When resume is true the logged events after lastId are
+// returned first; events older than the log are lost. The channel is closed when the
+// client is dropped for being slow, cancel disconnects it.
+func (b *Broker) Subscribe(lastId uint64, resume bool, filter Filter) ([]Message, <-chan Message, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var backlog []Message
+	if resume {
+		for _, message := range b.log {
+			if message.Id > lastId && filter.Match(message.Event) {
+				backlog = append(backlog, message)
+			}
+		}
+	}
+
+	c := &client{filter: filter, messages: make(chan Message, b.buffer)}
+	b.clients[c] = struct{}{}
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.clients[c]; ok {
+			delete(b.clients, c)
+			close(c.messages)
+		}
+	}
+
+	return backlog, c.messages, cancel
+}
+
+// Clients returns the number of connected clients
+func (b *Broker) Clients() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.clients)
+}
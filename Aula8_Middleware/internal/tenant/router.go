@@ -0,0 +1,40 @@
+package tenant
+
+import (
+	"aula4/internal/utils"
+	"net/http"
+)
+
+// Router sends every request to the handler of its tenant, each tenant having its own
+// storage, services and caches
+type Router struct {
+	Registry *Registry
+	Handlers map[string]http.Handler
+}
+
+func NewRouter(registry *Registry, handlers map[string]http.Handler) *Router {
+	return &Router{Registry: registry, Handlers: handlers}
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// shared caches must not answer a tenant with the catalogue of another
+	w.Header().Add("Vary", HeaderTenantID)
+
+	tenant, err := rt.Registry.Resolve(r.Header.Get("Token"), r.Header.Get(HeaderTenantID))
+	if err == ErrTenantMismatch {
+		utils.ResponseWithError(w, err, http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		utils.ResponseWithError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	handler, ok := rt.Handlers[tenant.Id]
+	if !ok {
+		utils.ResponseWithError(w, ErrUnknownTenant, http.StatusBadRequest)
+		return
+	}
+
+	handler.ServeHTTP(w, r.WithContext(NewContext(r.Context(), tenant)))
+}
@@ -0,0 +1,131 @@
+package tenant
+
+import (
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"regexp"
+)
+
+// HeaderTenantID names the tenant of a request made with the shared token, or without a
+// token on the public catalogue
+const HeaderTenantID = "X-Tenant-ID"
+
+var (
+	ErrUnknownTenant  = errors.New("unknown tenant")
+	ErrTenantMismatch = errors.New("the token belongs to another tenant")
+)
+
+var tenantIdPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Tenant is a shop with its own catalogue
+type Tenant struct {
+	Id string `json:"id"`
+	// Token authenticates the requests of the tenant and selects it, the TOKEN of the
+	// environment is used with the X-Tenant-ID header when empty
+	Token string `json:"token,omitempty"`
+	// Pricing are the taxes of the quotes of the tenant, service.DefaultPricingTiers when empty
+	Pricing []service.PricingTier `json:"pricing,omitempty"`
+	// CodePattern is the regular expression of the codes of the internal code_format, e.g. ^SKU-[0-9]{6}$
+	CodePattern string `json:"code_pattern,omitempty"`
+}
+
+// Registry holds the tenants, the default one always included
+type Registry struct {
+	tenants map[string]Tenant
+	tokens  map[string]string
+}
+
+// NewRegistry validates the tenants, a missing storage.DefaultTenant is added without a token
+func NewRegistry(tenants ...Tenant) (*Registry, error) {
+	registry := &Registry{
+		tenants: map[string]Tenant{storage.DefaultTenant: {Id: storage.DefaultTenant}},
+		tokens:  make(map[string]string),
+	}
+
+	for _, tenant := range tenants {
+		if !tenantIdPattern.MatchString(tenant.Id) {
+			return nil, errors.New("invalid tenant id " + tenant.Id + ", use lowercase letters, digits, - and _")
+		}
+		if err := service.ValidatePricingTiers(tenant.Pricing); err != nil {
+			return nil, errors.New(tenant.Id + ": " + err.Error())
+		}
+		if _, err := regexp.Compile(tenant.CodePattern); err != nil {
+			return nil, errors.New(tenant.Id + ": invalid code_pattern: " + err.Error())
+		}
+		if tenant.Token != "" {
+			if other, ok := registry.tokens[tenant.Token]; ok {
+				return nil, errors.New(tenant.Id + ": the token is already used by " + other)
+			}
+			registry.tokens[tenant.Token] = tenant.Id
+		}
+
+		registry.tenants[tenant.Id] = tenant
+	}
+
+	return registry, nil
+}
+
+// LoadRegistry reads a JSON list of tenants, an empty path gives the default tenant alone
+func LoadRegistry(path string) (*Registry, error) {
+	if path == "" {
+		return NewRegistry()
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tenants []Tenant
+	if err := json.Unmarshal(raw, &tenants); err != nil {
+		return nil, err
+	}
+
+	return NewRegistry(tenants...)
+}
+
+// Tenants lists every tenant, the default one included
+func (r *Registry) Tenants() []Tenant {
+	tenants := make([]Tenant, 0, len(r.tenants))
+	for _, tenant := range r.tenants {
+		tenants = append(tenants, tenant)
+	}
+	return tenants
+}
+
+// Resolve picks the tenant of the token first, then the one of the X-Tenant-ID header,
+// then the default tenant
+func (r *Registry) Resolve(token string, tenantId string) (Tenant, error) {
+	if id, ok := r.tokens[token]; ok {
+		if tenantId != "" && tenantId != id {
+			return Tenant{}, ErrTenantMismatch
+		}
+		return r.tenants[id], nil
+	}
+
+	if tenantId == "" {
+		return r.tenants[storage.DefaultTenant], nil
+	}
+
+	tenant, ok := r.tenants[tenantId]
+	if !ok {
+		return Tenant{}, ErrUnknownTenant
+	}
+	return tenant, nil
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, tenant Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenant)
+}
+
+// FromContext returns the tenant resolved by Router
+func FromContext(ctx context.Context) (Tenant, bool) {
+	tenant, ok := ctx.Value(contextKey{}).(Tenant)
+	return tenant, ok
+}
@@ -0,0 +1,67 @@
+package tenant
+
+import (
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRegistry(t *testing.T) {
+	tests := []struct {
+		name    string
+		tenants []Tenant
+		wantErr bool
+	}{
+		{name: "No tenants", tenants: nil},
+		{name: "Tenants", tenants: []Tenant{{Id: "shop-a", Token: "a"}, {Id: "shop_b"}}},
+		{name: "Invalid id", tenants: []Tenant{{Id: "../shop"}}, wantErr: true},
+		{name: "Shared token", tenants: []Tenant{{Id: "shop-a", Token: "a"}, {Id: "shop-b", Token: "a"}}, wantErr: true},
+		{name: "Pricing without a first tier", tenants: []Tenant{{Id: "shop-a", Pricing: []service.PricingTier{{MinQuantity: 5, Tax: 1}}}}, wantErr: true},
+		{name: "Negative tax", tenants: []Tenant{{Id: "shop-a", Pricing: []service.PricingTier{{MinQuantity: 0, Tax: -1}}}}, wantErr: true},
+		{name: "Pricing that makes a larger quote cheaper", tenants: []Tenant{{Id: "shop-a", Pricing: []service.PricingTier{{MinQuantity: 0, Tax: 3}, {MinQuantity: 2, Tax: 1}}}}, wantErr: true},
+		{name: "Code pattern", tenants: []Tenant{{Id: "shop-a", CodePattern: `^SKU-[0-9]{6}$`}}},
+		{name: "Invalid code pattern", tenants: []Tenant{{Id: "shop-a", CodePattern: `^SKU-[0-9`}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, err := NewRegistry(tt.tenants...)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, registry.Tenants(), len(tt.tenants)+1, "the default tenant is always there")
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	registry, err := NewRegistry(Tenant{Id: "shop-a", Token: "a"}, Tenant{Id: "shop-b"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		token      string
+		tenantId   string
+		expectedId string
+		expected   error
+	}{
+		{name: "Token", token: "a", expectedId: "shop-a"},
+		{name: "Token and matching header", token: "a", tenantId: "shop-a", expectedId: "shop-a"},
+		{name: "Token and another header", token: "a", tenantId: "shop-b", expected: ErrTenantMismatch},
+		{name: "Header", token: "shared", tenantId: "shop-b", expectedId: "shop-b"},
+		{name: "Unknown header", tenantId: "shop-z", expected: ErrUnknownTenant},
+		{name: "Nothing", token: "shared", expectedId: storage.DefaultTenant},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant, err := registry.Resolve(tt.token, tt.tenantId)
+			require.Equal(t, tt.expected, err)
+			require.Equal(t, tt.expectedId, tenant.Id)
+		})
+	}
+}
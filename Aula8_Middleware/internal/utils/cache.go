@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"aula4/internal/repository/storage"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SetValidators sets the ETag and Last-Modified headers of a response listing the products.
+// The ETag changes whenever a product of the list is written, added or removed.
+func SetValidators(w http.ResponseWriter, products ...*storage.Product) {
+	hash := sha256.New()
+	var lastModified time.Time
+	for _, product := range products {
+		hash.Write([]byte(product.Id + ":" + strconv.Itoa(product.Version) + ";"))
+		if product.Updated_at.After(lastModified) {
+			lastModified = product.Updated_at
+		}
+	}
+
+	w.Header().Set("ETag", `"`+hex.EncodeToString(hash.Sum(nil))[:32]+`"`)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"aula4/internal/repository/storage"
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	MessageCategoryCreated = "Category created"
+	MessageCategoryUpdated = "Category updated"
+	MessageCategoryDeleted = "Category deleted"
+)
+
+type RequestBodyCategory struct {
+	Name      string `json:"name" openapi:"maxLength=128"`
+	Parent_id string `json:"parent_id,omitempty" openapi:"maxLength=36"`
+}
+
+type CategoryData struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	Parent_id string `json:"parent_id,omitempty"`
+}
+
+type ResponseBodyCategory struct {
+	Message string        `json:"message"`
+	Data    *CategoryData `json:"data,omitempty"`
+	Error   bool          `json:"error"`
+}
+
+type CategorySummaryData struct {
+	Count int     `json:"count"`
+	Stock int     `json:"stock"`
+	Value float64 `json:"value"`
+}
+
+type CategoryTreeData struct {
+	CategoryData
+	Summary  CategorySummaryData `json:"summary"`
+	Children []*CategoryTreeData `json:"children"`
+}
+
+func ToCategoryData(category *storage.Category) CategoryData {
+	return CategoryData{
+		Id:        category.Id,
+		Name:      category.Name,
+		Parent_id: category.Parent_id,
+	}
+}
+
+func RespondWithCategory(w http.ResponseWriter, category *storage.Category, statusCode int, message string) {
+	body := &ResponseBodyCategory{
+		Message: message,
+		Error:   false,
+	}
+
+	if category != nil {
+		dt := ToCategoryData(category)
+		body.Data = &dt
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,13 @@
+package utils
+
+import "time"
+
+// EventData is the body of a webhook delivery and the data of a stream event
+type EventData struct {
+	Id          string    `json:"id"`
+	Type        string    `json:"type"`
+	Occurred_at time.Time `json:"occurred_at"`
+	// Changed lists the fields a product.updated event changed
+	Changed []string `json:"changed,omitempty"`
+	Data    Data     `json:"data"`
+}
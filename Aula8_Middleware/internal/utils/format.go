@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeCSV  = "text/csv"
+	ContentTypeXML  = "application/xml"
+	// ContentTypeTextXML is answered with the same body as ContentTypeXML
+	ContentTypeTextXML = "text/xml"
+)
+
+// ContentTypes are the media types of the listings, by order of preference
+var ContentTypes = []string{ContentTypeJSON, ContentTypeCSV, ContentTypeXML, ContentTypeTextXML}
+
+var ErrNotAcceptable = errors.New("the listings are available as " + ContentTypeJSON + ", " + ContentTypeCSV + " and " + ContentTypeXML)
+
+// StringMap is a map of strings written in XML as <entry key="size">M</entry> elements,
+// encoding/xml does not support maps
+type StringMap map[string]string
+
+func (m StringMap) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for _, key := range m.keys() {
+		entry := xml.StartElement{Name: xml.Name{Local: "entry"}, Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: key}}}
+		if err := e.EncodeElement(m[key], entry); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// String writes the map as key=value pairs separated by semicolons, sorted by key
+func (m StringMap) String() string {
+	pairs := make([]string, 0, len(m))
+	for _, key := range m.keys() {
+		pairs = append(pairs, key+"="+m[key])
+	}
+	return strings.Join(pairs, ";")
+}
+
+func (m StringMap) keys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Negotiate returns the media type of ContentTypes that the Accept header prefers,
+// ContentTypeJSON when the header is empty and ErrNotAcceptable when none is accepted.
+// The most specific range sets the quality of a media type, so "*/*, text/csv;q=0" refuses CSV.
+func Negotiate(accept string) (string, error) {
+	if strings.TrimSpace(accept) == "" {
+		return ContentTypeJSON, nil
+	}
+
+	type mediaRange struct {
+		mediaType string
+		quality   float64
+	}
+
+	var ranges []mediaRange
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+	}
+
+	best, bestQuality := "", 0.0
+	for _, offer := range ContentTypes {
+		quality, specificity := 0.0, -1
+		for _, r := range ranges {
+			if s := matchMediaType(r.mediaType, offer); s > specificity {
+				quality, specificity = r.quality, s
+			}
+		}
+
+		if quality > bestQuality {
+			best, bestQuality = offer, quality
+		}
+	}
+
+	if best == "" {
+		return "", ErrNotAcceptable
+	}
+	return best, nil
+}
+
+// matchMediaType returns how specifically the range matches the media type, -1 when it does not
+func matchMediaType(mediaRange, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	default:
+		return -1
+	}
+}
+
+// Formatted is a response body available in every media type of ContentTypes
+type Formatted struct {
+	JSON any
+	XML  any
+	// CSV is the header line followed by one record per row
+	CSV [][]string
+}
+
+// RespondFormatted writes the body in the negotiated media type. The representations of a
+// resource get different ETags, the JSON one keeps the ETag set by the handler.
+func RespondFormatted(w http.ResponseWriter, contentType string, statusCode int, body Formatted) {
+	w.Header().Add("Vary", "Accept")
+	if etag := w.Header().Get("ETag"); etag != "" && contentType != ContentTypeJSON {
+		suffix := strings.NewReplacer("/", "-").Replace(contentType)
+		w.Header().Set("ETag", strings.TrimSuffix(etag, `"`)+"-"+suffix+`"`)
+	}
+
+	switch contentType {
+	case ContentTypeCSV:
+		w.Header().Set("Content-Type", ContentTypeCSV+"; charset=utf-8")
+		w.WriteHeader(statusCode)
+		writer := csv.NewWriter(w)
+		writer.WriteAll(escapeFormulas(body.CSV))
+	case ContentTypeXML, ContentTypeTextXML:
+		w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+		w.WriteHeader(statusCode)
+		w.Write([]byte(xml.Header))
+		xml.NewEncoder(w).Encode(body.XML)
+	default:
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(body.JSON)
+	}
+}
+
+// escapeFormulas prefixes with ' the cells a spreadsheet would run as a formula, so an opened
+// export cannot run the text of a product
+func escapeFormulas(records [][]string) [][]string {
+	escaped := make([][]string, len(records))
+	for i, record := range records {
+		escaped[i] = make([]string, len(record))
+		for j, cell := range record {
+			if cell != "" && strings.ContainsRune("=+-@", rune(cell[0])) {
+				cell = "'" + cell
+			}
+			escaped[i][j] = cell
+		}
+	}
+	return escaped
+}
+
+// DataCSVHeader names the columns of DataCSVRecord
+var DataCSVHeader = []string{"id", "name", "quantity", "code_value", "is_published", "expiration", "price", "category_id", "tags", "parent_id", "attributes", "low_stock_threshold", "publish_at", "unpublish_at"}
+
+// DataCSVRecord is a product as a CSV record, the tags are separated by "|"
+func DataCSVRecord(data Data) []string {
+	return []string{
+		data.Id,
+		data.Name,
+		strconv.Itoa(data.Quantity),
+		data.Code_value,
+		strconv.FormatBool(data.Is_published),
+		data.Expiration,
+		strconv.FormatFloat(data.Price, 'f', -1, 64),
+		data.Category_id,
+		strings.Join(data.Tags, "|"),
+		data.Parent_id,
+		StringMap(data.Attributes).String(),
+		strconv.Itoa(data.Low_stock_threshold),
+		formatTime(data.Publish_at),
+		formatTime(data.Unpublish_at),
+	}
+}
+
+// formatTime writes an optional time in RFC 3339, empty when it is not set
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// ProductsXML is the XML root of a list of products
+type ProductsXML struct {
+	XMLName  xml.Name `xml:"products"`
+	Products []Data   `xml:"product"`
+}
@@ -0,0 +1,20 @@
+package utils
+
+import "encoding/json"
+
+type RequestBodyGraphQL struct {
+	Query         string         `json:"query" openapi:"maxLength=65536"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// ResponseBodyGraphQL is the standard GraphQL response, errors are reported here with a 200 status
+type ResponseBodyGraphQL struct {
+	Data   json.RawMessage `json:"data,omitempty"`
+	Errors []GraphQLError  `json:"errors,omitempty"`
+}
+
+type GraphQLError struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
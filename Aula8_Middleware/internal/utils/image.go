@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"aula4/internal/repository/storage"
+	"time"
+)
+
+// ImageData is an image of a product with the URLs serving it, relative to the API
+type ImageData struct {
+	Id            string    `json:"id" xml:"id"`
+	Url           string    `json:"url" xml:"url"`
+	Thumbnail_url string    `json:"thumbnail_url" xml:"thumbnail_url"`
+	Content_type  string    `json:"content_type" xml:"content_type"`
+	Size          int64     `json:"size" xml:"size"`
+	Width         int       `json:"width" xml:"width"`
+	Height        int       `json:"height" xml:"height"`
+	Primary       bool      `json:"primary" xml:"primary"`
+	Created_at    time.Time `json:"created_at" xml:"created_at"`
+}
+
+// RequestBodyImageOrder lists every image of a product in the new display order
+type RequestBodyImageOrder struct {
+	Ids []string `json:"ids"`
+}
+
+// ImageURL is the path of an image under prefix, /products for the API and /catalogue
+// for the public router
+func ImageURL(prefix string, productId string, imageId string) string {
+	return prefix + "/" + productId + "/images/" + imageId
+}
+
+func ToImageData(prefix string, productId string, image storage.Image) ImageData {
+	url := ImageURL(prefix, productId, image.Id)
+	return ImageData{
+		Id:            image.Id,
+		Url:           url,
+		Thumbnail_url: url + "/thumbnail",
+		Content_type:  image.Content_type,
+		Size:          image.Size,
+		Width:         image.Width,
+		Height:        image.Height,
+		Primary:       image.Primary,
+		Created_at:    image.Created_at,
+	}
+}
+
+// ToImagesData keeps the display order, nil without images
+func ToImagesData(prefix string, productId string, images []storage.Image) []ImageData {
+	var data []ImageData
+	for _, image := range images {
+		data = append(data, ToImageData(prefix, productId, image))
+	}
+	return data
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"aula4/internal/repository/storage"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+const (
+	MessageMovementRecorded = "Movement recorded"
+)
+
+type RequestBodyMovement struct {
+	Type string `json:"type" openapi:"enum=receipt|sale|adjustment|return"`
+	// Quantity is positive for receipts, sales and returns and signed for adjustments
+	Quantity int    `json:"quantity"`
+	Reason   string `json:"reason,omitempty" openapi:"maxLength=256"`
+	Actor    string `json:"actor" openapi:"maxLength=64"`
+}
+
+type MovementData struct {
+	Id         string    `json:"id"`
+	Product_id string    `json:"product_id"`
+	Type       string    `json:"type"`
+	Quantity   int       `json:"quantity"`
+	Reason     string    `json:"reason,omitempty"`
+	Actor      string    `json:"actor"`
+	Created_at time.Time `json:"created_at"`
+}
+
+type ResponseBodyMovement struct {
+	Message string        `json:"message"`
+	Data    *MovementData `json:"data,omitempty"`
+	Error   bool          `json:"error"`
+}
+
+type LedgerData struct {
+	Product_id string         `json:"product_id"`
+	Quantity   int            `json:"quantity"`
+	Balance    int            `json:"balance"`
+	Reconciled bool           `json:"reconciled"`
+	Movements  []MovementData `json:"movements"`
+}
+
+func ToMovementData(movement *storage.Movement) MovementData {
+	return MovementData{
+		Id:         movement.Id,
+		Product_id: movement.Product_id,
+		Type:       movement.Type,
+		Quantity:   movement.Quantity,
+		Reason:     movement.Reason,
+		Actor:      movement.Actor,
+		Created_at: movement.Created_at,
+	}
+}
+
+func RespondWithMovement(w http.ResponseWriter, movement *storage.Movement, statusCode int, message string) {
+	body := &ResponseBodyMovement{
+		Message: message,
+		Error:   false,
+	}
+
+	if movement != nil {
+		dt := ToMovementData(movement)
+		body.Data = &dt
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"aula4/internal/repository/storage"
+	"time"
+)
+
+// RequestBodyPrice schedules a price, effective_at must be in the future
+type RequestBodyPrice struct {
+	Price        float64   `json:"price" openapi:"minimum=0"`
+	Effective_at time.Time `json:"effective_at"`
+}
+
+// PriceChangeData is a change of the price timeline, its status is past, current or scheduled
+type PriceChangeData struct {
+	Id           string    `json:"id"`
+	Price        float64   `json:"price"`
+	Effective_at time.Time `json:"effective_at"`
+	Created_at   time.Time `json:"created_at"`
+	Status       string    `json:"status,omitempty" openapi:"enum=past|current|scheduled"`
+}
+
+// PriceTimelineData is the price of a product in effect now with every change, the earliest first
+type PriceTimelineData struct {
+	Product_id string            `json:"product_id"`
+	Price      float64           `json:"price"`
+	Prices     []PriceChangeData `json:"prices"`
+}
+
+func ToPriceChangeData(change storage.PriceChange, status string) PriceChangeData {
+	return PriceChangeData{
+		Id:           change.Id,
+		Price:        change.Price,
+		Effective_at: change.Effective_at,
+		Created_at:   change.Created_at,
+		Status:       status,
+	}
+}
@@ -1,11 +1,14 @@
 package utils
 
 import (
+	"aula4/internal/barcode"
 	"aula4/internal/repository/storage"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,22 +21,101 @@ const (
 )
 
 type RequestBodyProduct struct {
-	Name         string  `json:"name"`
-	Quantity     int     `json:"quantity"`
-	Code_value   string  `json:"code_value"`
-	Is_published *bool   `json:"is_published"`
-	Expiration   string  `json:"expiration"`
-	Price        float64 `json:"price"`
+	Name         string   `json:"name" openapi:"maxLength=128"`
+	Quantity     int      `json:"quantity" openapi:"minimum=0"`
+	Code_value   string   `json:"code_value" openapi:"maxLength=64"`
+	Code_format  string   `json:"code_format,omitempty" openapi:"enum=ean13|upca|gtin14|internal"`
+	Is_published *bool    `json:"is_published"`
+	Expiration   string   `json:"expiration" openapi:"maxLength=10"`
+	Price        float64  `json:"price" openapi:"minimum=0"`
+	Category_id  string   `json:"category_id,omitempty" openapi:"maxLength=36"`
+	Tags         []string `json:"tags,omitempty"`
+	// Attributes tell variants apart, e.g. {"size": "M", "color": "blue"}
+	Attributes          map[string]string `json:"attributes,omitempty"`
+	Low_stock_threshold int               `json:"low_stock_threshold,omitempty" openapi:"minimum=0"`
+	// Publish_at and Unpublish_at schedule a change of is_published, in RFC 3339
+	Publish_at   *time.Time `json:"publish_at,omitempty"`
+	Unpublish_at *time.Time `json:"unpublish_at,omitempty"`
+}
+
+type RequestBodyVariant struct {
+	Name         string            `json:"name,omitempty" openapi:"maxLength=128"`
+	Quantity     int               `json:"quantity" openapi:"minimum=0"`
+	Code_value   string            `json:"code_value" openapi:"maxLength=64"`
+	Code_format  string            `json:"code_format,omitempty" openapi:"enum=ean13|upca|gtin14|internal"`
+	Is_published *bool             `json:"is_published"`
+	Expiration   string            `json:"expiration,omitempty" openapi:"maxLength=10"`
+	Price        float64           `json:"price" openapi:"minimum=0"`
+	Attributes   map[string]string `json:"attributes"`
 }
 
 type Data struct {
-	Id           string  `json:"id"`
-	Name         string  `json:"name"`
-	Quantity     int     `json:"quantity"`
-	Code_value   string  `json:"code_value"`
-	Is_published bool    `json:"is_published"`
-	Expiration   string  `json:"expiration"`
-	Price        float64 `json:"price"`
+	Id           string   `json:"id" xml:"id"`
+	Name         string   `json:"name" xml:"name"`
+	Quantity     int      `json:"quantity" xml:"quantity"`
+	Code_value   string   `json:"code_value" xml:"code_value"`
+	Code_format  string   `json:"code_format,omitempty" xml:"code_format,omitempty"`
+	Is_published bool     `json:"is_published" xml:"is_published"`
+	Expiration   string   `json:"expiration" xml:"expiration"`
+	Price        float64  `json:"price" xml:"price"`
+	Category_id  string   `json:"category_id,omitempty" xml:"category_id,omitempty"`
+	Tags         []string `json:"tags,omitempty" xml:"tags>tag,omitempty"`
+	Parent_id    string   `json:"parent_id,omitempty" xml:"parent_id,omitempty"`
+	// Attributes tell variants apart, e.g. {"size": "M", "color": "blue"}
+	Attributes          map[string]string `json:"attributes,omitempty" xml:"-"`
+	Low_stock_threshold int               `json:"low_stock_threshold,omitempty" xml:"low_stock_threshold,omitempty"`
+	Publish_at          *time.Time        `json:"publish_at,omitempty" xml:"publish_at,omitempty"`
+	Unpublish_at        *time.Time        `json:"unpublish_at,omitempty" xml:"unpublish_at,omitempty"`
+	Images              []ImageData       `json:"images,omitempty" xml:"images>image,omitempty"`
+}
+
+// MarshalXML writes the attributes as a StringMap
+func (d Data) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type data Data
+	return e.EncodeElement(struct {
+		data
+		Attributes StringMap `xml:"attributes,omitempty"`
+	}{data(d), d.Attributes}, start)
+}
+
+// ProductWithVariants is a product with its variants embedded, sent as a ProductWithVariantsView
+type ProductWithVariants struct {
+	*storage.Product
+	Variants []*storage.Product `json:",omitempty"`
+}
+
+// MarshalJSON sends the images of the product and of its variants with their URLs
+func (p ProductWithVariants) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ProductWithVariantsView{
+		ProductView: ToProductView(p.Product),
+		Variants:    ToProductViews(p.Variants),
+	})
+}
+
+// ProductView is a stored product as the JSON reads send it, the images with the URLs
+// serving them like in Data
+type ProductView struct {
+	*storage.Product
+	Images []ImageData
+}
+
+// ProductWithVariantsView is the JSON of a ProductWithVariants
+type ProductWithVariantsView struct {
+	ProductView
+	Variants []ProductView `json:",omitempty"`
+}
+
+func ToProductView(product *storage.Product) ProductView {
+	return ProductView{Product: product, Images: ToImagesData("/products", product.Id, product.Images)}
+}
+
+// ToProductViews keeps the order of the products
+func ToProductViews(products []*storage.Product) []ProductView {
+	views := make([]ProductView, 0, len(products))
+	for _, product := range products {
+		views = append(views, ToProductView(product))
+	}
+	return views
 }
 
 type ResponseBodyProduct struct {
@@ -42,18 +124,39 @@ type ResponseBodyProduct struct {
 	Error   bool   `json:"error"`
 }
 
+// SearchHitData is a product found by the full-text search
+type SearchHitData struct {
+	Score float64 `json:"score" xml:"score"`
+	// Highlights maps the matching fields to their value with the matched words in <em> tags
+	Highlights StringMap `json:"highlights" xml:"highlights"`
+	Data       Data      `json:"data" xml:"data"`
+}
+
+type ResponseBodySearch struct {
+	XMLName   xml.Name        `json:"-" xml:"search"`
+	Query     string          `json:"query" xml:"query"`
+	Total     int             `json:"total" xml:"total"`
+	Page      int             `json:"page" xml:"page"`
+	Page_size int             `json:"page_size" xml:"page_size"`
+	Results   []SearchHitData `json:"results" xml:"results>result"`
+}
+
 type ResponseBodyTotalPrice struct {
-	Products   []*Data `json:"products,omitempty"`
-	TotalPrice float64 `json:"total_price"`
+	XMLName    xml.Name `json:"-" xml:"consumer_price"`
+	Products   []*Data  `json:"products,omitempty" xml:"products>product,omitempty"`
+	TotalPrice float64  `json:"total_price" xml:"total_price"`
 }
 
+// CheckUniqueCodeValue compares the codes by their barcode.Key, so the UPC-A and the EAN-13
+// of an item are the same code
 func CheckUniqueCodeValue(products []*storage.Product, prod storage.Product) error {
+	key := barcode.Key(prod.Code_format, prod.Code_value)
 	for _, product := range products {
 		if product.Id == prod.Id {
 			continue
 		}
 
-		if product.Code_value == prod.Code_value {
+		if barcode.Key(product.Code_format, product.Code_value) == key {
 			return errors.New("the code_value must be unique")
 		}
 	}
@@ -61,6 +164,23 @@ func CheckUniqueCodeValue(products []*storage.Product, prod storage.Product) err
 	return nil
 }
 
+// NormalizeTags lower cases and trims tags, dropping empty and repeated ones
+func NormalizeTags(tags []string) []string {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	return normalized
+}
+
 func ValidateUUID(id string) error {
 	if _, err := uuid.Parse(id); err != nil {
 		return fmt.Errorf("invalid UUID format: %s", id)
@@ -107,15 +227,7 @@ func RespondWithProduct(w http.ResponseWriter, product *storage.Product, statusC
 			Error:   false,
 		}
 	} else {
-		dt := Data{
-			Id:           product.Id,
-			Name:         product.Name,
-			Code_value:   product.Code_value,
-			Is_published: *product.Is_published,
-			Expiration:   product.Expiration,
-			Quantity:     product.Quantity,
-			Price:        product.Price,
-		}
+		dt := ToData(product)
 
 		body = &ResponseBodyProduct{
 			Message: message,
@@ -128,3 +240,25 @@ func RespondWithProduct(w http.ResponseWriter, product *storage.Product, statusC
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(body)
 }
+
+func ToData(product *storage.Product) Data {
+	return Data{
+		Id:           product.Id,
+		Name:         product.Name,
+		Code_value:   product.Code_value,
+		Code_format:  product.Code_format,
+		Is_published: product.Is_published != nil && *product.Is_published,
+		Expiration:   product.Expiration,
+		Quantity:     product.Quantity,
+		Price:        product.Price,
+		Category_id:  product.Category_id,
+		Tags:         product.Tags,
+		Parent_id:    product.Parent_id,
+		Attributes:   product.Attributes,
+
+		Low_stock_threshold: product.Low_stock_threshold,
+		Publish_at:          product.Publish_at,
+		Unpublish_at:        product.Unpublish_at,
+		Images:              ToImagesData("/products", product.Id, product.Images),
+	}
+}
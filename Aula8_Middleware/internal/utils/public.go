@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"aula4/internal/repository/storage"
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// PublicData is a product as the public catalogue shows it, without the stock level,
+// the visibility flags or the publishing window
+type PublicData struct {
+	Id          string   `json:"id" xml:"id"`
+	Name        string   `json:"name" xml:"name"`
+	Code_value  string   `json:"code_value" xml:"code_value"`
+	Code_format string   `json:"code_format,omitempty" xml:"code_format,omitempty"`
+	Expiration  string   `json:"expiration" xml:"expiration"`
+	Price       float64  `json:"price" xml:"price"`
+	Category_id string   `json:"category_id,omitempty" xml:"category_id,omitempty"`
+	Tags        []string `json:"tags,omitempty" xml:"tags>tag,omitempty"`
+	Parent_id   string   `json:"parent_id,omitempty" xml:"parent_id,omitempty"`
+	// Attributes tell variants apart, e.g. {"size": "M", "color": "blue"}
+	Attributes map[string]string `json:"attributes,omitempty" xml:"-"`
+	In_stock   bool              `json:"in_stock" xml:"in_stock"`
+	Images     []ImageData       `json:"images,omitempty" xml:"images>image,omitempty"`
+}
+
+// MarshalXML writes the attributes as a StringMap
+func (d PublicData) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type data PublicData
+	return e.EncodeElement(struct {
+		data
+		Attributes StringMap `xml:"attributes,omitempty"`
+	}{data(d), d.Attributes}, start)
+}
+
+func ToPublicData(product *storage.Product) PublicData {
+	return PublicData{
+		Id:          product.Id,
+		Name:        product.Name,
+		Code_value:  product.Code_value,
+		Code_format: product.Code_format,
+		Expiration:  product.Expiration,
+		Price:       product.Price,
+		Category_id: product.Category_id,
+		Tags:        product.Tags,
+		Parent_id:   product.Parent_id,
+		Attributes:  product.Attributes,
+		In_stock:    product.Quantity > 0,
+		Images:      ToImagesData("/catalogue", product.Id, product.Images),
+	}
+}
+
+// PublicProductsXML is the XML root of a public listing
+type PublicProductsXML struct {
+	XMLName  xml.Name     `xml:"products"`
+	Products []PublicData `xml:"product"`
+}
+
+// PublicDataCSVHeader names the columns of PublicDataCSVRecord
+var PublicDataCSVHeader = []string{"id", "name", "code_value", "expiration", "price", "category_id", "tags", "parent_id", "attributes", "in_stock"}
+
+// PublicDataCSVRecord is a public product as a CSV record, the tags are separated by "|"
+func PublicDataCSVRecord(data PublicData) []string {
+	return []string{
+		data.Id,
+		data.Name,
+		data.Code_value,
+		data.Expiration,
+		strconv.FormatFloat(data.Price, 'f', -1, 64),
+		data.Category_id,
+		strings.Join(data.Tags, "|"),
+		data.Parent_id,
+		StringMap(data.Attributes).String(),
+		strconv.FormatBool(data.In_stock),
+	}
+}
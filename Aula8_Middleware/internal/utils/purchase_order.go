@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"aula4/internal/repository/storage"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+const (
+	MessagePurchaseOrderCreated   = "Purchase order created"
+	MessagePurchaseOrderReceived  = "Purchase order received"
+	MessagePurchaseOrderCancelled = "Purchase order cancelled"
+)
+
+type RequestBodyPurchaseOrderLine struct {
+	Product_id string  `json:"product_id" openapi:"maxLength=36"`
+	Quantity   int     `json:"quantity" openapi:"minimum=1"`
+	Unit_cost  float64 `json:"unit_cost,omitempty" openapi:"minimum=0"`
+}
+
+type RequestBodyPurchaseOrder struct {
+	Supplier_id string                         `json:"supplier_id" openapi:"maxLength=36"`
+	Reference   string                         `json:"reference,omitempty" openapi:"maxLength=64"`
+	Lines       []RequestBodyPurchaseOrderLine `json:"lines"`
+	// Expected_at is the delivery date announced by the supplier, in RFC 3339
+	Expected_at *time.Time `json:"expected_at,omitempty"`
+}
+
+type RequestBodyReceiptLine struct {
+	Product_id string `json:"product_id" openapi:"maxLength=36"`
+	Quantity   int    `json:"quantity" openapi:"minimum=1"`
+}
+
+type RequestBodyReceipt struct {
+	Actor string `json:"actor" openapi:"maxLength=64"`
+	// Lines is the delivered quantities, the whole outstanding quantity of the order when empty
+	Lines []RequestBodyReceiptLine `json:"lines,omitempty"`
+}
+
+type PurchaseOrderLineData struct {
+	Product_id  string  `json:"product_id"`
+	Quantity    int     `json:"quantity"`
+	Received    int     `json:"received"`
+	Outstanding int     `json:"outstanding"`
+	Unit_cost   float64 `json:"unit_cost"`
+}
+
+type PurchaseOrderData struct {
+	Id          string                  `json:"id"`
+	Supplier_id string                  `json:"supplier_id"`
+	Reference   string                  `json:"reference,omitempty"`
+	Status      string                  `json:"status"`
+	Lines       []PurchaseOrderLineData `json:"lines"`
+	// Total is the sum of quantity * unit_cost of the lines
+	Total       float64    `json:"total"`
+	Expected_at *time.Time `json:"expected_at,omitempty"`
+	Created_at  time.Time  `json:"created_at"`
+	Closed_at   *time.Time `json:"closed_at,omitempty"`
+}
+
+type ResponseBodyPurchaseOrder struct {
+	Message string             `json:"message"`
+	Data    *PurchaseOrderData `json:"data,omitempty"`
+	Error   bool               `json:"error"`
+}
+
+type InboundOrderData struct {
+	Order_id    string     `json:"order_id"`
+	Supplier_id string     `json:"supplier_id"`
+	Quantity    int        `json:"quantity"`
+	Expected_at *time.Time `json:"expected_at,omitempty"`
+}
+
+type InboundData struct {
+	Product_id string             `json:"product_id"`
+	Quantity   int                `json:"quantity"`
+	Orders     []InboundOrderData `json:"orders"`
+}
+
+func ToPurchaseOrderData(order *storage.PurchaseOrder) PurchaseOrderData {
+	data := PurchaseOrderData{
+		Id:          order.Id,
+		Supplier_id: order.Supplier_id,
+		Reference:   order.Reference,
+		Status:      order.Status,
+		Lines:       []PurchaseOrderLineData{},
+		Expected_at: order.Expected_at,
+		Created_at:  order.Created_at,
+		Closed_at:   order.Closed_at,
+	}
+
+	for _, line := range order.Lines {
+		// a closed order has nothing outstanding, a cancelled one keeps what it received
+		outstanding := 0
+		if order.Status == storage.PurchaseOrderOpen || order.Status == storage.PurchaseOrderPartiallyReceived {
+			outstanding = line.Quantity - line.Received
+		}
+		data.Lines = append(data.Lines, PurchaseOrderLineData{
+			Product_id:  line.Product_id,
+			Quantity:    line.Quantity,
+			Received:    line.Received,
+			Outstanding: outstanding,
+			Unit_cost:   line.Unit_cost,
+		})
+		data.Total += float64(line.Quantity) * line.Unit_cost
+	}
+
+	return data
+}
+
+func RespondWithPurchaseOrder(w http.ResponseWriter, order *storage.PurchaseOrder, statusCode int, message string) {
+	body := &ResponseBodyPurchaseOrder{
+		Message: message,
+		Error:   false,
+	}
+
+	if order != nil {
+		dt := ToPurchaseOrderData(order)
+		body.Data = &dt
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
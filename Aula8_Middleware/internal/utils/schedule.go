@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"aula4/internal/repository/storage"
+	"time"
+)
+
+// ScheduledChangeData is a pending publish or unpublish of a product
+type ScheduledChangeData struct {
+	Product_id string    `json:"product_id"`
+	Code_value string    `json:"code_value"`
+	Name       string    `json:"name"`
+	Action     string    `json:"action" openapi:"enum=publish|unpublish"`
+	At         time.Time `json:"at"`
+	// Due changes are waiting for the next run of the scheduler, the public listings already apply them
+	Due bool `json:"due"`
+}
+
+func ToScheduledChangeData(product *storage.Product, action string, at time.Time, due bool) ScheduledChangeData {
+	return ScheduledChangeData{
+		Product_id: product.Id,
+		Code_value: product.Code_value,
+		Name:       product.Name,
+		Action:     action,
+		At:         at,
+		Due:        due,
+	}
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"aula4/internal/repository/storage"
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	MessageSupplierCreated = "Supplier created"
+	MessageSupplierUpdated = "Supplier updated"
+	MessageSupplierDeleted = "Supplier deleted"
+)
+
+type RequestBodySupplier struct {
+	Name           string `json:"name" openapi:"maxLength=128"`
+	Email          string `json:"email,omitempty" openapi:"maxLength=254"`
+	Phone          string `json:"phone,omitempty" openapi:"maxLength=32"`
+	Lead_time_days int    `json:"lead_time_days,omitempty" openapi:"minimum=0"`
+}
+
+type SupplierData struct {
+	Id             string `json:"id"`
+	Name           string `json:"name"`
+	Email          string `json:"email,omitempty"`
+	Phone          string `json:"phone,omitempty"`
+	Lead_time_days int    `json:"lead_time_days"`
+}
+
+type ResponseBodySupplier struct {
+	Message string        `json:"message"`
+	Data    *SupplierData `json:"data,omitempty"`
+	Error   bool          `json:"error"`
+}
+
+func ToSupplierData(supplier *storage.Supplier) SupplierData {
+	return SupplierData{
+		Id:             supplier.Id,
+		Name:           supplier.Name,
+		Email:          supplier.Email,
+		Phone:          supplier.Phone,
+		Lead_time_days: supplier.Lead_time_days,
+	}
+}
+
+func RespondWithSupplier(w http.ResponseWriter, supplier *storage.Supplier, statusCode int, message string) {
+	body := &ResponseBodySupplier{
+		Message: message,
+		Error:   false,
+	}
+
+	if supplier != nil {
+		dt := ToSupplierData(supplier)
+		body.Data = &dt
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"aula4/internal/repository/storage"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+const (
+	MessageSubscriberCreated = "Subscriber created"
+	MessageSubscriberDeleted = "Subscriber deleted"
+)
+
+type RequestBodySubscriber struct {
+	Url    string   `json:"url" openapi:"maxLength=2048"`
+	Events []string `json:"events"`
+	// Secret signs the deliveries, generated when empty
+	Secret string `json:"secret,omitempty" openapi:"maxLength=256"`
+}
+
+type SubscriberData struct {
+	Id     string   `json:"id"`
+	Url    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret is only returned when the subscriber is created
+	Secret     string    `json:"secret,omitempty"`
+	Created_at time.Time `json:"created_at"`
+}
+
+type ResponseBodySubscriber struct {
+	Message string          `json:"message"`
+	Data    *SubscriberData `json:"data,omitempty"`
+	Error   bool            `json:"error"`
+}
+
+func ToSubscriberData(subscriber *storage.Subscriber) SubscriberData {
+	return SubscriberData{
+		Id:         subscriber.Id,
+		Url:        subscriber.Url,
+		Events:     subscriber.Events,
+		Created_at: subscriber.Created_at,
+	}
+}
+
+func RespondWithSubscriber(w http.ResponseWriter, data *SubscriberData, statusCode int, message string) {
+	body := &ResponseBodySubscriber{
+		Message: message,
+		Data:    data,
+		Error:   false,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
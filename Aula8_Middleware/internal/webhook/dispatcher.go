@@ -0,0 +1,336 @@
+package webhook
+
+import (
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderId        = "X-Webhook-Id"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+type Config struct {
+	// Workers deliver events concurrently, 4 when zero
+	Workers int
+	// QueueSize bounds the deliveries waiting for a worker, 1024 when zero
+	QueueSize int
+	// MaxAttempts before a delivery goes to the dead-letter list, 5 when zero
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry, doubled on every retry, 1s when zero
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between retries, 1m when zero
+	MaxDelay time.Duration
+	// Timeout of every request, 10s when zero
+	Timeout time.Duration
+	// LogSize bounds the delivery log and the dead-letter list, 1000 when zero
+	LogSize int
+}
+
+// Delivery is one attempt to post an event to a subscriber
+type Delivery struct {
+	Id            string    `json:"id"`
+	Event_id      string    `json:"event_id"`
+	Event_type    string    `json:"event_type"`
+	Subscriber_id string    `json:"subscriber_id"`
+	Url           string    `json:"url"`
+	Attempt       int       `json:"attempt"`
+	Status_code   int       `json:"status_code,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	Delivered     bool      `json:"delivered"`
+	Created_at    time.Time `json:"created_at"`
+}
+
+// DeadLetter is an event a subscriber did not accept after every attempt
+type DeadLetter struct {
+	Event_id      string          `json:"event_id"`
+	Event_type    string          `json:"event_type"`
+	Subscriber_id string          `json:"subscriber_id"`
+	Url           string          `json:"url"`
+	Attempts      int             `json:"attempts"`
+	Error         string          `json:"error"`
+	Payload       json.RawMessage `json:"payload"`
+	Failed_at     time.Time       `json:"failed_at"`
+}
+
+type job struct {
+	event      service.Event
+	subscriber storage.Subscriber
+	body       []byte
+	// attempts made so far and the error of the last one
+	attempts int
+	err      error
+}
+
+// Dispatcher delivers the events of the services to the subscribers in the background.
+// It implements service.Publisher.
+type Dispatcher struct {
+	Subscribers repository.SubscriberRepository
+	Client      *http.Client
+
+	config Config
+	queue  chan job
+	wg     sync.WaitGroup
+	// retries counts the retries waiting for their backoff, ctx cancels them on Stop
+	retries sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	mu          sync.Mutex
+	closed      bool
+	deliveries  []Delivery
+	deadLetters []DeadLetter
+}
+
+func NewDispatcher(subscribers repository.SubscriberRepository, config Config) *Dispatcher {
+	if config.Workers <= 0 {
+		config.Workers = 4
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 1024
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.BaseDelay <= 0 {
+		config.BaseDelay = time.Second
+	}
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = time.Minute
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.LogSize <= 0 {
+		config.LogSize = 1000
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		Subscribers: subscribers,
+		Client:      &http.Client{Timeout: config.Timeout},
+		config:      config,
+		queue:       make(chan job, config.QueueSize),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Start runs the workers
+func (d *Dispatcher) Start() {
+	for i := 0; i < d.config.Workers; i++ {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for j := range d.queue {
+				d.deliver(j)
+			}
+		}()
+	}
+}
+
+// Stop refuses new events and waits for the queued ones. The retries waiting for their
+// backoff are cancelled and go to the dead-letter list.
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	d.cancel()
+	d.wg.Wait()
+	d.retries.Wait()
+}
+
+// Publish queues the event for every subscriber of its type
+func (d *Dispatcher) Publish(event service.Event) {
+	subscribers, err := d.Subscribers.GetAll()
+	if err != nil {
+		log.Printf("webhook: could not read subscribers for event %s: %v", event.Id, err)
+		return
+	}
+
+	body, err := json.Marshal(service.ToEventData(event))
+	if err != nil {
+		log.Printf("webhook: could not encode event %s: %v", event.Id, err)
+		return
+	}
+
+	for _, subscriber := range subscribers {
+		if !slices.Contains(subscriber.Events, event.Type) {
+			continue
+		}
+
+		d.enqueue(job{event: event, subscriber: *subscriber, body: body})
+	}
+}
+
+// Deliveries returns the delivery log, only the attempts made to subscriberId when it is not empty
+func (d *Dispatcher) Deliveries(subscriberId string) []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	deliveries := []Delivery{}
+	for _, delivery := range d.deliveries {
+		if subscriberId == "" || delivery.Subscriber_id == subscriberId {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries
+}
+
+// DeadLetters returns the deliveries that ran out of attempts
+func (d *Dispatcher) DeadLetters() []DeadLetter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]DeadLetter{}, d.deadLetters...)
+}
+
+func (d *Dispatcher) enqueue(j job) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		d.addDeadLetter(j, errors.New("dispatcher stopped"))
+		return
+	}
+
+	select {
+	case d.queue <- j:
+	default:
+		d.addDeadLetter(j, errors.New("delivery queue is full"))
+	}
+}
+
+// deliver makes one attempt, a failed one is retried after its backoff without holding the worker
+func (d *Dispatcher) deliver(j job) {
+	statusCode, err := d.send(j)
+	j.attempts++
+	j.err = err
+
+	delivery := Delivery{
+		Id:            uuid.New().String(),
+		Event_id:      j.event.Id,
+		Event_type:    j.event.Type,
+		Subscriber_id: j.subscriber.Id,
+		Url:           j.subscriber.Url,
+		Attempt:       j.attempts,
+		Status_code:   statusCode,
+		Delivered:     err == nil,
+		Created_at:    time.Now().UTC(),
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deliveries = appendBounded(d.deliveries, delivery, d.config.LogSize)
+
+	switch {
+	case err == nil:
+	case j.attempts >= d.config.MaxAttempts:
+		d.addDeadLetter(j, err)
+	default:
+		d.retry(j)
+	}
+}
+
+// retry queues j again after its backoff, or sends it to the dead-letter list when Stop cancels
+// the wait. It must be called with d.mu held.
+func (d *Dispatcher) retry(j job) {
+	d.retries.Add(1)
+	go func() {
+		defer d.retries.Done()
+
+		timer := time.NewTimer(d.backoff(j.attempts))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			d.enqueue(j)
+		case <-d.ctx.Done():
+			d.mu.Lock()
+			d.addDeadLetter(j, fmt.Errorf("dispatcher stopped: %w", j.err))
+			d.mu.Unlock()
+		}
+	}()
+}
+
+func (d *Dispatcher) send(j job) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, j.subscriber.Url, bytes.NewReader(j.body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, j.event.Type)
+	req.Header.Set(HeaderId, j.event.Id)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(j.subscriber.Secret, timestamp, j.body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("subscriber answered %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff is the wait before the given retry, BaseDelay doubled on every retry up to MaxDelay
+func (d *Dispatcher) backoff(retry int) time.Duration {
+	delay := d.config.BaseDelay
+	for i := 1; i < retry && delay < d.config.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, d.config.MaxDelay)
+}
+
+// addDeadLetter must be called with d.mu held
+func (d *Dispatcher) addDeadLetter(j job, err error) {
+	d.deadLetters = appendBounded(d.deadLetters, DeadLetter{
+		Event_id:      j.event.Id,
+		Event_type:    j.event.Type,
+		Subscriber_id: j.subscriber.Id,
+		Url:           j.subscriber.Url,
+		Attempts:      j.attempts,
+		Error:         err.Error(),
+		Payload:       j.body,
+		Failed_at:     time.Now().UTC(),
+	}, d.config.LogSize)
+}
+
+func appendBounded[T any](list []T, item T, size int) []T {
+	list = append(list, item)
+	if len(list) > size {
+		list = list[len(list)-size:]
+	}
+	return list
+}
@@ -0,0 +1,247 @@
+package webhook
+
+import (
+	"aula4/internal/repository"
+	"aula4/internal/repository/storage"
+	"aula4/internal/service"
+	"aula4/internal/utils"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const secret = "s3cr3t"
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver is a local subscriber answering with the given status codes in turn, the last one repeated
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedRequest
+	server   *httptest.Server
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	rc := &receiver{statuses: statuses}
+	rc.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		rc.mu.Lock()
+		rc.requests = append(rc.requests, receivedRequest{header: r.Header.Clone(), body: body})
+		status := rc.statuses[min(len(rc.requests), len(rc.statuses))-1]
+		rc.mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(rc.server.Close)
+	return rc
+}
+
+func (rc *receiver) received() []receivedRequest {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]receivedRequest{}, rc.requests...)
+}
+
+func newTestDispatcher(maxAttempts int) (*Dispatcher, *repository.MockSubscriberRepository) {
+	mockSubscriberRepo := repository.NewRepositorySubscribersMock()
+	dispatcher := NewDispatcher(&mockSubscriberRepo, Config{
+		Workers:     2,
+		MaxAttempts: maxAttempts,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    4 * time.Millisecond,
+	})
+	dispatcher.Start()
+	return dispatcher, &mockSubscriberRepo
+}
+
+func subscribe(t *testing.T, subscribers *repository.MockSubscriberRepository, url string, events ...string) storage.Subscriber {
+	subscriber, err := subscribers.Create(storage.Subscriber{Url: url, Events: events, Secret: secret})
+	require.NoError(t, err)
+	return subscriber
+}
+
+func newProduct() storage.Product {
+	isPublished := true
+	return storage.Product{
+		Name:                "Coffee",
+		Quantity:            10,
+		Code_value:          "COF",
+		Is_published:        &isPublished,
+		Expiration:          "01/01/2030",
+		Price:               5.0,
+		Low_stock_threshold: 3,
+	}
+}
+
+func TestDeliverSignedEvent(t *testing.T) {
+	rc := newReceiver(t, http.StatusOK)
+	other := newReceiver(t, http.StatusOK)
+	dispatcher, subscribers := newTestDispatcher(3)
+	subscriber := subscribe(t, subscribers, rc.server.URL, service.EventProductCreated)
+	subscribe(t, subscribers, other.server.URL, service.EventProductDeleted)
+
+	mockRepo := repository.NewRepositoryProductsMock()
+	productService := service.NewServiceProducts(&mockRepo)
+	productService.Events = dispatcher
+
+	product, err := productService.Create(newProduct())
+	require.NoError(t, err)
+
+	dispatcher.Stop()
+
+	requests := rc.received()
+	require.Len(t, requests, 1)
+	require.Empty(t, other.received(), "subscribers only receive the events they registered")
+
+	request := requests[0]
+	require.Equal(t, service.EventProductCreated, request.header.Get(HeaderEvent))
+	require.True(t, Verify(secret, request.header.Get(HeaderTimestamp), request.body, request.header.Get(HeaderSignature)), "invalid signature")
+	require.False(t, Verify("other", request.header.Get(HeaderTimestamp), request.body, request.header.Get(HeaderSignature)))
+
+	var payload utils.EventData
+	err = json.Unmarshal(request.body, &payload)
+	require.NoError(t, err)
+	require.Equal(t, request.header.Get(HeaderId), payload.Id)
+	require.Equal(t, service.EventProductCreated, payload.Type)
+	require.Equal(t, product.Id, payload.Data.Id)
+
+	deliveries := dispatcher.Deliveries(subscriber.Id)
+	require.Len(t, deliveries, 1)
+	require.True(t, deliveries[0].Delivered)
+	require.Equal(t, http.StatusOK, deliveries[0].Status_code)
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	rc := newReceiver(t, http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusNoContent)
+	dispatcher, subscribers := newTestDispatcher(5)
+	subscriber := subscribe(t, subscribers, rc.server.URL, service.EventProductUpdated)
+
+	dispatcher.Publish(service.NewEvent(service.EventProductUpdated, newProduct()))
+	// Stop cancels the retries, so it waits for the last one first
+	require.Eventually(t, func() bool { return len(dispatcher.Deliveries(subscriber.Id)) == 3 }, time.Second, time.Millisecond)
+	dispatcher.Stop()
+
+	require.Len(t, rc.received(), 3)
+
+	deliveries := dispatcher.Deliveries(subscriber.Id)
+	require.Len(t, deliveries, 3)
+	require.False(t, deliveries[0].Delivered)
+	require.Equal(t, http.StatusInternalServerError, deliveries[0].Status_code)
+	require.Equal(t, 3, deliveries[2].Attempt)
+	require.True(t, deliveries[2].Delivered)
+	require.Empty(t, dispatcher.DeadLetters())
+}
+
+func TestDeadLetter(t *testing.T) {
+	rc := newReceiver(t, http.StatusInternalServerError)
+	dispatcher, subscribers := newTestDispatcher(3)
+	subscriber := subscribe(t, subscribers, rc.server.URL, service.EventProductDeleted)
+
+	event := service.NewEvent(service.EventProductDeleted, newProduct())
+	dispatcher.Publish(event)
+	require.Eventually(t, func() bool { return len(dispatcher.DeadLetters()) == 1 }, time.Second, time.Millisecond)
+	dispatcher.Stop()
+
+	require.Len(t, rc.received(), 3)
+
+	deadLetters := dispatcher.DeadLetters()
+	require.Len(t, deadLetters, 1)
+	require.Equal(t, event.Id, deadLetters[0].Event_id)
+	require.Equal(t, subscriber.Id, deadLetters[0].Subscriber_id)
+	require.Equal(t, 3, deadLetters[0].Attempts)
+	require.Contains(t, deadLetters[0].Error, "500")
+}
+
+func TestRetryDoesNotHoldWorkers(t *testing.T) {
+	dead := newReceiver(t, http.StatusServiceUnavailable)
+	rc := newReceiver(t, http.StatusOK)
+	mockSubscriberRepo := repository.NewRepositorySubscribersMock()
+	dispatcher := NewDispatcher(&mockSubscriberRepo, Config{Workers: 1, MaxAttempts: 5, BaseDelay: time.Hour})
+	dispatcher.Start()
+	for i := 0; i < 3; i++ {
+		subscribe(t, &mockSubscriberRepo, dead.server.URL, service.EventProductCreated)
+	}
+	subscriber := subscribe(t, &mockSubscriberRepo, rc.server.URL, service.EventProductCreated)
+
+	event := service.NewEvent(service.EventProductCreated, newProduct())
+	dispatcher.Publish(event)
+	require.Eventually(t, func() bool { return len(rc.received()) == 1 }, time.Second, time.Millisecond,
+		"the retries of the dead endpoints wait without the only worker")
+
+	stopped := make(chan struct{})
+	go func() {
+		dispatcher.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop waits for the backoff of the retries")
+	}
+
+	require.Len(t, dead.received(), 3, "the cancelled retries are not sent")
+	require.True(t, dispatcher.Deliveries(subscriber.Id)[0].Delivered)
+	deadLetters := dispatcher.DeadLetters()
+	require.Len(t, deadLetters, 3)
+	for _, deadLetter := range deadLetters {
+		require.Equal(t, event.Id, deadLetter.Event_id)
+		require.Equal(t, 1, deadLetter.Attempts)
+		require.Contains(t, deadLetter.Error, "dispatcher stopped")
+	}
+}
+
+func TestStockLowEvent(t *testing.T) {
+	rc := newReceiver(t, http.StatusOK)
+	dispatcher, subscribers := newTestDispatcher(1)
+	subscribe(t, subscribers, rc.server.URL, service.EventStockLow)
+
+	mockRepo := repository.NewRepositoryProductsMock()
+	mockMovementRepo := repository.NewRepositoryMovementsMock()
+	productService := service.NewServiceProducts(&mockRepo)
+	movementService := service.NewServiceMovements(&mockMovementRepo, &mockRepo)
+	movementService.Events = dispatcher
+
+	product, err := productService.Create(newProduct())
+	require.NoError(t, err)
+
+	for _, quantity := range []int{5, 2, 1} {
+		_, err := movementService.Record(storage.Movement{
+			Product_id: product.Id,
+			Type:       storage.MovementSale,
+			Quantity:   quantity,
+			Actor:      "shop",
+		})
+		require.NoError(t, err)
+	}
+
+	dispatcher.Stop()
+
+	requests := rc.received()
+	require.Len(t, requests, 1, "stock.low is sent once, when the quantity reaches the threshold")
+
+	var payload utils.EventData
+	err = json.Unmarshal(requests[0].body, &payload)
+	require.NoError(t, err)
+	require.Equal(t, 3, payload.Data.Quantity)
+}
+
+func TestBackoff(t *testing.T) {
+	dispatcher := NewDispatcher(nil, Config{BaseDelay: time.Second, MaxDelay: 5 * time.Second})
+
+	require.Equal(t, time.Second, dispatcher.backoff(1))
+	require.Equal(t, 2*time.Second, dispatcher.backoff(2))
+	require.Equal(t, 4*time.Second, dispatcher.backoff(3))
+	require.Equal(t, 5*time.Second, dispatcher.backoff(4))
+	require.Equal(t, 5*time.Second, dispatcher.backoff(10))
+}
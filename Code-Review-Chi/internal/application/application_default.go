@@ -3,6 +3,7 @@ package application
 import (
 	"app/internal/handler"
 	"app/internal/loader"
+	"app/internal/openapi"
 	"app/internal/repository"
 	"app/internal/service"
 	"net/http"
@@ -17,6 +18,8 @@ type ConfigServerChi struct {
 	ServerAddress string
 	// LoaderFilePath is the path to the file that contains the vehicles
 	LoaderFilePath string
+	// MaxBodyBytes is the maximum size of a request body
+	MaxBodyBytes int64
 }
 
 // NewServerChi is a function that returns a new instance of ServerChi
@@ -24,6 +27,7 @@ func NewServerChi(cfg *ConfigServerChi) *ServerChi {
 	// default values
 	defaultConfig := &ConfigServerChi{
 		ServerAddress: ":8080",
+		MaxBodyBytes:  openapi.DefaultMaxBodyBytes,
 	}
 	if cfg != nil {
 		if cfg.ServerAddress != "" {
@@ -32,11 +36,15 @@ func NewServerChi(cfg *ConfigServerChi) *ServerChi {
 		if cfg.LoaderFilePath != "" {
 			defaultConfig.LoaderFilePath = cfg.LoaderFilePath
 		}
+		if cfg.MaxBodyBytes > 0 {
+			defaultConfig.MaxBodyBytes = cfg.MaxBodyBytes
+		}
 	}
 
 	return &ServerChi{
 		serverAddress:  defaultConfig.ServerAddress,
 		loaderFilePath: defaultConfig.LoaderFilePath,
+		maxBodyBytes:   defaultConfig.MaxBodyBytes,
 	}
 }
 
@@ -46,10 +54,24 @@ type ServerChi struct {
 	serverAddress string
 	// loaderFilePath is the path to the file that contains the vehicles
 	loaderFilePath string
+	// maxBodyBytes is the maximum size of a request body
+	maxBodyBytes int64
 }
 
 // Run is a method that runs the application
 func (a *ServerChi) Run() (err error) {
+	rt, err := a.router()
+	if err != nil {
+		return
+	}
+
+	// run server
+	err = http.ListenAndServe(a.serverAddress, rt)
+	return
+}
+
+// router is a method that builds the dependencies and registers every endpoint
+func (a *ServerChi) router() (rt *chi.Mux, err error) {
 	// dependencies
 	// - loader
 	ld := loader.NewVehicleJSONFile(a.loaderFilePath)
@@ -63,12 +85,20 @@ func (a *ServerChi) Run() (err error) {
 	sv := service.NewVehicleDefault(&rp)
 	// - handler
 	hd := handler.NewVehicleDefault(&sv)
+	// - docs
+	doc := handler.NewOpenAPI()
 	// router
-	rt := chi.NewRouter()
+	rt = chi.NewRouter()
 	// - middlewares
 	rt.Use(middleware.Logger)
 	rt.Use(middleware.Recoverer)
+	rt.Use(doc.ValidateRequests(openapi.ValidatorConfig{
+		MaxBodyBytes: a.maxBodyBytes,
+		OnError:      handler.ResponseWithError,
+	}))
 	// - endpoints
+	rt.Get(handler.OpenAPIPath, doc.ServeHTTP)
+	rt.Get(handler.DocsPath, openapi.UI(handler.OpenAPIPath))
 	rt.Route("/vehicles", func(r chi.Router) {
 		r.Get("/", hd.GetAll)
 		r.Get("/color/{color}/year/{year}", hd.GetColorYear)
@@ -86,7 +116,5 @@ func (a *ServerChi) Run() (err error) {
 		r.Get("/weight", hd.GetWeight)
 	})
 
-	// run server
-	err = http.ListenAndServe(a.serverAddress, rt)
 	return
 }
@@ -0,0 +1,178 @@
+package application
+
+import (
+	"app/internal/handler"
+	"app/internal/openapi"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerChi_EveryRouteIsDocumented(t *testing.T) {
+	app := NewServerChi(&ConfigServerChi{
+		LoaderFilePath: "../../docs/db/vehicles_100.json",
+	})
+	doc := handler.NewOpenAPI()
+
+	rt, err := app.router()
+	require.NoError(t, err)
+
+	registered := make(map[string]bool)
+	err = chi.Walk(rt, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		key := method + " " + openapi.NormalizePath(route)
+		registered[key] = true
+
+		_, ok := doc.Operation(method, route)
+		require.True(t, ok, "route %s is not documented in the OpenAPI spec", key)
+		return nil
+	})
+	require.NoError(t, err)
+
+	for _, route := range doc.Routes() {
+		require.True(t, registered[route], "documented route %s is not registered", route)
+	}
+}
+
+func TestServerChi_ValidateRequests(t *testing.T) {
+	validBody := `{"brand":"Fiat","model":"Uno","registration":"ABC1234","color":"Red","year":2010,"passengers":5,` +
+		`"max_speed":150,"fuel_type":"gasoline","transmission":"manual","weight":900,"height":1.5,"length":3.7,"width":1.6}`
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		contentType  string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "valid vehicle",
+			method:       http.MethodPost,
+			path:         "/vehicles",
+			contentType:  "application/json",
+			body:         validBody,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "fuel type outside the enum",
+			method:       http.MethodPost,
+			path:         "/vehicles",
+			contentType:  "application/json",
+			body:         strings.Replace(validBody, `"gasoline"`, `"coal"`, 1),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "transmission outside the enum",
+			method:       http.MethodPost,
+			path:         "/vehicles",
+			contentType:  "application/json",
+			body:         strings.Replace(validBody, `"manual"`, `"cvt"`, 1),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "misspelled field",
+			method:       http.MethodPost,
+			path:         "/vehicles",
+			contentType:  "application/json",
+			body:         strings.Replace(validBody, `"passengers"`, `"pasengers"`, 1),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "batch with an invalid item",
+			method:       http.MethodPost,
+			path:         "/vehicles/batch/",
+			contentType:  "application/json",
+			body:         "[" + validBody + "," + strings.Replace(validBody, `"manual"`, `"cvt"`, 1) + "]",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "fuel update outside the enum",
+			method:       http.MethodPut,
+			path:         "/vehicles/1/update_fuel",
+			contentType:  "application/json",
+			body:         `{"fuel_type":"coal"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "form content type",
+			method:       http.MethodPost,
+			path:         "/vehicles",
+			contentType:  "application/x-www-form-urlencoded",
+			body:         validBody,
+			expectedCode: http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := NewServerChi(&ConfigServerChi{
+				LoaderFilePath: "../../docs/db/vehicles_100.json",
+			})
+
+			rt, err := app.router()
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+			rt.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code, rr.Body.String())
+		})
+	}
+
+	t.Run("body over the limit", func(t *testing.T) {
+		app := NewServerChi(&ConfigServerChi{
+			LoaderFilePath: "../../docs/db/vehicles_100.json",
+			MaxBodyBytes:   64,
+		})
+
+		rt, err := app.router()
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "/vehicles", strings.NewReader(validBody))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		rt.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+}
+
+func TestServerChi_ValidateStoredVehicles(t *testing.T) {
+	// the enums accept every fuel type and transmission of the vehicles already stored
+	content, err := os.ReadFile("../../docs/db/vehicles_100.json")
+	require.NoError(t, err)
+	var vehicles []struct {
+		Id           int    `json:"id"`
+		FuelType     string `json:"fuel_type"`
+		Transmission string `json:"transmission"`
+	}
+	require.NoError(t, json.Unmarshal(content, &vehicles))
+
+	app := NewServerChi(&ConfigServerChi{
+		LoaderFilePath: "../../docs/db/vehicles_100.json",
+	})
+	rt, err := app.router()
+	require.NoError(t, err)
+
+	for _, vehicle := range vehicles {
+		body := fmt.Sprintf(`{"brand":"Fiat","model":"Uno","registration":"NEW%d","color":"Red","year":2010,"passengers":5,`+
+			`"max_speed":150,"fuel_type":%q,"transmission":%q,"weight":900,"height":1.5,"length":3.7,"width":1.6}`,
+			vehicle.Id, vehicle.FuelType, vehicle.Transmission)
+
+		req := httptest.NewRequest(http.MethodPost, "/vehicles", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		rt.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusCreated, rr.Code, "vehicle %d: %s", vehicle.Id, rr.Body.String())
+	}
+}
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"app/internal"
+	"app/internal/openapi"
+	"net/http"
+)
+
+const (
+	// OpenAPIPath is the route serving the OpenAPI specification
+	OpenAPIPath = "/openapi.json"
+	// DocsPath is the route serving the html page of the specification
+	DocsPath = "/docs"
+)
+
+// NewOpenAPI is a function that returns the OpenAPI specification of every vehicle route
+func NewOpenAPI() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:   "Vehicles API",
+		Version: "1.0.0",
+	})
+
+	errorBody := ResponseBodyVehicle{}
+	vehicles := openapi.MapOf(VehicleJSON{})
+	message := &openapi.Schema{Type: openapi.TypeString}
+	minSpeed := 0.0
+
+	doc.Add(
+		openapi.Route{
+			Method:    http.MethodGet,
+			Path:      OpenAPIPath,
+			Summary:   "OpenAPI specification of this API",
+			Tags:      []string{"docs"},
+			Responses: map[int]any{http.StatusOK: &openapi.Schema{Type: openapi.TypeObject}},
+		},
+		openapi.Route{
+			Method:    http.MethodGet,
+			Path:      DocsPath,
+			Summary:   "Html page rendering the specification",
+			Tags:      []string{"docs"},
+			Responses: map[int]any{http.StatusOK: nil},
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/vehicles",
+			Summary: "List all vehicles",
+			Tags:    []string{"vehicles"},
+			Responses: map[int]any{
+				http.StatusOK: &openapi.Schema{
+					Type: openapi.TypeObject,
+					Properties: map[string]*openapi.Schema{
+						"message": message,
+						"data":    vehicles,
+					},
+				},
+				http.StatusInternalServerError: nil,
+			},
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/vehicles/color/{color}/year/{year}",
+			Summary: "List vehicles by color and fabrication year",
+			Tags:    []string{"vehicles"},
+			Responses: map[int]any{
+				http.StatusOK:         vehicles,
+				http.StatusBadRequest: errorBody,
+			},
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/vehicles/brand/{brand}/between/{start_year}/{end_year}",
+			Summary: "List vehicles of a brand made between two years",
+			Tags:    []string{"vehicles"},
+			Responses: map[int]any{
+				http.StatusOK:         vehicles,
+				http.StatusBadRequest: errorBody,
+			},
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/vehicles/average_speed/brand/{brand}",
+			Summary: "Average max speed of a brand",
+			Tags:    []string{"vehicles"},
+			Responses: map[int]any{
+				http.StatusOK:         message,
+				http.StatusBadRequest: errorBody,
+			},
+		},
+		openapi.Route{
+			Method:  http.MethodPost,
+			Path:    "/vehicles/batch",
+			Summary: "Create many vehicles",
+			Tags:    []string{"vehicles"},
+			Body:    []RequestBodyVehicle{},
+			Responses: map[int]any{
+				http.StatusCreated:               message,
+				http.StatusBadRequest:            errorBody,
+				http.StatusConflict:              nil,
+				http.StatusRequestEntityTooLarge: errorBody,
+				http.StatusUnsupportedMediaType:  errorBody,
+			},
+		},
+		openapi.Route{
+			Method:  http.MethodPost,
+			Path:    "/vehicles",
+			Summary: "Create a vehicle",
+			Tags:    []string{"vehicles"},
+			Body:    RequestBodyVehicle{},
+			Responses: map[int]any{
+				http.StatusCreated:               ResponseBodyVehicle{},
+				http.StatusBadRequest:            errorBody,
+				http.StatusConflict:              nil,
+				http.StatusRequestEntityTooLarge: errorBody,
+				http.StatusUnsupportedMediaType:  errorBody,
+			},
+		},
+		openapi.Route{
+			Method:  http.MethodPut,
+			Path:    "/vehicles/{id}/update_speed",
+			Summary: "Update the max speed of a vehicle",
+			Tags:    []string{"vehicles"},
+			Body: &openapi.Schema{
+				Type: openapi.TypeObject,
+				Properties: map[string]*openapi.Schema{
+					"max_speed": {Type: openapi.TypeNumber, Format: "double", Minimum: &minSpeed},
+				},
+				Required:             []string{"max_speed"},
+				AdditionalProperties: false,
+			},
+			Responses: map[int]any{
+				http.StatusCreated:             internal.Vehicle{},
+				http.StatusBadRequest:          errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/vehicles/fuel_type/{type}",
+			Summary: "List vehicles by fuel type",
+			Tags:    []string{"vehicles"},
+			Responses: map[int]any{
+				http.StatusOK:         vehicles,
+				http.StatusBadRequest: errorBody,
+			},
+		},
+		openapi.Route{
+			Method:  http.MethodDelete,
+			Path:    "/vehicles/{id}",
+			Summary: "Delete a vehicle",
+			Tags:    []string{"vehicles"},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/vehicles/transmission/{type}",
+			Summary: "List vehicles by transmission",
+			Tags:    []string{"vehicles"},
+			Responses: map[int]any{
+				http.StatusOK:         vehicles,
+				http.StatusBadRequest: errorBody,
+			},
+		},
+		openapi.Route{
+			Method:  http.MethodPut,
+			Path:    "/vehicles/{id}/update_fuel",
+			Summary: "Update the fuel type of a vehicle",
+			Tags:    []string{"vehicles"},
+			Body:    RequestBodyFuelType{},
+			Responses: map[int]any{
+				http.StatusCreated:             internal.Vehicle{},
+				http.StatusBadRequest:          errorBody,
+				http.StatusInternalServerError: errorBody,
+			},
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/vehicles/average_capacity/brand/{brand}",
+			Summary: "Average passenger capacity of a brand",
+			Tags:    []string{"vehicles"},
+			Responses: map[int]any{
+				http.StatusOK:         message,
+				http.StatusBadRequest: errorBody,
+			},
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/vehicles/dimensions",
+			Summary: "List vehicles within a length and width range",
+			Tags:    []string{"vehicles"},
+			Query: []openapi.Parameter{
+				{Name: "length", In: "query", Required: true, Description: "min-max, e.g. 1.5-3", Schema: &openapi.Schema{Type: openapi.TypeString}},
+				{Name: "width", In: "query", Required: true, Description: "min-max, e.g. 1.5-3", Schema: &openapi.Schema{Type: openapi.TypeString}},
+			},
+			Responses: map[int]any{
+				http.StatusOK:         vehicles,
+				http.StatusBadRequest: errorBody,
+			},
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/vehicles/weight",
+			Summary: "List vehicles within a weight range",
+			Tags:    []string{"vehicles"},
+			Query: []openapi.Parameter{
+				{Name: "min", In: "query", Required: true, Schema: &openapi.Schema{Type: openapi.TypeNumber}},
+				{Name: "max", In: "query", Required: true, Schema: &openapi.Schema{Type: openapi.TypeNumber}},
+			},
+			Responses: map[int]any{
+				http.StatusOK:         vehicles,
+				http.StatusBadRequest: errorBody,
+			},
+		},
+	)
+
+	return doc
+}
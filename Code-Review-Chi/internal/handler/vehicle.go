@@ -38,23 +38,23 @@ type VehicleJSON struct {
 }
 
 type RequestBodyFuelType struct {
-	FuelType string `json:"fuel_type"`
+	FuelType string `json:"fuel_type" openapi:"enum=biodiesel|diesel|gas|gasoline"`
 }
 
 type RequestBodyVehicle struct {
-	Brand           string  `json:"brand"`
-	Model           string  `json:"model"`
-	Registration    string  `json:"registration"`
-	Color           string  `json:"color"`
-	FabricationYear int     `json:"year"`
-	Capacity        int     `json:"passengers"`
-	MaxSpeed        float64 `json:"max_speed"`
-	FuelType        string  `json:"fuel_type"`
-	Transmission    string  `json:"transmission"`
-	Weight          float64 `json:"weight"`
-	Height          float64 `json:"height"`
-	Length          float64 `json:"length"`
-	Width           float64 `json:"width"`
+	Brand           string  `json:"brand" openapi:"maxLength=64"`
+	Model           string  `json:"model" openapi:"maxLength=64"`
+	Registration    string  `json:"registration" openapi:"maxLength=32"`
+	Color           string  `json:"color" openapi:"maxLength=32"`
+	FabricationYear int     `json:"year" openapi:"minimum=0"`
+	Capacity        int     `json:"passengers" openapi:"minimum=0"`
+	MaxSpeed        float64 `json:"max_speed" openapi:"minimum=0"`
+	FuelType        string  `json:"fuel_type" openapi:"enum=biodiesel|diesel|gas|gasoline"`
+	Transmission    string  `json:"transmission" openapi:"enum=automatic|manual|semi-automatic"`
+	Weight          float64 `json:"weight" openapi:"minimum=0"`
+	Height          float64 `json:"height" openapi:"minimum=0"`
+	Length          float64 `json:"length" openapi:"minimum=0"`
+	Width           float64 `json:"width" openapi:"minimum=0"`
 }
 
 type ResponseBodyVehicle struct {
@@ -0,0 +1,298 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	Version = "3.0.3"
+
+	ContentTypeJSON      = "application/json"
+	ContentTypeMultipart = "multipart/form-data"
+)
+
+var pathParamRegex = regexp.MustCompile(`\{([^}]+)\}`)
+
+// Document is the root object of an OpenAPI 3 specification
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components *Components         `json:"components,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem maps a lower case http method to its operation
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	OperationID string                `json:"operationId,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+
+	// maxBodyBytes is the limit of Route.MaxBodyBytes, the one of the validator when zero
+	maxBodyBytes int64
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name,omitempty"`
+	In          string `json:"in,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Route describes a single endpoint registered in the router
+type Route struct {
+	Method  string
+	Path    string
+	Summary string
+	Tags    []string
+	// Query are the query string parameters accepted by the route
+	Query []Parameter
+	// Body is a value (or *Schema) describing the request body
+	Body any
+	// BodyContentType is the media type of Body, ContentTypeJSON when empty. Only JSON
+	// bodies are validated against their schema.
+	BodyContentType string
+	// MaxBodyBytes raises or lowers the body limit of the validator for this route
+	MaxBodyBytes int64
+	// Responses maps a status code to a value (or *Schema) describing the body, nil means no body
+	Responses map[int]any
+	// Security is the name of the security scheme protecting the route
+	Security string
+}
+
+func New(info Info) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+	}
+}
+
+// AddSchema documents a model that is not the body of any route, e.g. a domain entity
+func (d *Document) AddSchema(name string, v any) {
+	if d.Components == nil {
+		d.Components = &Components{}
+	}
+	if d.Components.Schemas == nil {
+		d.Components.Schemas = make(map[string]*Schema)
+	}
+
+	d.Components.Schemas[name] = schemaFor(v)
+}
+
+// AddSecurityScheme registers a scheme that routes can reference by name
+func (d *Document) AddSecurityScheme(name string, scheme SecurityScheme) {
+	if d.Components == nil {
+		d.Components = &Components{}
+	}
+	if d.Components.SecuritySchemes == nil {
+		d.Components.SecuritySchemes = make(map[string]SecurityScheme)
+	}
+
+	d.Components.SecuritySchemes[name] = scheme
+}
+
+func (d *Document) Add(routes ...Route) {
+	for _, route := range routes {
+		path := NormalizePath(route.Path)
+
+		op := &Operation{
+			Summary:     route.Summary,
+			OperationID: operationID(route.Method, path),
+			Tags:        route.Tags,
+			Responses:   make(map[string]Response),
+
+			maxBodyBytes: route.MaxBodyBytes,
+		}
+
+		for _, match := range pathParamRegex.FindAllStringSubmatch(path, -1) {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:     match[1],
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: TypeString},
+			})
+		}
+		op.Parameters = append(op.Parameters, route.Query...)
+
+		if route.Body != nil {
+			contentType := route.BodyContentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content: map[string]MediaType{
+					contentType: {Schema: schemaFor(route.Body)},
+				},
+			}
+		}
+
+		for status, body := range route.Responses {
+			resp := Response{Description: http.StatusText(status)}
+			if body != nil {
+				resp.Content = map[string]MediaType{
+					ContentTypeJSON: {Schema: schemaFor(body)},
+				}
+			}
+			op.Responses[strconv.Itoa(status)] = resp
+		}
+
+		if route.Security != "" {
+			op.Security = []map[string][]string{{route.Security: {}}}
+		}
+
+		item, ok := d.Paths[path]
+		if !ok {
+			item = make(PathItem)
+			d.Paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = op
+	}
+}
+
+// Operation returns the operation documented for the method and path template
+func (d *Document) Operation(method, path string) (*Operation, bool) {
+	item, ok := d.Paths[NormalizePath(path)]
+	if !ok {
+		return nil, false
+	}
+
+	op, ok := item[strings.ToLower(method)]
+	return op, ok
+}
+
+// Match finds the operation for a concrete request path, e.g. /products/123 matches /products/{id}.
+// Literal segments are preferred over parameters when more than one template matches.
+func (d *Document) Match(method, path string) (*Operation, bool) {
+	if op, ok := d.Operation(method, path); ok {
+		return op, true
+	}
+
+	segments := strings.Split(NormalizePath(path), "/")
+
+	var (
+		best      *Operation
+		bestScore = -1
+	)
+	for template, item := range d.Paths {
+		op, ok := item[strings.ToLower(method)]
+		if !ok {
+			continue
+		}
+
+		templateSegments := strings.Split(template, "/")
+		if len(templateSegments) != len(segments) {
+			continue
+		}
+
+		score := 0
+		for i, segment := range templateSegments {
+			if pathParamRegex.MatchString(segment) {
+				continue
+			}
+			if segment != segments[i] {
+				score = -1
+				break
+			}
+			score++
+		}
+
+		if score > bestScore {
+			best, bestScore = op, score
+		}
+	}
+
+	return best, best != nil
+}
+
+// Routes returns every documented "METHOD /path" pair, sorted
+func (d *Document) Routes() []string {
+	var routes []string
+	for path, item := range d.Paths {
+		for method := range item {
+			routes = append(routes, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	sort.Strings(routes)
+	return routes
+}
+
+// ServeHTTP writes the document as JSON
+func (d *Document) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentTypeJSON)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(d)
+}
+
+// NormalizePath removes the trailing slash chi keeps for sub-router roots
+func NormalizePath(path string) string {
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
+
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, segment := range strings.Split(path, "/") {
+		segment = strings.Trim(segment, "{}")
+		if segment == "" {
+			continue
+		}
+		id += "_" + segment
+	}
+	return id
+}
+
+func schemaFor(v any) *Schema {
+	if schema, ok := v.(*Schema); ok {
+		return schema
+	}
+	return SchemaOf(v)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"app/internal/handler"
+	"app/internal/openapi"
 	"app/internal/repository"
 	"app/internal/repository/loader"
 	"app/internal/service"
@@ -78,7 +79,10 @@ func (a *ApplicationDefault) SetUp() (err error) {
 	rp := repository.NewRepositoryTicketMap(tickets)
 	sv := service.NewServiceTicketDefault(&rp)
 	hd := handler.NewHandlerTickets(&sv)
+	doc := handler.NewOpenAPI()
 
+	(*a).rt.Get(handler.OpenAPIPath, doc.ServeHTTP)
+	(*a).rt.Get(handler.DocsPath, openapi.UI(handler.OpenAPIPath))
 	(*a).rt.Get("/ticket/getByCountry/{dest}", hd.GetByCountry)
 	(*a).rt.Get("/ticket/getAverage/{dest}", hd.GetAverage)
 
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"app/internal/handler"
+	"app/internal/openapi"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplicationDefault_EveryRouteIsDocumented(t *testing.T) {
+	app := NewApplicationDefault(nil)
+	doc := handler.NewOpenAPI()
+
+	err := app.SetUp()
+	require.NoError(t, err)
+
+	registered := make(map[string]bool)
+	err = chi.Walk(app.rt, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		key := method + " " + openapi.NormalizePath(route)
+		registered[key] = true
+
+		_, ok := doc.Operation(method, route)
+		require.True(t, ok, "route %s is not documented in the OpenAPI spec", key)
+		return nil
+	})
+	require.NoError(t, err)
+
+	for _, route := range doc.Routes() {
+		require.True(t, registered[route], "documented route %s is not registered", route)
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"app/internal"
+	"app/internal/openapi"
+	"net/http"
+)
+
+const (
+	OpenAPIPath = "/openapi.json"
+	DocsPath    = "/docs"
+)
+
+func NewOpenAPI() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:   "Tickets API",
+		Version: "1.0.0",
+	})
+
+	doc.AddSchema("TicketAttributes", internal.TicketAttributes{})
+	doc.AddSchema("Ticket", internal.Ticket{})
+
+	doc.Add(
+		openapi.Route{
+			Method:    http.MethodGet,
+			Path:      OpenAPIPath,
+			Summary:   "OpenAPI specification of this API",
+			Tags:      []string{"docs"},
+			Responses: map[int]any{http.StatusOK: &openapi.Schema{Type: openapi.TypeObject}},
+		},
+		openapi.Route{
+			Method:    http.MethodGet,
+			Path:      DocsPath,
+			Summary:   "Html page rendering the specification",
+			Tags:      []string{"docs"},
+			Responses: map[int]any{http.StatusOK: nil},
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/ticket/getByCountry/{dest}",
+			Summary: "How many tickets travel to a destination country",
+			Tags:    []string{"tickets"},
+			Responses: map[int]any{
+				http.StatusOK:                  ResponseBodyTicket{},
+				http.StatusInternalServerError: ResponseBodyTicket{},
+			},
+		},
+		openapi.Route{
+			Method:  http.MethodGet,
+			Path:    "/ticket/getAverage/{dest}",
+			Summary: "Percentage of tickets travelling to a destination country",
+			Tags:    []string{"tickets"},
+			Responses: map[int]any{
+				http.StatusOK:                  ResponseBodyTicket{},
+				http.StatusInternalServerError: ResponseBodyTicket{},
+			},
+		},
+	)
+
+	return doc
+}
@@ -0,0 +1,177 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeArray   = "array"
+	TypeObject  = "object"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// Schema is the subset of the OpenAPI schema object used by the API
+type Schema struct {
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Nullable    bool               `json:"nullable,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	// AdditionalProperties is either the *Schema of the values of a map or false for closed objects
+	AdditionalProperties any `json:"additionalProperties,omitempty"`
+}
+
+// SchemaOf derives a schema from the type of v using its json tags.
+// Fields that are neither pointers nor tagged omitempty are required, and
+// constraints come from the openapi tag, e.g. `openapi:"maxLength=64,enum=manual|automatic,minimum=0"`.
+func SchemaOf(v any) *Schema {
+	return schemaOfType(reflect.TypeOf(v), make(map[reflect.Type]bool))
+}
+
+// Partial derives the schema of v without required fields, used for PATCH bodies
+func Partial(v any) *Schema {
+	schema := SchemaOf(v)
+	schema.Required = nil
+	return schema
+}
+
+// ArrayOf returns a schema for a list of the type of v
+func ArrayOf(v any) *Schema {
+	return &Schema{Type: TypeArray, Items: SchemaOf(v)}
+}
+
+// MapOf returns a schema for an object whose values have the type of v
+func MapOf(v any) *Schema {
+	return &Schema{Type: TypeObject, AdditionalProperties: SchemaOf(v)}
+}
+
+func schemaOfType(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	if t.Kind() == reflect.Pointer {
+		schema := schemaOfType(t.Elem(), visiting)
+		schema.Nullable = true
+		return schema
+	}
+
+	if t == timeType {
+		return &Schema{Type: TypeString, Format: "date-time"}
+	}
+	if t == rawMessageType {
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: TypeString}
+	case reflect.Bool:
+		return &Schema{Type: TypeBoolean}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: TypeInteger, Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: TypeInteger, Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: TypeNumber, Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: TypeNumber, Format: "double"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: TypeArray, Items: schemaOfType(t.Elem(), visiting)}
+	case reflect.Map:
+		return &Schema{Type: TypeObject, AdditionalProperties: schemaOfType(t.Elem(), visiting)}
+	case reflect.Struct:
+		// recursive types, e.g. trees, are described as a plain object below the first level
+		if visiting[t] {
+			return &Schema{Type: TypeObject}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		schema := &Schema{Type: TypeObject, Properties: make(map[string]*Schema), AdditionalProperties: false}
+		addFields(schema, t, visiting)
+		return schema
+	default:
+		// interface{} and friends accept any value
+		return &Schema{}
+	}
+}
+
+func addFields(schema *Schema, t reflect.Type, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		// embedded structs without a json name are flattened, like encoding/json does
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				addFields(schema, embedded, visiting)
+				continue
+			}
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		property := schemaOfType(field.Type, visiting)
+		addConstraints(property, field.Tag.Get("openapi"))
+
+		// a field shadows the one of the same name in an embedded struct, like encoding/json does
+		schema.Properties[name] = property
+		if field.Type.Kind() != reflect.Pointer && !strings.Contains(opts, "omitempty") && !slices.Contains(schema.Required, name) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+func addConstraints(schema *Schema, tag string) {
+	if tag == "" {
+		return
+	}
+
+	for _, constraint := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(constraint, "=")
+		switch key {
+		case "enum":
+			schema.Enum = strings.Split(value, "|")
+		case "maxLength":
+			if n, err := strconv.Atoi(value); err == nil {
+				schema.MaxLength = &n
+			}
+		case "minimum":
+			if n, err := strconv.ParseFloat(value, 64); err == nil {
+				schema.Minimum = &n
+			}
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const DefaultMaxBodyBytes int64 = 1 << 20
+
+var (
+	ErrUnsupportedMediaType = errors.New("content type must be " + ContentTypeJSON)
+	ErrBodyTooLarge         = errors.New("request body is too large")
+	ErrEmptyBody            = errors.New("request body is required")
+)
+
+// ValidatorConfig configures the request validation middleware
+type ValidatorConfig struct {
+	// MaxBodyBytes limits the size of every request body, DefaultMaxBodyBytes when zero
+	MaxBodyBytes int64
+	// OnError writes the rejection, plain text when nil
+	OnError func(w http.ResponseWriter, err error, statusCode int)
+}
+
+// ValidateRequests rejects requests whose JSON body does not match the schema documented for the route,
+// before the handler runs. Routes without a documented body only get the size limit.
+func (d *Document) ValidateRequests(cfg ValidatorConfig) func(http.Handler) http.Handler {
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(w http.ResponseWriter, err error, statusCode int) {
+			http.Error(w, err.Error(), statusCode)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			op, ok := d.Match(r.Method, r.URL.Path)
+
+			maxBodyBytes := cfg.MaxBodyBytes
+			if ok && op.maxBodyBytes > 0 {
+				maxBodyBytes = op.maxBodyBytes
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+
+			if !ok || op.RequestBody == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if _, isJSON := op.RequestBody.Content[ContentTypeJSON]; !isJSON {
+				// the other media types are left to the handler
+				if _, documented := op.RequestBody.Content[mediaType]; err != nil || !documented {
+					cfg.OnError(w, unsupportedMediaType(op.RequestBody), http.StatusUnsupportedMediaType)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil || mediaType != ContentTypeJSON {
+				cfg.OnError(w, ErrUnsupportedMediaType, http.StatusUnsupportedMediaType)
+				return
+			}
+
+			raw, err := io.ReadAll(r.Body)
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					cfg.OnError(w, ErrBodyTooLarge, http.StatusRequestEntityTooLarge)
+					return
+				}
+				cfg.OnError(w, err, http.StatusBadRequest)
+				return
+			}
+
+			if len(bytes.TrimSpace(raw)) == 0 {
+				cfg.OnError(w, ErrEmptyBody, http.StatusBadRequest)
+				return
+			}
+
+			var body any
+			decoder := json.NewDecoder(bytes.NewReader(raw))
+			decoder.UseNumber()
+			if err := decoder.Decode(&body); err != nil {
+				cfg.OnError(w, fmt.Errorf("invalid JSON: %w", err), http.StatusBadRequest)
+				return
+			}
+			if _, err := decoder.Token(); err != io.EOF {
+				cfg.OnError(w, errors.New("invalid JSON: unexpected data after the body"), http.StatusBadRequest)
+				return
+			}
+
+			if err := op.RequestBody.Content[ContentTypeJSON].Schema.Validate(body); err != nil {
+				cfg.OnError(w, err, http.StatusBadRequest)
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(raw))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func unsupportedMediaType(body *RequestBody) error {
+	var mediaTypes []string
+	for mediaType := range body.Content {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Strings(mediaTypes)
+	return errors.New("content type must be " + strings.Join(mediaTypes, " or "))
+}
+
+// Validate checks a value decoded with json.Decoder.UseNumber against the schema
+func (s *Schema) Validate(value any) error {
+	return s.validate("body", value)
+}
+
+func (s *Schema) validate(path string, value any) error {
+	if value == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return fmt.Errorf("%s: must not be null", path)
+	}
+
+	switch s.Type {
+	case TypeString:
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: must be a string", path)
+		}
+		if s.MaxLength != nil && utf8.RuneCountInString(str) > *s.MaxLength {
+			return fmt.Errorf("%s: must be at most %d characters", path, *s.MaxLength)
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			return fmt.Errorf("%s: must be one of %s", path, strings.Join(s.Enum, ", "))
+		}
+	case TypeInteger, TypeNumber:
+		number, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: must be a %s", path, s.Type)
+		}
+		if s.Type == TypeInteger {
+			if _, err := number.Int64(); err != nil {
+				return fmt.Errorf("%s: must be an integer", path)
+			}
+		}
+		f, err := number.Float64()
+		if err != nil {
+			return fmt.Errorf("%s: must be a number", path)
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			return fmt.Errorf("%s: must be greater than or equal to %v", path, *s.Minimum)
+		}
+	case TypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: must be a boolean", path)
+		}
+	case TypeArray:
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: must be an array", path)
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case TypeObject:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: must be an object", path)
+		}
+		return s.validateObject(path, object)
+	}
+
+	return nil
+}
+
+func (s *Schema) validateObject(path string, object map[string]any) error {
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			return fmt.Errorf("%s.%s: is required", path, name)
+		}
+	}
+
+	// sorted so the reported error does not depend on map order
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fieldPath := path + "." + name
+
+		if property, ok := s.Properties[name]; ok {
+			if err := property.validate(fieldPath, object[name]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch additional := s.AdditionalProperties.(type) {
+		case bool:
+			if !additional {
+				return fmt.Errorf("%s: unknown field", fieldPath)
+			}
+		case *Schema:
+			if err := additional.validate(fieldPath, object[name]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}